@@ -5,11 +5,15 @@ import (
 	"blockchain-verifier/internal/blockchain"
 	"blockchain-verifier/internal/config"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -48,9 +52,20 @@ import (
 // #Endpoints
 // @description     Доступные конечные точки API:
 // @description     - POST /api/v1/deposit - Регистрация текста
+// @description     - GET /api/v1/jobs/{id} - Статус асинхронного депонирования
+// @description     - GET /deposit/progress/{id} - Страница ожидания депонирования
 // @description     - POST /api/v1/verify/id - Проверка по ID
 // @description     - POST /api/v1/verify/text - Проверка по тексту
+// @description     - GET /api/v1/keys/{fingerprint}/deposits - Работы автора по ключу
+// @description     - GET /keys/{fingerprint} - Страница автора
+// @description     - POST /api/v1/keys/records - Ротация и отзыв ключа автора
+// @description     - GET /api/v1/blocks/{id}/versions - Цепочка версий работы
+// @description     - GET /api/v1/cosign/{id} - Соавторы депозита
+// @description     - POST /api/v1/cosign/{id}/signatures - Подпись соавтора
+// @description     - POST /api/v1/notices - Уведомление о статусе работы
 // @description     - GET /api/v1/stats - Статистика
+// @description     - GET /api/v1/blocks/{height} - Блок по высоте
+// @description     - GET /api/v1/blocks/hash/{hash} - Блок по хешу
 //
 // @externalDocs.description  GitHub Repository
 // @externalDocs.url          https://github.com/mtzvd/textproof-go-verifier
@@ -66,21 +81,118 @@ func main() {
 
 	slog.Info("Конфигурация",
 		"data_dir", cfg.DataDir,
+		"storage", cfg.StorageBackend,
 		"port", cfg.Port,
 		"difficulty", cfg.Difficulty,
+		"difficulty_bits", cfg.DifficultyBits,
+		"target", cfg.Target,
+		"hash_alg", cfg.HashAlg,
+		"target_block_time", cfg.TargetBlockTime,
 		"debug", cfg.EnableDebug,
+		"recover", cfg.Recover,
+		"backup_keep", cfg.BackupKeep,
+		"backup_max_age", cfg.BackupMaxAge,
 	)
 
+	// Сухой прогон миграции: только отчёт, каталог данных не меняется
+	if cfg.MigrateDryRun {
+		plan, err := blockchain.PlanMigration(cfg.StorageBackend, cfg.DataDir)
+		if err != nil {
+			slog.Error("Не удалось составить план миграции", "error", err)
+			os.Exit(1)
+		}
+		printMigrationPlan(plan)
+		return
+	}
+
 	// Создаем хранилище
-	storage, err := blockchain.NewStorage(cfg.DataDir)
+	storeOpts := blockchain.StoreOptions{
+		Backups: blockchain.BackupPolicy{
+			Every:     cfg.BackupEvery,
+			KeepCount: cfg.BackupKeep,
+			MaxAge:    cfg.BackupMaxAge,
+		},
+	}
+	store, err := blockchain.OpenStore(cfg.StorageBackend, cfg.DataDir, storeOpts)
+	if errors.Is(err, blockchain.ErrDataDirLocked) {
+		slog.Error("Каталог данных уже используется другим экземпляром сервера", "error", err)
+		os.Exit(1)
+	}
+	if errors.Is(err, blockchain.ErrUnsupportedFormat) {
+		slog.Error("Данные записаны более новой версией сервера", "error", err)
+		os.Exit(1)
+	}
 	if err != nil {
 		slog.Error("Не удалось создать хранилище", "error", err)
 		os.Exit(1)
 	}
+	defer store.Close()
 
 	// Создаем блокчейн
-	bc, err := blockchain.NewBlockchain(storage, cfg.Difficulty)
+	var chainOpts []blockchain.Option
+	if cfg.Recover {
+		chainOpts = append(chainOpts, blockchain.WithRecovery(filepath.Join(cfg.DataDir, "quarantine")))
+	}
+	switch {
+	case cfg.Target != "":
+		target, err := blockchain.ParseTarget(cfg.Target)
+		if err != nil {
+			slog.Error("Неверная цель майнинга", "error", err)
+			os.Exit(1)
+		}
+		chainOpts = append(chainOpts, blockchain.WithTarget(target))
+	case cfg.DifficultyBits > 0:
+		chainOpts = append(chainOpts, blockchain.WithTarget(blockchain.TargetFromBits(cfg.DifficultyBits)))
+	}
+	if cfg.HashAlg != "" {
+		alg, err := blockchain.ParseHashAlgorithm(cfg.HashAlg)
+		if err != nil {
+			slog.Error("Неверный алгоритм хеша", "error", err)
+			os.Exit(1)
+		}
+		chainOpts = append(chainOpts, blockchain.WithHashAlgorithm(alg))
+	}
+	if cfg.Genesis != "" {
+		genesis, err := blockchain.LoadGenesis(cfg.Genesis)
+		if err != nil {
+			slog.Error("Неверный файл генезиса", "error", err)
+			os.Exit(1)
+		}
+		chainOpts = append(chainOpts, blockchain.WithGenesis(genesis))
+	}
+	if cfg.GenesisHash != "" {
+		chainOpts = append(chainOpts, blockchain.WithGenesisHash(cfg.GenesisHash))
+	}
+	if cfg.TargetBlockTime > 0 {
+		// Не легче -difficulty 1 и не труднее предела -difficulty-bits
+		chainOpts = append(chainOpts, blockchain.WithRetarget(blockchain.RetargetPolicy{
+			TargetTime: cfg.TargetBlockTime,
+			Interval:   cfg.RetargetInterval,
+			MinBits:    4,
+			MaxBits:    config.MaxDifficultyBits,
+		}))
+	}
+	// Шина событий создаётся до цепочки: так в лог попадут и сбои
+	// проверки при открытии. Лог не должен тормозить майнинг
+	events := blockchain.NewEventBus()
+	defer events.Close()
+	events.SubscribeFunc(blockchain.SubscribeOptions{
+		Types:        []blockchain.EventType{blockchain.EventValidationFailed, blockchain.EventChainRecovered},
+		Backpressure: blockchain.BackpressureDropOldest,
+	}, logChainEvent)
+	chainOpts = append(chainOpts, blockchain.WithEventBus(events))
+
+	bc, err := blockchain.NewBlockchain(store, cfg.Difficulty, chainOpts...)
 	if err != nil {
+		var bcErr *blockchain.BlockchainError
+		if errors.As(err, &bcErr) && bcErr.Code == "RECOVERY_REQUIRED" {
+			slog.Error("Цепочка повреждена, сервер не запущен. Сделайте копию каталога данных и перезапустите с флагом -recover", "error", err)
+			os.Exit(1)
+		}
+		if errors.Is(err, blockchain.ErrGenesisMismatch) {
+			slog.Error("Генезис цепочки не совпадает с -genesis-hash: каталог данных от другого экземпляра или подменён", "error", err)
+			os.Exit(1)
+		}
 		slog.Error("Не удалось создать блокчейн", "error", err)
 		os.Exit(1)
 	}
@@ -95,9 +207,45 @@ func main() {
 		logAttrs = append(logAttrs, "last_block", lastBlock)
 	}
 	slog.Info("Блокчейн загружен", logAttrs...)
+	if pinned, _ := info["genesis_pinned"].(bool); !pinned {
+		slog.Warn("Хеш генезиса не закреплён: подменённая цепочка пройдёт проверку. Задайте -genesis-hash", "genesis_hash", info["genesis_hash"])
+	}
+
+	// Майнинг в обработчиках и пакетах прерывается отменой
+	// miningCtx, если остановка сервера затянулась
+	miningCtx, stopMining := context.WithCancel(context.Background())
+	defer stopMining()
 
 	// Создаем API
 	apiHandler := api.NewAPI(bc)
+	var batcher *blockchain.Batcher
+	if cfg.BatchSize > 1 {
+		batcher = blockchain.NewBatcher(miningCtx, bc, blockchain.BatchOptions{
+			MaxSize: cfg.BatchSize,
+			MaxWait: cfg.BatchWait,
+		})
+		defer batcher.Close()
+		apiHandler.SetBatcher(batcher)
+		slog.Info("Пакетное депонирование включено", "batch_size", cfg.BatchSize, "batch_wait", cfg.BatchWait)
+	}
+
+	// Асинхронные задания. Закрываются раньше очереди пакетов:
+	// незавершённые остаются на диске и продолжатся после перезапуска
+	jobs, err := blockchain.NewJobManager(miningCtx, bc, batcher, cfg.DataDir)
+	if err != nil {
+		slog.Error("Не удалось загрузить задания на депонирование", "error", err)
+		os.Exit(1)
+	}
+	defer jobs.Close()
+	apiHandler.SetJobs(jobs)
+
+	// Работы с соавторами, ждущие их подписей
+	cosign, err := blockchain.NewCosignManager(cfg.DataDir)
+	if err != nil {
+		slog.Error("Не удалось загрузить подписи соавторов", "error", err)
+		os.Exit(1)
+	}
+	apiHandler.SetCosign(cosign)
 
 	// Настраиваем HTTP сервер
 	server := &http.Server{
@@ -106,6 +254,7 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  30 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return miningCtx },
 	}
 
 	// Запускаем тестовый сценарий в фоне, если включен debug
@@ -151,6 +300,9 @@ func main() {
 	slog.Info("Останавливаем сервер...")
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("Ошибка при остановке сервера", "error", err)
+		// Не дождались обработчиков - прерываем майнинг, чтобы
+		// отложенные Close не ждали его
+		stopMining()
 	}
 
 	slog.Info("Сервер остановлен")
@@ -189,3 +341,35 @@ func runTestScenario(bc *blockchain.Blockchain) error {
 
 	return nil
 }
+
+// printMigrationPlan выводит план миграции формата данных
+func printMigrationPlan(plan *blockchain.MigrationPlan) {
+	if !plan.Needed() {
+		fmt.Printf("%s: формат версии %d, миграция не нужна\n", plan.Path, plan.From)
+		return
+	}
+
+	fmt.Printf("%s: миграция формата v%d -> v%d\n", plan.Path, plan.From, plan.To)
+	for _, step := range plan.Steps {
+		fmt.Printf("  шаг %s\n", step)
+	}
+	fmt.Printf("Блоков: %d, изменится: %d\n", plan.Blocks, plan.ChangedBlocks)
+	fmt.Printf("Будут переписаны: %s\n", strings.Join(plan.Rewrite, ", "))
+	if plan.Originals != "" {
+		fmt.Printf("Исходные файлы будут сохранены в %s\n", plan.Originals)
+	}
+}
+
+// logChainEvent пишет в лог сбои проверки и восстановление цепочки
+func logChainEvent(ev blockchain.Event) {
+	attrs := []any{"event", ev.Type, "height", ev.Height}
+	if ev.Block != nil {
+		attrs = append(attrs, "block", ev.Block.ID)
+	}
+	switch ev.Type {
+	case blockchain.EventValidationFailed:
+		slog.Warn("Блок не прошёл проверку", append(attrs, "error", ev.Err)...)
+	case blockchain.EventChainRecovered:
+		slog.Warn("Цепочка восстановлена", attrs...)
+	}
+}
@@ -0,0 +1,1567 @@
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
+package docs
+
+import "github.com/swaggo/swag"
+
+const docTemplate = `{
+    "schemes": {{ marshal .Schemes }},
+    "swagger": "2.0",
+    "info": {
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "contact": {
+            "name": "TextProof",
+            "url": "https://textproof.ru",
+            "email": "info@textproof.ru"
+        },
+        "license": {
+            "name": "MIT",
+            "url": "https://github.com/mtzvd/textproof-go-verifier/blob/main/LICENSE"
+        },
+        "version": "{{.Version}}"
+    },
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
+    "paths": {
+        "/api/badge/{id}": {
+            "get": {
+                "description": "Генерирует HTML код badge для встраивания на веб-страницы",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Utils"
+                ],
+                "summary": "HTML Badge для встраивания",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"000-000-001\"",
+                        "description": "ID блока или ссылка на депозит",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML код badge",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Блок не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/qrcode/{id}": {
+            "get": {
+                "description": "Генерирует QR-код для быстрой проверки текста по ID блока",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "Utils"
+                ],
+                "summary": "Генерация QR-кода",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"000-000-001\"",
+                        "description": "ID блока или ссылка на депозит",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR-код в формате PNG",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Блок не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка генерации QR-кода",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/blockchain": {
+            "get": {
+                "description": "Возвращает техническую информацию о блокчейне",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Информация о блокчейне",
+                "responses": {
+                    "200": {
+                        "description": "Информация о блокчейне",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.BlockchainInfoResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/blockchain/export": {
+            "get": {
+                "description": "Отдаёт полную цепочку в формате JSON для независимой валидации",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Экспорт блокчейна",
+                "responses": {
+                    "200": {
+                        "description": "JSON-файл блокчейна",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/blocks/hash/{hash}": {
+            "get": {
+                "description": "Возвращает блок по его хешу (не по хешу содержимого)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Блок по хешу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш блока",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Блок",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.BlockResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Блок не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/blocks/{height}": {
+            "get": {
+                "description": "Возвращает блок на заданной высоте цепочки (генезис - 0)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Блок по высоте",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Высота блока",
+                        "name": "height",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Блок",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.BlockResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверная высота",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Блок не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/blocks/{id}/versions": {
+            "get": {
+                "description": "Возвращает все версии работы, в которую входит депозит: первую версию и следующие от неё (parent_id), в порядке цепочки с временем фиксации",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verify"
+                ],
+                "summary": "История версий работы",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID блока или депозита (ID.индекс)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.VersionsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Депозит не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/cosign/{id}": {
+            "get": {
+                "description": "Возвращает работу с соавторами, ожидающую подписей: сообщение для подписи и кто уже подписал. После записи в ответе есть депозит",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Сбор подписей соавторов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сбора подписей",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.CosignResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сбор подписей не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/cosign/{id}/signatures": {
+            "post": {
+                "description": "Добавляет подпись соавтора (signing_message из сбора подписей, подписанное его ключом). Когда подписали все соавторы с ключами, депозит записывается в цепочку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Подпись соавтора",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сбора подписей",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ключ и подпись соавтора",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.CosignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.CosignResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверная подпись или ключ не из списка соавторов",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сбор подписей не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Депозит уже записан или противоречит цепочке",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/deposit": {
+            "post": {
+                "description": "Регистрирует текст в блокчейне и возвращает JSON ответ.\nС async=true сразу отвечает 202 с заданием, статус которого доступен в /api/v1/jobs/{id}\nРаботу с соавторами (authors), которую подписали не все соавторы с ключами, отвечает 202 со сбором подписей в /api/v1/cosign/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Депонирование текста (JSON API)",
+                "parameters": [
+                    {
+                        "description": "Данные для регистрации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.DepositRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Не ждать майнинга, вернуть задание",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.DepositResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Ждёт подписей соавторов",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.CosignResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Текст уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/jobs/{id}": {
+            "get": {
+                "description": "Возвращает состояние асинхронного депонирования (queued, mining, committed, failed). После записи в ответе есть блок с депозитом",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Статус задания на депонирование",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.JobResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Задание не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/keys/records": {
+            "post": {
+                "description": "Записывает в цепочку переход авторства к новому ключу (key_rotation, подписан старым ключом) или отзыв ключа с момента revoked_at (key_revocation, подписан отзываемым ключом или его преемником).\nПодписывается то же сообщение, что и у депозита; content_hash - хеш тела записи алгоритмом цепочки, см. README",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Ротация или отзыв ключа автора",
+                "parameters": [
+                    {
+                        "description": "Запись о ключе",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.KeyRecordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.KeyRecordResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверная запись или подпись",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Запись противоречит цепочке ключей",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/keys/{fingerprint}/deposits": {
+            "get": {
+                "description": "Возвращает депозиты, подписанные ключом с данным отпечатком (SHA-256 от SubjectPublicKeyInfo в hex), в порядке цепочки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verify"
+                ],
+                "summary": "Работы автора по ключу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Отпечаток ключа",
+                        "name": "fingerprint",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.KeyDepositsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный отпечаток",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ключ не встречается в цепочке",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/notices": {
+            "post": {
+                "description": "Записывает в цепочку отзыв (retraction) или исправление (correction) депозита target с причиной reason. Запись подписывает ключ депозита или ключ, к которому он перешёл ротациями, либо вместо подписи передаётся account_token учётной записи депозита; депозит без подписи и без учётной записи отозвать нельзя.\nПодписывается то же сообщение, что и у депозита; content_hash - хеш тела записи алгоритмом цепочки, см. README",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Отзыв или исправление депозита",
+                "parameters": [
+                    {
+                        "description": "Отзыв или исправление",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.NoticeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.NoticeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверная запись или подпись",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Запись подписана не ключом и добавлена не из учётной записи депозита",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Депозит не найден",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ подписи отозван или передан другому ключу",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stats": {
+            "get": {
+                "description": "Возвращает общую информацию и статистику о состоянии блокчейна",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Статистика блокчейна",
+                "responses": {
+                    "200": {
+                        "description": "Статистика блокчейна",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.StatsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка получения статистики",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/verify/id": {
+            "post": {
+                "description": "Проверяет текст по ID блока (или ссылке на депозит пакетного блока) и возвращает JSON с доказательством включения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verify"
+                ],
+                "summary": "Проверка по ID (JSON API)",
+                "parameters": [
+                    {
+                        "description": "ID блока",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.VerifyByIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.VerificationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/verify/text": {
+            "post": {
+                "description": "Проверяет текст по содержимому и возвращает JSON с доказательством включения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verify"
+                ],
+                "summary": "Проверка по тексту (JSON API)",
+                "parameters": [
+                    {
+                        "description": "Текст для проверки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.VerifyByTextRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.VerificationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deposit/progress/{id}": {
+            "get": {
+                "description": "HTML-страница, опрашивающая статус задания. После записи блока перенаправляет на страницу результата",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Deposit"
+                ],
+                "summary": "Страница ожидания депонирования",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML страница ожидания",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "303": {
+                        "description": "Депозит записан, перенаправление на /deposit/result/{id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Задание не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/keys/{fingerprint}": {
+            "get": {
+                "description": "HTML-страница со всеми работами, подписанными ключом с данным отпечатком",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Verify"
+                ],
+                "summary": "Страница автора",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Отпечаток ключа",
+                        "name": "fingerprint",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML страница автора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "303": {
+                        "description": "Неверный отпечаток, перенаправление на /verify",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "blockchain-verifier_internal_viewmodels.AuthorInfo": {
+            "type": "object",
+            "properties": {
+                "key_fingerprint": {
+                    "type": "string"
+                },
+                "key_revoked": {
+                    "type": "boolean"
+                },
+                "key_type": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "signed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.BlockResponse": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "content_hash": {
+                    "type": "string"
+                },
+                "deposits": {
+                    "type": "integer"
+                },
+                "difficulty": {
+                    "description": "сложность в hex-нулях у блоков до целей",
+                    "type": "integer"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "description": "алгоритм хеша блока",
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merkle_root": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "integer"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "target": {
+                    "description": "цель Proof-of-Work",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "версия заголовка, от которого считается хеш",
+                    "type": "integer"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.BlockchainInfoResponse": {
+            "type": "object",
+            "properties": {
+                "difficulty": {
+                    "description": "сложность следующего блока в hex-нулях, округлённая вниз",
+                    "type": "integer"
+                },
+                "difficulty_bits": {
+                    "description": "она же в нулевых битах",
+                    "type": "integer"
+                },
+                "genesis_hash": {
+                    "description": "хеш генезиса: по нему отличают экземпляры",
+                    "type": "string"
+                },
+                "genesis_pinned": {
+                    "description": "хеш генезиса закреплён в конфигурации",
+                    "type": "boolean"
+                },
+                "hash_alg": {
+                    "description": "алгоритм хеша новых блоков и текстов",
+                    "type": "string"
+                },
+                "last_block": {
+                    "type": "string"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "target": {
+                    "description": "точная цель следующего блока",
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.CosignRequest": {
+            "type": "object",
+            "properties": {
+                "public_key": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.CosignResponse": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.AuthorInfo"
+                    }
+                },
+                "block_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deposit_ref": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "missing": {
+                    "description": "сколько подписей не хватает",
+                    "type": "integer"
+                },
+                "signing_message": {
+                    "description": "base64: сообщение, которое подписывает каждый соавтор",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "verify_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.DepositAuthor": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "подпись соавтора в base64, см. README",
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.DepositNotice": {
+            "type": "object",
+            "properties": {
+                "block_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "record_ref": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "retraction или correction",
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.DepositRequest": {
+            "type": "object",
+            "properties": {
+                "account_token": {
+                    "description": "Секретный токен учётной записи автора: в блок попадает только\nего хеш. Новую версию работы можно добавить с тем же токеном",
+                    "type": "string"
+                },
+                "author_name": {
+                    "type": "string"
+                },
+                "authors": {
+                    "description": "Соавторы: вместо author_name, public_key и signature. Депозит\nзаписывается, когда подпишут все соавторы с ключами",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.DepositAuthor"
+                    }
+                },
+                "parent_id": {
+                    "description": "ID прежней версии этой работы",
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "подпись автора в base64, см. README",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.DepositResponse": {
+            "type": "object",
+            "properties": {
+                "badge_url": {
+                    "type": "string"
+                },
+                "duplicate": {
+                    "type": "boolean"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "qrcode_url": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.JobResponse": {
+            "type": "object",
+            "properties": {
+                "block": {
+                    "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.BlockResponse"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deposit_ref": {
+                    "type": "string"
+                },
+                "duplicate": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "queued, mining, committed, failed",
+                    "type": "string"
+                },
+                "status_url": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "verify_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.KeyDeposit": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "block_id": {
+                    "type": "string"
+                },
+                "deposit_ref": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "verify_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.KeyDepositsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "deposits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.KeyDeposit"
+                    }
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "key_type": {
+                    "type": "string"
+                },
+                "predecessors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "profile_url": {
+                    "type": "string"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "successors": {
+                    "description": "Ротации: ключи, к которым перешло авторство (последний - текущий),\nи ключи, от которых оно перешло к этому (от ближайшего)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.KeyRecordRequest": {
+            "type": "object",
+            "properties": {
+                "new_key": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Ключ подписи",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "revoked_key": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.KeyRecordResponse": {
+            "type": "object",
+            "properties": {
+                "block_id": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "type": "string"
+                },
+                "record_ref": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.MerkleProof": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "description": "хеш-функция дерева",
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "leaf": {
+                    "type": "string"
+                },
+                "root": {
+                    "type": "string"
+                },
+                "siblings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.ProofStep"
+                    }
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.NoticeRequest": {
+            "type": "object",
+            "properties": {
+                "account_token": {
+                    "description": "Токен учётной записи депозита: вместо ключа и подписи",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Ключ подписи",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "target": {
+                    "description": "ID блока или депозита",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.NoticeResponse": {
+            "type": "object",
+            "properties": {
+                "block_id": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "type": "string"
+                },
+                "record_ref": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.ProofStep": {
+            "type": "object",
+            "properties": {
+                "hash": {
+                    "type": "string"
+                },
+                "left": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "chain_valid": {
+                    "type": "boolean"
+                },
+                "last_added": {
+                    "type": "string"
+                },
+                "total_blocks": {
+                    "type": "integer"
+                },
+                "total_deposits": {
+                    "type": "integer"
+                },
+                "unique_authors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.VerificationResponse": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "authors": {
+                    "description": "Соавторы работы",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.AuthorInfo"
+                    }
+                },
+                "block_hash": {
+                    "type": "string"
+                },
+                "block_id": {
+                    "type": "string"
+                },
+                "found": {
+                    "type": "boolean"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "description": "алгоритм хеша текста",
+                    "type": "string"
+                },
+                "key_fingerprint": {
+                    "type": "string"
+                },
+                "key_revoked": {
+                    "description": "Состояние ключа подписи по записям цепочки: отзыв и ротации\nк следующим ключам (последний - текущий ключ автора)",
+                    "type": "boolean"
+                },
+                "key_revoked_at": {
+                    "type": "string"
+                },
+                "key_successors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key_type": {
+                    "type": "string"
+                },
+                "matches": {
+                    "description": "Совпадает ли хеш",
+                    "type": "boolean"
+                },
+                "merkle_root": {
+                    "type": "string"
+                },
+                "notices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.DepositNotice"
+                    }
+                },
+                "parent_id": {
+                    "description": "Версии работы: прежняя версия этого депозита и вся история,\nесли версий больше одной",
+                    "type": "string"
+                },
+                "proof": {
+                    "description": "Нет у блоков без корня Меркла",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.MerkleProof"
+                        }
+                    ]
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "record_type": {
+                    "description": "Служебная запись о ключах, а не текст",
+                    "type": "string"
+                },
+                "retracted": {
+                    "description": "Отзыв и исправления депозита более поздними записями автора:\nretracted_by - ссылка на запись об отзыве",
+                    "type": "boolean"
+                },
+                "retracted_by": {
+                    "type": "string"
+                },
+                "signed": {
+                    "description": "Подпись автора: депозит подписан ключом с этим отпечатком",
+                    "type": "boolean"
+                },
+                "signed_after_revocation": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "description": "Депозит, который отзывает или исправляет эта запись, и причина",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.WorkVersion"
+                    }
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.VerifyByIDRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.VerifyByTextRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.VersionsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "deposit_ref": {
+                    "type": "string"
+                },
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/blockchain-verifier_internal_viewmodels.WorkVersion"
+                    }
+                }
+            }
+        },
+        "blockchain-verifier_internal_viewmodels.WorkVersion": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "deposit_ref": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "hash_alg": {
+                    "type": "string"
+                },
+                "key_fingerprint": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "номер с единицы в порядке цепочки",
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "tags": [
+        {
+            "description": "Операции депонирования (регистрации) текстов в блокчейне",
+            "name": "Deposit"
+        },
+        {
+            "description": "Операции проверки и верификации текстов",
+            "name": "Verify"
+        },
+        {
+            "description": "Статистика и информация о блокчейне",
+            "name": "Stats"
+        },
+        {
+            "description": "Вспомогательные утилиты (QR-коды, badges)",
+            "name": "Utils"
+        }
+    ],
+    "externalDocs": {
+        "description": "GitHub Repository",
+        "url": "https://github.com/mtzvd/textproof-go-verifier"
+    }
+}`
+
+// SwaggerInfo holds exported Swagger Info so clients can modify it
+var SwaggerInfo = &swag.Spec{
+	Version:          "1.0",
+	Host:             "textproof.ru",
+	BasePath:         "/",
+	Schemes:          []string{"https"},
+	Title:            "TextProof API",
+	Description:      "Доступные конечные точки API:\n- POST /api/v1/deposit - Регистрация текста\n- GET /api/v1/jobs/{id} - Статус асинхронного депонирования\n- GET /deposit/progress/{id} - Страница ожидания депонирования\n- POST /api/v1/verify/id - Проверка по ID\n- POST /api/v1/verify/text - Проверка по тексту\n- GET /api/v1/keys/{fingerprint}/deposits - Работы автора по ключу\n- GET /keys/{fingerprint} - Страница автора\n- POST /api/v1/keys/records - Ротация и отзыв ключа автора\n- GET /api/v1/blocks/{id}/versions - Цепочка версий работы\n- GET /api/v1/cosign/{id} - Соавторы депозита\n- POST /api/v1/cosign/{id}/signatures - Подпись соавтора\n- POST /api/v1/notices - Уведомление о статусе работы\n- GET /api/v1/stats - Статистика\n- GET /api/v1/blocks/{height} - Блок по высоте\n- GET /api/v1/blocks/hash/{hash} - Блок по хешу",
+	InfoInstanceName: "swagger",
+	SwaggerTemplate:  docTemplate,
+}
+
+func init() {
+	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
+}
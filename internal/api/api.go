@@ -18,13 +18,19 @@ import (
 const (
 	MaxTextLength   = 1_000_000
 	MaxAuthorLength = 200
+	MaxRoleLength   = 100
+	MaxAuthors      = 20
 	MaxTitleLength  = 500
+	MaxReasonLength = 1000
 	MaxBodySize     = 2 << 20 // 2MB
 )
 
 // API представляет собой HTTP API сервер
 type API struct {
 	blockchain *blockchain.Blockchain
+	batcher    *blockchain.Batcher       // nil - каждый депозит майнится своим блоком
+	jobs       *blockchain.JobManager    // nil - асинхронное депонирование выключено
+	cosign     *blockchain.CosignManager // nil - работы с соавторами принимаются только со всеми подписями
 	router     *mux.Router
 }
 
@@ -38,6 +44,24 @@ func NewAPI(bc *blockchain.Blockchain) *API {
 	return api
 }
 
+// SetBatcher включает пакетное депонирование: депозиты копятся
+// в очереди b и запечатываются общим блоком
+func (api *API) SetBatcher(b *blockchain.Batcher) {
+	api.batcher = b
+}
+
+// SetJobs включает асинхронное депонирование: форма и
+// POST /api/v1/deposit?async=true ставят депозит заданием в m
+func (api *API) SetJobs(m *blockchain.JobManager) {
+	api.jobs = m
+}
+
+// SetCosign включает сбор подписей соавторов: работа, которую подписали
+// не все соавторы с ключами, ждёт недостающих подписей в m
+func (api *API) SetCosign(m *blockchain.CosignManager) {
+	api.cosign = m
+}
+
 // setupRoutes настраивает маршруты API
 func (api *API) setupRoutes() {
 	// Глобальные middleware
@@ -51,6 +75,7 @@ func (api *API) setupRoutes() {
 	api.router.HandleFunc("/", api.handleHome).Methods("GET")
 	api.router.HandleFunc("/deposit", api.handleDepositPage).Methods("GET")
 	api.router.HandleFunc("/deposit/result/{id}", api.handleDepositResult).Methods("GET")
+	api.router.HandleFunc("/deposit/progress/{id}", api.handleDepositProgress).Methods("GET")
 	api.router.HandleFunc("/verify", api.handleVerifyPage).Methods("GET")
 	api.router.HandleFunc("/verify/{id}", api.handleVerifyDirectLink).Methods("GET")
 	api.router.HandleFunc("/verify/result/{id}", api.handleVerifyResultPage).Methods("GET")
@@ -58,6 +83,7 @@ func (api *API) setupRoutes() {
 	api.router.HandleFunc("/privacy", api.handlePrivacyPage).Methods("GET")
 	api.router.HandleFunc("/terms", api.handleTermsPage).Methods("GET")
 	api.router.HandleFunc("/docs", api.handleDocsPage).Methods("GET")
+	api.router.HandleFunc("/keys/{fingerprint}", api.handleKeyProfilePage).Methods("GET")
 	// API routes (с rate limiting и ограничением body)
 	api.router.HandleFunc("/api/deposit", rl.middleware(maxBody(MaxBodySize, api.handleDeposit))).Methods("POST")
 	api.router.HandleFunc("/api/verify/id", rl.middleware(maxBody(MaxBodySize, api.handleVerifyByIDSubmit))).Methods("POST")
@@ -69,9 +95,18 @@ func (api *API) setupRoutes() {
 	api.router.HandleFunc("/api/v1/deposit", rl.middleware(maxBody(MaxBodySize, api.handleDepositJSON))).Methods("POST")
 	api.router.HandleFunc("/api/v1/verify/id", rl.middleware(maxBody(MaxBodySize, api.handleVerifyByIDJSON))).Methods("POST")
 	api.router.HandleFunc("/api/v1/verify/text", rl.middleware(maxBody(MaxBodySize, api.handleVerifyByTextJSON))).Methods("POST")
+	api.router.HandleFunc("/api/v1/jobs/{id}", api.handleJobJSON).Methods("GET")
+	api.router.HandleFunc("/api/v1/cosign/{id}", api.handleCosignJSON).Methods("GET")
+	api.router.HandleFunc("/api/v1/cosign/{id}/signatures", rl.middleware(maxBody(MaxBodySize, api.handleCosignSignJSON))).Methods("POST")
 	api.router.HandleFunc("/api/v1/stats", api.handleStats).Methods("GET")
 	api.router.HandleFunc("/api/v1/blockchain", api.handleBlockchainInfo).Methods("GET")
 	api.router.HandleFunc("/api/v1/blockchain/export", api.handleBlockchainExport).Methods("GET")
+	api.router.HandleFunc("/api/v1/blocks/{height:[0-9]+}", api.handleBlockByHeight).Methods("GET")
+	api.router.HandleFunc("/api/v1/blocks/hash/{hash}", api.handleBlockByHash).Methods("GET")
+	api.router.HandleFunc("/api/v1/blocks/{id}/versions", api.handleVersionsJSON).Methods("GET")
+	api.router.HandleFunc("/api/v1/keys/{fingerprint}/deposits", api.handleKeyDepositsJSON).Methods("GET")
+	api.router.HandleFunc("/api/v1/keys/records", rl.middleware(maxBody(MaxBodySize, api.handleKeyRecordJSON))).Methods("POST")
+	api.router.HandleFunc("/api/v1/notices", rl.middleware(maxBody(MaxBodySize, api.handleNoticeJSON))).Methods("POST")
 
 	// Static files (embedded)
 	staticSub, _ := fs.Sub(web.StaticFS, "static")
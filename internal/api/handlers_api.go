@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/skip2/go-qrcode"
 
+	"blockchain-verifier/internal/blockchain"
 	"blockchain-verifier/internal/viewmodels"
 	"blockchain-verifier/web/templates/components"
 )
@@ -30,8 +33,17 @@ func (api *API) handleStats(w http.ResponseWriter, r *http.Request) {
 	authors := make(map[string]bool)
 	var lastAdded time.Time
 
+	deposits := 0
 	for _, block := range allBlocks {
-		authors[block.Data.AuthorName] = true
+		for _, data := range block.DepositList() {
+			if data.IsRecord() {
+				continue
+			}
+			for _, name := range data.AuthorList() {
+				authors[name] = true
+			}
+			deposits++
+		}
 		if block.Timestamp.After(lastAdded) {
 			lastAdded = block.Timestamp
 		}
@@ -39,9 +51,10 @@ func (api *API) handleStats(w http.ResponseWriter, r *http.Request) {
 
 	resp := viewmodels.StatsResponse{
 		TotalBlocks:   len(allBlocks),
+		TotalDeposits: deposits,
 		UniqueAuthors: len(authors),
 		LastAdded:     lastAdded,
-		ChainValid:    api.blockchain.ValidateChain(),
+		ChainValid:    api.blockchain.Valid(),
 	}
 
 	api.sendJSON(w, http.StatusOK, resp)
@@ -60,14 +73,21 @@ func (api *API) handleBlockchainInfo(w http.ResponseWriter, r *http.Request) {
 	info := api.blockchain.GetChainInfo()
 
 	resp := viewmodels.BlockchainInfoResponse{
-		Length:     info["length"].(int),
-		Difficulty: info["difficulty"].(int),
-		Valid:      info["valid"].(bool),
+		Length:         info["length"].(int),
+		Difficulty:     info["difficulty"].(int),
+		DifficultyBits: info["difficulty_bits"].(int),
+		Target:         info["target"].(string),
+		HashAlg:        info["hash_alg"].(string),
+		Valid:          info["valid"].(bool),
 	}
 
 	if lastBlock, ok := info["last_block"]; ok {
 		resp.LastBlock = lastBlock.(string)
 	}
+	if genesisHash, ok := info["genesis_hash"]; ok {
+		resp.GenesisHash = genesisHash.(string)
+		resp.GenesisPinned = info["genesis_pinned"].(bool)
+	}
 
 	api.sendJSON(w, http.StatusOK, resp)
 }
@@ -75,20 +95,93 @@ func (api *API) handleBlockchainInfo(w http.ResponseWriter, r *http.Request) {
 // handleBlockchainExport godoc
 //
 // @Summary      Экспорт блокчейна
-// @Description  Отдаёт полный файл блокчейна в формате JSON для независимой валидации
+// @Description  Отдаёт полную цепочку в формате JSON для независимой валидации
 // @Tags         Stats
 // @Produce      json
 // @Success      200 {string} string "JSON-файл блокчейна"
-// @Failure      500 {object} viewmodels.ErrorResponse "Файл не найден"
 // @Router       /api/v1/blockchain/export [get]
 func (api *API) handleBlockchainExport(w http.ResponseWriter, r *http.Request) {
-	path := api.blockchain.GetChainFilePath()
-	if path == "" {
-		api.sendError(w, http.StatusInternalServerError, "Blockchain file not available", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="blockchain.json"`)
+	if err := api.blockchain.ExportChain(w); err != nil {
+		slog.Error("Blockchain export error", "error", err)
+	}
+}
+
+// handleBlockByHeight godoc
+//
+// @Summary      Блок по высоте
+// @Description  Возвращает блок на заданной высоте цепочки (генезис - 0)
+// @Tags         Stats
+// @Produce      json
+// @Param        height path int true "Высота блока" example(1)
+// @Success      200 {object} viewmodels.BlockResponse "Блок"
+// @Failure      400 {object} viewmodels.ErrorResponse "Неверная высота"
+// @Failure      404 {object} viewmodels.ErrorResponse "Блок не найден"
+// @Router       /api/v1/blocks/{height} [get]
+func (api *API) handleBlockByHeight(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.Atoi(mux.Vars(r)["height"])
+	if err != nil || height < 0 {
+		api.sendError(w, http.StatusBadRequest, "Неверная высота блока", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	http.ServeFile(w, r, path)
+
+	block, err := api.blockchain.GetBlockByHeight(height)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Блок не найден", err)
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, blockResponse(height, block))
+}
+
+// handleBlockByHash godoc
+//
+// @Summary      Блок по хешу
+// @Description  Возвращает блок по его хешу (не по хешу содержимого)
+// @Tags         Stats
+// @Produce      json
+// @Param        hash path string true "Хеш блока"
+// @Success      200 {object} viewmodels.BlockResponse "Блок"
+// @Failure      404 {object} viewmodels.ErrorResponse "Блок не найден"
+// @Router       /api/v1/blocks/hash/{hash} [get]
+func (api *API) handleBlockByHash(w http.ResponseWriter, r *http.Request) {
+	hash := strings.ToLower(mux.Vars(r)["hash"])
+
+	block, err := api.blockchain.GetBlockByHash(hash)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Блок не найден", err)
+		return
+	}
+
+	height, err := api.blockchain.HeightOf(block.ID)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Блок не найден", err)
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, blockResponse(height, block))
+}
+
+// blockResponse собирает ответ API с блоком
+func blockResponse(height int, block *blockchain.Block) viewmodels.BlockResponse {
+	return viewmodels.BlockResponse{
+		Height:      height,
+		Version:     block.Version,
+		HashAlg:     string(block.HashAlgorithm()),
+		ID:          block.ID,
+		Hash:        block.Hash,
+		PrevHash:    block.PrevHash,
+		Timestamp:   block.Timestamp,
+		Nonce:       block.Nonce,
+		Author:      block.Data.AuthorName,
+		Title:       block.Data.Title,
+		ContentHash: block.Data.ContentHash,
+		MerkleRoot:  block.MerkleRoot,
+		Deposits:    len(block.DepositList()),
+		Difficulty:  block.Difficulty,
+		Target:      block.Target,
+	}
 }
 
 // handleQRCode godoc
@@ -97,7 +190,7 @@ func (api *API) handleBlockchainExport(w http.ResponseWriter, r *http.Request) {
 // @Description  Генерирует QR-код для быстрой проверки текста по ID блока
 // @Tags         Utils
 // @Produce      image/png
-// @Param        id path string true "ID блока" example("000-000-001")
+// @Param        id path string true "ID блока или ссылка на депозит" example("000-000-001")
 // @Success      200 {file} binary "QR-код в формате PNG"
 // @Failure      400 {object} viewmodels.ErrorResponse "Неверный ID"
 // @Failure      404 {object} viewmodels.ErrorResponse "Блок не найден"
@@ -108,8 +201,8 @@ func (api *API) handleQRCode(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Проверяем, существует ли блок
-	_, err := api.blockchain.GetBlockByID(id)
+	// Проверяем, существует ли депозит
+	_, err := api.blockchain.GetDepositByRef(id)
 	if err != nil {
 		api.sendError(w, http.StatusNotFound, "Блок не найден", err)
 		return
@@ -136,7 +229,7 @@ func (api *API) handleQRCode(w http.ResponseWriter, r *http.Request) {
 // @Description  Генерирует HTML код badge для встраивания на веб-страницы
 // @Tags         Utils
 // @Produce      text/html
-// @Param        id path string true "ID блока" example("000-000-001")
+// @Param        id path string true "ID блока или ссылка на депозит" example("000-000-001")
 // @Success      200 {string} string "HTML код badge"
 // @Failure      400 {object} viewmodels.ErrorResponse "Неверный ID"
 // @Failure      404 {object} viewmodels.ErrorResponse "Блок не найден"
@@ -146,8 +239,8 @@ func (api *API) handleBadge(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Ищем блок
-	block, err := api.blockchain.GetBlockByID(id)
+	// Ищем депозит
+	receipt, err := api.blockchain.GetDepositByRef(id)
 	if err != nil {
 		api.sendError(w, http.StatusNotFound, "Блок не найден", err)
 		return
@@ -163,12 +256,13 @@ func (api *API) handleBadge(w http.ResponseWriter, r *http.Request) {
 
 	// Рендерим templ-компонент
 	err = components.Badge(
-		block.Data.Title,
-		block.Data.AuthorName,
-		block.ID,
+		receipt.Data.Title,
+		badgeAuthors(receipt.Data),
+		receipt.Ref,
 		qrCodeURL,
-		block.Timestamp.Format("02.01.2006 15:04"),
+		receipt.Block.Timestamp.Format("02.01.2006 15:04"),
 		verifyURL,
+		api.retractedBy(receipt),
 	).Render(r.Context(), w)
 
 	if err != nil {
@@ -176,3 +270,19 @@ func (api *API) handleBadge(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to render badge", http.StatusInternalServerError)
 	}
 }
+
+// badgeAuthors возвращает авторов для значка: соавторов с ролями
+// или имя единственного автора
+func badgeAuthors(data blockchain.DepositData) []string {
+	if !data.Coauthored() {
+		return []string{data.AuthorName}
+	}
+	authors := make([]string, len(data.Authors))
+	for i, a := range data.Authors {
+		authors[i] = a.Name
+		if a.Role != "" {
+			authors[i] += " (" + a.Role + ")"
+		}
+	}
+	return authors
+}
@@ -119,3 +119,77 @@ func TestAPI_HandleQRCode_NotFound(t *testing.T) {
 	// Должна быть ошибка
 	testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
 }
+
+func TestAPI_HandleBlockByHeight(t *testing.T) {
+	storage := blockchain.NewTestStorage()
+	bc := blockchain.NewBlockchainWithStorage(storage, 1)
+	api := NewAPI(bc)
+
+	block, _ := bc.AddBlock(blockchain.CreateTestBlock("Author", "Title", "Block by height"))
+
+	t.Run("existing height", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/1", nil)
+		resp := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"height": "1"})
+
+		api.handleBlockByHeight(resp, req)
+
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		var got viewmodels.BlockResponse
+		testutil.ParseJSONResponse(t, resp, &got)
+		testutil.AssertEqual(t, got.Height, 1, "height")
+		testutil.AssertEqual(t, got.ID, block.ID, "block ID")
+		testutil.AssertEqual(t, got.Hash, block.Hash, "block hash")
+	})
+
+	t.Run("height above tip", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/5", nil)
+		resp := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"height": "5"})
+
+		api.handleBlockByHeight(resp, req)
+
+		testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
+	})
+
+	t.Run("routed through router", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/0", nil)
+		resp := httptest.NewRecorder()
+
+		api.ServeHTTP(resp, req)
+
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	})
+}
+
+func TestAPI_HandleBlockByHash(t *testing.T) {
+	storage := blockchain.NewTestStorage()
+	bc := blockchain.NewBlockchainWithStorage(storage, 1)
+	api := NewAPI(bc)
+
+	block, _ := bc.AddBlock(blockchain.CreateTestBlock("Author", "Title", "Block by hash"))
+
+	t.Run("existing hash", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/hash/"+block.Hash, nil)
+		resp := httptest.NewRecorder()
+
+		api.ServeHTTP(resp, req)
+
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		var got viewmodels.BlockResponse
+		testutil.ParseJSONResponse(t, resp, &got)
+		testutil.AssertEqual(t, got.Height, 1, "height")
+		testutil.AssertEqual(t, got.ID, block.ID, "block ID")
+	})
+
+	t.Run("unknown hash", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/hash/deadbeef", nil)
+		resp := httptest.NewRecorder()
+
+		api.ServeHTTP(resp, req)
+
+		testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
+	})
+}
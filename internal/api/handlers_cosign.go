@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+// openCosign начинает сбор подписей соавторов и отвечает 202
+// со ссылкой на него. Хеши text всеми алгоритмами сохраняются, чтобы
+// при записи найти копию текста, даже если алгоритм цепочки сменится
+func (api *API) openCosign(w http.ResponseWriter, r *http.Request, data blockchain.DepositData, text string) {
+	if api.cosign == nil {
+		api.sendError(w, http.StatusBadRequest, "Сбор подписей соавторов не включён: нужны подписи всех соавторов с ключами", nil)
+		return
+	}
+
+	c, err := api.cosign.Open(data, blockchain.HashText([]byte(text), blockchain.HashAlgorithms()...))
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось начать сбор подписей соавторов", err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/cosign/%s", c.ID))
+	api.sendJSON(w, http.StatusAccepted, api.cosignResponse(r, c))
+}
+
+// handleCosignJSON godoc
+//
+// @Summary      Сбор подписей соавторов
+// @Description  Возвращает работу с соавторами, ожидающую подписей: сообщение для подписи и кто уже подписал. После записи в ответе есть депозит
+// @Tags         Deposit
+// @Produce      json
+// @Param        id path string true "ID сбора подписей"
+// @Success      200 {object} viewmodels.CosignResponse
+// @Failure      404 {object} viewmodels.ErrorResponse "Сбор подписей не найден"
+// @Router       /api/v1/cosign/{id} [get]
+func (api *API) handleCosignJSON(w http.ResponseWriter, r *http.Request) {
+	if api.cosign == nil {
+		api.sendError(w, http.StatusNotFound, "Сбор подписей соавторов не включён", nil)
+		return
+	}
+
+	c, err := api.cosign.Get(mux.Vars(r)["id"])
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Сбор подписей не найден", err)
+		return
+	}
+	api.sendJSON(w, http.StatusOK, api.cosignResponse(r, c))
+}
+
+// handleCosignSignJSON godoc
+//
+// @Summary      Подпись соавтора
+// @Description  Добавляет подпись соавтора (signing_message из сбора подписей, подписанное его ключом). Когда подписали все соавторы с ключами, депозит записывается в цепочку
+// @Tags         Deposit
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "ID сбора подписей"
+// @Param        request body viewmodels.CosignRequest true "Ключ и подпись соавтора"
+// @Success      200 {object} viewmodels.CosignResponse
+// @Failure      400 {object} viewmodels.ErrorResponse "Неверная подпись или ключ не из списка соавторов"
+// @Failure      404 {object} viewmodels.ErrorResponse "Сбор подписей не найден"
+// @Failure      409 {object} viewmodels.ErrorResponse "Депозит уже записан или противоречит цепочке"
+// @Failure      500 {object} viewmodels.ErrorResponse
+// @Router       /api/v1/cosign/{id}/signatures [post]
+func (api *API) handleCosignSignJSON(w http.ResponseWriter, r *http.Request) {
+	if api.cosign == nil {
+		api.sendError(w, http.StatusNotFound, "Сбор подписей соавторов не включён", nil)
+		return
+	}
+
+	var req viewmodels.CosignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.sendError(w, http.StatusBadRequest, "Неверный формат JSON", err)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	c, err := api.cosign.Sign(id, req.PublicKey, req.Signature)
+	switch {
+	case errors.Is(err, blockchain.ErrCosignNotFound):
+		api.sendError(w, http.StatusNotFound, "Сбор подписей не найден", err)
+		return
+	case errors.Is(err, blockchain.ErrCosignCommitted):
+		api.sendError(w, http.StatusConflict, "Депозит уже записан", err)
+		return
+	case err != nil:
+		api.sendError(w, http.StatusBadRequest, "Подпись соавтора не прошла проверку", err)
+		return
+	}
+
+	if !c.Complete() {
+		api.sendJSON(w, http.StatusOK, api.cosignResponse(r, c))
+		return
+	}
+
+	// Подписали все: депозит проверяется по цепочке и записывается
+	data := c.Deposit
+	if err := api.checkChain(data); err != nil {
+		api.sendError(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	hashes := c.TextHashes
+	if len(hashes) == 0 {
+		// Сбор подписей начат до сохранения хешей текста
+		hashes = blockchain.TextHashes{data.ContentHashAlgorithm(): data.ContentHash}
+	}
+	if _, err := api.blockchain.FindDepositByHashes(hashes); err == nil {
+		api.sendError(w, http.StatusConflict, "Текст уже существует в блокчейне", nil)
+		return
+	}
+
+	receipt, err := api.deposit(r.Context(), data)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось добавить блок", err)
+		return
+	}
+	if err := api.cosign.Commit(id, receipt); err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось сохранить сбор подписей", err)
+		return
+	}
+	c.Status, c.BlockID, c.Ref = blockchain.CosignCommitted, receipt.Block.ID, receipt.Ref
+
+	api.sendJSON(w, http.StatusOK, api.cosignResponse(r, c))
+}
+
+// cosignResponse переводит сбор подписей в модель ответа
+func (api *API) cosignResponse(r *http.Request, c *blockchain.Cosign) viewmodels.CosignResponse {
+	resp := viewmodels.CosignResponse{
+		ID:             c.ID,
+		Status:         string(c.Status),
+		SigningMessage: base64.StdEncoding.EncodeToString(c.Deposit.SigningMessage()),
+		Authors:        api.authorInfos(c.Deposit.Authors),
+		Missing:        len(c.Deposit.MissingSignatures()),
+		CreatedAt:      c.CreatedAt,
+	}
+	if c.Status == blockchain.CosignCommitted {
+		resp.BlockID = c.BlockID
+		resp.DepositRef = c.Ref
+		resp.VerifyURL = fmt.Sprintf("%s/verify/%s", getBaseURL(r), c.Ref)
+	}
+	return resp
+}
+
+// authorInfos переводит соавторов в модель ответа с отпечатками
+// и состоянием ключей
+func (api *API) authorInfos(authors []blockchain.Author) []viewmodels.AuthorInfo {
+	infos := make([]viewmodels.AuthorInfo, len(authors))
+	for i, a := range authors {
+		infos[i] = viewmodels.AuthorInfo{Name: a.Name, Role: a.Role, Signed: a.Signed()}
+		if a.PublicKey == "" {
+			continue
+		}
+		if key, err := blockchain.ParseAuthorKey(a.PublicKey); err == nil {
+			infos[i].KeyType = string(key.Type)
+			infos[i].KeyFingerprint = key.Fingerprint
+			infos[i].KeyRevoked = api.blockchain.KeyStatus(key.Fingerprint).Revoked
+		}
+	}
+	return infos
+}
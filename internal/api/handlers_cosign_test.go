@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/testutil"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCoauthoredDeposit(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+	cosign, err := blockchain.NewCosignManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCosignManager() error = %v", err)
+	}
+	api.SetCosign(cosign)
+
+	newKey := func() (ed25519.PrivateKey, string) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		return priv, base64.StdEncoding.EncodeToString(pub)
+	}
+	alice, aliceKey := newKey()
+	bob, bobKey := newKey()
+	mallory, malloryKey := newKey()
+
+	req := viewmodels.DepositRequest{
+		Title: "Joint novel",
+		Text:  "Text written by two authors and illustrated by a third",
+		Authors: []viewmodels.DepositAuthor{
+			{Name: "Alice", Role: "автор", PublicKey: aliceKey},
+			{Name: "Bob", Role: "переводчик", PublicKey: bobKey},
+			{Name: "Carol", Role: "иллюстратор"},
+		},
+	}
+	message := api.depositData(req).SigningMessage()
+	req.Authors[0].Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(alice, message))
+
+	post := func(handler http.HandlerFunc, path, id string, body interface{}) *httptest.ResponseRecorder {
+		r := testutil.HTTPTestRequest("POST", path, testutil.CreateJSONBody(t, body))
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		resp := httptest.NewRecorder()
+		handler(resp, r)
+		return resp
+	}
+
+	t.Run("without cosign manager", func(t *testing.T) {
+		resp := post(NewAPI(bc).handleDepositJSON, "/api/v1/deposit", "", req)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("deposit key with co-authors", func(t *testing.T) {
+		bad := req
+		bad.PublicKey = aliceKey
+		resp := post(api.handleDepositJSON, "/api/v1/deposit", "", bad)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	resp := post(api.handleDepositJSON, "/api/v1/deposit", "", req)
+	testutil.AssertStatusCode(t, resp.Code, http.StatusAccepted)
+	var pending viewmodels.CosignResponse
+	testutil.ParseJSONResponse(t, resp, &pending)
+	testutil.AssertEqual(t, pending.Status, "pending", "status")
+	testutil.AssertEqual(t, pending.Missing, 1, "missing signatures")
+	testutil.AssertEqual(t, resp.Header().Get("Location"), "/api/v1/cosign/"+pending.ID, "location")
+	testutil.AssertEqual(t, pending.SigningMessage, base64.StdEncoding.EncodeToString(message), "signing message")
+
+	sign := func(priv ed25519.PrivateKey, publicKey string) *httptest.ResponseRecorder {
+		body := viewmodels.CosignRequest{PublicKey: publicKey, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, message))}
+		return post(api.handleCosignSignJSON, "/api/v1/cosign/"+pending.ID+"/signatures", pending.ID, body)
+	}
+
+	t.Run("not a co-author", func(t *testing.T) {
+		testutil.AssertStatusCode(t, sign(mallory, malloryKey).Code, http.StatusBadRequest)
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		body := viewmodels.CosignRequest{PublicKey: bobKey, Signature: "c2ln"}
+		resp := post(api.handleCosignSignJSON, "/api/v1/cosign/missing/signatures", "missing", body)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
+	})
+
+	resp = sign(bob, bobKey)
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	var committed viewmodels.CosignResponse
+	testutil.ParseJSONResponse(t, resp, &committed)
+	testutil.AssertEqual(t, committed.Status, "committed", "status")
+	testutil.AssertEqual(t, committed.Missing, 0, "missing signatures")
+	if committed.DepositRef == "" {
+		t.Fatal("committed co-authored deposit has no deposit_ref")
+	}
+
+	t.Run("signed again", func(t *testing.T) {
+		testutil.AssertStatusCode(t, sign(bob, bobKey).Code, http.StatusConflict)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		r := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/cosign/"+pending.ID, nil), map[string]string{"id": pending.ID})
+		resp := httptest.NewRecorder()
+		api.handleCosignJSON(resp, r)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+		var out viewmodels.CosignResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.DepositRef, committed.DepositRef, "deposit ref")
+	})
+
+	t.Run("verify lists authors", func(t *testing.T) {
+		resp := post(api.handleVerifyByIDJSON, "/api/v1/verify/id", "", viewmodels.VerifyByIDRequest{ID: committed.DepositRef})
+		var out viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.Author, "Alice, Bob, Carol", "author")
+		if len(out.Authors) != 3 {
+			t.Fatalf("authors = %v, want 3", out.Authors)
+		}
+		testutil.AssertEqual(t, out.Authors[1].Role, "переводчик", "role")
+		testutil.AssertEqual(t, out.Authors[1].Signed, true, "co-author signed")
+		testutil.AssertEqual(t, out.Authors[2].KeyFingerprint, "", "co-author without key")
+	})
+
+	t.Run("badge lists authors", func(t *testing.T) {
+		r := mux.SetURLVars(httptest.NewRequest("GET", "/api/badge/"+committed.DepositRef, nil), map[string]string{"id": committed.DepositRef})
+		resp := httptest.NewRecorder()
+		api.handleBadge(resp, r)
+		body := resp.Body.String()
+		testutil.AssertContains(t, body, "Авторы:")
+		for _, name := range []string{"Alice (автор)", "Bob (переводчик)", "Carol (иллюстратор)"} {
+			if !strings.Contains(body, name) {
+				t.Errorf("badge does not list %q", name)
+			}
+		}
+	})
+
+	t.Run("key profile", func(t *testing.T) {
+		key, _ := blockchain.ParseAuthorKey(bobKey)
+		r := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/keys/"+key.Fingerprint+"/deposits", nil), map[string]string{"fingerprint": key.Fingerprint})
+		resp := httptest.NewRecorder()
+		api.handleKeyDepositsJSON(resp, r)
+		var out viewmodels.KeyDepositsResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.Count, 1, "works of co-author key")
+		testutil.AssertEqual(t, out.KeyType, "ed25519", "key type")
+	})
+}
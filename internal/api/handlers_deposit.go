@@ -1,8 +1,7 @@
 package api
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -32,11 +31,17 @@ func (api *API) handleDepositPage(w http.ResponseWriter, r *http.Request) {
 
 // Функция валидации депозита
 func (api *API) validateDepositRequest(req viewmodels.DepositRequest) error {
-	if strings.TrimSpace(req.AuthorName) == "" {
-		return fmt.Errorf("имя автора не может быть пустым")
-	}
-	if len(req.AuthorName) > MaxAuthorLength {
-		return fmt.Errorf("имя автора слишком длинное (макс %d символов)", MaxAuthorLength)
+	if len(req.Authors) > 0 {
+		if err := validateAuthors(req); err != nil {
+			return err
+		}
+	} else {
+		if strings.TrimSpace(req.AuthorName) == "" {
+			return fmt.Errorf("имя автора не может быть пустым")
+		}
+		if len(req.AuthorName) > MaxAuthorLength {
+			return fmt.Errorf("имя автора слишком длинное (макс %d символов)", MaxAuthorLength)
+		}
 	}
 
 	if strings.TrimSpace(req.Title) == "" {
@@ -53,9 +58,109 @@ func (api *API) validateDepositRequest(req viewmodels.DepositRequest) error {
 		return fmt.Errorf("текст слишком длинный (макс %d символов)", MaxTextLength)
 	}
 
+	if req.AccountToken != "" && len(req.AccountToken) < blockchain.MinAccountTokenLength {
+		return fmt.Errorf("токен учётной записи слишком короткий (мин %d символов)", blockchain.MinAccountTokenLength)
+	}
+
+	// Подписи проверяются по тем же данным, что попадут в блок
+	data := api.depositData(req)
+	pending := len(data.MissingSignatures()) > 0
+
+	// Новую версию работы вправе добавить только владелец ключа или
+	// учётной записи прежней
+	if req.ParentID != "" && len(data.SignerKeys()) == 0 && data.Account == "" && !pending {
+		return fmt.Errorf("новую версию работы нужно подписать ключом прежней версии или добавить из её учётной записи")
+	}
+
+	if data.Coauthored() {
+		if err := data.CheckAuthors(false); err != nil {
+			return fmt.Errorf("подписи соавторов не прошли проверку: %v", err)
+		}
+		// Цепочка проверяется, когда соберутся все подписи
+		if pending {
+			return nil
+		}
+	} else if req.Signature != "" {
+		if strings.TrimSpace(req.PublicKey) == "" {
+			return fmt.Errorf("для подписи нужен публичный ключ")
+		}
+		if _, err := data.VerifySignature(); err != nil {
+			return fmt.Errorf("подпись автора не прошла проверку: %v", err)
+		}
+	}
+
+	return api.checkChain(data)
+}
+
+// validateAuthors проверяет список соавторов запроса
+func validateAuthors(req viewmodels.DepositRequest) error {
+	if len(req.Authors) > MaxAuthors {
+		return fmt.Errorf("слишком много соавторов (макс %d)", MaxAuthors)
+	}
+	if req.PublicKey != "" || req.Signature != "" {
+		return fmt.Errorf("у работы с соавторами ключи и подписи указываются для каждого соавтора")
+	}
+	for i, author := range req.Authors {
+		if strings.TrimSpace(author.Name) == "" {
+			return fmt.Errorf("имя соавтора %d не может быть пустым", i+1)
+		}
+		if len(author.Name) > MaxAuthorLength {
+			return fmt.Errorf("имя соавтора %d слишком длинное (макс %d символов)", i+1, MaxAuthorLength)
+		}
+		if len(author.Role) > MaxRoleLength {
+			return fmt.Errorf("роль соавтора %d слишком длинная (макс %d символов)", i+1, MaxRoleLength)
+		}
+	}
 	return nil
 }
 
+// checkChain проверяет депозит по состоянию цепочки: ключи подписи
+// и право на новую версию работы
+func (api *API) checkChain(data blockchain.DepositData) error {
+	if len(data.SignerKeys()) > 0 {
+		if err := api.blockchain.CheckKeys(data); err != nil {
+			return fmt.Errorf("ключ подписи отозван или передан другому ключу: %v", err)
+		}
+	}
+	if data.ParentID != "" {
+		if err := api.blockchain.CheckVersion(data); err != nil {
+			return fmt.Errorf("нельзя добавить новую версию работы: %v", err)
+		}
+	}
+	return nil
+}
+
+// depositData собирает данные депозита из запроса: хеш текста
+// алгоритмом новых депозитов и фрагменты начала и конца текста
+func (api *API) depositData(req viewmodels.DepositRequest) blockchain.DepositData {
+	hashAlg := api.blockchain.HashAlgorithm()
+	data := blockchain.DepositData{
+		AuthorName:  req.AuthorName,
+		Title:       req.Title,
+		TextStart:   extractTextFragment(req.Text, 3, true),
+		TextEnd:     extractTextFragment(req.Text, 3, false),
+		ContentHash: hashAlg.ContentHash([]byte(req.Text)),
+		PublicKey:   req.PublicKey,
+
+		ContentHashAlg: hashAlg,
+		Signature:      req.Signature,
+		ParentID:       req.ParentID,
+	}
+	if req.AccountToken != "" {
+		data.Account = blockchain.AccountID(req.AccountToken)
+	}
+
+	// Имя автора работы с соавторами - их имена через запятую
+	if len(req.Authors) > 0 {
+		data.Authors = make([]blockchain.Author, len(req.Authors))
+		for i, a := range req.Authors {
+			data.Authors[i] = blockchain.Author{Name: a.Name, Role: a.Role, PublicKey: a.PublicKey, Signature: a.Signature}
+		}
+		data.AuthorName = blockchain.AuthorNames(data.Authors)
+	}
+	return data
+}
+
 // handleDeposit обрабатывает запрос на депонирование текста
 func (api *API) handleDeposit(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -68,6 +173,10 @@ func (api *API) handleDeposit(w http.ResponseWriter, r *http.Request) {
 		Title:      r.FormValue("title"),
 		Text:       r.FormValue("text"),
 		PublicKey:  r.FormValue("public_key"),
+		Signature:  strings.TrimSpace(r.FormValue("signature")),
+		ParentID:   strings.TrimSpace(r.FormValue("parent_id")),
+
+		AccountToken: r.FormValue("account_token"),
 	}
 
 	// Валидация
@@ -76,51 +185,60 @@ func (api *API) handleDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Вычисляем хеш текста
-	hash := sha256.Sum256([]byte(req.Text))
-	contentHash := hex.EncodeToString(hash[:])
-
-	_, existedBefore := api.blockchain.HasContentHash(contentHash)
+	// Создаем данные для блока: хеш и фрагменты текста
+	data := api.depositData(req)
 
-	// Извлекаем фрагменты текста (первые и последние 2-3 слова)
-	textStart := extractTextFragment(req.Text, 3, true)
-	textEnd := extractTextFragment(req.Text, 3, false)
+	// Дубликат, в том числе записанный другим алгоритмом, не записывается
+	// повторно: показываем прежний депозит
+	if existing, err := api.blockchain.FindDepositByText([]byte(req.Text)); err == nil {
+		setFlash(w, "warning", "duplicate", map[string]string{"duplicate": "true"})
+		http.Redirect(w, r, fmt.Sprintf("/deposit/result/%s", existing.Ref), http.StatusSeeOther)
+		return
+	}
 
-	// Создаем данные для блока
-	data := blockchain.DepositData{
-		AuthorName:  req.AuthorName,
-		Title:       req.Title,
-		TextStart:   textStart,
-		TextEnd:     textEnd,
-		ContentHash: contentHash,
-		PublicKey:   req.PublicKey,
+	// Асинхронный режим: страница прогресса опрашивает задание
+	if api.jobs != nil {
+		job, err := api.jobs.Submit(data)
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Не удалось создать задание", err)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/deposit/progress/%s", job.ID), http.StatusSeeOther)
+		return
 	}
 
 	// Добавляем блок в цепочку
-	block, err := api.blockchain.AddBlock(data)
+	receipt, err := api.deposit(r.Context(), data)
 	if err != nil {
 		api.sendError(w, http.StatusInternalServerError, "Не удалось добавить блок", err)
 		return
 	}
 
 	//Устанавливаем flash message вместо query параметров
-	flashData := make(map[string]string)
-	if existedBefore {
-		flashData["duplicate"] = "true"
-		setFlash(w, "warning", "duplicate", flashData)
-	} else {
-		setFlash(w, "success", "new_deposit", flashData)
-	}
+	setFlash(w, "success", "new_deposit", make(map[string]string))
 
-	redirectURL := fmt.Sprintf("/deposit/result/%s", block.ID)
+	redirectURL := fmt.Sprintf("/deposit/result/%s", receipt.Ref)
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// deposit записывает депозит: через очередь пакетов, если она
+// включена, иначе отдельным блоком
+func (api *API) deposit(ctx context.Context, data blockchain.DepositData) (*blockchain.DepositReceipt, error) {
+	if api.batcher != nil {
+		return api.batcher.Submit(ctx, data)
+	}
+
+	if _, err := api.blockchain.AddBlockContext(ctx, data); err != nil {
+		return nil, err
+	}
+	return api.blockchain.FindDeposit(data.ContentHash)
+}
+
 // handleDepositResult обрабатывает страницу результата депонирования
 func (api *API) handleDepositResult(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	block, err := api.blockchain.GetBlockByID(id)
+	receipt, err := api.blockchain.GetDepositByRef(id)
 	if err != nil {
 		api.sendError(w, http.StatusNotFound, "Результат депозита не найден", err)
 		return
@@ -130,7 +248,7 @@ func (api *API) handleDepositResult(w http.ResponseWriter, r *http.Request) {
 	flashData := getFlashData(r, w)
 
 	nav := mapNavBar(viewmodels.BuildHomeNavBar(r))
-	formattedTime := block.Timestamp.Format("02.01.2006 15:04:05")
+	formattedTime := receipt.Block.Timestamp.Format("02.01.2006 15:04:05")
 
 	api.renderHTML(
 		w,
@@ -139,14 +257,14 @@ func (api *API) handleDepositResult(w http.ResponseWriter, r *http.Request) {
 			viewmodels.PageMeta{Title: "Результат депонирования", Description: "Информация о зафиксированном тексте в блокчейне TextProof"},
 			nav,
 			templates.DepositResultPage(
-				block.ID,
-				block.Data.ContentHash,
+				receipt.Ref,
+				receipt.Data.ContentHash,
 				formattedTime,
-				fmt.Sprintf("%s/api/qrcode/%s", getBaseURL(r), block.ID),
-				fmt.Sprintf("%s/verify/%s", getBaseURL(r), block.ID),
-				fmt.Sprintf("%s/api/badge/%s", getBaseURL(r), block.ID),
-				block.Data.AuthorName,
-				block.Data.Title,
+				fmt.Sprintf("%s/api/qrcode/%s", getBaseURL(r), receipt.Ref),
+				fmt.Sprintf("%s/verify/%s", getBaseURL(r), receipt.Ref),
+				fmt.Sprintf("%s/api/badge/%s", getBaseURL(r), receipt.Ref),
+				receipt.Data.AuthorName,
+				receipt.Data.Title,
 				flashData,
 			),
 		),
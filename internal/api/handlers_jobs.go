@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/viewmodels"
+	"blockchain-verifier/web/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// handleJobJSON godoc
+//
+// @Summary      Статус задания на депонирование
+// @Description  Возвращает состояние асинхронного депонирования (queued, mining, committed, failed). После записи в ответе есть блок с депозитом
+// @Tags         Deposit
+// @Produce      json
+// @Param        id path string true "ID задания"
+// @Success      200 {object} viewmodels.JobResponse
+// @Failure      404 {object} viewmodels.ErrorResponse "Задание не найдено"
+// @Router       /api/v1/jobs/{id} [get]
+func (api *API) handleJobJSON(w http.ResponseWriter, r *http.Request) {
+	job, ok := api.findJob(w, r)
+	if !ok {
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, api.jobResponse(r, job))
+}
+
+// handleDepositProgress показывает страницу ожидания асинхронного
+// депозита, а записанный депозит перенаправляет на страницу результата
+//
+// @Summary      Страница ожидания депонирования
+// @Description  HTML-страница, опрашивающая статус задания. После записи блока перенаправляет на страницу результата
+// @Tags         Deposit
+// @Produce      text/html
+// @Param        id path string true "ID задания"
+// @Success      200 {string} string "HTML страница ожидания"
+// @Success      303 {string} string "Депозит записан, перенаправление на /deposit/result/{id}"
+// @Failure      404 {object} viewmodels.ErrorResponse "Задание не найдено"
+// @Router       /deposit/progress/{id} [get]
+func (api *API) handleDepositProgress(w http.ResponseWriter, r *http.Request) {
+	job, ok := api.findJob(w, r)
+	if !ok {
+		return
+	}
+
+	if job.Status == blockchain.JobCommitted {
+		if job.Duplicate {
+			setFlash(w, "warning", "duplicate", map[string]string{"duplicate": "true"})
+		} else {
+			setFlash(w, "success", "new_deposit", map[string]string{})
+		}
+		http.Redirect(w, r, fmt.Sprintf("/deposit/result/%s", job.Ref), http.StatusSeeOther)
+		return
+	}
+
+	nav := mapNavBar(viewmodels.BuildHomeNavBar(r))
+
+	api.renderHTML(
+		w,
+		r,
+		templates.Base(
+			viewmodels.PageMeta{Title: "Депонирование", Description: "Текст ожидает записи в блокчейн TextProof"},
+			nav,
+			templates.DepositProgressPage(
+				fmt.Sprintf("/api/v1/jobs/%s", job.ID),
+				fmt.Sprintf("/deposit/progress/%s", job.ID),
+				string(job.Status),
+				job.Error,
+			),
+		),
+	)
+}
+
+// findJob ищет задание из пути запроса и отвечает ошибкой, если его нет
+func (api *API) findJob(w http.ResponseWriter, r *http.Request) (*blockchain.Job, bool) {
+	if api.jobs == nil {
+		api.sendError(w, http.StatusNotFound, "Задание не найдено", blockchain.ErrJobNotFound)
+		return nil, false
+	}
+
+	job, err := api.jobs.Get(mux.Vars(r)["id"])
+	if errors.Is(err, blockchain.ErrJobNotFound) {
+		api.sendError(w, http.StatusNotFound, "Задание не найдено", err)
+		return nil, false
+	}
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось получить задание", err)
+		return nil, false
+	}
+	return job, true
+}
+
+// jobResponse собирает ответ API с заданием
+func (api *API) jobResponse(r *http.Request, job *blockchain.Job) viewmodels.JobResponse {
+	resp := viewmodels.JobResponse{
+		ID:        job.ID,
+		Status:    string(job.Status),
+		StatusURL: fmt.Sprintf("%s/api/v1/jobs/%s", getBaseURL(r), job.ID),
+		Duplicate: job.Duplicate,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if job.Status != blockchain.JobCommitted {
+		return resp
+	}
+
+	resp.DepositRef = job.Ref
+	resp.VerifyURL = fmt.Sprintf("%s/verify/%s", getBaseURL(r), job.Ref)
+	block, err := api.blockchain.GetBlockByID(job.BlockID)
+	if err != nil {
+		return resp
+	}
+	if height, err := api.blockchain.HeightOf(block.ID); err == nil {
+		b := blockResponse(height, block)
+		resp.Block = &b
+	}
+	return resp
+}
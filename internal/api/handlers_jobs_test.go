@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/testutil"
+	"blockchain-verifier/internal/viewmodels"
+)
+
+// newJobsAPI создаёт API с включёнными асинхронными заданиями
+func newJobsAPI(t *testing.T, difficulty int) (*API, *blockchain.Blockchain) {
+	t.Helper()
+
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), difficulty)
+	jobs, err := blockchain.NewJobManager(context.Background(), bc, nil, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJobManager() error = %v", err)
+	}
+	t.Cleanup(jobs.Close)
+
+	api := NewAPI(bc)
+	api.SetJobs(jobs)
+	return api, bc
+}
+
+// waitJobResponse опрашивает /api/v1/jobs/{id}, пока задание не завершится
+func waitJobResponse(t *testing.T, api *API, statusPath string) viewmodels.JobResponse {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := httptest.NewRecorder()
+		api.ServeHTTP(resp, httptest.NewRequest("GET", statusPath, nil))
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		var job viewmodels.JobResponse
+		testutil.ParseJSONResponse(t, resp, &job)
+		if job.Status == "committed" || job.Status == "failed" {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job status = %s after deadline", job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPublicAPI_AsyncDeposit(t *testing.T) {
+	api, bc := newJobsAPI(t, 1)
+
+	body := testutil.CreateJSONBody(t, viewmodels.DepositRequest{
+		AuthorName: "Async Author",
+		Title:      "Async Title",
+		Text:       "Deposited without waiting for proof of work",
+	})
+	req := testutil.HTTPTestRequest("POST", "/api/v1/deposit?async=true", body)
+	resp := httptest.NewRecorder()
+
+	api.ServeHTTP(resp, req)
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusAccepted)
+
+	var accepted viewmodels.JobResponse
+	testutil.ParseJSONResponse(t, resp, &accepted)
+	testutil.AssertNotEqual(t, accepted.ID, "", "job ID")
+	location := resp.Header().Get("Location")
+	testutil.AssertEqual(t, location, "/api/v1/jobs/"+accepted.ID, "Location header")
+
+	job := waitJobResponse(t, api, location)
+	testutil.AssertEqual(t, job.Status, "committed", "job status")
+	testutil.AssertEqual(t, job.DepositRef, bc.GetLastBlock().ID, "deposit ref")
+	if job.Block == nil {
+		t.Fatal("Committed job should include block")
+	}
+	testutil.AssertEqual(t, job.Block.Height, 1, "block height")
+	testutil.AssertEqual(t, job.Block.Author, "Async Author", "block author")
+}
+
+func TestPublicAPI_AsyncDeposit_Disabled(t *testing.T) {
+	api := NewAPI(blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1))
+
+	body := testutil.CreateJSONBody(t, viewmodels.DepositRequest{
+		AuthorName: "Author",
+		Title:      "Title",
+		Text:       "Async mode is off",
+	})
+	req := testutil.HTTPTestRequest("POST", "/api/v1/deposit?async=true", body)
+	resp := httptest.NewRecorder()
+
+	api.ServeHTTP(resp, req)
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+}
+
+func TestHandleJobJSON_NotFound(t *testing.T) {
+	api, _ := newJobsAPI(t, 1)
+
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, httptest.NewRequest("GET", "/api/v1/jobs/missing", nil))
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
+}
+
+func TestHandleDeposit_Async(t *testing.T) {
+	api, bc := newJobsAPI(t, 1)
+
+	formData := map[string]string{
+		"author_name": "John Doe",
+		"title":       "Test Article",
+		"text":        "Form deposit through the progress page",
+	}
+	req := testutil.HTTPTestFormRequest("POST", "/api/deposit", formData)
+	resp := httptest.NewRecorder()
+
+	api.handleDeposit(resp, req)
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusSeeOther)
+	progress := resp.Header().Get("Location")
+	if !strings.HasPrefix(progress, "/deposit/progress/") {
+		t.Fatalf("Redirect = %q, want progress page", progress)
+	}
+
+	jobID := strings.TrimPrefix(progress, "/deposit/progress/")
+	waitJobResponse(t, api, "/api/v1/jobs/"+jobID)
+
+	// Записанное задание перенаправляет на результат
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, httptest.NewRequest("GET", progress, nil))
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusSeeOther)
+	testutil.AssertEqual(t, resp.Header().Get("Location"), "/deposit/result/"+bc.GetLastBlock().ID, "result redirect")
+}
+
+func TestHandleDepositProgress_Pending(t *testing.T) {
+	// 64 нуля недостижимы: задание не завершится до конца теста
+	api, _ := newJobsAPI(t, 64)
+
+	job, err := api.jobs.Submit(blockchain.CreateTestBlock("Author", "Title", "Still mining"))
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	resp := httptest.NewRecorder()
+	api.ServeHTTP(resp, httptest.NewRequest("GET", "/deposit/progress/"+job.ID, nil))
+
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	if !strings.Contains(resp.Body.String(), "/api/v1/jobs/"+job.ID) {
+		t.Error("Progress page should poll the job status")
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/viewmodels"
+	"blockchain-verifier/web/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// handleKeyDepositsJSON godoc
+//
+// @Summary      Работы автора по ключу
+// @Description  Возвращает депозиты, подписанные ключом с данным отпечатком (SHA-256 от SubjectPublicKeyInfo в hex), в порядке цепочки
+// @Tags         Verify
+// @Produce      json
+// @Param        fingerprint path string true "Отпечаток ключа"
+// @Success      200 {object} viewmodels.KeyDepositsResponse
+// @Failure      400 {object} viewmodels.ErrorResponse "Неверный отпечаток"
+// @Failure      404 {object} viewmodels.ErrorResponse "Ключ не встречается в цепочке"
+// @Router       /api/v1/keys/{fingerprint}/deposits [get]
+func (api *API) handleKeyDepositsJSON(w http.ResponseWriter, r *http.Request) {
+	fingerprint, ok := keyFingerprint(r)
+	if !ok {
+		api.sendError(w, http.StatusBadRequest, "Неверный отпечаток ключа", nil)
+		return
+	}
+
+	resp, err := api.keyDeposits(r, fingerprint)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось получить депозиты ключа", err)
+		return
+	}
+	if resp.Count == 0 && !resp.Revoked && len(resp.Successors) == 0 && len(resp.Predecessors) == 0 {
+		api.sendError(w, http.StatusNotFound, "Нет депозитов, подписанных этим ключом", nil)
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, resp)
+}
+
+// handleKeyProfilePage показывает страницу автора: все работы,
+// подписанные ключом
+//
+// @Summary      Страница автора
+// @Description  HTML-страница со всеми работами, подписанными ключом с данным отпечатком
+// @Tags         Verify
+// @Produce      text/html
+// @Param        fingerprint path string true "Отпечаток ключа"
+// @Success      200 {string} string "HTML страница автора"
+// @Success      303 {string} string "Неверный отпечаток, перенаправление на /verify"
+// @Router       /keys/{fingerprint} [get]
+func (api *API) handleKeyProfilePage(w http.ResponseWriter, r *http.Request) {
+	fingerprint, ok := keyFingerprint(r)
+	if !ok {
+		setFlash(w, "danger", "not_found", map[string]string{"id": mux.Vars(r)["fingerprint"]})
+		http.Redirect(w, r, "/verify", http.StatusSeeOther)
+		return
+	}
+
+	profile, err := api.keyDeposits(r, fingerprint)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	nav := mapNavBar(viewmodels.BuildHomeNavBar(r))
+
+	api.renderHTML(
+		w,
+		r,
+		templates.Base(
+			viewmodels.PageMeta{Title: "Работы автора", Description: "Тексты, подписанные одним ключом автора в блокчейне TextProof"},
+			nav,
+			templates.KeyProfilePage(profile),
+		),
+	)
+}
+
+// keyFingerprint читает отпечаток ключа из пути запроса: 64 hex-символа
+func keyFingerprint(r *http.Request) (string, bool) {
+	fingerprint := strings.ToLower(mux.Vars(r)["fingerprint"])
+	raw, err := hex.DecodeString(fingerprint)
+	if err != nil || len(raw) != 32 {
+		return "", false
+	}
+	return fingerprint, true
+}
+
+// keyDeposits собирает список работ ключа
+func (api *API) keyDeposits(r *http.Request, fingerprint string) (viewmodels.KeyDepositsResponse, error) {
+	resp := viewmodels.KeyDepositsResponse{
+		Fingerprint: fingerprint,
+		ProfileURL:  fmt.Sprintf("%s/keys/%s", getBaseURL(r), fingerprint),
+		Deposits:    []viewmodels.KeyDeposit{},
+	}
+
+	receipts, err := api.blockchain.DepositsByKey(fingerprint)
+	if err != nil {
+		return resp, err
+	}
+	for _, receipt := range receipts {
+		resp.Deposits = append(resp.Deposits, keyDeposit(r, receipt))
+	}
+	resp.Count = len(resp.Deposits)
+
+	status := api.blockchain.KeyStatus(fingerprint)
+	resp.Successors = status.Successors
+	resp.Predecessors = status.Predecessors
+	if status.Revoked {
+		resp.Revoked = true
+		resp.RevokedAt = &status.RevokedAt
+	}
+
+	// Первая работа может быть с соавторами: тип берётся у своего ключа
+	if resp.Count > 0 {
+		for _, publicKey := range receipts[0].Data.SignerKeys() {
+			if key, err := blockchain.ParseAuthorKey(publicKey); err == nil && key.Fingerprint == fingerprint {
+				resp.KeyType = string(key.Type)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// keyDeposit переводит квитанцию в элемент списка работ ключа
+func keyDeposit(r *http.Request, receipt *blockchain.DepositReceipt) viewmodels.KeyDeposit {
+	return viewmodels.KeyDeposit{
+		DepositRef: receipt.Ref,
+		BlockID:    receipt.Block.ID,
+		Author:     receipt.Data.AuthorName,
+		Title:      receipt.Data.Title,
+		Timestamp:  receipt.Block.Timestamp,
+		Hash:       receipt.Data.ContentHash,
+		HashAlg:    string(receipt.Data.ContentHashAlgorithm()),
+		VerifyURL:  fmt.Sprintf("%s/verify/%s", getBaseURL(r), receipt.Ref),
+	}
+}
+
+// handleKeyRecordJSON godoc
+//
+// @Summary      Ротация или отзыв ключа автора
+// @Description  Записывает в цепочку переход авторства к новому ключу (key_rotation, подписан старым ключом) или отзыв ключа с момента revoked_at (key_revocation, подписан отзываемым ключом или его преемником).
+// @Description  Подписывается то же сообщение, что и у депозита; content_hash - хеш тела записи алгоритмом цепочки, см. README
+// @Tags         Deposit
+// @Accept       json
+// @Produce      json
+// @Param        request body viewmodels.KeyRecordRequest true "Запись о ключе"
+// @Success      200 {object} viewmodels.KeyRecordResponse
+// @Failure      400 {object} viewmodels.ErrorResponse "Неверная запись или подпись"
+// @Failure      409 {object} viewmodels.ErrorResponse "Запись противоречит цепочке ключей"
+// @Failure      500 {object} viewmodels.ErrorResponse
+// @Router       /api/v1/keys/records [post]
+func (api *API) handleKeyRecordJSON(w http.ResponseWriter, r *http.Request) {
+	var req viewmodels.KeyRecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.sendError(w, http.StatusBadRequest, "Неверный формат JSON", err)
+		return
+	}
+
+	data, err := api.keyRecord(req)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if _, err := data.VerifySignature(); err != nil {
+		api.sendError(w, http.StatusBadRequest, "Подпись записи не прошла проверку", err)
+		return
+	}
+	if err := api.blockchain.CheckKeys(data); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, blockchain.ErrInvalidPublicKey) {
+			status = http.StatusBadRequest
+		}
+		api.sendError(w, status, "Запись противоречит ротациям и отзывам ключей", err)
+		return
+	}
+
+	receipt, err := api.deposit(r.Context(), data)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось добавить блок", err)
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, viewmodels.KeyRecordResponse{
+		Success:   true,
+		Type:      string(data.Type),
+		BlockID:   receipt.Block.ID,
+		RecordRef: receipt.Ref,
+		Hash:      data.ContentHash,
+		HashAlg:   string(data.ContentHashAlg),
+		Timestamp: receipt.Block.Timestamp,
+	})
+}
+
+// keyRecord собирает служебную запись из запроса алгоритмом новых
+// депозитов
+func (api *API) keyRecord(req viewmodels.KeyRecordRequest) (blockchain.DepositData, error) {
+	if strings.TrimSpace(req.PublicKey) == "" || req.Signature == "" {
+		return blockchain.DepositData{}, fmt.Errorf("нужны ключ подписи и подпись")
+	}
+
+	var data blockchain.DepositData
+	hashAlg := api.blockchain.HashAlgorithm()
+	switch blockchain.RecordType(req.Type) {
+	case blockchain.RecordKeyRotation:
+		if strings.TrimSpace(req.NewKey) == "" {
+			return data, fmt.Errorf("для ротации нужен новый ключ")
+		}
+		data = blockchain.NewKeyRotation(hashAlg, req.PublicKey, req.NewKey)
+	case blockchain.RecordKeyRevocation:
+		if strings.TrimSpace(req.RevokedKey) == "" || req.RevokedAt.IsZero() {
+			return data, fmt.Errorf("для отзыва нужны отзываемый ключ и время отзыва")
+		}
+		data = blockchain.NewKeyRevocation(hashAlg, req.PublicKey, req.RevokedKey, req.RevokedAt)
+	default:
+		return data, fmt.Errorf("неизвестный тип записи %q", req.Type)
+	}
+	data.Signature = req.Signature
+	return data, nil
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/testutil"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+func TestKeyDeposits(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	key, err := blockchain.ParseAuthorKey(publicKey)
+	if err != nil {
+		t.Fatalf("ParseAuthorKey() error = %v", err)
+	}
+
+	// Две подписанные работы под разными именами и одна без подписи
+	for _, title := range []string{"Poem", "Novel"} {
+		req := viewmodels.DepositRequest{AuthorName: "Pen name " + title, Title: title, Text: "Text of " + title, PublicKey: publicKey}
+		data := api.depositData(req)
+		data.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data.SigningMessage()))
+		if _, err := bc.AddBlock(data); err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+	}
+	if _, err := bc.AddBlock(blockchain.CreateTestBlock("Someone", "Unsigned", "unsigned")); err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	get := func(handler http.HandlerFunc, path, fingerprint string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = mux.SetURLVars(req, map[string]string{"fingerprint": fingerprint})
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+		return resp
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resp := get(api.handleKeyDepositsJSON, "/api/v1/keys/"+key.Fingerprint+"/deposits", key.Fingerprint)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		var out viewmodels.KeyDepositsResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.Fingerprint, key.Fingerprint, "fingerprint")
+		testutil.AssertEqual(t, out.KeyType, "ed25519", "key type")
+		testutil.AssertEqual(t, out.Count, 2, "count")
+		if len(out.Deposits) == 2 {
+			testutil.AssertEqual(t, out.Deposits[0].Title, "Poem", "first work")
+			testutil.AssertEqual(t, out.Deposits[1].Title, "Novel", "second work")
+			testutil.AssertContains(t, out.Deposits[0].VerifyURL, "/verify/"+out.Deposits[0].DepositRef)
+		}
+	})
+
+	t.Run("json uppercase fingerprint", func(t *testing.T) {
+		upper := strings.ToUpper(key.Fingerprint)
+		resp := get(api.handleKeyDepositsJSON, "/api/v1/keys/"+upper+"/deposits", upper)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	})
+
+	t.Run("json unknown key", func(t *testing.T) {
+		unknown := strings.Repeat("a", 64)
+		resp := get(api.handleKeyDepositsJSON, "/api/v1/keys/"+unknown+"/deposits", unknown)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusNotFound)
+	})
+
+	t.Run("json invalid fingerprint", func(t *testing.T) {
+		resp := get(api.handleKeyDepositsJSON, "/api/v1/keys/xyz/deposits", "xyz")
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("profile page", func(t *testing.T) {
+		resp := get(api.handleKeyProfilePage, "/keys/"+key.Fingerprint, key.Fingerprint)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+		body := resp.Body.String()
+		testutil.AssertContains(t, body, key.Fingerprint)
+		testutil.AssertContains(t, body, "Poem")
+		testutil.AssertContains(t, body, "Novel")
+		if strings.Contains(body, "Unsigned") {
+			t.Error("profile lists unsigned deposit")
+		}
+	})
+
+	t.Run("profile page invalid fingerprint", func(t *testing.T) {
+		resp := get(api.handleKeyProfilePage, "/keys/xyz", "xyz")
+		testutil.AssertStatusCode(t, resp.Code, http.StatusSeeOther)
+	})
+}
+
+func TestKeyRecords(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+
+	type authorKey struct {
+		priv        ed25519.PrivateKey
+		public      string
+		fingerprint string
+	}
+	newKey := func() authorKey {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		k := authorKey{priv: priv, public: base64.StdEncoding.EncodeToString(pub)}
+		parsed, err := blockchain.ParseAuthorKey(k.public)
+		if err != nil {
+			t.Fatalf("ParseAuthorKey() error = %v", err)
+		}
+		k.fingerprint = parsed.Fingerprint
+		return k
+	}
+	a, b := newKey(), newKey()
+
+	// Запрос подписывается так же, как собирает запись сервер
+	record := func(signer authorKey, req viewmodels.KeyRecordRequest) *httptest.ResponseRecorder {
+		req.PublicKey = signer.public
+		req.Signature = "unsigned"
+		data, err := api.keyRecord(req)
+		if err != nil {
+			t.Fatalf("keyRecord() error = %v", err)
+		}
+		req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(signer.priv, data.SigningMessage()))
+
+		resp := httptest.NewRecorder()
+		api.handleKeyRecordJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/keys/records", testutil.CreateJSONBody(t, req)))
+		return resp
+	}
+	deposit := func(signer authorKey, text string) *httptest.ResponseRecorder {
+		req := viewmodels.DepositRequest{AuthorName: "Author", Title: "Title", Text: text, PublicKey: signer.public}
+		req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(signer.priv, api.depositData(req).SigningMessage()))
+		resp := httptest.NewRecorder()
+		api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", testutil.CreateJSONBody(t, req)))
+		return resp
+	}
+
+	testutil.AssertStatusCode(t, deposit(a, "Signed before the key was lost").Code, http.StatusOK)
+
+	resp := record(a, viewmodels.KeyRecordRequest{Type: "key_rotation", NewKey: b.public})
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	var out viewmodels.KeyRecordResponse
+	testutil.ParseJSONResponse(t, resp, &out)
+	testutil.AssertEqual(t, out.Type, "key_rotation", "record type")
+
+	t.Run("second rotation conflicts", func(t *testing.T) {
+		resp := record(a, viewmodels.KeyRecordRequest{Type: "key_rotation", NewKey: newKey().public})
+		testutil.AssertStatusCode(t, resp.Code, http.StatusConflict)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		req := viewmodels.KeyRecordRequest{Type: "key_revocation", PublicKey: b.public, RevokedKey: a.public, RevokedAt: time.Now(), Signature: base64.StdEncoding.EncodeToString(make([]byte, 64))}
+		resp := httptest.NewRecorder()
+		api.handleKeyRecordJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/keys/records", testutil.CreateJSONBody(t, req)))
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		req := viewmodels.KeyRecordRequest{Type: "key_escrow", PublicKey: a.public, Signature: "sig"}
+		resp := httptest.NewRecorder()
+		api.handleKeyRecordJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/keys/records", testutil.CreateJSONBody(t, req)))
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	// Преемник отзывает потерянный ключ задним числом
+	revokedAt := time.Now().Add(-24 * time.Hour).UTC()
+	resp = record(b, viewmodels.KeyRecordRequest{Type: "key_revocation", RevokedKey: a.public, RevokedAt: revokedAt})
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+	testutil.AssertStatusCode(t, deposit(a, "Signed after revocation").Code, http.StatusBadRequest)
+	testutil.AssertStatusCode(t, deposit(b, "Signed by the new key").Code, http.StatusOK)
+
+	body := testutil.CreateJSONBody(t, viewmodels.VerifyByTextRequest{Text: "Signed before the key was lost"})
+	resp = httptest.NewRecorder()
+	api.handleVerifyByTextJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/text", body))
+	var found viewmodels.VerificationResponse
+	testutil.ParseJSONResponse(t, resp, &found)
+	testutil.AssertEqual(t, found.KeyRevoked, true, "key revoked")
+	testutil.AssertEqual(t, found.SignedAfterRevocation, true, "signed after revocation time")
+	if found.KeyRevokedAt == nil || !found.KeyRevokedAt.Equal(revokedAt) {
+		t.Errorf("key_revoked_at = %v, want %v", found.KeyRevokedAt, revokedAt)
+	}
+	if len(found.KeySuccessors) != 1 || found.KeySuccessors[0] != b.fingerprint {
+		t.Errorf("key_successors = %v, want [%s]", found.KeySuccessors, b.fingerprint)
+	}
+
+	// Профиль нового ключа ведёт к прежнему
+	req := httptest.NewRequest("GET", "/api/v1/keys/"+b.fingerprint+"/deposits", nil)
+	req = mux.SetURLVars(req, map[string]string{"fingerprint": b.fingerprint})
+	resp = httptest.NewRecorder()
+	api.handleKeyDepositsJSON(resp, req)
+	var profile viewmodels.KeyDepositsResponse
+	testutil.ParseJSONResponse(t, resp, &profile)
+	testutil.AssertEqual(t, profile.Count, 1, "works of new key")
+	if len(profile.Predecessors) != 1 || profile.Predecessors[0] != a.fingerprint {
+		t.Errorf("predecessors = %v, want [%s]", profile.Predecessors, a.fingerprint)
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/viewmodels"
+)
+
+// handleNoticeJSON godoc
+//
+// @Summary      Отзыв или исправление депозита
+// @Description  Записывает в цепочку отзыв (retraction) или исправление (correction) депозита target с причиной reason. Запись подписывает ключ депозита или ключ, к которому он перешёл ротациями, либо вместо подписи передаётся account_token учётной записи депозита; депозит без подписи и без учётной записи отозвать нельзя.
+// @Description  Подписывается то же сообщение, что и у депозита; content_hash - хеш тела записи алгоритмом цепочки, см. README
+// @Tags         Deposit
+// @Accept       json
+// @Produce      json
+// @Param        request body viewmodels.NoticeRequest true "Отзыв или исправление"
+// @Success      200 {object} viewmodels.NoticeResponse
+// @Failure      400 {object} viewmodels.ErrorResponse "Неверная запись или подпись"
+// @Failure      403 {object} viewmodels.ErrorResponse "Запись подписана не ключом и добавлена не из учётной записи депозита"
+// @Failure      404 {object} viewmodels.ErrorResponse "Депозит не найден"
+// @Failure      409 {object} viewmodels.ErrorResponse "Ключ подписи отозван или передан другому ключу"
+// @Failure      500 {object} viewmodels.ErrorResponse
+// @Router       /api/v1/notices [post]
+func (api *API) handleNoticeJSON(w http.ResponseWriter, r *http.Request) {
+	var req viewmodels.NoticeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.sendError(w, http.StatusBadRequest, "Неверный формат JSON", err)
+		return
+	}
+
+	data, err := api.noticeRecord(req)
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if data.Signed() {
+		if _, err := data.VerifySignature(); err != nil {
+			api.sendError(w, http.StatusBadRequest, "Подпись записи не прошла проверку", err)
+			return
+		}
+	}
+	if err := api.blockchain.CheckKeys(data); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, blockchain.ErrInvalidPublicKey) {
+			status = http.StatusBadRequest
+		}
+		api.sendError(w, status, "Ключ подписи отозван или передан другому ключу", err)
+		return
+	}
+	if err := api.blockchain.CheckNotice(data); err != nil {
+		switch {
+		case errors.Is(err, blockchain.ErrTargetNotFound):
+			api.sendError(w, http.StatusNotFound, "Депозит не найден", err)
+		case errors.Is(err, blockchain.ErrNoticeNotAuthorized):
+			api.sendError(w, http.StatusForbidden, "Отозвать или исправить депозит может только владелец его ключа или учётной записи", err)
+		default:
+			api.sendError(w, http.StatusBadRequest, "Неверная запись", err)
+		}
+		return
+	}
+
+	receipt, err := api.deposit(r.Context(), data)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Не удалось добавить блок", err)
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, viewmodels.NoticeResponse{
+		Success:   true,
+		Type:      string(data.Type),
+		Target:    data.Target,
+		BlockID:   receipt.Block.ID,
+		RecordRef: receipt.Ref,
+		Hash:      data.ContentHash,
+		HashAlg:   string(data.ContentHashAlg),
+		Timestamp: receipt.Block.Timestamp,
+	})
+}
+
+// noticeRecord собирает отзыв или исправление из запроса алгоритмом
+// новых депозитов
+func (api *API) noticeRecord(req viewmodels.NoticeRequest) (blockchain.DepositData, error) {
+	switch {
+	case req.AccountToken != "":
+		if req.PublicKey != "" || req.Signature != "" {
+			return blockchain.DepositData{}, fmt.Errorf("нужны либо ключ подписи и подпись, либо токен учётной записи")
+		}
+		if len(req.AccountToken) < blockchain.MinAccountTokenLength {
+			return blockchain.DepositData{}, fmt.Errorf("токен учётной записи слишком короткий (мин %d символов)", blockchain.MinAccountTokenLength)
+		}
+	case strings.TrimSpace(req.PublicKey) == "" || req.Signature == "":
+		return blockchain.DepositData{}, fmt.Errorf("нужны ключ подписи и подпись или токен учётной записи")
+	}
+	if strings.TrimSpace(req.Target) == "" {
+		return blockchain.DepositData{}, fmt.Errorf("нужен ID отзываемого или исправляемого депозита")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return blockchain.DepositData{}, fmt.Errorf("причина не может быть пустой")
+	}
+	if len(req.Reason) > MaxReasonLength {
+		return blockchain.DepositData{}, fmt.Errorf("причина слишком длинная (макс %d символов)", MaxReasonLength)
+	}
+
+	var data blockchain.DepositData
+	hashAlg := api.blockchain.HashAlgorithm()
+	switch blockchain.RecordType(req.Type) {
+	case blockchain.RecordRetraction:
+		data = blockchain.NewRetraction(hashAlg, req.PublicKey, req.Target, req.Reason)
+	case blockchain.RecordCorrection:
+		data = blockchain.NewCorrection(hashAlg, req.PublicKey, req.Target, req.Reason)
+	default:
+		return data, fmt.Errorf("неизвестный тип записи %q", req.Type)
+	}
+	data.Signature = req.Signature
+	if req.AccountToken != "" {
+		data.Account = blockchain.AccountID(req.AccountToken)
+	}
+	return data, nil
+}
+
+// noticesResponse дополняет ответ проверки отзывом и исправлениями
+// депозита, а ответ о самой записи - депозитом, к которому она относится
+func (api *API) noticesResponse(resp *viewmodels.VerificationResponse, receipt *blockchain.DepositReceipt) {
+	if receipt.Data.IsNotice() {
+		resp.Target = receipt.Data.Target
+		resp.Reason = receipt.Data.Reason
+		return
+	}
+
+	receipts, err := api.blockchain.Notices(receipt.Ref)
+	if err != nil {
+		return
+	}
+	for _, n := range receipts {
+		resp.Notices = append(resp.Notices, viewmodels.DepositNotice{
+			Type:      string(n.Data.Type),
+			Reason:    n.Data.Reason,
+			RecordRef: n.Ref,
+			BlockID:   n.Block.ID,
+			Timestamp: n.Block.Timestamp,
+		})
+		// Отзыв окончателен: показывается первый
+		if n.Data.Type == blockchain.RecordRetraction && !resp.Retracted {
+			resp.Retracted = true
+			resp.RetractedBy = n.Ref
+		}
+	}
+}
+
+// retractedBy возвращает ID отзыва депозита или пустую строку, если
+// депозит не отозван
+func (api *API) retractedBy(receipt *blockchain.DepositReceipt) string {
+	var resp viewmodels.VerificationResponse
+	api.noticesResponse(&resp, receipt)
+	return resp.RetractedBy
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/testutil"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+func TestNotices(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+
+	newKey := func() (ed25519.PrivateKey, string) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		return priv, base64.StdEncoding.EncodeToString(pub)
+	}
+	author, authorKey := newKey()
+	stranger, strangerKey := newKey()
+
+	data := api.depositData(viewmodels.DepositRequest{AuthorName: "Author", Title: "Essay", Text: "Essay with a wrong attachment", PublicKey: authorKey})
+	data.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(author, data.SigningMessage()))
+	block, err := bc.AddBlock(data)
+	if err != nil {
+		t.Fatalf("AddBlock() error = %v", err)
+	}
+
+	notice := func(priv ed25519.PrivateKey, publicKey, typ, target, reason string) *httptest.ResponseRecorder {
+		data := blockchain.NewRetraction(bc.HashAlgorithm(), publicKey, target, reason)
+		if typ == "correction" {
+			data = blockchain.NewCorrection(bc.HashAlgorithm(), publicKey, target, reason)
+		}
+		req := viewmodels.NoticeRequest{
+			Type:      typ,
+			Target:    target,
+			Reason:    reason,
+			PublicKey: publicKey,
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data.SigningMessage())),
+		}
+		r := testutil.HTTPTestRequest("POST", "/api/v1/notices", testutil.CreateJSONBody(t, req))
+		resp := httptest.NewRecorder()
+		api.handleNoticeJSON(resp, r)
+		return resp
+	}
+
+	tests := []struct {
+		name   string
+		priv   ed25519.PrivateKey
+		key    string
+		typ    string
+		target string
+		reason string
+		status int
+	}{
+		{"unknown type", author, authorKey, "key_rotation", block.ID, "wrong file", http.StatusBadRequest},
+		{"no reason", author, authorKey, "retraction", block.ID, "", http.StatusBadRequest},
+		{"unknown target", author, authorKey, "retraction", "999-999-999", "wrong file", http.StatusNotFound},
+		{"foreign key", stranger, strangerKey, "retraction", block.ID, "not mine", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertStatusCode(t, notice(tt.priv, tt.key, tt.typ, tt.target, tt.reason).Code, tt.status)
+		})
+	}
+
+	resp := notice(author, authorKey, "correction", block.ID, "attachment is outdated")
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+	resp = notice(author, authorKey, "retraction", block.ID, "wrong file deposited")
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+	var retraction viewmodels.NoticeResponse
+	testutil.ParseJSONResponse(t, resp, &retraction)
+	testutil.AssertEqual(t, retraction.Target, block.ID, "target")
+
+	t.Run("verify shows retraction", func(t *testing.T) {
+		r := testutil.HTTPTestRequest("POST", "/api/v1/verify/id", testutil.CreateJSONBody(t, viewmodels.VerifyByIDRequest{ID: block.ID}))
+		resp := httptest.NewRecorder()
+		api.handleVerifyByIDJSON(resp, r)
+		var out viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.Retracted, true, "retracted")
+		testutil.AssertEqual(t, out.RetractedBy, retraction.RecordRef, "retracted by")
+		if len(out.Notices) != 2 {
+			t.Fatalf("notices = %v, want 2", out.Notices)
+		}
+		testutil.AssertEqual(t, out.Notices[0].Type, "correction", "first notice")
+		testutil.AssertEqual(t, out.Notices[1].Reason, "wrong file deposited", "reason")
+	})
+
+	t.Run("verify record", func(t *testing.T) {
+		r := testutil.HTTPTestRequest("POST", "/api/v1/verify/id", testutil.CreateJSONBody(t, viewmodels.VerifyByIDRequest{ID: retraction.RecordRef}))
+		resp := httptest.NewRecorder()
+		api.handleVerifyByIDJSON(resp, r)
+		var out viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.RecordType, "retraction", "record type")
+		testutil.AssertEqual(t, out.Target, block.ID, "target")
+		testutil.AssertEqual(t, out.Retracted, false, "record is not retracted")
+	})
+
+	t.Run("badge", func(t *testing.T) {
+		r := mux.SetURLVars(httptest.NewRequest("GET", "/api/badge/"+block.ID, nil), map[string]string{"id": block.ID})
+		resp := httptest.NewRecorder()
+		api.handleBadge(resp, r)
+		testutil.AssertContains(t, resp.Body.String(), "Отозвано автором")
+		testutil.AssertContains(t, resp.Body.String(), retraction.RecordRef)
+	})
+
+	t.Run("same account", func(t *testing.T) {
+		data := api.depositData(viewmodels.DepositRequest{AuthorName: "Author", Title: "Notes", Text: "Unsigned notes", AccountToken: "owner-account-token"})
+		notes, err := bc.AddBlock(data)
+		if err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+
+		byAccount := func(token string) *httptest.ResponseRecorder {
+			req := viewmodels.NoticeRequest{Type: "retraction", Target: notes.ID, Reason: "withdrawn", AccountToken: token}
+			resp := httptest.NewRecorder()
+			api.handleNoticeJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/notices", testutil.CreateJSONBody(t, req)))
+			return resp
+		}
+		testutil.AssertStatusCode(t, byAccount("other-account-token").Code, http.StatusForbidden)
+		testutil.AssertStatusCode(t, byAccount("short").Code, http.StatusBadRequest)
+		testutil.AssertStatusCode(t, byAccount("owner-account-token").Code, http.StatusOK)
+	})
+
+	t.Run("verify page", func(t *testing.T) {
+		r := mux.SetURLVars(httptest.NewRequest("GET", "/verify/"+block.ID, nil), map[string]string{"id": block.ID})
+		resp := httptest.NewRecorder()
+		api.handleVerifyDirectLink(resp, r)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+		testutil.AssertContains(t, resp.Body.String(), "Депозит отозван автором")
+		testutil.AssertContains(t, resp.Body.String(), "wrong file deposited")
+	})
+}
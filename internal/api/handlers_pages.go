@@ -15,8 +15,17 @@ func (api *API) handleHome(w http.ResponseWriter, r *http.Request) {
 	authors := make(map[string]bool)
 	var lastAdded time.Time
 
+	deposits := 0
 	for _, block := range allBlocks {
-		authors[block.Data.AuthorName] = true
+		for _, data := range block.DepositList() {
+			if data.IsRecord() {
+				continue
+			}
+			for _, name := range data.AuthorList() {
+				authors[name] = true
+			}
+			deposits++
+		}
 		if block.Timestamp.After(lastAdded) {
 			lastAdded = block.Timestamp
 		}
@@ -24,9 +33,10 @@ func (api *API) handleHome(w http.ResponseWriter, r *http.Request) {
 
 	stats := viewmodels.StatsResponse{
 		TotalBlocks:   len(allBlocks),
+		TotalDeposits: deposits,
 		UniqueAuthors: len(authors),
 		LastAdded:     lastAdded,
-		ChainValid:    api.blockchain.ValidateChain(),
+		ChainValid:    api.blockchain.Valid(),
 	}
 
 	navVM := viewmodels.BuildHomeNavBar(r)
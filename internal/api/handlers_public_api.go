@@ -1,10 +1,7 @@
 package api
 
 import (
-	"blockchain-verifier/internal/blockchain"
 	"blockchain-verifier/internal/viewmodels"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,12 +15,17 @@ import (
 // handleDepositJSON godoc
 //
 // @Summary      Депонирование текста (JSON API)
-// @Description  Регистрирует текст в блокчейне и возвращает JSON ответ
+// @Description  Регистрирует текст в блокчейне и возвращает JSON ответ.
+// @Description  С async=true сразу отвечает 202 с заданием, статус которого доступен в /api/v1/jobs/{id}
+// @Description  Работу с соавторами (authors), которую подписали не все соавторы с ключами, отвечает 202 со сбором подписей в /api/v1/cosign/{id}
 // @Tags         Deposit
 // @Accept       json
 // @Produce      json
 // @Param        request body viewmodels.DepositRequest true "Данные для регистрации"
+// @Param        async query bool false "Не ждать майнинга, вернуть задание"
 // @Success      200 {object} viewmodels.DepositResponse
+// @Success      202 {object} viewmodels.JobResponse "Задание принято"
+// @Success      202 {object} viewmodels.CosignResponse "Ждёт подписей соавторов"
 // @Failure      400 {object} viewmodels.ErrorResponse
 // @Failure      409 {object} viewmodels.ErrorResponse "Текст уже существует"
 // @Failure      500 {object} viewmodels.ErrorResponse
@@ -43,33 +45,39 @@ func (api *API) handleDepositJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Вычисляем хеш
-	hash := sha256.Sum256([]byte(req.Text))
-	contentHash := hex.EncodeToString(hash[:])
+	// Создаем данные для блока: хеш и фрагменты текста
+	data := api.depositData(req)
 
-	// Проверка на дубликат
-	_, existedBefore := api.blockchain.HasContentHash(contentHash)
-	if existedBefore {
+	// Проверка на дубликат, в том числе записанный другим алгоритмом
+	if _, err := api.blockchain.FindDepositByText([]byte(req.Text)); err == nil {
 		api.sendError(w, http.StatusConflict, "Текст уже существует в блокчейне", nil)
 		return
 	}
 
-	// Извлекаем фрагменты
-	textStart := extractTextFragment(req.Text, 3, true)
-	textEnd := extractTextFragment(req.Text, 3, false)
-
-	// Создаем данные для блока
-	data := blockchain.DepositData{
-		AuthorName:  req.AuthorName,
-		Title:       req.Title,
-		TextStart:   textStart,
-		TextEnd:     textEnd,
-		ContentHash: contentHash,
-		PublicKey:   req.PublicKey,
+	// Работа с соавторами ждёт их подписей
+	if len(data.MissingSignatures()) > 0 {
+		api.openCosign(w, r, data, req.Text)
+		return
+	}
+
+	// Асинхронный режим: задание вместо ожидания майнинга
+	if r.URL.Query().Get("async") == "true" {
+		if api.jobs == nil {
+			api.sendError(w, http.StatusBadRequest, "Асинхронное депонирование не включено", nil)
+			return
+		}
+		job, err := api.jobs.Submit(data)
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Не удалось создать задание", err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/jobs/%s", job.ID))
+		api.sendJSON(w, http.StatusAccepted, api.jobResponse(r, job))
+		return
 	}
 
 	// Добавляем блок
-	block, err := api.blockchain.AddBlock(data)
+	receipt, err := api.deposit(r.Context(), data)
 	if err != nil {
 		api.sendError(w, http.StatusInternalServerError, "Не удалось добавить блок", err)
 		return
@@ -77,12 +85,15 @@ func (api *API) handleDepositJSON(w http.ResponseWriter, r *http.Request) {
 
 	// Формируем JSON ответ
 	response := viewmodels.DepositResponsePublic{
-		Success:   true,
-		BlockID:   block.ID,
-		Hash:      contentHash,
-		Timestamp: block.Timestamp,
-		VerifyURL: fmt.Sprintf("%s/verify/%s", getBaseURL(r), block.ID),
-		QRCodeURL: fmt.Sprintf("%s/api/qrcode/%s", getBaseURL(r), block.ID),
+		Success:    true,
+		BlockID:    receipt.Block.ID,
+		DepositRef: receipt.Ref,
+		Hash:       data.ContentHash,
+		HashAlg:    string(data.ContentHashAlg),
+		Timestamp:  receipt.Block.Timestamp,
+		VerifyURL:  fmt.Sprintf("%s/verify/%s", getBaseURL(r), receipt.Ref),
+		QRCodeURL:  fmt.Sprintf("%s/api/qrcode/%s", getBaseURL(r), receipt.Ref),
+		Proof:      merkleProofResponse(receipt.Proof),
 	}
 
 	api.sendJSON(w, http.StatusOK, response)
@@ -91,7 +102,7 @@ func (api *API) handleDepositJSON(w http.ResponseWriter, r *http.Request) {
 // handleVerifyByIDJSON godoc
 //
 // @Summary      Проверка по ID (JSON API)
-// @Description  Проверяет текст по ID блока и возвращает JSON
+// @Description  Проверяет текст по ID блока (или ссылке на депозит пакетного блока) и возвращает JSON с доказательством включения
 // @Tags         Verify
 // @Accept       json
 // @Produce      json
@@ -113,7 +124,7 @@ func (api *API) handleVerifyByIDJSON(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Ищем блок
-	block, err := api.blockchain.GetBlockByID(req.ID)
+	receipt, err := api.blockchain.GetDepositByRef(req.ID)
 	if err != nil {
 		resp := viewmodels.VerificationResponse{
 			Found: false,
@@ -123,15 +134,7 @@ func (api *API) handleVerifyByIDJSON(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Формируем ответ
-	resp := viewmodels.VerificationResponse{
-		Found:     true,
-		BlockID:   block.ID,
-		Author:    block.Data.AuthorName,
-		Title:     block.Data.Title,
-		Timestamp: block.Timestamp,
-		Hash:      block.Data.ContentHash,
-		Matches:   true,
-	}
+	resp := api.verificationResponse(receipt)
 
 	api.sendJSON(w, http.StatusOK, resp)
 }
@@ -139,7 +142,7 @@ func (api *API) handleVerifyByIDJSON(w http.ResponseWriter, r *http.Request) {
 // handleVerifyByTextJSON godoc
 //
 // @Summary      Проверка по тексту (JSON API)
-// @Description  Проверяет текст по содержимому и возвращает JSON
+// @Description  Проверяет текст по содержимому и возвращает JSON с доказательством включения
 // @Tags         Verify
 // @Accept       json
 // @Produce      json
@@ -167,13 +170,9 @@ func (api *API) handleVerifyByTextJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Вычисляем хеш
-	hash := sha256.Sum256([]byte(req.Text))
-	contentHash := hex.EncodeToString(hash[:])
-
-	// Поиск по хешу
-	block, exists := api.blockchain.HasContentHash(contentHash)
-	if !exists {
+	// Поиск по хешу каждого алгоритма цепочки
+	receipt, err := api.blockchain.FindDepositByText([]byte(req.Text))
+	if err != nil {
 		resp := viewmodels.VerificationResponse{
 			Found: false,
 		}
@@ -182,15 +181,7 @@ func (api *API) handleVerifyByTextJSON(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Формируем ответ
-	resp := viewmodels.VerificationResponse{
-		Found:     true,
-		BlockID:   block.ID,
-		Author:    block.Data.AuthorName,
-		Title:     block.Data.Title,
-		Timestamp: block.Timestamp,
-		Hash:      block.Data.ContentHash,
-		Matches:   true,
-	}
+	resp := api.verificationResponse(receipt)
 
 	api.sendJSON(w, http.StatusOK, resp)
 }
@@ -1,10 +1,18 @@
 package api
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"blockchain-verifier/internal/blockchain"
 	"blockchain-verifier/internal/testutil"
@@ -299,6 +307,83 @@ func TestPublicAPI_VerifyNonExistent(t *testing.T) {
 	})
 }
 
+func TestPublicAPI_BatchedDeposits(t *testing.T) {
+	storage := blockchain.NewTestStorage()
+	bc := blockchain.NewBlockchainWithStorage(storage, 1)
+	api := NewAPI(bc)
+	batcher := blockchain.NewBatcher(context.Background(), bc, blockchain.BatchOptions{MaxSize: 2, MaxWait: time.Hour})
+	defer batcher.Close()
+	api.SetBatcher(batcher)
+
+	texts := []string{"First batched text", "Second batched text"}
+	deposits := make([]viewmodels.DepositResponsePublic, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			body := testutil.CreateJSONBody(t, viewmodels.DepositRequest{
+				AuthorName: "Batch Author",
+				Title:      fmt.Sprintf("Batch %d", i),
+				Text:       text,
+			})
+			resp := httptest.NewRecorder()
+			api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", body))
+			testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+			testutil.ParseJSONResponse(t, resp, &deposits[i])
+		}(i, text)
+	}
+	wg.Wait()
+
+	block := bc.GetLastBlock()
+	testutil.AssertEqual(t, len(bc.GetAllBlocks()), 2, "one block for both deposits")
+	testutil.AssertEqual(t, deposits[0].BlockID, block.ID, "block ID")
+	testutil.AssertNotEqual(t, deposits[0].DepositRef, deposits[1].DepositRef, "deposit refs")
+	testutil.AssertContains(t, deposits[0].VerifyURL, deposits[0].DepositRef)
+
+	for i, text := range texts {
+		body := testutil.CreateJSONBody(t, viewmodels.VerifyByTextRequest{Text: text})
+		resp := httptest.NewRecorder()
+		api.handleVerifyByTextJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/text", body))
+
+		var verifyResp viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &verifyResp)
+		testutil.AssertEqual(t, verifyResp.Found, true, "found")
+		testutil.AssertEqual(t, verifyResp.BlockID, deposits[i].DepositRef, "deposit ref")
+		testutil.AssertEqual(t, verifyResp.MerkleRoot, block.MerkleRoot, "merkle root")
+		testutil.AssertEqual(t, verifyResp.BlockHash, block.Hash, "block hash")
+		if verifyResp.Proof == nil {
+			t.Fatal("verification response should carry a proof")
+		}
+
+		// Доказательство из ответа сходится к корню блока
+		proof := blockchain.MerkleProof{
+			Index: verifyResp.Proof.Index,
+			Leaf:  verifyResp.Proof.Leaf,
+			Root:  verifyResp.Proof.Root,
+		}
+		for _, step := range verifyResp.Proof.Siblings {
+			proof.Siblings = append(proof.Siblings, blockchain.ProofStep{Hash: step.Hash, Left: step.Left})
+		}
+		if !proof.Verify(block.Deposits[verifyResp.Proof.Index]) {
+			t.Errorf("proof of %q should verify", text)
+		}
+	}
+
+	// Ссылка на депозит работает в проверке по ID и в QR-коде
+	body := testutil.CreateJSONBody(t, viewmodels.VerifyByIDRequest{ID: deposits[1].DepositRef})
+	resp := httptest.NewRecorder()
+	api.handleVerifyByIDJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/id", body))
+	var verifyResp viewmodels.VerificationResponse
+	testutil.ParseJSONResponse(t, resp, &verifyResp)
+	testutil.AssertEqual(t, verifyResp.Found, true, "found by ref")
+	testutil.AssertEqual(t, verifyResp.Author, "Batch Author", "author")
+
+	resp = httptest.NewRecorder()
+	api.ServeHTTP(resp, httptest.NewRequest("GET", "/api/qrcode/"+deposits[1].DepositRef, nil))
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+}
+
 func BenchmarkPublicAPI_Deposit(b *testing.B) {
 	storage := blockchain.NewTestStorage()
 	bc := blockchain.NewBlockchainWithStorage(storage, 1)
@@ -338,3 +423,156 @@ func BenchmarkPublicAPI_Stats(b *testing.B) {
 		api.handleStats(resp, req)
 	}
 }
+
+func TestPublicAPI_HashAlgorithmSwitch(t *testing.T) {
+	storage := blockchain.NewTestStorage()
+	bc := blockchain.NewBlockchainWithStorage(storage, 1)
+
+	deposit := func(api *API, text string) viewmodels.DepositResponsePublic {
+		t.Helper()
+		body := testutil.CreateJSONBody(t, viewmodels.DepositRequest{
+			AuthorName: "Author",
+			Title:      "Title",
+			Text:       text,
+		})
+		resp := httptest.NewRecorder()
+		api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", body))
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+		var out viewmodels.DepositResponsePublic
+		testutil.ParseJSONResponse(t, resp, &out)
+		return out
+	}
+	verify := func(api *API, text string) viewmodels.VerificationResponse {
+		t.Helper()
+		body := testutil.CreateJSONBody(t, viewmodels.VerifyByTextRequest{Text: text})
+		resp := httptest.NewRecorder()
+		api.handleVerifyByTextJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/text", body))
+		var out viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		return out
+	}
+
+	old := deposit(NewAPI(bc), "Text deposited with SHA-256")
+	testutil.AssertEqual(t, old.HashAlg, "sha256", "old deposit algorithm")
+
+	// Новые депозиты - SHA3-256, старые по-прежнему находятся по тексту
+	bc, err := blockchain.NewBlockchain(storage, 1, blockchain.WithHashAlgorithm(blockchain.HashSHA3_256))
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	api := NewAPI(bc)
+
+	fresh := deposit(api, "Text deposited with SHA3-256")
+	testutil.AssertEqual(t, fresh.HashAlg, "sha3-256", "new deposit algorithm")
+	testutil.AssertEqual(t, fresh.Hash, blockchain.HashSHA3_256.ContentHash([]byte("Text deposited with SHA3-256")), "new deposit hash")
+
+	found := verify(api, "Text deposited with SHA-256")
+	testutil.AssertEqual(t, found.Found, true, "old text found")
+	testutil.AssertEqual(t, found.BlockID, old.DepositRef, "old text ref")
+	testutil.AssertEqual(t, found.HashAlg, "sha256", "old text algorithm")
+
+	found = verify(api, "Text deposited with SHA3-256")
+	testutil.AssertEqual(t, found.Found, true, "new text found")
+	testutil.AssertEqual(t, found.HashAlg, "sha3-256", "new text algorithm")
+	if found.Proof == nil || found.Proof.Algorithm != "sha3-256" {
+		t.Errorf("proof = %+v, want sha3-256 tree", found.Proof)
+	}
+
+	// Старый текст - дубликат и под новым алгоритмом
+	body := testutil.CreateJSONBody(t, viewmodels.DepositRequest{
+		AuthorName: "Author",
+		Title:      "Title",
+		Text:       "Text deposited with SHA-256",
+	})
+	resp := httptest.NewRecorder()
+	api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", body))
+	testutil.AssertStatusCode(t, resp.Code, http.StatusConflict)
+	if _, ok := bc.HasContentHash(blockchain.HashSHA3_256.ContentHash([]byte("Text deposited with SHA-256"))); ok {
+		t.Error("Duplicate text was deposited with the new algorithm")
+	}
+
+	t.Run("form", func(t *testing.T) {
+		form := url.Values{"author_name": {"Author"}, "title": {"Title"}, "text": {"Text deposited with SHA-256"}}
+		req := httptest.NewRequest("POST", "/deposit", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		api.handleDeposit(resp, req)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusSeeOther)
+		testutil.AssertEqual(t, resp.Header().Get("Location"), "/deposit/result/"+old.DepositRef, "redirect to old deposit")
+	})
+	testutil.AssertEqual(t, verify(api, "Text deposited with SHA-256").BlockID, old.DepositRef, "earliest deposit found")
+}
+
+func TestPublicAPI_SignedDeposit(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, err := blockchain.ParseAuthorKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil {
+		t.Fatalf("ParseAuthorKey() error = %v", err)
+	}
+
+	// Автор подписывает те же данные, что сервер положит в блок
+	signed := func(text, title string) viewmodels.DepositRequest {
+		req := viewmodels.DepositRequest{
+			AuthorName: "Author",
+			Title:      title,
+			Text:       text,
+			PublicKey:  base64.StdEncoding.EncodeToString(pub),
+		}
+		msg := api.depositData(req).SigningMessage()
+		req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+		return req
+	}
+	deposit := func(req viewmodels.DepositRequest) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", testutil.CreateJSONBody(t, req)))
+		return resp
+	}
+
+	resp := deposit(signed("Signed text for the chain", "Signed"))
+	testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+	body := testutil.CreateJSONBody(t, viewmodels.VerifyByTextRequest{Text: "Signed text for the chain"})
+	resp = httptest.NewRecorder()
+	api.handleVerifyByTextJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/text", body))
+	var found viewmodels.VerificationResponse
+	testutil.ParseJSONResponse(t, resp, &found)
+	testutil.AssertEqual(t, found.Signed, true, "signed")
+	testutil.AssertEqual(t, found.KeyType, "ed25519", "key type")
+	testutil.AssertEqual(t, found.KeyFingerprint, key.Fingerprint, "key fingerprint")
+
+	t.Run("tampered title", func(t *testing.T) {
+		req := signed("Another signed text", "Original")
+		req.Title = "Changed"
+		testutil.AssertStatusCode(t, deposit(req).Code, http.StatusBadRequest)
+	})
+
+	t.Run("signature without key", func(t *testing.T) {
+		req := signed("Text without key", "Title")
+		req.PublicKey = ""
+		testutil.AssertStatusCode(t, deposit(req).Code, http.StatusBadRequest)
+	})
+
+	t.Run("unsigned deposit", func(t *testing.T) {
+		resp := deposit(viewmodels.DepositRequest{
+			AuthorName: "Author",
+			Title:      "Unsigned",
+			Text:       "Unsigned text with a free-form key",
+			PublicKey:  "test-key-123",
+		})
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		body := testutil.CreateJSONBody(t, viewmodels.VerifyByTextRequest{Text: "Unsigned text with a free-form key"})
+		resp = httptest.NewRecorder()
+		api.handleVerifyByTextJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/text", body))
+		var found viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &found)
+		testutil.AssertEqual(t, found.Signed, false, "signed")
+		testutil.AssertEqual(t, found.KeyFingerprint, "", "key fingerprint")
+	})
+}
@@ -1,12 +1,11 @@
 package api
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"blockchain-verifier/internal/blockchain"
 	"blockchain-verifier/internal/viewmodels"
 	"blockchain-verifier/web/templates"
 
@@ -35,7 +34,7 @@ func (api *API) handleVerifyDirectLink(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	block, err := api.blockchain.GetBlockByID(id)
+	receipt, err := api.blockchain.GetDepositByRef(id)
 
 	navVM := viewmodels.BuildHomeNavBar(r)
 	nav := mapNavBar(navVM)
@@ -60,15 +59,7 @@ func (api *API) handleVerifyDirectLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Блок найден - показываем результат
-	result := viewmodels.VerificationResponse{
-		Found:     true,
-		BlockID:   block.ID,
-		Author:    block.Data.AuthorName,
-		Title:     block.Data.Title,
-		Timestamp: block.Timestamp,
-		Hash:      block.Data.ContentHash,
-		Matches:   true,
-	}
+	result := api.verificationResponse(receipt)
 
 	// Устанавливаем flash для успешной проверки
 	setFlash(w, "success", "verified", nil)
@@ -99,7 +90,7 @@ func (api *API) handleVerifyByIDSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Пытаемся найти блок
-	block, err := api.blockchain.GetBlockByID(id)
+	receipt, err := api.blockchain.GetDepositByRef(id)
 
 	if err != nil {
 		// Блок не найден - возвращаем на форму с ошибкой
@@ -112,7 +103,7 @@ func (api *API) handleVerifyByIDSubmit(w http.ResponseWriter, r *http.Request) {
 
 	// Блок найден - редирект на страницу результата
 	setFlash(w, "success", "verified", nil)
-	http.Redirect(w, r, fmt.Sprintf("/verify/result/%s", block.ID), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/verify/result/%s", receipt.Ref), http.StatusSeeOther)
 }
 
 // handleVerifyByTextSubmit - обработка формы проверки по тексту
@@ -138,14 +129,10 @@ func (api *API) handleVerifyByTextSubmit(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Вычисляем хеш текста
-	hash := sha256.Sum256([]byte(text))
-	contentHash := hex.EncodeToString(hash[:])
-
-	// O(1) поиск через индекс
-	block, exists := api.blockchain.HasContentHash(contentHash)
+	// Хешируем текст каждым алгоритмом цепочки: O(1) поиск через индекс
+	receipt, err := api.blockchain.FindDepositByText([]byte(text))
 
-	if !exists {
+	if err != nil {
 		// Текст не найден
 		setFlash(w, "warning", "text_not_found", nil)
 		http.Redirect(w, r, "/verify", http.StatusSeeOther)
@@ -154,7 +141,7 @@ func (api *API) handleVerifyByTextSubmit(w http.ResponseWriter, r *http.Request)
 
 	// Текст найден - редирект на страницу результата
 	setFlash(w, "success", "verified", nil)
-	http.Redirect(w, r, fmt.Sprintf("/verify/result/%s", block.ID), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/verify/result/%s", receipt.Ref), http.StatusSeeOther)
 }
 
 // handleVerifyResultPage - страница результата проверки
@@ -162,7 +149,7 @@ func (api *API) handleVerifyResultPage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	block, err := api.blockchain.GetBlockByID(id)
+	receipt, err := api.blockchain.GetDepositByRef(id)
 	if err != nil {
 		// Если блок не найден - редирект на verify с ошибкой
 		setFlash(w, "danger", "not_found", map[string]string{"id": id})
@@ -170,15 +157,7 @@ func (api *API) handleVerifyResultPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := viewmodels.VerificationResponse{
-		Found:     true,
-		BlockID:   block.ID,
-		Author:    block.Data.AuthorName,
-		Title:     block.Data.Title,
-		Timestamp: block.Timestamp,
-		Hash:      block.Data.ContentHash,
-		Matches:   true,
-	}
+	result := api.verificationResponse(receipt)
 
 	flashData := getFlashData(r, w)
 	navVM := viewmodels.BuildHomeNavBar(r)
@@ -194,3 +173,85 @@ func (api *API) handleVerifyResultPage(w http.ResponseWriter, r *http.Request) {
 		),
 	)
 }
+
+// verificationResponse собирает ответ проверки с доказательством
+// включения депозита в блок
+func (api *API) verificationResponse(receipt *blockchain.DepositReceipt) viewmodels.VerificationResponse {
+	resp := viewmodels.VerificationResponse{
+		Found:      true,
+		BlockID:    receipt.Ref,
+		Author:     receipt.Data.AuthorName,
+		Title:      receipt.Data.Title,
+		Timestamp:  receipt.Block.Timestamp,
+		Hash:       receipt.Data.ContentHash,
+		HashAlg:    string(receipt.Data.ContentHashAlgorithm()),
+		Matches:    true,
+		BlockHash:  receipt.Block.Hash,
+		MerkleRoot: receipt.Block.MerkleRoot,
+		Proof:      merkleProofResponse(receipt.Proof),
+	}
+	api.signedResponse(&resp, receipt)
+	api.versionsResponse(&resp, receipt)
+	api.noticesResponse(&resp, receipt)
+	return resp
+}
+
+// signedResponse дополняет ответ проверки подписью автора и состоянием
+// ключа: отзывом и ротациями, а у работы с соавторами - их списком. Подписи в цепочке проверены при записи
+// блока, здесь ключ только разбирается заново ради отпечатка
+func (api *API) signedResponse(resp *viewmodels.VerificationResponse, receipt *blockchain.DepositReceipt) {
+	data := receipt.Data
+	resp.RecordType = string(data.Type)
+	if data.Coauthored() {
+		resp.Authors = api.authorInfos(data.Authors)
+		return
+	}
+	if !data.Signed() {
+		return
+	}
+	key, err := data.VerifySignature()
+	if err != nil {
+		return
+	}
+	resp.Signed = true
+	resp.KeyType = string(key.Type)
+	resp.KeyFingerprint = key.Fingerprint
+
+	status := api.blockchain.KeyStatus(key.Fingerprint)
+	resp.KeySuccessors = status.Successors
+	if status.Revoked {
+		resp.KeyRevoked = true
+		resp.KeyRevokedAt = &status.RevokedAt
+		resp.SignedAfterRevocation = !receipt.Block.Timestamp.Before(status.RevokedAt)
+	}
+}
+
+// versionsResponse дополняет ответ проверки историей версий работы,
+// если у неё есть другие версии
+func (api *API) versionsResponse(resp *viewmodels.VerificationResponse, receipt *blockchain.DepositReceipt) {
+	resp.ParentID = receipt.Data.ParentID
+	receipts, err := api.blockchain.Versions(receipt.Ref)
+	if err != nil || len(receipts) < 2 {
+		return
+	}
+	resp.Versions = workVersions(receipts)
+}
+
+// merkleProofResponse переводит доказательство включения в модель ответа
+func merkleProofResponse(proof *blockchain.MerkleProof) *viewmodels.MerkleProof {
+	if proof == nil {
+		return nil
+	}
+
+	steps := make([]viewmodels.ProofStep, len(proof.Siblings))
+	for i, step := range proof.Siblings {
+		steps[i] = viewmodels.ProofStep{Hash: step.Hash, Left: step.Left}
+	}
+	return &viewmodels.MerkleProof{
+		Algorithm: string(proof.Algorithm),
+		Index:     proof.Index,
+		Leaf:      proof.Leaf,
+		Siblings:  steps,
+		Root:      proof.Root,
+	}
+}
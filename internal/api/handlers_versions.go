@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+// handleVersionsJSON godoc
+//
+// @Summary      История версий работы
+// @Description  Возвращает все версии работы, в которую входит депозит: первую версию и следующие от неё (parent_id), в порядке цепочки с временем фиксации
+// @Tags         Verify
+// @Produce      json
+// @Param        id path string true "ID блока или депозита (ID.индекс)"
+// @Success      200 {object} viewmodels.VersionsResponse
+// @Failure      404 {object} viewmodels.ErrorResponse "Депозит не найден"
+// @Router       /api/v1/blocks/{id}/versions [get]
+func (api *API) handleVersionsJSON(w http.ResponseWriter, r *http.Request) {
+	ref := mux.Vars(r)["id"]
+
+	receipts, err := api.blockchain.Versions(ref)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Депозит не найден", err)
+		return
+	}
+
+	versions := workVersions(receipts)
+	api.sendJSON(w, http.StatusOK, viewmodels.VersionsResponse{
+		DepositRef: ref,
+		Count:      len(versions),
+		Versions:   versions,
+	})
+}
+
+// workVersions переводит квитанции истории версий в модель ответа
+func workVersions(receipts []*blockchain.DepositReceipt) []viewmodels.WorkVersion {
+	versions := make([]viewmodels.WorkVersion, len(receipts))
+	for i, receipt := range receipts {
+		versions[i] = viewmodels.WorkVersion{
+			Version:    i + 1,
+			DepositRef: receipt.Ref,
+			ParentID:   receipt.Data.ParentID,
+			Author:     receipt.Data.AuthorName,
+			Title:      receipt.Data.Title,
+			Timestamp:  receipt.Block.Timestamp,
+			Hash:       receipt.Data.ContentHash,
+			HashAlg:    string(receipt.Data.ContentHashAlgorithm()),
+		}
+		if key, err := blockchain.ParseAuthorKey(receipt.Data.PublicKey); err == nil {
+			versions[i].KeyFingerprint = key.Fingerprint
+		}
+	}
+	return versions
+}
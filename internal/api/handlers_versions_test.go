@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blockchain-verifier/internal/blockchain"
+	"blockchain-verifier/internal/testutil"
+	"blockchain-verifier/internal/viewmodels"
+
+	"github.com/gorilla/mux"
+)
+
+func TestWorkVersions(t *testing.T) {
+	bc := blockchain.NewBlockchainWithStorage(blockchain.NewTestStorage(), 1)
+	api := NewAPI(bc)
+
+	newKey := func() (ed25519.PrivateKey, string) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		return priv, base64.StdEncoding.EncodeToString(pub)
+	}
+	owner, ownerKey := newKey()
+	stranger, strangerKey := newKey()
+
+	deposit := func(priv ed25519.PrivateKey, publicKey, text, parent string) *httptest.ResponseRecorder {
+		req := viewmodels.DepositRequest{AuthorName: "Author", Title: "Novel", Text: text, PublicKey: publicKey, ParentID: parent}
+		if priv != nil {
+			req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, api.depositData(req).SigningMessage()))
+		}
+		resp := httptest.NewRecorder()
+		api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", testutil.CreateJSONBody(t, req)))
+		return resp
+	}
+	depositID := func(resp *httptest.ResponseRecorder) string {
+		t.Helper()
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+		var out viewmodels.DepositResponsePublic
+		testutil.ParseJSONResponse(t, resp, &out)
+		return out.DepositRef
+	}
+
+	first := depositID(deposit(owner, ownerKey, "First draft", ""))
+	second := depositID(deposit(owner, ownerKey, "Second draft", first))
+
+	t.Run("foreign key", func(t *testing.T) {
+		resp := deposit(stranger, strangerKey, "Stolen draft", first)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("unsigned version", func(t *testing.T) {
+		resp := deposit(nil, "", "Unsigned draft", first)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("unknown parent", func(t *testing.T) {
+		resp := deposit(owner, ownerKey, "Orphan draft", "999-999-999")
+		testutil.AssertStatusCode(t, resp.Code, http.StatusBadRequest)
+	})
+
+	t.Run("same account", func(t *testing.T) {
+		byAccount := func(text, parent, token string) *httptest.ResponseRecorder {
+			req := viewmodels.DepositRequest{AuthorName: "Author", Title: "Essay", Text: text, ParentID: parent, AccountToken: token}
+			resp := httptest.NewRecorder()
+			api.handleDepositJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/deposit", testutil.CreateJSONBody(t, req)))
+			return resp
+		}
+
+		draft := depositID(byAccount("Unsigned essay", "", "owner-account-token"))
+		depositID(byAccount("Unsigned essay, revised", draft, "owner-account-token"))
+
+		testutil.AssertStatusCode(t, byAccount("Hijacked essay", draft, "other-account-token").Code, http.StatusBadRequest)
+		testutil.AssertStatusCode(t, byAccount("Short token essay", "", "short").Code, http.StatusBadRequest)
+	})
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/blocks/"+id+"/versions", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		resp := httptest.NewRecorder()
+		api.handleVersionsJSON(resp, req)
+		return resp
+	}
+
+	t.Run("history", func(t *testing.T) {
+		resp := get(second)
+		testutil.AssertStatusCode(t, resp.Code, http.StatusOK)
+
+		var out viewmodels.VersionsResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.Count, 2, "count")
+		if len(out.Versions) == 2 {
+			testutil.AssertEqual(t, out.Versions[0].DepositRef, first, "first version")
+			testutil.AssertEqual(t, out.Versions[1].DepositRef, second, "second version")
+			testutil.AssertEqual(t, out.Versions[1].ParentID, first, "parent")
+			testutil.AssertEqual(t, out.Versions[1].Version, 2, "version number")
+			if out.Versions[1].Timestamp.Before(out.Versions[0].Timestamp) {
+				t.Error("versions are not in chain order")
+			}
+		}
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		testutil.AssertStatusCode(t, get("999-999-999").Code, http.StatusNotFound)
+	})
+
+	t.Run("verify response", func(t *testing.T) {
+		body := testutil.CreateJSONBody(t, viewmodels.VerifyByIDRequest{ID: second})
+		resp := httptest.NewRecorder()
+		api.handleVerifyByIDJSON(resp, testutil.HTTPTestRequest("POST", "/api/v1/verify/id", body))
+
+		var out viewmodels.VerificationResponse
+		testutil.ParseJSONResponse(t, resp, &out)
+		testutil.AssertEqual(t, out.ParentID, first, "parent_id")
+		testutil.AssertEqual(t, len(out.Versions), 2, "versions")
+	})
+}
@@ -18,7 +18,7 @@ func mapStatsCards(stats viewmodels.StatsResponse) []components.StatsCardParams
 		{
 			Icon:     "fas fa-file-alt",
 			Title:    "Всего текстов",
-			Value:    strconv.Itoa(stats.TotalBlocks),
+			Value:    strconv.Itoa(stats.TotalDeposits),
 			Subtitle: "Зафиксированных документов",
 		},
 		{
@@ -0,0 +1,37 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Учётные записи авторов.
+//
+// Учётная запись - секретный токен, который автор передаёт вместе с
+// депозитом. В блок попадает только её идентификатор (AccountID), а
+// токен нигде не хранится. Депозит с учётной записью может продолжить
+// новой версией, отозвать или исправить автор с тем же токеном, даже
+// если работа не подписана.
+//
+// В отличие от подписи, владение токеном проверяет сервер при приёме
+// депозита: по самой цепочке видно лишь, что идентификаторы совпадают
+const (
+	// MinAccountTokenLength - минимальная длина токена: идентификатор
+	// публичен, и короткий токен по нему можно подобрать
+	MinAccountTokenLength = 16
+
+	accountIDDomain = "textproof-account-v1\x00"
+)
+
+// AccountID возвращает идентификатор учётной записи с токеном token:
+// SHA-256 от токена с префиксом домена в hex
+func AccountID(token string) string {
+	sum := sha256.Sum256([]byte(accountIDDomain + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sameAccount сообщает, что data добавлен из учётной записи депозита
+// owner. Депозит без учётной записи ни с кем не совпадает
+func sameAccount(owner, data DepositData) bool {
+	return owner.Account != "" && owner.Account == data.Account
+}
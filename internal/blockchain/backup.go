@@ -0,0 +1,366 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxBackups - сколько бэкапов хранится по умолчанию
+	MaxBackups = 5
+
+	// DefaultBackupEvery - бэкап создаётся каждые N добавленных блоков
+	DefaultBackupEvery = 100
+
+	backupManifestName = "manifest.json"
+	backupTimeFormat   = "20060102T150405.000000000Z"
+)
+
+// BackupPolicy - политика создания и хранения резервных копий
+type BackupPolicy struct {
+	// Every - создавать бэкап каждые N блоков; 0 - только перед
+	// перезаписью цепочки (SaveChain)
+	Every int
+
+	// KeepCount - сколько последних бэкапов хранить; 0 - MaxBackups
+	KeepCount int
+
+	// MaxAge - бэкапы старше удаляются; 0 - без ограничения.
+	// Самый свежий бэкап не удаляется никогда
+	MaxAge time.Duration
+}
+
+// DefaultBackupPolicy возвращает политику по умолчанию
+func DefaultBackupPolicy() BackupPolicy {
+	return BackupPolicy{
+		Every:     DefaultBackupEvery,
+		KeepCount: MaxBackups,
+	}
+}
+
+// BackupEntry - запись манифеста о резервной копии
+type BackupEntry struct {
+	File      string    `json:"file"`
+	CreatedAt time.Time `json:"created_at"`
+	Length    int       `json:"length"`   // число блоков в цепочке
+	TipHash   string    `json:"tip_hash"` // хеш последнего блока
+	SHA256    string    `json:"sha256"`   // хеш содержимого файла
+}
+
+// backupManifest - список бэкапов от старых к новым
+type backupManifest struct {
+	Backups []BackupEntry `json:"backups"`
+}
+
+// backupFileName формирует имя бэкапа из времени и высоты цепочки
+func backupFileName(created time.Time, length int) string {
+	return fmt.Sprintf("blockchain_%s_h%06d.json", created.UTC().Format(backupTimeFormat), length-1)
+}
+
+// CreateBackup создает резервную копию блокчейна и регистрирует
+// её в манифесте
+func (s *Storage) CreateBackup() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+
+	// Проверяем, есть ли что сохранять
+	if s.log.Len() == 0 {
+		return nil // Нет цепочки для бэкапа
+	}
+
+	// Снимаем копию цепочки из журнала
+	blocks, err := s.log.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read segment log: %v", err)
+	}
+
+	data, err := encodeChainFile(blocks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup: %v", err)
+	}
+
+	manifest, err := s.loadBackupManifest()
+	if err != nil {
+		return err
+	}
+
+	created := time.Now().UTC()
+	sum := sha256.Sum256(data)
+	entry := BackupEntry{
+		File:      backupFileName(created, len(blocks)),
+		CreatedAt: created,
+		Length:    len(blocks),
+		TipHash:   blocks[len(blocks)-1].Hash,
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+
+	// Записываем бэкап: сначала файл, потом запись в манифесте
+	if err := writeFileSync(s.fs, filepath.Join(s.backupDir, entry.File), data); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	manifest.Backups = append(manifest.Backups, entry)
+
+	// Удаляем бэкапы, вышедшие за политику хранения
+	removed := s.applyBackupRetention(manifest, created)
+
+	if err := s.saveBackupManifest(manifest); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %v", err)
+	}
+
+	for _, name := range removed {
+		if err := s.fs.Remove(filepath.Join(s.backupDir, name)); err != nil && !os.IsNotExist(err) {
+			// Не критическая ошибка, можно продолжить
+			slog.Warn("Failed to remove old backup", "file", name, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// startBackupWorker запускает горутину периодических бэкапов
+func (s *Storage) startBackupWorker() {
+	s.backupReq = make(chan struct{}, 1)
+	s.backupDone = make(chan struct{})
+
+	go func() {
+		defer close(s.backupDone)
+		for range s.backupReq {
+			// Блоки уже зафиксированы, ошибка бэкапа их не отменяет
+			if err := s.CreateBackup(); err != nil {
+				slog.Warn("Failed to create backup", "error", err)
+			}
+		}
+	}()
+}
+
+// requestBackup просит фоновую горутину создать бэкап. Запросы,
+// пришедшие пока бэкап ещё создаётся, сливаются в один: бэкап всё
+// равно снимает журнал целиком
+func (s *Storage) requestBackup() {
+	if s.backupReq == nil {
+		return
+	}
+	select {
+	case s.backupReq <- struct{}{}:
+	default:
+	}
+}
+
+// stopBackupWorker дожидается запрошенного бэкапа и останавливает
+// горутину
+func (s *Storage) stopBackupWorker() {
+	if s.backupReq == nil {
+		return
+	}
+	close(s.backupReq)
+	<-s.backupDone
+	s.backupReq = nil
+}
+
+// applyBackupRetention убирает из манифеста бэкапы сверх KeepCount
+// и старше MaxAge. Возвращает имена файлов, которые нужно удалить
+func (s *Storage) applyBackupRetention(m *backupManifest, now time.Time) []string {
+	keep := s.backups.KeepCount
+	if keep <= 0 {
+		keep = MaxBackups
+	}
+
+	var kept []BackupEntry
+	var removed []string
+	last := len(m.Backups) - 1
+
+	for i, entry := range m.Backups {
+		tooMany := last-i >= keep
+		tooOld := s.backups.MaxAge > 0 && now.Sub(entry.CreatedAt) > s.backups.MaxAge
+		if i != last && (tooMany || tooOld) {
+			removed = append(removed, entry.File)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	m.Backups = kept
+	return removed
+}
+
+// RestoreFromBackup восстанавливает цепочку из самого свежего
+// бэкапа, который проходит проверку.
+//
+// Бэкапы перебираются по манифесту от новых к старым. Бэкап
+// пропускается, если файл не совпадает с манифестом по SHA-256,
+// длине или хешу последнего блока, либо если accept его отклоняет.
+// accept == nil принимает любую цепочку, прошедшую сверку с манифестом
+func (s *Storage) RestoreFromBackup(accept func(chain []*Block) bool) (*BackupEntry, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+
+	manifest, err := s.loadBackupManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Backups) == 0 {
+		return nil, fmt.Errorf("no backups found")
+	}
+
+	for i := len(manifest.Backups) - 1; i >= 0; i-- {
+		entry := manifest.Backups[i]
+
+		chain, err := s.readBackup(entry)
+		if err != nil {
+			slog.Warn("Skipping backup", "file", entry.File, "error", err)
+			continue
+		}
+
+		if accept != nil && !accept(chain) {
+			slog.Warn("Skipping backup", "file", entry.File, "error", "chain validation failed")
+			continue
+		}
+
+		if err := s.log.Reset(chain); err != nil {
+			return nil, fmt.Errorf("failed to restore from backup: %v", err)
+		}
+
+		slog.Info("Restored from backup", "file", entry.File, "blocks", entry.Length)
+		return &entry, nil
+	}
+
+	return nil, fmt.Errorf("no valid backups found")
+}
+
+// readBackup читает бэкап и сверяет его с записью манифеста
+func (s *Storage) readBackup(entry BackupEntry) ([]*Block, error) {
+	data, err := s.fs.ReadFile(filepath.Join(s.backupDir, entry.File))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return nil, fmt.Errorf("sha256 mismatch")
+	}
+
+	// Бэкапы прежних версий формата поднимаются до текущей при чтении
+	chain, err := decodeChainFile(entry.File, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse backup file: %w", err)
+	}
+
+	if len(chain) != entry.Length {
+		return nil, fmt.Errorf("length mismatch: manifest %d, file %d", entry.Length, len(chain))
+	}
+	if len(chain) == 0 || chain[len(chain)-1].Hash != entry.TipHash {
+		return nil, fmt.Errorf("tip hash mismatch")
+	}
+
+	return chain, nil
+}
+
+// Backups возвращает записи манифеста от старых к новым
+func (s *Storage) Backups() ([]BackupEntry, error) {
+	manifest, err := s.loadBackupManifest()
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Backups, nil
+}
+
+// loadBackupManifest читает манифест. Если манифеста нет, он
+// собирается из уже лежащих в каталоге бэкапов прежнего формата
+func (s *Storage) loadBackupManifest() (*backupManifest, error) {
+	path := filepath.Join(s.backupDir, backupManifestName)
+
+	data, err := s.fs.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s.importLegacyBackups()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %v", err)
+	}
+
+	var m backupManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// importLegacyBackups регистрирует в манифесте бэкапы, созданные
+// до его появления (blockchain_backup_<pid>.json). Порядок - по
+// времени модификации, нечитаемые файлы пропускаются
+func (s *Storage) importLegacyBackups() (*backupManifest, error) {
+	entries, err := s.fs.ReadDir(s.backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	m := &backupManifest{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		data, err := s.fs.ReadFile(filepath.Join(s.backupDir, e.Name()))
+		if err != nil {
+			continue
+		}
+
+		var bc Blockchain
+		if err := json.Unmarshal(data, &bc); err != nil || len(bc.Chain) == 0 {
+			slog.Warn("Ignoring unreadable legacy backup", "file", e.Name())
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		m.Backups = append(m.Backups, BackupEntry{
+			File:      e.Name(),
+			CreatedAt: info.ModTime().UTC(),
+			Length:    len(bc.Chain),
+			TipHash:   bc.Chain[len(bc.Chain)-1].Hash,
+			SHA256:    hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.SliceStable(m.Backups, func(i, j int) bool {
+		return m.Backups[i].CreatedAt.Before(m.Backups[j].CreatedAt)
+	})
+
+	return m, nil
+}
+
+// saveBackupManifest атомарно перезаписывает манифест
+func (s *Storage) saveBackupManifest(m *backupManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.backupDir, backupManifestName)
+	tmp := path + ".tmp"
+	if err := writeFileSync(s.fs, tmp, data); err != nil {
+		return err
+	}
+	return s.fs.Rename(tmp, path)
+}
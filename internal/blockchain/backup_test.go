@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBackupStorage открывает Storage с политикой бэкапов policy
+// и записывает в журнал цепочку из n блоков (0 - пустой журнал)
+func newTestBackupStorage(t *testing.T, policy BackupPolicy, n int) *Storage {
+	t.Helper()
+
+	storage, err := NewStorageWithOptions(t.TempDir(), StoreOptions{Backups: policy})
+	if err != nil {
+		t.Fatalf("NewStorageWithOptions() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+
+	if n > 0 {
+		AssertNoError(t, storage.log.Reset(testChainBlocks(n)))
+	}
+	return storage
+}
+
+func TestStorage_CreateBackupManifest(t *testing.T) {
+	storage := newTestBackupStorage(t, BackupPolicy{}, 3)
+
+	AssertNoError(t, storage.CreateBackup())
+
+	backups, err := storage.Backups()
+	AssertNoError(t, err)
+	AssertEqual(t, len(backups), 1, "Manifest entries")
+
+	entry := backups[0]
+	AssertEqual(t, entry.Length, 3, "Backup length")
+	if !strings.HasPrefix(entry.File, "blockchain_") || !strings.HasSuffix(entry.File, "_h000002.json") {
+		t.Errorf("Backup name = %q, want blockchain_<time>_h000002.json", entry.File)
+	}
+
+	blocks, _ := storage.log.ReadAll()
+	AssertEqual(t, entry.TipHash, blocks[2].Hash, "Tip hash")
+
+	chain, err := storage.readBackup(entry)
+	AssertNoError(t, err, "Backup should match manifest")
+	AssertEqual(t, len(chain), 3, "Backup chain length")
+}
+
+func TestStorage_BackupRetention(t *testing.T) {
+	t.Run("by count", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{KeepCount: 3}, 2)
+
+		for i := 0; i < 6; i++ {
+			AssertNoError(t, storage.CreateBackup())
+		}
+
+		backups, _ := storage.Backups()
+		AssertEqual(t, len(backups), 3, "Manifest entries")
+
+		files, _ := filepath.Glob(filepath.Join(storage.backupDir, "blockchain_*.json"))
+		AssertEqual(t, len(files), 3, "Backup files on disk")
+	})
+
+	t.Run("by age keeps newest", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{KeepCount: 10, MaxAge: time.Hour}, 2)
+
+		AssertNoError(t, storage.CreateBackup())
+		AssertNoError(t, storage.CreateBackup())
+
+		// Состариваем оба бэкапа
+		manifest, _ := storage.loadBackupManifest()
+		for i := range manifest.Backups {
+			manifest.Backups[i].CreatedAt = manifest.Backups[i].CreatedAt.Add(-2 * time.Hour)
+		}
+		AssertNoError(t, storage.saveBackupManifest(manifest))
+
+		AssertNoError(t, storage.CreateBackup())
+
+		backups, _ := storage.Backups()
+		AssertEqual(t, len(backups), 1, "Only the fresh backup should remain")
+	})
+
+	t.Run("every N blocks", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{Every: 2}, 0)
+
+		// Пока бэкап занят, блоки всё равно подтверждаются
+		storage.backupMu.Lock()
+		for _, block := range testChainBlocks(5) {
+			AssertNoError(t, storage.SaveBlock(block))
+		}
+		storage.backupMu.Unlock()
+
+		// Close дожидается запрошенного бэкапа
+		AssertNoError(t, storage.Close())
+
+		backups, _ := storage.Backups()
+		if len(backups) == 0 {
+			t.Fatal("Periodic backup should be created")
+		}
+		AssertEqual(t, backups[len(backups)-1].Length, 5, "Latest backup length")
+	})
+}
+
+func TestStorage_RestoreFromBackup(t *testing.T) {
+	t.Run("skips tampered and rejected backups", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{KeepCount: 10}, 2)
+		AssertNoError(t, storage.CreateBackup()) // 2 блока - останется целым
+
+		AssertNoError(t, storage.log.Reset(testChainBlocks(3)))
+		AssertNoError(t, storage.CreateBackup()) // 3 блока - отклонит accept
+
+		AssertNoError(t, storage.log.Reset(testChainBlocks(4)))
+		AssertNoError(t, storage.CreateBackup()) // 4 блока - испортим файл
+
+		backups, _ := storage.Backups()
+		tampered := filepath.Join(storage.backupDir, backups[2].File)
+		AssertNoError(t, os.WriteFile(tampered, []byte(`{"chain":[]}`), 0644))
+
+		AssertNoError(t, storage.log.Reset(testChainBlocks(1)))
+
+		entry, err := storage.RestoreFromBackup(func(chain []*Block) bool {
+			return len(chain) != 3
+		})
+		AssertNoError(t, err)
+		AssertEqual(t, entry.File, backups[0].File, "Restored backup")
+		AssertEqual(t, storage.log.Len(), 2, "Restored chain length")
+	})
+
+	t.Run("no valid backups", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{}, 2)
+		AssertNoError(t, storage.CreateBackup())
+
+		_, err := storage.RestoreFromBackup(func([]*Block) bool { return false })
+		AssertError(t, err, "RestoreFromBackup should fail when every backup is rejected")
+		AssertEqual(t, storage.log.Len(), 2, "Chain must stay untouched")
+	})
+
+	t.Run("legacy backups are imported", func(t *testing.T) {
+		storage := newTestBackupStorage(t, BackupPolicy{}, 1)
+
+		data, _ := json.Marshal(&Blockchain{Chain: testChainBlocks(3)})
+		legacy := filepath.Join(storage.backupDir, "blockchain_backup_1234.json")
+		AssertNoError(t, os.WriteFile(legacy, data, 0644))
+
+		entry, err := storage.RestoreFromBackup(nil)
+		AssertNoError(t, err)
+		AssertEqual(t, entry.File, "blockchain_backup_1234.json", "Restored legacy backup")
+		AssertEqual(t, storage.log.Len(), 3, "Restored chain length")
+	})
+}
@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchOptions задаёт, когда Batcher запечатывает накопленные депозиты
+// в блок
+type BatchOptions struct {
+	MaxSize int           // блок запечатывается, как только в пакете MaxSize депозитов
+	MaxWait time.Duration // сколько первый депозит пакета ждёт остальных
+}
+
+// Batcher собирает депозиты в очередь и запечатывает их одним блоком
+// по размеру или по времени: один запуск Proof-of-Work на пакет
+// вместо запуска на каждый депозит
+type Batcher struct {
+	bc   *Blockchain
+	opts BatchOptions
+	ctx  context.Context // ограничивает майнинг пакетов
+
+	mu      sync.Mutex
+	pending []*pendingDeposit
+	batch   int // номер текущего пакета, чтобы таймер не запечатал следующий
+	timer   *time.Timer
+	closed  bool
+
+	// sealMu выстраивает запечатывание пакетов в очередь, чтобы Close
+	// мог дождаться пакетов, запечатанных в других горутинах
+	sealMu sync.Mutex
+}
+
+// pendingDeposit - депозит, ожидающий запечатывания
+type pendingDeposit struct {
+	data    DepositData
+	done    chan struct{}
+	receipt *DepositReceipt
+	err     error
+}
+
+// NewBatcher создаёт очередь депозитов поверх bc. MaxSize меньше 1
+// считается равным 1. Отмена ctx прерывает майнинг пакетов: ожидающие
+// депозиты получают ошибку
+func NewBatcher(ctx context.Context, bc *Blockchain, opts BatchOptions) *Batcher {
+	if opts.MaxSize < 1 {
+		opts.MaxSize = 1
+	}
+	return &Batcher{bc: bc, opts: opts, ctx: ctx}
+}
+
+// Submit ставит депозит в очередь и ждёт, пока его блок будет записан.
+//
+// Уже записанный депозит возвращается сразу. Отмена ctx прекращает
+// ожидание, но не убирает депозит из пакета
+func (b *Batcher) Submit(ctx context.Context, data DepositData) (*DepositReceipt, error) {
+	if receipt, err := b.bc.FindDeposit(data.ContentHash); err == nil {
+		return receipt, nil
+	}
+
+	p := &pendingDeposit{data: data, done: make(chan struct{})}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrBatcherClosed
+	}
+	b.pending = append(b.pending, p)
+	var full []*pendingDeposit
+	if len(b.pending) >= b.opts.MaxSize {
+		full = b.takeLocked()
+	} else if b.timer == nil {
+		batch := b.batch
+		b.timer = time.AfterFunc(b.opts.MaxWait, func() { b.sealExpired(batch) })
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		go b.seal(full)
+	}
+
+	select {
+	case <-p.done:
+		return p.receipt, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Flush запечатывает текущий пакет, не дожидаясь таймера
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	pending := b.takeLocked()
+	b.mu.Unlock()
+
+	b.seal(pending)
+}
+
+// Close запечатывает оставшиеся депозиты и дожидается записи всех
+// пакетов. После Close Submit возвращает ErrBatcherClosed
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	pending := b.takeLocked()
+	b.mu.Unlock()
+
+	b.seal(pending)
+
+	// Ждём пакеты, запечатанные по размеру в других горутинах
+	b.sealMu.Lock()
+	b.sealMu.Unlock()
+}
+
+// sealExpired запечатывает пакет batch по истечении MaxWait, если его
+// ещё не запечатали по размеру
+func (b *Batcher) sealExpired(batch int) {
+	b.mu.Lock()
+	if b.batch != batch {
+		b.mu.Unlock()
+		return
+	}
+	pending := b.takeLocked()
+	b.mu.Unlock()
+
+	b.seal(pending)
+}
+
+// takeLocked забирает накопленный пакет и начинает следующий.
+// Вызывается под b.mu
+func (b *Batcher) takeLocked() []*pendingDeposit {
+	pending := b.pending
+	b.pending = nil
+	b.batch++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return pending
+}
+
+// seal записывает пакет одним блоком и раздаёт квитанции ожидающим
+func (b *Batcher) seal(pending []*pendingDeposit) {
+	if len(pending) == 0 {
+		return
+	}
+
+	b.sealMu.Lock()
+	defer b.sealMu.Unlock()
+
+	// Депозит, противоречащий цепочке или депозитам пакета перед ним
+	// (например, подписанный ключом, отозванным в том же пакете),
+	// отклоняется один, а не вместе со всем пакетом
+	deposits := make([]DepositData, len(pending))
+	for i, p := range pending {
+		deposits[i] = p.data
+	}
+	errs := b.bc.checkBatch(deposits)
+
+	accepted := pending[:0:0]
+	deposits = deposits[:0]
+	for i, p := range pending {
+		if errs[i] != nil {
+			p.err = errs[i]
+			close(p.done)
+			continue
+		}
+		accepted = append(accepted, p)
+		deposits = append(deposits, p.data)
+	}
+	if len(accepted) == 0 {
+		return
+	}
+
+	receipts, err := b.bc.AddBatchContext(b.ctx, deposits)
+	for i, p := range accepted {
+		if err != nil {
+			p.err = err
+		} else {
+			p.receipt = receipts[i]
+		}
+		close(p.done)
+	}
+}
+
+// checkBatch проверяет депозиты пакета по состоянию цепочки, как их
+// проверит addBlockInternal, но по одному: каждый - вместе с уже
+// принятыми депозитами пакета перед ним, то есть по реестру ключей с
+// их ротациями и отзывами. Возвращает ошибку каждого депозита; nil -
+// депозит можно запечатывать. Подписи здесь не проверяются: их
+// проверяют при приёме депозита
+func (bc *Blockchain) checkBatch(deposits []DepositData) []error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	nextID := "000-000-000"
+	if n := len(bc.Chain); n > 0 {
+		if id, err := incrementID(bc.Chain[n-1].ID); err == nil {
+			nextID = id
+		}
+	}
+
+	errs := make([]error, len(deposits))
+	var accepted []DepositData
+	for i, data := range deposits {
+		trial := &Block{ID: nextID, Deposits: append(accepted[:len(accepted):len(accepted)], data)}
+		if len(trial.Deposits) == 1 {
+			trial.Data, trial.Deposits = data, nil
+		}
+
+		keys, err := bc.keys.apply(trial)
+		if err == nil {
+			err = checkVersions(trial, bc.resolveDeposit, keys)
+		}
+		if err == nil {
+			err = checkNotices(trial, bc.resolveDeposit, keys)
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		accepted = append(accepted, data)
+	}
+	return errs
+}
@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher_SealsBySize(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	b := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 4, MaxWait: time.Hour})
+	defer b.Close()
+
+	deposits := testDeposits(4)
+	receipts := make([]*DepositReceipt, len(deposits))
+	var wg sync.WaitGroup
+	for i, data := range deposits {
+		wg.Add(1)
+		go func(i int, data DepositData) {
+			defer wg.Done()
+			receipt, err := b.Submit(context.Background(), data)
+			if err != nil {
+				t.Errorf("Submit() error = %v", err)
+				return
+			}
+			receipts[i] = receipt
+		}(i, data)
+	}
+	wg.Wait()
+
+	AssertEqual(t, len(bc.Chain), 2, "One block for the whole batch")
+	block := bc.GetLastBlock()
+	AssertEqual(t, len(block.Deposits), 4, "Deposits in block")
+
+	for i, receipt := range receipts {
+		if receipt == nil {
+			t.Fatalf("No receipt for deposit %d", i)
+		}
+		AssertEqual(t, receipt.Block, block, "Receipt block")
+		AssertEqual(t, receipt.Ref, fmt.Sprintf("%s.%d", block.ID, receipt.Index), "Receipt ref")
+		if !receipt.Proof.Verify(deposits[i]) {
+			t.Errorf("Proof of deposit %d should verify", i)
+		}
+		AssertEqual(t, receipt.Proof.Root, block.MerkleRoot, "Proof root")
+	}
+}
+
+func TestBatcher_SealsByTime(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	b := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 100, MaxWait: 20 * time.Millisecond})
+	defer b.Close()
+
+	data := testDeposits(1)[0]
+	receipt, err := b.Submit(context.Background(), data)
+	AssertNoError(t, err)
+	AssertEqual(t, receipt.Ref, receipt.Block.ID, "Ref of single-deposit block")
+	AssertEqual(t, receipt.Data, data, "Receipt data")
+	AssertEqual(t, len(bc.Chain), 2, "Chain length")
+}
+
+func TestBatcher_ExistingDeposit(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	data := testDeposits(1)[0]
+	block, err := bc.AddBlock(data)
+	AssertNoError(t, err)
+
+	b := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 10, MaxWait: time.Hour})
+	defer b.Close()
+
+	receipt, err := b.Submit(context.Background(), data)
+	AssertNoError(t, err)
+	AssertEqual(t, receipt.Block, block, "Receipt of existing deposit")
+	AssertEqual(t, len(bc.Chain), 2, "No new block for existing deposit")
+}
+
+func TestBatcher_ContextCanceled(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	b := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 10, MaxWait: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	data := testDeposits(1)[0]
+	_, err := b.Submit(ctx, data)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Submit() error = %v, want context.Canceled", err)
+	}
+
+	// Депозит остался в пакете и записывается при закрытии
+	b.Close()
+	if _, err := bc.FindDeposit(data.ContentHash); err != nil {
+		t.Errorf("Deposit should be sealed on Close: %v", err)
+	}
+
+	_, err = b.Submit(context.Background(), testDeposits(2)[1])
+	if !errors.Is(err, ErrBatcherClosed) {
+		t.Errorf("Submit() after Close error = %v, want ErrBatcherClosed", err)
+	}
+}
+
+func TestBatcher_RejectsOnlyConflictingDeposit(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	b := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 3, MaxWait: time.Hour})
+	defer b.Close()
+	a, next := newTestAuthorKey(t), newTestAuthorKey(t)
+
+	// Отменённый контекст оставляет депозит в пакете, не дожидаясь
+	// записи: так задаётся порядок депозитов в пакете
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rotation := a.sign(NewKeyRotation(bc.HashAlgorithm(), a.public, next.public))
+	b.Submit(canceled, rotation)
+	plain := testDeposits(1)[0]
+	b.Submit(canceled, plain)
+
+	// Депозит старым ключом после ротации в том же пакете
+	stale := CreateTestBlock("Author", "Title", "signed by the rotated key")
+	stale.PublicKey = a.public
+	_, err := b.Submit(context.Background(), a.sign(stale))
+	if !errors.Is(err, ErrKeyRotated) {
+		t.Fatalf("Submit() error = %v, want ErrKeyRotated", err)
+	}
+
+	b.Close()
+	for name, data := range map[string]DepositData{"rotation": rotation, "plain deposit": plain} {
+		if _, err := bc.FindDeposit(data.ContentHash); err != nil {
+			t.Errorf("%s should be sealed: %v", name, err)
+		}
+	}
+	AssertEqual(t, len(bc.Chain), 2, "One block for the rest of the batch")
+	AssertEqual(t, len(bc.GetLastBlock().Deposits), 2, "Deposits in block")
+}
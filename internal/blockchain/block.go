@@ -1,111 +1,183 @@
 package blockchain
 
 import (
-	"crypto/sha256"
+	"context"
 	"encoding/hex"
-	"encoding/json"
+	"log/slog"
 	"time"
 )
 
-// DepositData содержит данные о депонируемом тексте
+// DepositData содержит данные о депонируемом тексте или, если задан
+// Type, служебную запись о ключах автора (см. RecordType)
 type DepositData struct {
 	AuthorName  string `json:"author_name"`
 	Title       string `json:"title"`
 	TextStart   string `json:"text_start"`   // 2-3 слова из начала
 	TextEnd     string `json:"text_end"`     // 2-3 слова из конца
-	ContentHash string `json:"content_hash"` // SHA-256 всего текста
+	ContentHash string `json:"content_hash"` // Хеш всего текста
 	PublicKey   string `json:"public_key,omitempty"`
+
+	// ContentHashAlg - алгоритм ContentHash, пустой - SHA-256
+	ContentHashAlg HashAlgorithm `json:"content_hash_alg,omitempty"`
+
+	// Signature - подпись автора ключом PublicKey в base64,
+	// см. SigningMessage
+	Signature string `json:"signature,omitempty"`
+
+	// ParentID - ссылка (см. DepositRef) на прежнюю версию того же
+	// произведения. Новую версию подписывает ключ прежней или его
+	// преемник либо добавляет та же учётная запись
+	ParentID string `json:"parent_id,omitempty"`
+
+	// Account - идентификатор учётной записи автора, см. AccountID
+	Account string `json:"account,omitempty"`
+
+	// Authors - соавторы работы, см. Author. У работы с соавторами
+	// AuthorName - их имена через запятую, а PublicKey и Signature пусты:
+	// каждый соавтор подписывает депозит своим ключом
+	Authors []Author `json:"authors,omitempty"`
+
+	// Type - тип записи, пустой - депонированный текст. Поля ниже
+	// есть только у служебных записей
+	Type       RecordType `json:"type,omitempty"`
+	NewKey     string     `json:"new_key,omitempty"`     // Ключ-преемник (ротация)
+	RevokedKey string     `json:"revoked_key,omitempty"` // Отзываемый ключ
+	RevokedAt  time.Time  `json:"revoked_at,omitzero"`   // Ключ недействителен с этого момента
+	Target     string     `json:"target,omitempty"`      // Отзываемый или исправляемый депозит
+	Reason     string     `json:"reason,omitempty"`      // Причина отзыва или исправления
 }
 
-// Block представляет один блок в цепочке
-type Block struct {
-	ID        string      `json:"id"`        // "000-000-001"
-	PrevHash  string      `json:"prev_hash"` // Хеш предыдущего блока
-	Timestamp time.Time   `json:"timestamp"` // Время создания
-	Data      DepositData `json:"data"`      // Данные депозита
-	Nonce     int         `json:"nonce"`     // Число для Proof-of-Work
-	Hash      string      `json:"hash"`      // Хеш этого блока
+// ContentHashAlgorithm возвращает алгоритм хеша содержимого
+func (d DepositData) ContentHashAlgorithm() HashAlgorithm {
+	return d.ContentHashAlg.normalize()
 }
 
-// hashData структура только для хеширования
-type hashData struct {
-	ID        string      `json:"id"`
-	PrevHash  string      `json:"prev_hash"`
-	Timestamp time.Time   `json:"timestamp"`
-	Data      DepositData `json:"data"`
-	Nonce     int         `json:"nonce"`
+// Block представляет один блок в цепочке.
+//
+// Блок с одним депозитом хранит его в Data, пакетный - в Deposits
+// (Data тогда пустой). Депозиты закрепляются в заголовке через
+// MerkleRoot; у блоков, записанных до пакетов, корня нет.
+//
+// Target - 256-битная цель, с которой блок намайнен. Блоки, записанные
+// до целей, хранят вместо неё Difficulty - число hex-нулей в начале
+// хеша. У генезиса и более старых блоков нет ни того, ни другого.
+//
+// Version задаёт кодирование заголовка для хеша; у блоков, записанных
+// до версий, его нет (версия 0). HashAlg - алгоритм хеша блока и его
+// дерева Меркла; у блоков, записанных до алгоритмов, его нет (SHA-256)
+type Block struct {
+	Version    int           `json:"version,omitempty"`     // Версия заголовка, см. HeaderBytes
+	HashAlg    HashAlgorithm `json:"hash_alg,omitempty"`    // Алгоритм хеша блока
+	ID         string        `json:"id"`                    // "000-000-001"
+	PrevHash   string        `json:"prev_hash"`             // Хеш предыдущего блока
+	Timestamp  time.Time     `json:"timestamp"`             // Время создания
+	Data       DepositData   `json:"data"`                  // Данные депозита
+	Deposits   []DepositData `json:"deposits,omitempty"`    // Депозиты пакетного блока
+	MerkleRoot string        `json:"merkle_root,omitempty"` // Корень дерева Меркла депозитов
+	Difficulty int           `json:"difficulty,omitempty"`  // Число hex-нулей в начале хеша (старые блоки)
+	Target     string        `json:"target,omitempty"`      // Цель Proof-of-Work в hex
+	Nonce      int           `json:"nonce"`                 // Число для Proof-of-Work
+	Hash       string        `json:"hash"`                  // Хеш этого блока
 }
 
-// CalculateHash вычисляет хеш блока
+// CalculateHash вычисляет хеш блока: хеш заголовка (см. HeaderBytes)
+// алгоритмом блока. Для блока неизвестной версии или алгоритма
+// возвращает пустую строку, и проверка хеша его отвергает
 func (b *Block) CalculateHash() string {
-	// Создаем структуру для хеширования (без поля Hash)
-	data := hashData{
-		ID:        b.ID,
-		PrevHash:  b.PrevHash,
-		Timestamp: b.Timestamp,
-		Data:      b.Data,
-		Nonce:     b.Nonce,
-	}
-
-	// Сериализуем в JSON
-	jsonData, err := json.Marshal(data)
+	header, err := b.HeaderBytes()
 	if err != nil {
-		// ✅ УЛУЧШЕНО: добавлен лог ошибки
-		// В production лучше вернуть error, но для совместимости оставляем как есть
+		slog.Error("Failed to encode block header", "block", b.ID, "error", err)
 		return ""
 	}
 
-	// Вычисляем SHA-256
-	hash := sha256.Sum256(jsonData)
+	hash := b.HashAlgorithm().sum(header)
 	return hex.EncodeToString(hash[:])
 }
 
+// HashAlgorithm возвращает алгоритм хеша блока
+func (b *Block) HashAlgorithm() HashAlgorithm {
+	return b.HashAlg.normalize()
+}
+
 // ValidateHash проверяет, соответствует ли хеш блока его содержимому
 func (b *Block) ValidateHash() bool {
-	return b.Hash == b.CalculateHash()
+	hash := b.CalculateHash()
+	return hash != "" && b.Hash == hash
 }
 
-// Mine выполняет майнинг блока с заданной сложностью
-func (b *Block) Mine(difficulty int) {
-	// Генерируем строку из нужного количества нулей
-	prefix := ""
-	for i := 0; i < difficulty; i++ {
-		prefix += "0"
+// DepositList возвращает депозиты блока: Deposits пакетного блока
+// или единственный Data
+func (b *Block) DepositList() []DepositData {
+	if len(b.Deposits) > 0 {
+		return b.Deposits
+	}
+	return []DepositData{b.Data}
+}
+
+// ValidateMerkleRoot проверяет, что MerkleRoot закрепляет депозиты
+// блока. Блок без корня валиден, только если он не пакетный и записан
+// в заголовке версии 0: двоичный заголовок не содержит подпись,
+// соавторов, ссылки и остальные поля депозита, их закрепляет только корень
+func (b *Block) ValidateMerkleRoot() bool {
+	if b.MerkleRoot == "" {
+		return len(b.Deposits) == 0 && b.Version == HeaderVersionJSON
 	}
+	return b.MerkleRoot == merkleRoot(b.HashAlgorithm(), b.DepositList())
+}
+
+// Mine выполняет майнинг блока со сложностью в hex-нулях и записывает
+// в блок соответствующую цель. Не прерывается; для отмены используйте
+// MineContext
+func (b *Block) Mine(difficulty int) {
+	b.MineContext(context.Background(), difficulty)
+}
 
-	// Пытаемся найти подходящий nonce
-	for {
-		b.Hash = b.CalculateHash()
-		if len(b.Hash) >= difficulty && b.Hash[:difficulty] == prefix {
-			return // Нашли!
-		}
-		b.Nonce++
+// ProofTarget возвращает цель, которой должен соответствовать хеш
+// блока: записанную в нём или полученную из сложности в hex-нулях.
+// false - блок не намайнен (генезис и блоки, записанные до хранения
+// сложности)
+func (b *Block) ProofTarget() (Target, bool) {
+	if b.Target != "" {
+		t, err := ParseTarget(b.Target)
+		return t, err == nil
+	}
+	if b.Difficulty > 0 {
+		return TargetFromDigits(b.Difficulty), true
 	}
+	return Target{}, false
 }
 
-// NewBlock создает новый блок
+// NewBlock создает новый блок. Депозит закрепляется корнем Меркла:
+// без корня заголовок не закрепляет поля депозита, которых в нём нет
 func NewBlock(id, prevHash string, data DepositData) *Block {
 	block := &Block{
-		ID:        id,
-		PrevHash:  prevHash,
-		Timestamp: time.Now(),
-		Data:      data,
-		Nonce:     0,
+		Version:    CurrentHeaderVersion,
+		HashAlg:    DefaultHashAlgorithm,
+		ID:         id,
+		PrevHash:   prevHash,
+		Timestamp:  time.Now(),
+		Data:       data,
+		Nonce:      0,
+		MerkleRoot: merkleRoot(DefaultHashAlgorithm, []DepositData{data}),
 	}
 	return block
 }
 
-// GenesisBlock создает генезис-блок
-func GenesisBlock() *Block {
-	data := DepositData{
-		AuthorName:  "Александр Сергеевич Пушкин",
-		Title:       "Евгений Онегин",
-		TextStart:   "Мой дядя самых честных правил",
-		TextEnd:     "Иных уж нет, а те далече",
-		ContentHash: "genesis_hash", // Для генезис-блока особый хеш
-	}
+// NewBatchBlock создает блок с пакетом депозитов и их корнем Меркла.
+// Единственный депозит кладётся в Data, как у обычного блока
+func NewBatchBlock(id, prevHash string, deposits []DepositData) *Block {
+	return newBatchBlock(id, prevHash, DefaultHashAlgorithm, deposits)
+}
 
-	block := NewBlock("000-000-000", "", data)
-	block.Hash = block.CalculateHash() // Генезис-блок не требует майнинга
+// newBatchBlock - NewBatchBlock с алгоритмом хеша alg
+func newBatchBlock(id, prevHash string, alg HashAlgorithm, deposits []DepositData) *Block {
+	block := NewBlock(id, prevHash, DepositData{})
+	block.HashAlg = alg
+	if len(deposits) == 1 {
+		block.Data = deposits[0]
+	} else {
+		block.Deposits = deposits
+	}
+	block.MerkleRoot = merkleRoot(alg, deposits)
 	return block
 }
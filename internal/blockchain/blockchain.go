@@ -1,48 +1,145 @@
 package blockchain
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-// Blockchain представляет цепочку блоков
+// Blockchain представляет цепочку блоков.
+//
+// Difficulty - сложность новых блоков в hex-нулях, как её передали
+// в NewBlockchain; точная цель - NextTarget. Новый блок сверяется
+// с ожидаемой целью, а сохранённые - с целью, записанной в них самих,
+// поэтому перезапуск с другой сложностью или политикой пересчёта
+// меняет только новые блоки
 type Blockchain struct {
 	Chain      []*Block `json:"chain"`
 	Difficulty int      `json:"difficulty"`
 
+	// target - цель новых блоков; с пересчётом (WithRetarget) - начальная
+	target Target
+
+	// retarget - политика пересчёта цели, nil - цель постоянна
+	retarget *RetargetPolicy
+
+	// hashAlg - алгоритм хеша новых блоков и их депозитов
+	hashAlg HashAlgorithm
+
+	// genesis - генезис новой цепочки, genesisHash - закреплённый
+	// хеш генезиса ("" - не закреплён)
+	genesis     Genesis
+	genesisHash string
+
 	mu sync.RWMutex
 
-	storage      *Storage
-	blockStorage BlockStorage // для тестов
+	store Store
 
 	// индекс для O(1) проверки дубликатов текста
 	contentHashIndex map[string]*Block
+
+	// contentHashAlgs - алгоритм хеша содержимого -> число депозитов
+	// с ним в цепочке: по алгоритмам с ненулевым счётчиком ищется текст
+	contentHashAlgs map[HashAlgorithm]int
+
+	// keyIndex - отпечаток ключа автора -> подписанные им депозиты
+	keyIndex map[string][]depositPos
+
+	// keys - ротации и отзывы ключей авторов
+	keys *keyRegistry
+
+	// versionIndex - ссылка на версию произведения -> следующие версии
+	versionIndex map[string][]depositPos
+
+	// noticeIndex - ссылка на депозит -> его отзывы и исправления
+	noticeIndex map[string][]depositPos
+
+	// индексы для O(1) поиска: ID и хеш блока -> высота.
+	// Высота -> блок - это сам Chain
+	idIndex   map[string]int
+	hashIndex map[string]int
+
+	// valid - итог полной проверки цепочки при загрузке. Каждый новый
+	// блок проверяется до добавления, а откат снимает только вершину,
+	// поэтому итог остаётся верным без повторной проверки подписей
+	valid bool
+
+	// очередь майнинга: новые блоки майнит один воркер
+	mining miningQueue
+
+	// events - шина событий: записанные блоки, восстановление, сбои проверки
+	events *EventBus
 }
 
-// NewBlockchain создает новую цепочку блоков
-func NewBlockchain(storage *Storage, difficulty int) (*Blockchain, error) {
+// NewBlockchain создает новую цепочку блоков поверх хранилища store.
+//
+// Если сохранённая цепочка повреждена, NewBlockchain возвращает ошибку
+// с кодом RECOVERY_REQUIRED и ничего не меняет, пока не передана
+// опция WithRecovery
+func NewBlockchain(store Store, difficulty int, opts ...Option) (*Blockchain, error) {
+	var options chainOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	bc := &Blockchain{
 		Difficulty: difficulty,
-		storage:    storage,
+		target:     TargetFromDigits(difficulty),
+		retarget:   options.retarget,
+		hashAlg:    DefaultHashAlgorithm,
+		genesis:    DefaultGenesis(),
+		store:      store,
 
 		contentHashIndex: make(map[string]*Block),
+		contentHashAlgs:  make(map[HashAlgorithm]int),
+		keyIndex:         make(map[string][]depositPos),
+		keys:             newKeyRegistry(),
+		versionIndex:     make(map[string][]depositPos),
+		noticeIndex:      make(map[string][]depositPos),
+		idIndex:          make(map[string]int),
+		hashIndex:        make(map[string]int),
+	}
+	if options.target != nil {
+		bc.target = *options.target
+	}
+	if options.hashAlg != "" {
+		bc.hashAlg = options.hashAlg
+	}
+	if options.genesis != nil {
+		bc.genesis = *options.genesis
+	}
+	bc.genesisHash = options.genesisHash
+	bc.events = options.events
+	if bc.events == nil {
+		bc.events = NewEventBus()
 	}
 
 	// Пытаемся загрузить существующую цепочку
-	loadedBC, err := storage.LoadChain()
+	loadedBC, err := store.LoadChain()
 	if err != nil {
 		// Если файла нет, создаем новую цепочку
 		if os.IsNotExist(err) {
 			if err := bc.createGenesis(); err != nil {
 				return nil, NewBlockchainError("GENESIS_CREATION_FAILED", "failed to create genesis block", err)
 			}
+			if err := checkGenesis(bc.Chain, bc.genesisHash); err != nil {
+				return nil, err
+			}
 
 			// Сохраняем новую цепочку
-			if err := bc.saveChain(); err != nil {
+			if err := bc.store.SaveBlock(bc.Chain[0]); err != nil {
 				return nil, NewBlockchainError("CHAIN_SAVE_FAILED", "failed to save new chain", err)
 			}
 
+			bc.rebuildIndexes()
+			bc.valid = bc.validateBlocks(bc.Chain)
 			return bc, nil
 		} else {
 			// Другая ошибка
@@ -51,52 +148,71 @@ func NewBlockchain(storage *Storage, difficulty int) (*Blockchain, error) {
 	} else {
 		// Используем загруженную цепочку
 		bc.Chain = loadedBC.Chain
-		bc.rebuildContentHashIndex()
-		// Восстанавливаем из WAL, если он есть
-		if err := bc.recoverFromWAL(); err != nil {
-			// Пытаемся восстановить из бэкапа
-			// Это не критическая ошибка - просто логируем
-			// fmt.Printf("WAL recovery failed: %v\n", err)
+		bc.rebuildIndexes()
 
-			if err := bc.restoreFromBackup(); err != nil {
-				// Если не удалось восстановить, продолжаем с текущей цепочкой
-				// fmt.Printf("Backup restore failed: %v\n", err)
-			}
+		// Чужой генезис - не повреждение: восстановление его не исправит
+		if err := checkGenesis(bc.Chain, bc.genesisHash); err != nil {
+			bc.events.Publish(Event{Type: EventValidationFailed, Block: bc.Chain[0], Height: 0, Err: err})
+			return nil, err
 		}
 
-		// Проверяем целостность цепочки
-		if !bc.ValidateChain() {
-			// Пытаемся восстановить из бэкапа
-			if err := bc.restoreFromBackup(); err != nil {
-				// Если не удалось, создаем новую цепочку
-				bc.Chain = []*Block{}
-				if err := bc.createGenesis(); err != nil {
-					return nil, NewBlockchainError("GENESIS_CREATION_FAILED", "failed to create genesis block after corruption", err)
-				}
-			}
+		// Восстанавливаем из WAL, если он есть, и проверяем целостность
+		var reason string
+		var cause error
+		if err := bc.recoverFromWAL(); err != nil {
+			reason, cause = "WAL recovery failed", err
+		} else if height, err := bc.invalidBlock(bc.Chain); err != nil {
+			reason, cause = fmt.Sprintf("chain validation failed at height %d", height), err
+			bc.events.Publish(Event{Type: EventValidationFailed, Block: bc.Chain[height], Height: height, Err: err})
+		}
 
-			// Проверяем снова после восстановления
-			if !bc.ValidateChain() {
-				// Создаем новую цепочку
-				bc.Chain = []*Block{}
-				if err := bc.createGenesis(); err != nil {
-					return nil, NewBlockchainError("GENESIS_CREATION_FAILED", "failed to create genesis block", err)
-				}
+		if cause != nil {
+			if !options.recovery {
+				return nil, NewBlockchainError("RECOVERY_REQUIRED", reason, cause)
+			}
+			if _, err := bc.recoverChain(reason, cause, options.quarantineDir); err != nil {
+				return nil, NewBlockchainError("RECOVERY_FAILED", "failed to recover chain", err)
 			}
+			bc.valid = bc.validateBlocks(bc.Chain)
+		} else {
+			bc.valid = true
 		}
 	}
-	bc.rebuildContentHashIndex()
+	bc.rebuildIndexes()
 	return bc, nil
 }
 
+// rebuildIndexes перестраивает все индексы цепочки после её загрузки
+// или замены целиком
+func (bc *Blockchain) rebuildIndexes() {
+	bc.rebuildContentHashIndex()
+	bc.rebuildKeyIndex()
+	bc.rebuildVersionIndex()
+	bc.rebuildNoticeIndex()
+
+	bc.idIndex = make(map[string]int, len(bc.Chain))
+	bc.hashIndex = make(map[string]int, len(bc.Chain))
+	for height, block := range bc.Chain {
+		bc.idIndex[block.ID] = height
+		bc.hashIndex[block.Hash] = height
+	}
+}
+
 // rebuildContentHashIndex перестраивает индекс хешей содержимого
 func (bc *Blockchain) rebuildContentHashIndex() {
 	bc.contentHashIndex = make(map[string]*Block, len(bc.Chain))
+	bc.contentHashAlgs = make(map[HashAlgorithm]int)
 
 	for _, block := range bc.Chain {
+		if block == nil {
+			continue
+		}
 		// genesis тоже попадёт, это нормально
-		if block != nil && block.Data.ContentHash != "" {
-			bc.contentHashIndex[block.Data.ContentHash] = block
+		for _, data := range block.DepositList() {
+			if data.ContentHash != "" {
+				bc.contentHashIndex[data.ContentHash] = block
+				bc.contentHashAlgs[data.ContentHashAlgorithm()]++
+			}
 		}
 	}
 }
@@ -106,15 +222,24 @@ func (bc *Blockchain) createGenesis() error {
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
-	genesis := GenesisBlock()
-	bc.Chain = []*Block{genesis}
+	bc.Chain = []*Block{bc.genesis.Block()}
 	return nil
 }
 
-// recoverFromWAL восстанавливает состояние из WAL
+// recoverFromWAL восстанавливает состояние из WAL.
+//
+// Воспроизводит все целые записи. Рваный хвост (запись, прерванная
+// сбоем) не отбрасывается молча: о нём пишется предупреждение, а сам
+// WAL сохраняется рядом для разбора
 func (bc *Blockchain) recoverFromWAL() error {
-	blocks, err := bc.storage.ReadWAL()
-	if err != nil {
+	wal, ok := bc.store.(walStore)
+	if !ok {
+		return nil // Хранилище без WAL
+	}
+
+	blocks, err := wal.ReadWAL()
+	var torn *WALTornTailError
+	if err != nil && !errors.As(err, &torn) {
 		// Если файла WAL нет - это нормально
 		if os.IsNotExist(err) {
 			return nil
@@ -122,24 +247,40 @@ func (bc *Blockchain) recoverFromWAL() error {
 		return err
 	}
 
-	if len(blocks) == 0 {
-		return nil // WAL пуст
-	}
-
-	// Добавляем блоки из WAL в цепочку
+	// Добавляем блоки из WAL в цепочку и дописываем их в журнал
 	for _, block := range blocks {
+		if bc.hasBlock(block) {
+			continue // Сбой после сохранения блока, но до очистки WAL
+		}
+
 		if err := bc.addBlockInternal(block); err != nil {
+			var dup *DuplicateBlockError
+			if errors.As(err, &dup) || errors.Is(err, ErrPrevHashMismatch) {
+				// Блок проиграл гонку за вершину цепочки и не был
+				// подтверждён клиенту
+				slog.Warn("Skipping stale WAL record", "block", block.ID, "reason", err)
+				continue
+			}
 			return fmt.Errorf("failed to add block from WAL: %w", err)
 		}
+		if err := bc.store.SaveBlock(block); err != nil {
+			return fmt.Errorf("failed to save recovered block: %w", err)
+		}
 	}
 
-	// Сохраняем восстановленную цепочку
-	if err := bc.saveChain(); err != nil {
-		return fmt.Errorf("failed to save recovered chain: %w", err)
+	if torn != nil {
+		path, err := wal.PreserveWAL()
+		if err != nil {
+			return fmt.Errorf("failed to preserve torn WAL: %w", err)
+		}
+		slog.Warn("WAL has a torn tail, replayed intact records",
+			"records", torn.Records, "tail_offset", torn.Offset,
+			"tail_bytes", torn.Size, "preserved", path)
+		return nil
 	}
 
 	// Очищаем WAL после успешного восстановления
-	if err := bc.storage.ClearWAL(); err != nil {
+	if err := wal.ClearWAL(); err != nil {
 		// Не критическая ошибка
 		return nil
 	}
@@ -147,23 +288,13 @@ func (bc *Blockchain) recoverFromWAL() error {
 	return nil
 }
 
-// restoreFromBackup восстанавливает цепочку из последнего бэкапа
-func (bc *Blockchain) restoreFromBackup() error {
-	if err := bc.storage.RestoreFromBackup(); err != nil {
-		return NewBlockchainError("BACKUP_RESTORE_FAILED", "failed to restore from backup", err)
-	}
-
-	// Загружаем восстановленную цепочку
-	loadedBC, err := bc.storage.LoadChain()
-	if err != nil {
-		return NewBlockchainError("CHAIN_LOAD_FAILED", "failed to load restored chain", err)
-	}
-
-	bc.mu.Lock()
-	bc.Chain = loadedBC.Chain
-	bc.mu.Unlock()
+// hasBlock проверяет, есть ли блок с тем же ID и хешем в цепочке
+func (bc *Blockchain) hasBlock(block *Block) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 
-	return nil
+	existing := bc.indexedBlock(bc.idIndex, block.ID)
+	return existing != nil && existing.ID == block.ID && existing.Hash == block.Hash
 }
 
 // saveChain сохраняет цепочку
@@ -171,7 +302,7 @@ func (bc *Blockchain) saveChain() error {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
-	return bc.storage.SaveChain(bc)
+	return bc.store.SaveChain(bc)
 }
 
 // GetLastBlock возвращает последний блок в цепочке
@@ -190,15 +321,56 @@ func (bc *Blockchain) GetBlockByID(id string) (*Block, error) {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
-	for _, block := range bc.Chain {
-		if block.ID == id {
-			return block, nil
-		}
+	if block := bc.indexedBlock(bc.idIndex, id); block != nil && block.ID == id {
+		return block, nil
 	}
+	return nil, ErrBlockNotFound
+}
+
+// GetBlockByHash ищет блок по его хешу
+func (bc *Blockchain) GetBlockByHash(hash string) (*Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 
+	if block := bc.indexedBlock(bc.hashIndex, hash); block != nil && block.Hash == hash {
+		return block, nil
+	}
 	return nil, ErrBlockNotFound
 }
 
+// GetBlockByHeight возвращает блок на высоте height (генезис - 0)
+func (bc *Blockchain) GetBlockByHeight(height int) (*Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if height < 0 || height >= len(bc.Chain) {
+		return nil, ErrBlockNotFound
+	}
+	return bc.Chain[height], nil
+}
+
+// HeightOf возвращает высоту блока с данным ID
+func (bc *Blockchain) HeightOf(id string) (int, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if block := bc.indexedBlock(bc.idIndex, id); block != nil && block.ID == id {
+		return bc.idIndex[id], nil
+	}
+	return 0, ErrBlockNotFound
+}
+
+// indexedBlock возвращает блок, на высоту которого указывает index[key].
+// Вызывающий сверяет ключ с блоком: индекс мог устареть, если Chain
+// заменили в обход rebuildIndexes
+func (bc *Blockchain) indexedBlock(index map[string]int, key string) *Block {
+	height, ok := index[key]
+	if !ok || height >= len(bc.Chain) {
+		return nil
+	}
+	return bc.Chain[height]
+}
+
 // GenerateNextID генерирует ID для следующего блока
 func (bc *Blockchain) GenerateNextID() (string, error) {
 	lastBlock := bc.GetLastBlock()
@@ -216,64 +388,142 @@ func (bc *Blockchain) GenerateNextID() (string, error) {
 
 // AddBlock добавляет новый блок в цепочку
 func (bc *Blockchain) AddBlock(data DepositData) (*Block, error) {
+	return bc.AddBlockContext(context.Background(), data)
+}
+
+// AddBlockContext добавляет новый блок в цепочку через очередь
+// майнинга. Отмена ctx прерывает майнинг, блок тогда не записывается.
+//
+// Дубликат ищется по data.ContentHash, то есть только среди депозитов
+// того же алгоритма. Копии текста, записанные другими алгоритмами,
+// вызывающий, у которого есть текст, ищет до записи через
+// FindDepositByText или FindDepositByHashes
+func (bc *Blockchain) AddBlockContext(ctx context.Context, data DepositData) (*Block, error) {
 	// Проверяем на дубликат
 	if existing, exists := bc.HasContentHash(data.ContentHash); exists {
 		return existing, nil
 	}
 
-	// Генерируем ID для нового блока
-	nextID, err := bc.GenerateNextID()
+	block, err := bc.SubmitBlock(ctx, []DepositData{data}).Wait(ctx)
 	if err != nil {
+		if dup, ok := err.(*DuplicateBlockError); ok {
+			return dup.Block, nil
+		}
 		return nil, err
 	}
 
-	// Получаем хеш последнего блока
-	lastBlock := bc.GetLastBlock()
-	prevHash := "0"
-	if lastBlock != nil {
-		prevHash = lastBlock.Hash
+	return block, nil
+}
+
+// AddBatch добавляет депозиты одним блоком с общим корнем Меркла.
+//
+// Депозиты, уже записанные в цепочку, и повторы внутри пакета новый
+// блок не создают: для них возвращаются квитанции существующих
+// записей. Квитанции идут в порядке deposits
+func (bc *Blockchain) AddBatch(deposits []DepositData) ([]*DepositReceipt, error) {
+	return bc.AddBatchContext(context.Background(), deposits)
+}
+
+// AddBatchContext - AddBatch с отменой майнинга через ctx
+func (bc *Blockchain) AddBatchContext(ctx context.Context, deposits []DepositData) ([]*DepositReceipt, error) {
+	if len(deposits) == 0 {
+		return nil, nil
+	}
+
+	if _, err := bc.SubmitBlock(ctx, deposits).Wait(ctx); err != nil {
+		if _, ok := err.(*DuplicateBlockError); !ok {
+			return nil, err
+		}
 	}
 
-	// Создаем новый блок
-	block := NewBlock(nextID, prevHash, data)
+	receipts := make([]*DepositReceipt, len(deposits))
+	for i, data := range deposits {
+		receipt, err := bc.FindDeposit(data.ContentHash)
+		if err != nil {
+			return nil, err
+		}
+		receipts[i] = receipt
+	}
+	return receipts, nil
+}
+
+// mineBlock создаёт и майнит блок с депозитами поверх текущей вершины.
+// Вызывается только воркером очереди майнинга, который владеет вершиной
+func (bc *Blockchain) mineBlock(ctx context.Context, deposits []DepositData) (*Block, error) {
+	// ID, хеш предыдущего блока и цель берём с одной и той же вершины
+	bc.mu.RLock()
+	var lastBlock *Block
+	if len(bc.Chain) > 0 {
+		lastBlock = bc.Chain[len(bc.Chain)-1]
+	}
+	target := bc.nextTargetLocked()
+	alg := bc.hashAlg
+	bc.mu.RUnlock()
 
-	// Майним блок
-	block.Mine(bc.Difficulty)
+	nextID, prevHash := "000-000-000", "0"
+	if lastBlock != nil {
+		id, err := incrementID(lastBlock.ID)
+		if err != nil {
+			return nil, NewBlockchainError("ID_GENERATION_FAILED", "failed to generate next ID", err)
+		}
+		nextID, prevHash = id, lastBlock.Hash
+	}
+
+	// Создаем новый блок и майним его
+	block := newBatchBlock(nextID, prevHash, alg, deposits)
+	stats, err := block.MineTarget(ctx, target)
+	if err != nil {
+		return nil, NewBlockchainError("MINING_FAILED", "failed to mine block", err)
+	}
+	slog.Info("Block mined", "block", block.ID, "deposits", len(deposits), "hash_alg", alg, "difficulty_bits", target.LeadingZeroBits(),
+		"attempts", stats.Attempts, "workers", stats.Workers,
+		"duration", stats.Duration, "hashes_per_second", int64(stats.HashesPerSecond()))
+	return block, nil
+}
 
-	// Записываем в WAL (только если используется файловое хранилище)
-	if bc.storage != nil {
-		if err := bc.storage.WriteToWAL(block); err != nil {
-			return nil, NewBlockchainError("WAL_WRITE_FAILED", "failed to write block to WAL", err)
+// commitBlock записывает намайненный блок в WAL, цепочку и хранилище.
+// Дубликат возвращается как *DuplicateBlockError
+func (bc *Blockchain) commitBlock(block *Block) error {
+	// Записываем в WAL (если хранилище его поддерживает)
+	wal, hasWAL := bc.store.(walStore)
+	if hasWAL {
+		if err := wal.WriteToWAL(block); err != nil {
+			return NewBlockchainError("WAL_WRITE_FAILED", "failed to write block to WAL", err)
 		}
 	}
 
 	// Добавляем блок в цепочку
 	if err := bc.addBlockInternal(block); err != nil {
-		if dup, ok := err.(*DuplicateBlockError); ok {
-			return dup.Block, nil
+		if _, dup := err.(*DuplicateBlockError); !dup {
+			bc.events.Publish(Event{Type: EventValidationFailed, Block: block, Height: -1, Err: err})
 		}
-		return nil, err
+		return err
 	}
 
 	// Сохраняем блок в хранилище
-	if bc.blockStorage != nil {
-		if err := bc.blockStorage.SaveBlock(block); err != nil {
-			return nil, NewBlockchainError("BLOCK_SAVE_FAILED", "failed to save block", err)
-		}
-	} else if bc.storage != nil {
-		// Сохраняем цепочку
-		if err := bc.saveChain(); err != nil {
-			return nil, NewBlockchainError("CHAIN_SAVE_FAILED", "failed to save chain", err)
+	if bc.store != nil {
+		if err := bc.store.SaveBlock(block); err != nil {
+			// Блок не зафиксирован: убираем его из памяти и WAL,
+			// иначе следующий блок сошлётся на несохранённый
+			bc.removeLastBlock(block)
+			if hasWAL {
+				wal.ClearWAL()
+			}
+			return NewBlockchainError("BLOCK_SAVE_FAILED", "failed to save block", err)
 		}
+	}
 
-		// Очищаем WAL после успешного сохранения
-		if err := bc.storage.ClearWAL(); err != nil {
+	// Очищаем WAL после успешного сохранения
+	if hasWAL {
+		if err := wal.ClearWAL(); err != nil {
 			// Не критическая ошибка
 			// fmt.Printf("Warning: failed to clear WAL: %v\n", err)
 		}
 	}
 
-	return block, nil
+	height, _ := bc.HeightOf(block.ID)
+	bc.events.Publish(Event{Type: EventBlockCommitted, Block: block, Height: height})
+	return nil
 }
 
 // addBlockInternal добавляет блок в цепочку в памяти
@@ -282,22 +532,38 @@ func (bc *Blockchain) addBlockInternal(block *Block) error {
 	defer bc.mu.Unlock()
 
 	// Атомарная проверка дубликата
-	if existing, exists := bc.contentHashIndex[block.Data.ContentHash]; exists {
-		return &DuplicateBlockError{Block: existing}
+	deposits := block.DepositList()
+	for _, data := range deposits {
+		if existing, exists := bc.contentHashIndex[data.ContentHash]; exists {
+			return &DuplicateBlockError{Block: existing}
+		}
 	}
 
 	// Проверяем валидность блока
 	if !block.ValidateHash() {
 		return ErrInvalidBlockHash
 	}
+	if !block.ValidateMerkleRoot() {
+		return ErrMerkleRootMismatch
+	}
 
-	// Проверяем сложность
-	prefix := ""
-	for i := 0; i < bc.Difficulty; i++ {
-		prefix += "0"
+	// Проверяем цель блока против ожидаемой на вершине
+	expected, exact := bc.expectedTarget(bc.Chain)
+	if err := checkNextTarget(block, expected, exact); err != nil {
+		return err
 	}
-	if block.Hash[:bc.Difficulty] != prefix {
-		return ErrInvalidDifficulty
+	if err := checkSignatures(block); err != nil {
+		return err
+	}
+	keys, err := bc.keys.apply(block)
+	if err != nil {
+		return err
+	}
+	if err := checkVersions(block, bc.resolveDeposit, keys); err != nil {
+		return err
+	}
+	if err := checkNotices(block, bc.resolveDeposit, keys); err != nil {
+		return err
 	}
 
 	// Проверяем связь с предыдущим блоком
@@ -309,60 +575,152 @@ func (bc *Blockchain) addBlockInternal(block *Block) error {
 	}
 
 	// Добавляем блок
+	bc.idIndex[block.ID] = len(bc.Chain)
+	bc.hashIndex[block.Hash] = len(bc.Chain)
 	bc.Chain = append(bc.Chain, block)
-	bc.contentHashIndex[block.Data.ContentHash] = block
+	for _, data := range deposits {
+		bc.contentHashIndex[data.ContentHash] = block
+		bc.contentHashAlgs[data.ContentHashAlgorithm()]++
+	}
+	bc.indexKeys(block)
+	bc.keys = keys
+	bc.indexVersions(block)
+	bc.indexNotices(block)
 
 	return nil
 }
 
+// removeLastBlock откатывает добавление block в цепочку в памяти
+func (bc *Blockchain) removeLastBlock(block *Block) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if n := len(bc.Chain); n > 0 && bc.Chain[n-1] == block {
+		bc.Chain = bc.Chain[:n-1]
+		for _, data := range block.DepositList() {
+			delete(bc.contentHashIndex, data.ContentHash)
+			alg := data.ContentHashAlgorithm()
+			if bc.contentHashAlgs[alg]--; bc.contentHashAlgs[alg] <= 0 {
+				delete(bc.contentHashAlgs, alg)
+			}
+		}
+		bc.unindexKeys(block)
+		bc.keys = keyRegistryOf(bc.Chain)
+		bc.unindexVersions(block)
+		bc.unindexNotices(block)
+		delete(bc.idIndex, block.ID)
+		delete(bc.hashIndex, block.Hash)
+	}
+}
+
 // ValidateChain проверяет целостность всей цепочки
 func (bc *Blockchain) ValidateChain() bool {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
-	if len(bc.Chain) == 0 {
-		return true
+	return bc.validateBlocks(bc.Chain)
+}
+
+// Valid возвращает итог проверки цепочки, сохранённый при загрузке.
+// В отличие от ValidateChain, не проверяет блоки и подписи заново
+func (bc *Blockchain) Valid() bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.valid
+}
+
+// validateBlocks проверяет генезис, хеши, связность и сложность
+// блоков chain
+func (bc *Blockchain) validateBlocks(chain []*Block) bool {
+	height, _ := bc.invalidBlock(chain)
+	return height < 0
+}
+
+// invalidBlock - firstInvalidBlock, который сверяет генезис
+// с закреплённым хешем
+func (bc *Blockchain) invalidBlock(chain []*Block) (int, error) {
+	if err := checkGenesis(chain, bc.genesisHash); err != nil {
+		return 0, err
+	}
+	return firstInvalidBlock(chain)
+}
+
+// firstInvalidBlock возвращает высоту первого невалидного блока
+// и причину. Для валидной цепочки возвращает -1, nil
+func firstInvalidBlock(chain []*Block) (int, error) {
+	if len(chain) == 0 {
+		return -1, nil
 	}
 
 	// Проверяем генезис-блок
-	if !bc.Chain[0].ValidateHash() {
-		return false
+	if !chain[0].ValidateHash() {
+		return 0, ErrInvalidBlockHash
+	}
+	if !chain[0].ValidateMerkleRoot() {
+		return 0, ErrMerkleRootMismatch
 	}
 
-	// Проверяем остальные блоки
-	for i := 1; i < len(bc.Chain); i++ {
-		current := bc.Chain[i]
-		previous := bc.Chain[i-1]
+	// Проверяем остальные блоки. Ротации и отзывы ключей, ссылки
+	// на прежние версии и отзывы депозитов проверяются по предыдущим блокам
+	keys := newKeyRegistry()
+	byID := map[string]*Block{chain[0].ID: chain[0]}
+	resolve := func(ref string) (DepositData, bool) {
+		id, index, ok := parseDepositRef(ref)
+		if block := byID[id]; ok && block != nil && refersTo(block, ref, index) {
+			return block.DepositList()[index], true
+		}
+		return DepositData{}, false
+	}
+	for i := 1; i < len(chain); i++ {
+		current := chain[i]
+		previous := chain[i-1]
 
 		// Проверяем хеш текущего блока
 		if !current.ValidateHash() {
-			return false
+			return i, ErrInvalidBlockHash
+		}
+		if !current.ValidateMerkleRoot() {
+			return i, ErrMerkleRootMismatch
 		}
 
 		// Проверяем связь с предыдущим блоком
 		if current.PrevHash != previous.Hash {
-			return false
+			return i, ErrPrevHashMismatch
 		}
 
-		// Проверяем сложность
-		prefix := ""
-		for j := 0; j < bc.Difficulty; j++ {
-			prefix += "0"
+		// Проверяем цель, записанную в блоке
+		if err := checkDifficulty(current); err != nil {
+			return i, err
 		}
-		if current.Hash[:bc.Difficulty] != prefix {
-			return false
+		if err := checkSignatures(current); err != nil {
+			return i, err
 		}
+		var err error
+		if keys, err = keys.apply(current); err != nil {
+			return i, err
+		}
+		if err := checkVersions(current, resolve, keys); err != nil {
+			return i, err
+		}
+		if err := checkNotices(current, resolve, keys); err != nil {
+			return i, err
+		}
+		byID[current.ID] = current
 	}
 
-	return true
+	return -1, nil
 }
 
-// GetChainFilePath возвращает путь к файлу блокчейна
-func (bc *Blockchain) GetChainFilePath() string {
-	if bc.storage == nil {
-		return ""
-	}
-	return bc.storage.GetChainPath()
+// ExportChain записывает цепочку в w в формате JSON
+// (тот же формат, что у прежнего blockchain.json)
+func (bc *Blockchain) ExportChain(w io.Writer) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bc)
 }
 
 // GetChainInfo возвращает информацию о цепочке
@@ -370,15 +728,21 @@ func (bc *Blockchain) GetChainInfo() map[string]interface{} {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
+	next := bc.nextTargetLocked()
 	info := map[string]interface{}{
-		"length":     len(bc.Chain),
-		"difficulty": bc.Difficulty,
-		"valid":      bc.ValidateChain(),
+		"length":          len(bc.Chain),
+		"difficulty":      next.LeadingZeroBits() / 4,
+		"difficulty_bits": next.LeadingZeroBits(),
+		"target":          next.String(),
+		"hash_alg":        string(bc.hashAlg),
+		"valid":           bc.valid,
 	}
 
 	if len(bc.Chain) > 0 {
 		info["last_block"] = bc.Chain[len(bc.Chain)-1].ID
 		info["first_block"] = bc.Chain[0].ID
+		info["genesis_hash"] = bc.Chain[0].Hash
+		info["genesis_pinned"] = bc.genesisHash != ""
 	}
 
 	return info
@@ -417,3 +781,160 @@ func (bc *Blockchain) HasContentHash(hash string) (*Block, bool) {
 	block, ok := bc.contentHashIndex[hash]
 	return block, ok
 }
+
+// DepositReceipt - квитанция о депозите: блок, в который он попал,
+// и доказательство включения
+type DepositReceipt struct {
+	Block *Block
+	Index int // номер депозита в блоке
+	Data  DepositData
+	Ref   string       // ссылка для проверки, см. DepositRef
+	Proof *MerkleProof // nil для блоков без MerkleRoot
+}
+
+// DepositRef возвращает ссылку на депозит с номером index в блоке.
+// Для блока с одним депозитом это ID блока, для пакетного -
+// "<ID>.<index>"
+func DepositRef(block *Block, index int) string {
+	if len(block.Deposits) == 0 {
+		return block.ID
+	}
+	return fmt.Sprintf("%s.%d", block.ID, index)
+}
+
+// newDepositReceipt собирает квитанцию для депозита index блока block
+func newDepositReceipt(block *Block, index int) (*DepositReceipt, error) {
+	deposits := block.DepositList()
+	receipt := &DepositReceipt{
+		Block: block,
+		Index: index,
+		Data:  deposits[index],
+		Ref:   DepositRef(block, index),
+	}
+	if block.MerkleRoot != "" {
+		proof, err := newMerkleProof(block.HashAlgorithm(), deposits, index)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Proof = proof
+	}
+	return receipt, nil
+}
+
+// FindDeposit ищет депозит по хешу содержимого
+func (bc *Blockchain) FindDeposit(contentHash string) (*DepositReceipt, error) {
+	block, ok := bc.HasContentHash(contentHash)
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+
+	for i, data := range block.DepositList() {
+		if data.ContentHash == contentHash {
+			return newDepositReceipt(block, i)
+		}
+	}
+	return nil, ErrBlockNotFound
+}
+
+// HashAlgorithm возвращает алгоритм хеша новых блоков и их депозитов
+func (bc *Blockchain) HashAlgorithm() HashAlgorithm {
+	return bc.hashAlg
+}
+
+// ContentHashAlgorithms возвращает алгоритмы, которыми нужно хешировать
+// текст, чтобы найти его в цепочке: алгоритм новых депозитов первым,
+// затем остальные встречающиеся в цепочке
+func (bc *Blockchain) ContentHashAlgorithms() []HashAlgorithm {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	algs := []HashAlgorithm{bc.hashAlg}
+	for _, alg := range HashAlgorithms() {
+		if alg != bc.hashAlg && bc.contentHashAlgs[alg] > 0 {
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// TextHashes - хеши содержимого одного текста по алгоритмам
+type TextHashes map[HashAlgorithm]string
+
+// HashText хеширует text алгоритмами algs
+func HashText(text []byte, algs ...HashAlgorithm) TextHashes {
+	hashes := make(TextHashes, len(algs))
+	for _, alg := range algs {
+		hashes[alg.normalize()] = alg.ContentHash(text)
+	}
+	return hashes
+}
+
+// FindDepositByText ищет депозит текста, перебирая алгоритмы из
+// ContentHashAlgorithms: текст находится, каким бы алгоритмом его
+// ни захешировали при депонировании. Если текст депонировали
+// несколькими алгоритмами, возвращается самый ранний депозит
+func (bc *Blockchain) FindDepositByText(text []byte) (*DepositReceipt, error) {
+	return bc.FindDepositByHashes(HashText(text, bc.ContentHashAlgorithms()...))
+}
+
+// FindDepositByHashes ищет самый ранний депозит, хеш содержимого
+// которого совпадает с хешем его алгоритма из hashes. Так проверяются
+// дубликаты текста после смены алгоритма: хеш новым алгоритмом
+// не находит копию, записанную прежним
+func (bc *Blockchain) FindDepositByHashes(hashes TextHashes) (*DepositReceipt, error) {
+	var earliest *DepositReceipt
+	earliestHeight := -1
+	for alg, hash := range hashes {
+		receipt, err := bc.FindDeposit(hash)
+		if err != nil || receipt.Data.ContentHashAlgorithm() != alg.normalize() {
+			continue
+		}
+		height, err := bc.HeightOf(receipt.Block.ID)
+		if err != nil {
+			continue
+		}
+		if earliest == nil || height < earliestHeight {
+			earliest, earliestHeight = receipt, height
+		}
+	}
+	if earliest == nil {
+		return nil, ErrBlockNotFound
+	}
+	return earliest, nil
+}
+
+// GetDepositByRef ищет депозит по ссылке из DepositRef
+func (bc *Blockchain) GetDepositByRef(ref string) (*DepositReceipt, error) {
+	id, index, ok := parseDepositRef(ref)
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+
+	block, err := bc.GetBlockByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !refersTo(block, ref, index) {
+		return nil, ErrBlockNotFound
+	}
+	return newDepositReceipt(block, index)
+}
+
+// parseDepositRef делит ссылку из DepositRef на ID блока и номер
+// депозита
+func parseDepositRef(ref string) (id string, index int, ok bool) {
+	dot := strings.LastIndexByte(ref, '.')
+	if dot < 0 {
+		return ref, 0, true
+	}
+	n, err := strconv.Atoi(ref[dot+1:])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return ref[:dot], n, true
+}
+
+// refersTo сообщает, что ref - ссылка на депозит index блока block
+func refersTo(block *Block, ref string, index int) bool {
+	return DepositRef(block, index) == ref && index < len(block.DepositList())
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -214,6 +215,116 @@ func TestBlockchain_GetBlockByID(t *testing.T) {
 	})
 }
 
+func TestBlockchain_GetBlockByHeight(t *testing.T) {
+	storage := NewTestStorage()
+	bc := NewBlockchainWithStorage(storage, 1)
+
+	var added []*Block
+	for i := 0; i < 3; i++ {
+		block, err := bc.AddBlock(CreateTestBlock("Author", "Title", fmt.Sprintf("Height text %d", i)))
+		if err != nil {
+			t.Fatalf("AddBlock() error = %v", err)
+		}
+		added = append(added, block)
+	}
+
+	for i, want := range added {
+		block, err := bc.GetBlockByHeight(i + 1)
+		if err != nil {
+			t.Fatalf("GetBlockByHeight(%d) error = %v", i+1, err)
+		}
+		if block.ID != want.ID {
+			t.Errorf("GetBlockByHeight(%d) = %s, want %s", i+1, block.ID, want.ID)
+		}
+	}
+
+	for _, height := range []int{-1, 4, 100} {
+		if _, err := bc.GetBlockByHeight(height); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("GetBlockByHeight(%d) error = %v, want ErrBlockNotFound", height, err)
+		}
+	}
+}
+
+func TestBlockchain_GetBlockByHash(t *testing.T) {
+	storage := NewTestStorage()
+	bc := NewBlockchainWithStorage(storage, 1)
+
+	added, _ := bc.AddBlock(CreateTestBlock("Author", "Title", "Hash lookup text"))
+
+	block, err := bc.GetBlockByHash(added.Hash)
+	if err != nil {
+		t.Fatalf("GetBlockByHash() error = %v", err)
+	}
+	if block.ID != added.ID {
+		t.Errorf("Block ID = %s, want %s", block.ID, added.ID)
+	}
+
+	// Хеш блока, а не хеш содержимого
+	if _, err := bc.GetBlockByHash(added.Data.ContentHash); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("GetBlockByHash(content hash) error = %v, want ErrBlockNotFound", err)
+	}
+}
+
+func TestBlockchain_Indexes(t *testing.T) {
+	t.Run("rebuilt on load", func(t *testing.T) {
+		tempStorage := NewTempDirStorage(t)
+		defer tempStorage.Close()
+
+		bc1, _ := NewBlockchain(tempStorage.GetStorage(), 1)
+		added, _ := bc1.AddBlock(CreateTestBlock("Author", "Title", "Indexed text"))
+
+		bc2, err := NewBlockchain(tempStorage.GetStorage(), 1)
+		if err != nil {
+			t.Fatalf("NewBlockchain() error = %v", err)
+		}
+
+		if len(bc2.idIndex) != 2 || len(bc2.hashIndex) != 2 {
+			t.Errorf("Index sizes = %d/%d, want 2/2", len(bc2.idIndex), len(bc2.hashIndex))
+		}
+		if block, err := bc2.GetBlockByID(added.ID); err != nil || block.Hash != added.Hash {
+			t.Errorf("GetBlockByID() after load = %v, %v", block, err)
+		}
+		if block, err := bc2.GetBlockByHash(added.Hash); err != nil || block.ID != added.ID {
+			t.Errorf("GetBlockByHash() after load = %v, %v", block, err)
+		}
+	})
+
+	t.Run("removed block is not found", func(t *testing.T) {
+		storage := NewTestStorage()
+		bc := NewBlockchainWithStorage(storage, 1)
+
+		added, _ := bc.AddBlock(CreateTestBlock("Author", "Title", "Rolled back text"))
+		bc.removeLastBlock(added)
+
+		if _, err := bc.GetBlockByID(added.ID); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("GetBlockByID() error = %v, want ErrBlockNotFound", err)
+		}
+		if _, err := bc.GetBlockByHash(added.Hash); !errors.Is(err, ErrBlockNotFound) {
+			t.Errorf("GetBlockByHash() error = %v, want ErrBlockNotFound", err)
+		}
+	})
+
+	t.Run("removed block algorithm is not searched", func(t *testing.T) {
+		storage := NewTestStorage()
+		bc := NewBlockchainWithStorage(storage, 1)
+
+		text := []byte("Rolled back SHA3-256 text")
+		added, err := bc.AddBlock(DepositData{
+			AuthorName:     "Author",
+			Title:          "Title",
+			ContentHash:    HashSHA3_256.ContentHash(text),
+			ContentHashAlg: HashSHA3_256,
+		})
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.ContentHashAlgorithms()), 2, "Algorithms after add")
+
+		bc.removeLastBlock(added)
+		algs := bc.ContentHashAlgorithms()
+		AssertEqual(t, len(algs), 1, "Algorithms after remove")
+		AssertEqual(t, algs[0], bc.HashAlgorithm(), "Remaining algorithm")
+	})
+}
+
 func TestBlockchain_GetAllBlocks(t *testing.T) {
 	storage := NewTestStorage()
 	bc := NewBlockchainWithStorage(storage, 1)
@@ -321,6 +432,22 @@ func TestBlockchain_GetChainInfo(t *testing.T) {
 	if _, ok := info["last_block"].(string); !ok {
 		t.Error("last_block should be present")
 	}
+
+	t.Run("validity is not rechecked", func(t *testing.T) {
+		storage := NewTestStorage()
+		bc := NewBlockchainWithStorage(storage, 1)
+		_, err := bc.AddBlock(CreateTestBlock("Author", "Title", "Cached validity"))
+		AssertNoError(t, err)
+		AssertEqual(t, bc.Valid(), true, "Validity after add")
+
+		// Подмена в памяти видна только полной проверке: сводка
+		// берёт итог проверки при загрузке и не проходит цепочку
+		bc.Chain[1].Data.Title = "Tampered"
+		if bc.ValidateChain() {
+			t.Fatal("Tampered chain should fail full validation")
+		}
+		AssertEqual(t, bc.GetChainInfo()["valid"], true, "Cached validity in info")
+	})
 }
 
 func TestBlockchain_Concurrency(t *testing.T) {
@@ -355,10 +482,13 @@ func TestBlockchain_Concurrency(t *testing.T) {
 		<-done
 	}
 
-	// Проверяем что хотя бы некоторые блоки добавлены
-	// (не все из-за race conditions в concurrent добавлении)
-	if len(bc.Chain) < 2 {
-		t.Errorf("Chain length = %d, want at least 2", len(bc.Chain))
+	// Блоки майнит один воркер, поэтому гонки за вершину нет
+	// и добавляются все
+	if successCount != numGoroutines {
+		t.Errorf("Successful additions = %d, want %d", successCount, numGoroutines)
+	}
+	if len(bc.Chain) != numGoroutines+1 {
+		t.Errorf("Chain length = %d, want %d", len(bc.Chain), numGoroutines+1)
 	}
 
 	// Проверяем целостность цепочки
@@ -472,8 +602,15 @@ func TestNewBlockchain(t *testing.T) {
 		bc1.Chain[1].Hash = "corrupted-hash"
 		bc1.saveChain()
 
+		// Без режима восстановления цепочка не открывается
+		_, err = NewBlockchain(tempStorage.GetStorage(), 2)
+		var bcErr *BlockchainError
+		if !errors.As(err, &bcErr) || bcErr.Code != "RECOVERY_REQUIRED" {
+			t.Fatalf("NewBlockchain() error = %v, want RECOVERY_REQUIRED", err)
+		}
+
 		// Пытаемся загрузить испорченную цепочку
-		bc2, err := NewBlockchain(tempStorage.GetStorage(), 2)
+		bc2, err := NewBlockchain(tempStorage.GetStorage(), 2, WithRecovery(filepath.Join(tempStorage.Dir, "quarantine")))
 		if err != nil {
 			t.Fatalf("NewBlockchain() error = %v", err)
 		}
@@ -513,7 +650,7 @@ func TestRecoverFromWAL(t *testing.T) {
 		bc, _ := NewBlockchain(tempStorage.GetStorage(), 2)
 
 		// Создаём пустой WAL
-		os.WriteFile(bc.storage.GetWALPath(), []byte("[]"), 0644)
+		os.WriteFile(tempStorage.GetWALPath(), []byte("[]"), 0644)
 
 		err := bc.recoverFromWAL()
 		if err != nil {
@@ -546,7 +683,7 @@ func TestRecoverFromWAL(t *testing.T) {
 			blocks = append(blocks, block)
 
 			// Записываем в WAL
-			bc1.storage.WriteToWAL(block)
+			tempStorage.WriteToWAL(block)
 
 			// Добавляем в цепочку чтобы следующий ID был правильным
 			bc1.addBlockInternal(block)
@@ -579,8 +716,12 @@ func TestRecoverFromWAL(t *testing.T) {
 
 		bc, _ := NewBlockchain(tempStorage.GetStorage(), 2)
 
-		// Создаём испорченный WAL
-		os.WriteFile(bc.storage.GetWALPath(), []byte("invalid json"), 0644)
+		// Создаём WAL, повреждённый в середине: за битой записью есть целая
+		block := NewBlock("000-000-001", bc.GetLastBlock().Hash, CreateTestBlock("Author", "Title", "Text"))
+		block.Mine(2)
+		tempStorage.WriteToWAL(block)
+		data, _ := os.ReadFile(tempStorage.GetWALPath())
+		os.WriteFile(tempStorage.GetWALPath(), append([]byte("invalid json\n"), data...), 0644)
 
 		// Должно вернуть ошибку
 		err := bc.recoverFromWAL()
@@ -588,62 +729,63 @@ func TestRecoverFromWAL(t *testing.T) {
 			t.Error("recoverFromWAL() error = nil, want error for corrupted WAL")
 		}
 	})
-}
 
-// TestRestoreFromBackup тестирует восстановление из бэкапа
-func TestRestoreFromBackup(t *testing.T) {
-	t.Run("no backups available", func(t *testing.T) {
+	t.Run("torn WAL tail", func(t *testing.T) {
 		tempStorage := NewTempDirStorage(t)
 		defer tempStorage.Close()
 
-		bc, _ := NewBlockchain(tempStorage.GetStorage(), 2)
+		bc, _ := NewBlockchain(tempStorage.GetStorage(), 1)
 
-		err := bc.restoreFromBackup()
-		if err == nil {
-			t.Error("restoreFromBackup() error = nil, want error when no backups")
+		// Одна целая запись и недописанная вторая
+		block := NewBlock("000-000-001", bc.GetLastBlock().Hash, CreateTestBlock("Author", "Title", "Text"))
+		block.Mine(1)
+		tempStorage.WriteToWAL(block)
+		f, _ := os.OpenFile(tempStorage.GetWALPath(), os.O_WRONLY|os.O_APPEND, 0644)
+		f.WriteString(`{"crc":12345,"block":{"id":"000-0`)
+		f.Close()
+
+		err := bc.recoverFromWAL()
+		if err != nil {
+			t.Fatalf("recoverFromWAL() error = %v, want nil for torn tail", err)
 		}
 
-		var bcErr *BlockchainError
-		if !errors.As(err, &bcErr) {
-			t.Error("Expected BlockchainError")
+		// Целая запись воспроизведена
+		if len(bc.Chain) != 2 {
+			t.Errorf("Chain length = %d, want 2", len(bc.Chain))
+		}
+
+		// WAL с хвостом сохранён, а не удалён
+		torn, _ := filepath.Glob(tempStorage.GetWALPath() + ".torn-*")
+		if len(torn) != 1 {
+			t.Errorf("Preserved torn WAL files = %d, want 1", len(torn))
+		}
+		if _, err := os.Stat(tempStorage.GetWALPath()); !os.IsNotExist(err) {
+			t.Error("WAL should be moved aside after torn tail recovery")
 		}
 	})
 
-	t.Run("restore from backup successfully", func(t *testing.T) {
+	t.Run("block already saved", func(t *testing.T) {
 		tempStorage := NewTempDirStorage(t)
 		defer tempStorage.Close()
 
-		// Создаём блокчейн и добавляем блоки
 		bc1, _ := NewBlockchain(tempStorage.GetStorage(), 1)
+		block, _ := bc1.AddBlock(CreateTestBlock("Author", "Title", "Text"))
 
-		for i := 0; i < 3; i++ {
-			data := CreateTestBlock(
-				fmt.Sprintf("Author%d", i),
-				"Title",
-				fmt.Sprintf("Text %d", i),
-			)
-			bc1.AddBlock(data)
-		}
-
-		// Создание бэкапа происходит автоматически при saveChain
-		// который вызывается в AddBlock
-
-		// Портим текущую цепочку
-		bc1.Chain = bc1.Chain[:1] // Оставляем только genesis
-		bc1.saveChain()
+		// Сбой после сохранения блока, но до очистки WAL
+		tempStorage.WriteToWAL(block)
 
-		// Восстанавливаем из бэкапа
-		err := bc1.restoreFromBackup()
+		bc2, err := NewBlockchain(tempStorage.GetStorage(), 1)
 		if err != nil {
-			t.Fatalf("restoreFromBackup() error = %v", err)
+			t.Fatalf("NewBlockchain() error = %v", err)
 		}
-
-		// Проверяем что восстановились все блоки
-		if len(bc1.Chain) != 4 { // genesis + 3
-			t.Errorf("Chain length after restore = %d, want 4", len(bc1.Chain))
+		if len(bc2.Chain) != 2 {
+			t.Errorf("Chain length = %d, want 2", len(bc2.Chain))
 		}
 	})
+}
 
+// TestRestoreFromBackup тестирует восстановление из бэкапа
+func TestRestoreFromBackup(t *testing.T) {
 	t.Run("validation failure triggers backup restore", func(t *testing.T) {
 		tempStorage := NewTempDirStorage(t)
 		defer tempStorage.Close()
@@ -665,7 +807,7 @@ func TestRestoreFromBackup(t *testing.T) {
 		bc1.saveChain()
 
 		// При загрузке должно произойти восстановление из бэкапа
-		bc2, err := NewBlockchain(tempStorage.GetStorage(), 2)
+		bc2, err := NewBlockchain(tempStorage.GetStorage(), 2, WithRecovery(filepath.Join(tempStorage.Dir, "quarantine")))
 		if err != nil {
 			t.Fatalf("NewBlockchain() error = %v", err)
 		}
@@ -789,7 +931,7 @@ func TestRebuildContentHashIndex(t *testing.T) {
 			Difficulty:       1,
 			contentHashIndex: make(map[string]*Block),
 		}
-		bc.blockStorage = storage
+		bc.store = storage
 
 		bc.rebuildContentHashIndex()
 
@@ -861,3 +1003,142 @@ func TestGetBlocksRange(t *testing.T) {
 		}
 	})
 }
+
+func TestBlockchain_AddBatch(t *testing.T) {
+	t.Run("one block for the batch", func(t *testing.T) {
+		bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+		deposits := testDeposits(5)
+
+		receipts, err := bc.AddBatch(deposits)
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.Chain), 2, "Chain length")
+		AssertEqual(t, len(receipts), 5, "Receipts")
+
+		block := bc.GetLastBlock()
+		AssertEqual(t, block.MerkleRoot, MerkleRoot(deposits), "Block root")
+		for i, receipt := range receipts {
+			AssertEqual(t, receipt.Index, i, "Receipt index")
+			if !receipt.Proof.Verify(deposits[i]) {
+				t.Errorf("Proof of deposit %d should verify", i)
+			}
+			if existing, ok := bc.HasContentHash(deposits[i].ContentHash); !ok || existing != block {
+				t.Errorf("Deposit %d is not indexed", i)
+			}
+		}
+	})
+
+	t.Run("existing and repeated deposits", func(t *testing.T) {
+		bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+		deposits := testDeposits(3)
+		first, err := bc.AddBlock(deposits[0])
+		AssertNoError(t, err)
+
+		receipts, err := bc.AddBatch([]DepositData{deposits[0], deposits[1], deposits[2], deposits[1]})
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.Chain), 3, "Chain length")
+		AssertEqual(t, len(bc.GetLastBlock().Deposits), 2, "Deposits in new block")
+
+		AssertEqual(t, receipts[0].Block, first, "Receipt of existing deposit")
+		AssertEqual(t, receipts[3].Ref, receipts[1].Ref, "Receipt of repeated deposit")
+	})
+
+	t.Run("nothing new", func(t *testing.T) {
+		bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+		deposits := testDeposits(2)
+		_, err := bc.AddBatch(deposits)
+		AssertNoError(t, err)
+
+		_, err = bc.AddBatch(deposits)
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.Chain), 2, "No block for a batch of known deposits")
+	})
+
+	t.Run("survives reload", func(t *testing.T) {
+		tempStorage := NewTempDirStorage(t)
+		defer tempStorage.Close()
+
+		bc1, err := NewBlockchain(tempStorage.GetStorage(), 1)
+		AssertNoError(t, err)
+		deposits := testDeposits(3)
+		_, err = bc1.AddBatch(deposits)
+		AssertNoError(t, err)
+
+		bc2, err := NewBlockchain(tempStorage.GetStorage(), 1)
+		AssertNoError(t, err)
+		receipt, err := bc2.FindDeposit(deposits[2].ContentHash)
+		AssertNoError(t, err)
+		if !receipt.Proof.Verify(deposits[2]) {
+			t.Error("Proof after reload should verify")
+		}
+	})
+}
+
+func TestBlockchain_GetDepositByRef(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	single, err := bc.AddBlock(CreateTestBlock("Author", "Title", "single text"))
+	AssertNoError(t, err)
+	deposits := testDeposits(3)
+	receipts, err := bc.AddBatch(deposits)
+	AssertNoError(t, err)
+	batch := receipts[0].Block
+
+	t.Run("single-deposit block by ID", func(t *testing.T) {
+		receipt, err := bc.GetDepositByRef(single.ID)
+		AssertNoError(t, err)
+		AssertEqual(t, receipt.Data, single.Data, "Deposit data")
+		if !receipt.Proof.Verify(single.Data) {
+			t.Error("Proof of single deposit should verify")
+		}
+	})
+
+	t.Run("batch deposit by ref", func(t *testing.T) {
+		receipt, err := bc.GetDepositByRef(batch.ID + ".2")
+		AssertNoError(t, err)
+		AssertEqual(t, receipt.Data, deposits[2], "Deposit data")
+	})
+
+	for _, ref := range []string{
+		batch.ID,         // у пакетного блока нужен номер
+		batch.ID + ".3",  // номер вне блока
+		batch.ID + ".-1", // отрицательный номер
+		batch.ID + ".01", // не каноническая запись
+		single.ID + ".0", // у обычного блока номера нет
+		"999-999-999",    // нет такого блока
+	} {
+		t.Run("invalid "+ref, func(t *testing.T) {
+			if _, err := bc.GetDepositByRef(ref); !errors.Is(err, ErrBlockNotFound) {
+				t.Errorf("GetDepositByRef(%q) error = %v, want ErrBlockNotFound", ref, err)
+			}
+		})
+	}
+
+	t.Run("legacy block has no proof", func(t *testing.T) {
+		// Блок до пакетов: заголовок версии 0 без корня
+		legacy := NewBlock("000-000-003", bc.GetLastBlock().Hash, CreateTestBlock("Author", "Title", "legacy text"))
+		legacy.Version, legacy.HashAlg, legacy.MerkleRoot = HeaderVersionJSON, "", ""
+		legacy.Mine(bc.Difficulty)
+		AssertNoError(t, bc.addBlockInternal(legacy))
+
+		receipt, err := bc.FindDeposit(legacy.Data.ContentHash)
+		AssertNoError(t, err)
+		AssertEqual(t, receipt.Ref, legacy.ID, "Legacy ref")
+		if receipt.Proof != nil {
+			t.Error("Block without root should have no proof")
+		}
+	})
+}
+
+func TestValidateChain_MerkleRoot(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	_, err := bc.AddBatch(testDeposits(4))
+	AssertNoError(t, err)
+
+	// Хеш блока не меняется, но депозит больше не совпадает с корнем
+	bc.Chain[1].Deposits[2].AuthorName = "Forger"
+
+	height, err := bc.invalidBlock(bc.Chain)
+	AssertEqual(t, height, 1, "First invalid block")
+	if !errors.Is(err, ErrMerkleRootMismatch) {
+		t.Errorf("invalidBlock() error = %v, want ErrMerkleRootMismatch", err)
+	}
+}
@@ -0,0 +1,197 @@
+package blockchain
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// coauthorSignatureDomain отделяет подписи соавторов от подписей
+// депозитов с одним автором: сообщения у них разного состава
+const coauthorSignatureDomain = "textproof-coauthored-signature-v1"
+
+// Author - соавтор работы. Ключ необязателен; соавтор с ключом
+// подписывает депозит (см. SigningMessage), и депозит записывается
+// в цепочку только с подписями всех соавторов с ключами
+type Author struct {
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"` // например, "переводчик"
+	PublicKey string `json:"public_key,omitempty"`
+	Signature string `json:"signature,omitempty"` // base64
+}
+
+// Signed сообщает, что соавтор подписал депозит
+func (a Author) Signed() bool {
+	return a.Signature != ""
+}
+
+// AuthorNames возвращает имена соавторов через запятую - AuthorName
+// депозита с соавторами
+func AuthorNames(authors []Author) string {
+	names := make([]string, len(authors))
+	for i, a := range authors {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Coauthored сообщает, что у работы список соавторов
+func (d DepositData) Coauthored() bool {
+	return len(d.Authors) > 0
+}
+
+// AuthorList возвращает имена авторов работы: соавторов или AuthorName
+func (d DepositData) AuthorList() []string {
+	if !d.Coauthored() {
+		return []string{d.AuthorName}
+	}
+	names := make([]string, len(d.Authors))
+	for i, a := range d.Authors {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// SignerKeys возвращает ключи, подписавшие депозит: PublicKey подписанного
+// депозита или ключи подписавших соавторов
+func (d DepositData) SignerKeys() []string {
+	if !d.Coauthored() {
+		if d.Signed() {
+			return []string{d.PublicKey}
+		}
+		return nil
+	}
+	var keys []string
+	for _, a := range d.Authors {
+		if a.PublicKey != "" && a.Signed() {
+			keys = append(keys, a.PublicKey)
+		}
+	}
+	return keys
+}
+
+// MissingSignatures возвращает номера соавторов с ключом, ещё не
+// подписавших депозит
+func (d DepositData) MissingSignatures() []int {
+	var missing []int
+	for i, a := range d.Authors {
+		if a.PublicKey != "" && !a.Signed() {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// coauthoredSigningMessage - SigningMessage депозита с соавторами.
+// Подпись каждого соавтора закрепляет весь список, поэтому добавить
+// или убрать соавтора после подписи нельзя:
+//
+//	str "textproof-coauthored-signature-v1"
+//	str content_hash_alg
+//	str content_hash
+//	str author_name
+//	str title
+//	str parent_id        пустая строка, если нет
+//	u32 число соавторов
+//	str name, str role, str public_key   для каждого соавтора
+func (d DepositData) coauthoredSigningMessage() []byte {
+	buf := make([]byte, 0, 256+len(d.AuthorName)+len(d.Title))
+	buf = appendHeaderString(buf, coauthorSignatureDomain)
+	buf = appendHeaderString(buf, string(d.ContentHashAlgorithm()))
+	buf = appendHeaderString(buf, d.ContentHash)
+	buf = appendHeaderString(buf, d.AuthorName)
+	buf = appendHeaderString(buf, d.Title)
+	buf = appendHeaderString(buf, d.ParentID)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(d.Authors)))
+	for _, a := range d.Authors {
+		buf = appendHeaderString(buf, a.Name)
+		buf = appendHeaderString(buf, a.Role)
+		buf = appendHeaderString(buf, a.PublicKey)
+	}
+	return buf
+}
+
+// VerifyCosignature проверяет подпись signature соавтора с ключом
+// publicKey и возвращает номер соавтора и ключ
+func (d DepositData) VerifyCosignature(publicKey, signature string) (int, *AuthorKey, error) {
+	key, err := ParseAuthorKey(publicKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	index := -1
+	for i, a := range d.Authors {
+		if a.PublicKey == "" {
+			continue
+		}
+		if k, err := ParseAuthorKey(a.PublicKey); err == nil && k.Fingerprint == key.Fingerprint {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0, nil, fmt.Errorf("%w: key %s is not a co-author key", ErrInvalidAuthors, key.Fingerprint)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: signature is not base64", ErrInvalidSignature)
+	}
+	if !key.Verify(d.SigningMessage(), sig) {
+		return 0, nil, ErrInvalidSignature
+	}
+	return index, key, nil
+}
+
+// CheckAuthors проверяет список соавторов и уже собранные подписи.
+// complete требует подписей всех соавторов с ключами - так проверяется
+// депозит перед записью в цепочку
+func (d DepositData) CheckAuthors(complete bool) error {
+	if !d.Coauthored() {
+		return nil
+	}
+	if d.IsRecord() {
+		return fmt.Errorf("%w: %s record cannot have co-authors", ErrInvalidAuthors, d.Type)
+	}
+	if d.PublicKey != "" || d.Signature != "" {
+		return fmt.Errorf("%w: co-authored deposit is signed by each co-author", ErrInvalidAuthors)
+	}
+	if d.AuthorName != AuthorNames(d.Authors) {
+		return fmt.Errorf("%w: author_name does not match co-authors", ErrInvalidAuthors)
+	}
+
+	message := d.SigningMessage()
+	seen := make(map[string]bool)
+	for i, a := range d.Authors {
+		if strings.TrimSpace(a.Name) == "" {
+			return fmt.Errorf("%w: co-author %d has no name", ErrInvalidAuthors, i+1)
+		}
+		if a.PublicKey == "" {
+			if a.Signed() {
+				return fmt.Errorf("%w: co-author %d signed without a key", ErrInvalidAuthors, i+1)
+			}
+			continue
+		}
+
+		key, err := ParseAuthorKey(a.PublicKey)
+		if err != nil {
+			return err
+		}
+		if seen[key.Fingerprint] {
+			return fmt.Errorf("%w: key %s listed twice", ErrInvalidAuthors, key.Fingerprint)
+		}
+		seen[key.Fingerprint] = true
+
+		if !a.Signed() {
+			if complete {
+				return fmt.Errorf("%w: %s", ErrMissingCosignature, a.Name)
+			}
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(a.Signature)
+		if err != nil || !key.Verify(message, sig) {
+			return fmt.Errorf("%w: co-author %s", ErrInvalidSignature, a.Name)
+		}
+	}
+	return nil
+}
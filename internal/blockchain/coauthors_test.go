@@ -0,0 +1,157 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// cosign возвращает подпись соавтора ключом k в base64
+func (k testAuthorKey) cosign(data DepositData) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, data.SigningMessage()))
+}
+
+// coauthoredDeposit собирает работу соавторов без подписей
+func coauthoredDeposit(text string, authors ...Author) DepositData {
+	data := CreateTestBlock("", "Joint work", text)
+	data.PublicKey = ""
+	data.Authors = authors
+	data.AuthorName = AuthorNames(authors)
+	return data
+}
+
+// signAll подписывает работу всеми ключами keys по порядку соавторов
+func signAll(data DepositData, keys ...testAuthorKey) DepositData {
+	authors := append([]Author(nil), data.Authors...)
+	for i, key := range keys {
+		authors[i].Signature = key.cosign(data)
+	}
+	data.Authors = authors
+	return data
+}
+
+func TestDepositData_CheckAuthors(t *testing.T) {
+	a, b := newTestAuthorKey(t), newTestAuthorKey(t)
+	unsigned := coauthoredDeposit("joint text",
+		Author{Name: "Alice", Role: "author", PublicKey: a.public},
+		Author{Name: "Bob", Role: "translator", PublicKey: b.public},
+		Author{Name: "Carol", Role: "illustrator"},
+	)
+	AssertEqual(t, unsigned.AuthorName, "Alice, Bob, Carol", "Author name of co-authored work")
+
+	t.Run("complete", func(t *testing.T) {
+		data := signAll(unsigned, a, b)
+		AssertNoError(t, data.CheckAuthors(true))
+		AssertEqual(t, len(data.SignerKeys()), 2, "Signer keys")
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		data := signAll(unsigned, a)
+		AssertNoError(t, data.CheckAuthors(false))
+		if err := data.CheckAuthors(true); !errors.Is(err, ErrMissingCosignature) {
+			t.Errorf("CheckAuthors(true) error = %v, want ErrMissingCosignature", err)
+		}
+		AssertEqual(t, len(data.MissingSignatures()), 1, "Missing signatures")
+	})
+
+	t.Run("author list is signed", func(t *testing.T) {
+		data := signAll(unsigned, a, b)
+		data.Authors = append(data.Authors, Author{Name: "Mallory"})
+		data.AuthorName = AuthorNames(data.Authors)
+		if err := data.CheckAuthors(true); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("CheckAuthors() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		change func(d *DepositData)
+	}{
+		{"author name mismatch", func(d *DepositData) { d.AuthorName = "Alice" }},
+		{"deposit key", func(d *DepositData) { d.PublicKey = a.public }},
+		{"empty name", func(d *DepositData) { d.Authors[2].Name = " "; d.AuthorName = AuthorNames(d.Authors) }},
+		{"signature without key", func(d *DepositData) { d.Authors[2].Signature = "c2ln" }},
+		{"duplicate key", func(d *DepositData) { d.Authors[1].PublicKey = a.public }},
+		{"record", func(d *DepositData) { d.Type = RecordKeyRotation }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := unsigned
+			data.Authors = append([]Author(nil), unsigned.Authors...)
+			tt.change(&data)
+			if err := data.CheckAuthors(false); !errors.Is(err, ErrInvalidAuthors) {
+				t.Errorf("CheckAuthors() error = %v, want ErrInvalidAuthors", err)
+			}
+		})
+	}
+}
+
+func TestBlockchain_Coauthored(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	a, b, c := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+
+	work := coauthoredDeposit("first edition",
+		Author{Name: "Alice", PublicKey: a.public},
+		Author{Name: "Bob", PublicKey: b.public},
+	)
+
+	_, err = bc.AddBlock(signAll(work, a))
+	if !errors.Is(err, ErrMissingCosignature) {
+		t.Fatalf("AddBlock() error = %v, want ErrMissingCosignature", err)
+	}
+
+	first, err := bc.AddBlock(signAll(work, a, b))
+	AssertNoError(t, err)
+
+	for _, key := range []testAuthorKey{a, b} {
+		deposits, err := bc.DepositsByKey(key.fingerprint)
+		AssertNoError(t, err)
+		AssertEqual(t, len(deposits), 1, "Deposits of co-author key")
+	}
+
+	// Новую версию может подписать и один из прежних соавторов
+	solo := CreateTestBlock("Alice", "Joint work", "second edition")
+	solo.PublicKey = a.public
+	solo.ParentID = first.ID
+	_, err = bc.AddBlock(a.sign(solo))
+	AssertNoError(t, err)
+
+	stranger := CreateTestBlock("Carol", "Joint work", "hijacked edition")
+	stranger.PublicKey = c.public
+	stranger.ParentID = first.ID
+	if err := bc.CheckVersion(c.sign(stranger)); !errors.Is(err, ErrVersionNotAuthorized) {
+		t.Errorf("CheckVersion() error = %v, want ErrVersionNotAuthorized", err)
+	}
+
+	// Отозванный ключ соавтора больше не подписывает
+	_, err = bc.AddBlock(b.sign(NewKeyRevocation(bc.HashAlgorithm(), b.public, b.public, first.Timestamp)))
+	AssertNoError(t, err)
+	next := signAll(coauthoredDeposit("third edition",
+		Author{Name: "Alice", PublicKey: a.public},
+		Author{Name: "Bob", PublicKey: b.public},
+	), a, b)
+	if err := bc.CheckKeys(next); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("CheckKeys() error = %v, want ErrKeyRevoked", err)
+	}
+
+	reloaded, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	if !reloaded.ValidateChain() {
+		t.Error("Chain with co-authored deposit is invalid after reload")
+	}
+	receipt, err := reloaded.GetDepositByRef(first.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, len(receipt.Data.Authors), 2, "Co-authors after reload")
+}
+
+func TestBlock_CoauthoredNeedsMerkleRoot(t *testing.T) {
+	a := newTestAuthorKey(t)
+	work := coauthoredDeposit("text", Author{Name: "Alice", PublicKey: a.public}, Author{Name: "Bob"})
+	block := &Block{Data: signAll(work, a)}
+	if err := checkSignatures(block); !errors.Is(err, ErrInvalidAuthors) {
+		t.Errorf("checkSignatures() error = %v, want ErrInvalidAuthors", err)
+	}
+}
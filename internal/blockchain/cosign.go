@@ -0,0 +1,220 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	cosignFileName = "cosign.json"
+
+	// cosignExpiry - сколько работа с соавторами ждёт недостающих подписей
+	cosignExpiry = 7 * 24 * time.Hour
+)
+
+// CosignStatus - состояние сбора подписей соавторов
+type CosignStatus string
+
+const (
+	CosignPending   CosignStatus = "pending"   // ждёт подписей соавторов
+	CosignCommitted CosignStatus = "committed" // депозит записан в цепочку
+)
+
+// Cosign - работа с соавторами, ожидающая их подписей. Подписи
+// собираются в Deposit.Authors
+type Cosign struct {
+	ID        string       `json:"id"`
+	Status    CosignStatus `json:"status"`
+	Deposit   DepositData  `json:"deposit"`
+	BlockID   string       `json:"block_id,omitempty"`
+	Ref       string       `json:"ref,omitempty"` // ссылка на депозит, см. DepositRef
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+
+	// TextHashes - хеши текста всеми алгоритмами: по ним перед записью
+	// ищутся копии текста, в том числе записанные другим алгоритмом
+	TextHashes TextHashes `json:"text_hashes,omitempty"`
+}
+
+// Complete сообщает, что все соавторы с ключами подписали депозит
+func (c *Cosign) Complete() bool {
+	return len(c.Deposit.MissingSignatures()) == 0
+}
+
+// expired сообщает, что работу пора отбросить: она записана дольше
+// jobRetention назад или не подписана всеми за cosignExpiry
+func (c *Cosign) expired(now time.Time) bool {
+	if c.Status == CosignCommitted {
+		return c.UpdatedAt.Before(now.Add(-jobRetention))
+	}
+	return c.CreatedAt.Before(now.Add(-cosignExpiry))
+}
+
+// cosignFile - формат cosign.json
+type cosignFile struct {
+	Cosigns []*Cosign `json:"cosigns"`
+}
+
+// CosignManager собирает подписи соавторов до записи депозита
+// в цепочку и хранит их в cosign.json каталога данных. Работа,
+// не подписанная всеми за cosignExpiry, отбрасывается
+type CosignManager struct {
+	fsys FS
+	path string
+
+	mu      sync.Mutex
+	cosigns map[string]*Cosign
+}
+
+// NewCosignManager загружает ожидающие подписей работы из dataDir
+func NewCosignManager(dataDir string) (*CosignManager, error) {
+	return newCosignManager(OSFS{}, dataDir)
+}
+
+func newCosignManager(fsys FS, dataDir string) (*CosignManager, error) {
+	m := &CosignManager{
+		fsys:    fsys,
+		path:    filepath.Join(dataDir, cosignFileName),
+		cosigns: make(map[string]*Cosign),
+	}
+
+	data, err := fsys.ReadFile(m.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", m.path, err)
+	}
+	if err == nil {
+		var file cosignFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", m.path, err)
+		}
+		for _, c := range file.Cosigns {
+			m.cosigns[c.ID] = c
+		}
+	}
+	return m, nil
+}
+
+// Open начинает сбор подписей для работы с соавторами. Подписи,
+// уже переданные в data, проверяются. hashes - хеши текста работы
+// (см. HashText), сохраняются для проверки дубликатов при записи
+func (m *CosignManager) Open(data DepositData, hashes TextHashes) (*Cosign, error) {
+	if !data.Coauthored() {
+		return nil, fmt.Errorf("%w: deposit has no co-authors", ErrInvalidAuthors)
+	}
+	if err := data.CheckAuthors(false); err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &Cosign{ID: id, Status: CosignPending, Deposit: data, TextHashes: maps.Clone(hashes), CreatedAt: now, UpdatedAt: now}
+	c.Deposit.Authors = append([]Author(nil), data.Authors...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cosigns[id] = c
+	if err := m.saveLocked(); err != nil {
+		delete(m.cosigns, id)
+		return nil, err
+	}
+	return c.snapshot(), nil
+}
+
+// Get возвращает копию работы, ожидающей подписей
+func (m *CosignManager) Get(id string) (*Cosign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cosigns[id]
+	if !ok || c.expired(time.Now()) {
+		return nil, ErrCosignNotFound
+	}
+	return c.snapshot(), nil
+}
+
+// Sign проверяет подпись соавтора с ключом publicKey и добавляет её
+// к работе. Повторная подпись того же соавтора заменяет прежнюю
+func (m *CosignManager) Sign(id, publicKey, signature string) (*Cosign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cosigns[id]
+	if !ok || c.expired(time.Now()) {
+		return nil, ErrCosignNotFound
+	}
+	if c.Status == CosignCommitted {
+		return nil, ErrCosignCommitted
+	}
+	index, _, err := c.Deposit.VerifyCosignature(publicKey, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := c.Deposit.Authors[index].Signature
+	c.Deposit.Authors[index].Signature = signature
+	c.UpdatedAt = time.Now()
+	if err := m.saveLocked(); err != nil {
+		c.Deposit.Authors[index].Signature = prev
+		return nil, err
+	}
+	return c.snapshot(), nil
+}
+
+// Commit отмечает, что депозит работы записан в цепочку
+func (m *CosignManager) Commit(id string, receipt *DepositReceipt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cosigns[id]
+	if !ok {
+		return ErrCosignNotFound
+	}
+	c.Status = CosignCommitted
+	c.BlockID = receipt.Block.ID
+	c.Ref = receipt.Ref
+	c.UpdatedAt = time.Now()
+	return m.saveLocked()
+}
+
+// snapshot копирует работу вместе со списком соавторов
+func (c *Cosign) snapshot() *Cosign {
+	s := *c
+	s.Deposit.Authors = append([]Author(nil), c.Deposit.Authors...)
+	s.TextHashes = maps.Clone(c.TextHashes)
+	return &s
+}
+
+// saveLocked атомарно перезаписывает cosign.json, отбрасывая устаревшие
+// работы (см. expired). Вызывается под m.mu
+func (m *CosignManager) saveLocked() error {
+	now := time.Now()
+	file := cosignFile{Cosigns: make([]*Cosign, 0, len(m.cosigns))}
+	for id, c := range m.cosigns {
+		if c.expired(now) {
+			delete(m.cosigns, id)
+			continue
+		}
+		file.Cosigns = append(file.Cosigns, c)
+	}
+	sort.Slice(file.Cosigns, func(i, j int) bool { return file.Cosigns[i].CreatedAt.Before(file.Cosigns[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := writeFileSync(m.fsys, tmp, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.path, err)
+	}
+	return m.fsys.Rename(tmp, m.path)
+}
@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCosignManager(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewCosignManager(dir)
+	AssertNoError(t, err)
+	a, b, c := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+
+	work := coauthoredDeposit("joint text",
+		Author{Name: "Alice", PublicKey: a.public},
+		Author{Name: "Bob", PublicKey: b.public},
+		Author{Name: "Carol"},
+	)
+
+	if _, err := m.Open(CreateTestBlock("Alice", "Solo", "solo"), nil); !errors.Is(err, ErrInvalidAuthors) {
+		t.Errorf("Open() of solo work error = %v, want ErrInvalidAuthors", err)
+	}
+
+	pending, err := m.Open(signAll(work, a), nil)
+	AssertNoError(t, err)
+	AssertEqual(t, pending.Status, CosignPending, "Status")
+	AssertEqual(t, pending.Complete(), false, "Complete with one signature")
+
+	t.Run("foreign key", func(t *testing.T) {
+		_, err := m.Sign(pending.ID, c.public, c.cosign(work))
+		if !errors.Is(err, ErrInvalidAuthors) {
+			t.Errorf("Sign() error = %v, want ErrInvalidAuthors", err)
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		_, err := m.Sign(pending.ID, b.public, a.cosign(work))
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Sign() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		if _, err := m.Sign("missing", b.public, b.cosign(work)); err != ErrCosignNotFound {
+			t.Errorf("Sign() error = %v, want ErrCosignNotFound", err)
+		}
+	})
+
+	signed, err := m.Sign(pending.ID, b.public, b.cosign(work))
+	AssertNoError(t, err)
+	AssertEqual(t, signed.Complete(), true, "Complete after all signatures")
+	AssertNoError(t, signed.Deposit.CheckAuthors(true))
+
+	// Подписи переживают перезапуск
+	reopened, err := NewCosignManager(dir)
+	AssertNoError(t, err)
+	loaded, err := reopened.Get(pending.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, loaded.Complete(), true, "Complete after reload")
+
+	block := &Block{ID: "000-000-001", Timestamp: time.Now(), Data: loaded.Deposit}
+	AssertNoError(t, reopened.Commit(pending.ID, &DepositReceipt{Block: block, Ref: block.ID}))
+	committed, err := reopened.Get(pending.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, committed.Status, CosignCommitted, "Status after commit")
+	AssertEqual(t, committed.Ref, "000-000-001", "Ref after commit")
+
+	if _, err := reopened.Sign(pending.ID, b.public, b.cosign(work)); err != ErrCosignCommitted {
+		t.Errorf("Sign() after commit error = %v, want ErrCosignCommitted", err)
+	}
+}
+
+func TestCosignManager_Expiry(t *testing.T) {
+	m, err := NewCosignManager(t.TempDir())
+	AssertNoError(t, err)
+	a := newTestAuthorKey(t)
+
+	pending, err := m.Open(coauthoredDeposit("text", Author{Name: "Alice", PublicKey: a.public}, Author{Name: "Bob"}), nil)
+	AssertNoError(t, err)
+
+	m.mu.Lock()
+	m.cosigns[pending.ID].CreatedAt = time.Now().Add(-cosignExpiry - time.Minute)
+	m.mu.Unlock()
+
+	if _, err := m.Get(pending.ID); err != ErrCosignNotFound {
+		t.Errorf("Get() of expired work error = %v, want ErrCosignNotFound", err)
+	}
+}
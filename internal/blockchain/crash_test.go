@@ -0,0 +1,185 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+const crashDataDir = "/data"
+
+// crashStoreOptions - частые бэкапы, чтобы сбои попадали и в них
+func crashStoreOptions(fsys FS) StoreOptions {
+	return StoreOptions{
+		FS:      fsys,
+		Backups: BackupPolicy{Every: 2, KeepCount: 2},
+	}
+}
+
+// runDepositWorkload открывает цепочку на fsys и депонирует n текстов.
+// Возвращает депозиты, которые AddBlock подтвердил
+func runDepositWorkload(fsys *MemFS, n int) []DepositData {
+	storage, err := NewStorageWithOptions(crashDataDir, crashStoreOptions(fsys))
+	if err != nil {
+		return nil
+	}
+	defer storage.Close()
+
+	bc, err := NewBlockchain(storage, 1)
+	if err != nil {
+		return nil
+	}
+
+	var acked []DepositData
+	for i := 0; i < n; i++ {
+		data := CreateTestBlock(fmt.Sprintf("Author%d", i), "Title", fmt.Sprintf("Crash text %d", i))
+		if _, err := bc.AddBlock(data); err == nil {
+			acked = append(acked, data)
+		}
+		if fsys.Crashed() {
+			break
+		}
+	}
+	return acked
+}
+
+// assertRecovered перезапускает fsys и проверяет, что цепочка
+// открывается без режима восстановления, валидна, содержит все
+// подтверждённые депозиты и принимает новые
+func assertRecovered(t *testing.T, fsys *MemFS, acked []DepositData) {
+	t.Helper()
+
+	fsys.Restart()
+
+	storage, err := NewStorageWithOptions(crashDataDir, crashStoreOptions(fsys))
+	if err != nil {
+		t.Fatalf("NewStorage() after crash error = %v", err)
+	}
+	defer storage.Close()
+
+	bc, err := NewBlockchain(storage, 1)
+	if err != nil {
+		t.Fatalf("NewBlockchain() after crash error = %v", err)
+	}
+
+	if !bc.ValidateChain() {
+		t.Fatal("Chain is invalid after crash")
+	}
+
+	for _, data := range acked {
+		if _, ok := bc.HasContentHash(data.ContentHash); !ok {
+			t.Errorf("Acknowledged deposit %q lost after crash", data.Title+" "+data.AuthorName)
+		}
+	}
+
+	if _, err := bc.AddBlock(CreateTestBlock("After", "Crash", "Text after restart")); err != nil {
+		t.Errorf("AddBlock() after crash error = %v", err)
+	}
+}
+
+// forEachFaultPoint прогоняет нагрузку со сбоем на каждой по очереди
+// подходящей операции, пока нагрузка не завершится без сбоя
+func forEachFaultPoint(t *testing.T, fault func(after int) Fault, check func(t *testing.T, fsys *MemFS, acked []DepositData)) {
+	for after := 0; ; after++ {
+		fsys := NewMemFS()
+		f := fault(after)
+		fsys.InjectFault(f)
+
+		acked := runDepositWorkload(fsys, 5)
+
+		triggered := false
+		for _, injected := range fsys.faults {
+			triggered = triggered || injected.fired
+		}
+		if !triggered {
+			return
+		}
+
+		op := f.Op
+		if op == "" {
+			op = "any"
+		}
+		t.Run(fmt.Sprintf("%s#%d", op, after), func(t *testing.T) {
+			check(t, fsys, acked)
+		})
+	}
+}
+
+func TestStorage_CrashAtEveryOperation(t *testing.T) {
+	forEachFaultPoint(t, func(after int) Fault {
+		return Fault{After: after, Crash: true}
+	}, assertRecovered)
+}
+
+func TestStorage_CrashWithTornWrite(t *testing.T) {
+	forEachFaultPoint(t, func(after int) Fault {
+		return Fault{Op: FSOpWrite, After: after, Crash: true, ShortWrite: 7}
+	}, assertRecovered)
+}
+
+func TestStorage_WriteErrors(t *testing.T) {
+	t.Run("ENOSPC", func(t *testing.T) {
+		forEachFaultPoint(t, func(after int) Fault {
+			return Fault{Op: FSOpWrite, After: after, Err: syscall.ENOSPC, ShortWrite: 3}
+		}, assertRecovered)
+	})
+
+	t.Run("fsync failure", func(t *testing.T) {
+		forEachFaultPoint(t, func(after int) Fault {
+			return Fault{Op: FSOpSync, After: after, Err: syscall.EIO}
+		}, assertRecovered)
+	})
+
+	t.Run("rename failure", func(t *testing.T) {
+		forEachFaultPoint(t, func(after int) Fault {
+			return Fault{Op: FSOpRename, After: after}
+		}, assertRecovered)
+	})
+}
+
+func TestStorage_CrashDuringChainRewrite(t *testing.T) {
+	for after := 0; ; after++ {
+		fsys := NewMemFS()
+		acked := runDepositWorkload(fsys, 3)
+
+		storage, err := NewStorageWithOptions(crashDataDir, crashStoreOptions(fsys))
+		AssertNoError(t, err)
+		bc, err := NewBlockchain(storage, 1)
+		AssertNoError(t, err)
+
+		// SaveChain делает бэкап и подменяет каталог сегментов
+		fsys.InjectFault(Fault{After: after, Crash: true})
+		bc.saveChain()
+		storage.Close()
+
+		if !fsys.Crashed() {
+			return
+		}
+
+		t.Run(fmt.Sprintf("op#%d", after), func(t *testing.T) {
+			assertRecovered(t, fsys, acked)
+		})
+	}
+}
+
+func TestMemFS_CrashDropsUnsyncedData(t *testing.T) {
+	fsys := NewMemFS()
+	AssertNoError(t, fsys.MkdirAll("/d", 0755))
+
+	f, err := fsys.OpenFile("/d/file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	AssertNoError(t, err)
+	f.Write([]byte("synced"))
+	AssertNoError(t, f.Sync())
+	f.Write([]byte(" lost"))
+
+	fsys.Crash()
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("Write after crash should fail")
+	}
+
+	fsys.Restart()
+	data, err := fsys.ReadFile("/d/file")
+	AssertNoError(t, err)
+	AssertEqual(t, string(data), "synced", "Content after restart")
+}
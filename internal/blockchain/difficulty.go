@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// retargetLimit - во сколько раз цель может измениться за один пересчёт.
+// Ограничение не даёт одному аномальному окну обрушить сложность
+const retargetLimit = 4
+
+// RetargetPolicy подстраивает цель новых блоков под целевое время блока.
+//
+// Каждые Interval блоков время последнего окна из Interval блоков
+// сравнивается с Interval*TargetTime, и цель масштабируется в том же
+// отношении (не больше чем в retargetLimit раз за пересчёт): блоки шли
+// вдвое быстрее - работа на блок удваивается. Цель не выходит за
+// пределы от MinBits до MaxBits нулевых бит
+type RetargetPolicy struct {
+	TargetTime time.Duration // целевое время между блоками
+	Interval   int           // блоков между пересчётами
+	MinBits    int           // самая лёгкая цель
+	MaxBits    int           // самая трудная цель
+}
+
+// WithRetarget включает пересчёт цели по policy. Без этой опции новые
+// блоки майнятся с целью из NewBlockchain (или WithTarget). MinBits
+// меньше 1 считается равным 1, нулевой MaxBits - без ограничения
+func WithRetarget(policy RetargetPolicy) Option {
+	if policy.MinBits < 1 {
+		policy.MinBits = 1
+	}
+	if policy.MaxBits == 0 {
+		policy.MaxBits = maxTargetBits
+	}
+	return func(o *chainOptions) {
+		o.retarget = &policy
+	}
+}
+
+// WithTarget задаёт цель новых блоков точнее, чем сложность
+// в hex-нулях, переданная в NewBlockchain
+func WithTarget(target Target) Option {
+	return func(o *chainOptions) {
+		o.target = &target
+	}
+}
+
+// next возвращает цель блока, который продолжит chain.
+// base - цель, если в цепочке ещё нет блоков с целью
+func (p *RetargetPolicy) next(chain []*Block, base Target) Target {
+	current := base
+	if n := len(chain); n > 0 {
+		if t, ok := chain[n-1].ProofTarget(); ok {
+			current = t
+		}
+	}
+
+	height := len(chain)
+	if p.Interval > 0 && height > p.Interval && height%p.Interval == 0 {
+		elapsed := chain[height-1].Timestamp.Sub(chain[height-1-p.Interval].Timestamp)
+		expected := p.TargetTime * time.Duration(p.Interval)
+		if elapsed < expected/retargetLimit {
+			elapsed = expected / retargetLimit
+		}
+		if elapsed > expected*retargetLimit {
+			elapsed = expected * retargetLimit
+		}
+		current = current.scale(int64(elapsed), int64(expected))
+	}
+
+	if easiest := TargetFromBits(p.MinBits); current.Cmp(easiest) > 0 {
+		current = easiest
+	}
+	if hardest := TargetFromBits(p.MaxBits); current.Cmp(hardest) < 0 {
+		current = hardest
+	}
+	return current
+}
+
+// nextTargetLocked возвращает цель следующего блока.
+// Вызывается под bc.mu
+func (bc *Blockchain) nextTargetLocked() Target {
+	target, _ := bc.expectedTarget(bc.Chain)
+	return target
+}
+
+// NextTarget возвращает цель, с которой будет намайнен следующий блок
+func (bc *Blockchain) NextTarget() Target {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.nextTargetLocked()
+}
+
+// expectedTarget возвращает цель блока, который продолжит prefix:
+// начальную цель цепочки или, с пересчётом, цель по RetargetPolicy.
+// exact - цель блока должна совпасть с ней, иначе блок может быть
+// только труднее
+func (bc *Blockchain) expectedTarget(prefix []*Block) (target Target, exact bool) {
+	if bc.retarget == nil {
+		return bc.target, false
+	}
+	return bc.retarget.next(prefix, bc.target), true
+}
+
+// checkNextTarget проверяет цель нового блока против ожидаемой
+// expected: она не может быть легче, а при exact должна совпасть.
+// Блок без цели в цепочке с ненулевой сложностью не проходит.
+// Сохранённые блоки так не проверяются: сложность и политика пересчёта
+// задаются флагами запуска и меняют только новые блоки
+func checkNextTarget(block *Block, expected Target, exact bool) error {
+	target, ok := block.ProofTarget()
+	if !ok {
+		if block.Target != "" {
+			return ErrInvalidDifficulty
+		}
+		target = TargetFromBits(0)
+	}
+
+	if target.Cmp(expected) > 0 || (exact && target.Cmp(expected) != 0) {
+		return ErrInvalidDifficulty
+	}
+	return checkDifficulty(block)
+}
+
+// checkDifficulty проверяет, что хеш block соответствует цели,
+// записанной в самом блоке: Target или, у блоков прежнего формата,
+// Difficulty в hex-нулях (по 4 бита на ноль). У генезиса и блоков,
+// записанных до хранения сложности, проверяются только хеш и связь -
+// их защищают блоки, намайненные поверх
+func checkDifficulty(block *Block) error {
+	hash, err := hex.DecodeString(block.Hash)
+	if err != nil {
+		return ErrInvalidDifficulty
+	}
+
+	target, ok := block.ProofTarget()
+	if !ok {
+		if block.Target != "" {
+			return ErrInvalidDifficulty
+		}
+		return nil
+	}
+
+	if !target.Met(hash) {
+		return ErrInvalidDifficulty
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timedChain строит цепочку из n блоков с целью в bits нулевых бит,
+// идущих с интервалом step
+func timedChain(n int, bits int, step time.Duration) []*Block {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	chain := make([]*Block, n)
+	for i := range chain {
+		chain[i] = &Block{Timestamp: start.Add(time.Duration(i) * step)}
+		if i > 0 {
+			chain[i].Target = TargetFromBits(bits).String()
+		}
+	}
+	return chain
+}
+
+func TestRetargetPolicy_Next(t *testing.T) {
+	policy := RetargetPolicy{TargetTime: time.Minute, Interval: 4, MinBits: 4, MaxBits: 20}
+
+	tests := []struct {
+		name  string
+		chain []*Block
+		base  int
+		want  Target
+	}{
+		{"only genesis uses base", timedChain(1, 0, time.Minute), 12, TargetFromBits(12)},
+		{"between retargets keeps tip target", timedChain(6, 10, time.Second), 12, TargetFromBits(10)},
+		{"twice as fast halves target", timedChain(8, 10, 30*time.Second), 12, TargetFromBits(11)},
+		{"twice as slow doubles target", timedChain(8, 10, 2*time.Minute), 12, TargetFromBits(10).scale(2, 1)},
+		{"change limited per retarget", timedChain(8, 10, time.Millisecond), 12, TargetFromBits(12)},
+		{"window on target keeps", timedChain(8, 10, time.Minute), 12, TargetFromBits(10)},
+		{"clamped to hardest", timedChain(8, 20, time.Second), 12, TargetFromBits(20)},
+		{"clamped to easiest", timedChain(8, 4, time.Hour), 12, TargetFromBits(4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.next(tt.chain, TargetFromBits(tt.base))
+			AssertEqual(t, got.String(), tt.want.String(), "Next target")
+		})
+	}
+}
+
+func TestBlockchain_DifficultyStoredInBlock(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 2)
+	AssertNoError(t, err)
+
+	block, err := bc.AddBlock(CreateTestBlock("Author", "Title", "Mined at difficulty 2"))
+	AssertNoError(t, err)
+	AssertEqual(t, block.Target, TargetFromDigits(2).String(), "Stored target")
+
+	// Более трудные блоки проходят и при меньшей сложности
+	bc, err = NewBlockchain(storage, 1)
+	AssertNoError(t, err, "Chain should open with lower difficulty")
+	AssertEqual(t, bc.ValidateChain(), true, "Chain valid after difficulty change")
+
+	next, err := bc.AddBlock(CreateTestBlock("Author", "Title", "Mined at difficulty 1"))
+	AssertNoError(t, err)
+	AssertEqual(t, next.Target, TargetFromDigits(1).String(), "New block target")
+	AssertEqual(t, next.Hash[:1], "0", "New block hash prefix")
+
+	t.Run("easier blocks pass higher difficulty", func(t *testing.T) {
+		bc, err := NewBlockchain(storage, 2)
+		AssertNoError(t, err, "Chain should open with higher difficulty")
+		AssertEqual(t, bc.ValidateChain(), true, "Chain valid after difficulty change")
+		AssertEqual(t, bc.NextTarget().String(), TargetFromDigits(2).String(), "Target of new blocks")
+	})
+
+	t.Run("target is part of the hash", func(t *testing.T) {
+		forged := *bc.Chain[1]
+		forged.Target = TargetFromBits(0).String()
+		if forged.ValidateHash() {
+			t.Error("Changing target should change the block hash")
+		}
+	})
+
+	t.Run("hash must meet stored target", func(t *testing.T) {
+		chain := append([]*Block{}, bc.Chain...)
+		forged := *chain[2]
+		forged.Target = TargetFromBits(80).String()
+		forged.Hash = forged.CalculateHash()
+		chain[2] = &forged
+
+		height, err := bc.invalidBlock(chain)
+		AssertEqual(t, height, 2, "First invalid block")
+		if !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("invalidBlock() error = %v, want ErrInvalidDifficulty", err)
+		}
+	})
+}
+
+func TestBlockchain_HexDifficultyBlocksValidate(t *testing.T) {
+	storage := NewTestStorage()
+	bc := NewBlockchainWithStorage(storage, 1)
+
+	// Блок в прежнем формате: сложность в hex-нулях вместо цели
+	legacy := NewBlock("000-000-001", bc.GetLastBlock().Hash, CreateTestBlock("Author", "Title", "hex difficulty"))
+	legacy.Difficulty = 2
+	for legacy.Hash = legacy.CalculateHash(); !strings.HasPrefix(legacy.Hash, "00"); legacy.Hash = legacy.CalculateHash() {
+		legacy.Nonce++
+	}
+	AssertNoError(t, bc.addBlockInternal(legacy))
+	AssertNoError(t, storage.SaveBlock(legacy))
+
+	target, ok := legacy.ProofTarget()
+	AssertEqual(t, ok, true, "Hex difficulty block has a target")
+	AssertEqual(t, target.LeadingZeroBits(), 8, "Target bits of hex difficulty 2")
+
+	_, err := bc.AddBlock(CreateTestBlock("Author", "Title", "after hex block"))
+	AssertNoError(t, err)
+	AssertEqual(t, bc.ValidateChain(), true, "Mixed hex and target chain is valid")
+
+	t.Run("hex difficulty still enforced", func(t *testing.T) {
+		forged := *legacy
+		forged.Difficulty = 20
+		forged.Hash = forged.CalculateHash()
+		if err := checkDifficulty(&forged); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("checkDifficulty() error = %v, want ErrInvalidDifficulty", err)
+		}
+	})
+
+	t.Run("reopen with bit difficulty", func(t *testing.T) {
+		// Блок в hex-нулях проверяется по своей сложности (8 бит),
+		// а не по цели новых блоков
+		for _, bits := range []int{9, 17} {
+			reopened, err := NewBlockchain(storage, 1, WithTarget(TargetFromBits(bits)))
+			if err != nil {
+				t.Fatalf("NewBlockchain() with %d bits error = %v", bits, err)
+			}
+			AssertEqual(t, reopened.ValidateChain(), true, "Hex chain valid with %d bits", bits)
+		}
+	})
+
+	t.Run("blocks without difficulty", func(t *testing.T) {
+		// Записаны до хранения сложности: проверяются хеш и связь
+		unmined := NewTestStorage()
+		AssertNoError(t, unmined.SaveChain(&Blockchain{Chain: testChainBlocks(3)}))
+		reopened, err := NewBlockchain(unmined, 2)
+		if err != nil {
+			t.Fatalf("NewBlockchain() error = %v", err)
+		}
+		AssertEqual(t, reopened.ValidateChain(), true, "Chain without difficulty is valid")
+	})
+}
+
+func TestBlockchain_TargetEnforced(t *testing.T) {
+	// nextBlock строит блок поверх вершины bc с целью target и хешем,
+	// который ей соответствует. Без цели хеш не начинается с нуля,
+	// чтобы не пройти сложность 1 случайно
+	nextBlock := func(t *testing.T, bc *Blockchain, target string) *Block {
+		t.Helper()
+		tip := bc.GetLastBlock()
+		id, err := incrementID(tip.ID)
+		AssertNoError(t, err)
+		block := NewBlock(id, tip.Hash, CreateTestBlock("Author", "Title", "forged block"))
+		if target == "" {
+			for block.Hash = block.CalculateHash(); strings.HasPrefix(block.Hash, "0"); block.Hash = block.CalculateHash() {
+				block.Nonce++
+			}
+			return block
+		}
+
+		parsed, err := ParseTarget(target)
+		AssertNoError(t, err)
+		_, err = block.mine(context.Background(), parsed, 1)
+		AssertNoError(t, err)
+		return block
+	}
+
+	tests := []struct {
+		name   string
+		bc     func() *Blockchain
+		target func(bc *Blockchain) string
+	}{
+		{
+			"difficulty 0",
+			func() *Blockchain { return NewBlockchainWithStorage(NewTestStorage(), 1) },
+			func(*Blockchain) string { return "" },
+		},
+		{
+			"all-ff target",
+			func() *Blockchain { return NewBlockchainWithStorage(NewTestStorage(), 1) },
+			func(*Blockchain) string { return TargetFromBits(0).String() },
+		},
+		{
+			"lowered target",
+			func() *Blockchain { return NewBlockchainWithStorage(NewTestStorage(), 2) },
+			func(*Blockchain) string { return TargetFromDigits(1).String() },
+		},
+		{
+			"target off retarget policy",
+			func() *Blockchain {
+				bc, err := NewBlockchain(NewTestStorage(), 1, WithRetarget(RetargetPolicy{TargetTime: time.Hour, Interval: 2}))
+				AssertNoError(t, err)
+				return bc
+			},
+			// Труднее ожидаемой, но не равна ей
+			func(bc *Blockchain) string { return TargetFromBits(bc.NextTarget().LeadingZeroBits() + 1).String() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := tt.bc()
+			_, err := bc.AddBlock(CreateTestBlock("Author", "Title", "honest block"))
+			AssertNoError(t, err)
+
+			forged := nextBlock(t, bc, tt.target(bc))
+			if err := bc.addBlockInternal(forged); !errors.Is(err, ErrInvalidDifficulty) {
+				t.Errorf("addBlockInternal() error = %v, want ErrInvalidDifficulty", err)
+			}
+		})
+	}
+}
+
+func TestBlockchain_ReopenWithOtherDifficulty(t *testing.T) {
+	t.Run("higher difficulty", func(t *testing.T) {
+		storage := NewTestStorage()
+		bc := NewBlockchainWithStorage(storage, 1)
+		for i := 0; i < 3; i++ {
+			_, err := bc.AddBlock(CreateTestBlock("Author", "Title", string(rune('a'+i))))
+			AssertNoError(t, err)
+		}
+
+		for _, opts := range [][]Option{nil, {WithTarget(TargetFromBits(9))}} {
+			bc, err := NewBlockchain(storage, 2, opts...)
+			if err != nil {
+				t.Fatalf("NewBlockchain() with higher difficulty error = %v", err)
+			}
+			AssertEqual(t, bc.ValidateChain(), true, "Chain valid with higher difficulty")
+		}
+	})
+
+	t.Run("other retarget policy", func(t *testing.T) {
+		storage := NewTestStorage()
+		bc, err := NewBlockchain(storage, 1, WithRetarget(RetargetPolicy{TargetTime: time.Hour, Interval: 2}))
+		AssertNoError(t, err)
+		for i := 0; i < 5; i++ {
+			_, err := bc.AddBlock(CreateTestBlock("Author", "Title", string(rune('a'+i))))
+			AssertNoError(t, err)
+		}
+
+		policies := []RetargetPolicy{
+			{TargetTime: time.Nanosecond, Interval: 2},
+			{TargetTime: time.Hour, Interval: 3},
+		}
+		for _, policy := range policies {
+			bc, err := NewBlockchain(storage, 1, WithRetarget(policy))
+			if err != nil {
+				t.Fatalf("NewBlockchain() with %+v error = %v", policy, err)
+			}
+			AssertEqual(t, bc.ValidateChain(), true, "Chain valid with another retarget policy")
+		}
+
+		// Без пересчёта цепочка тоже открывается
+		bc, err = NewBlockchain(storage, 1)
+		if err != nil {
+			t.Fatalf("NewBlockchain() without retargeting error = %v", err)
+		}
+		AssertEqual(t, bc.ValidateChain(), true, "Chain valid without retargeting")
+	})
+
+	t.Run("stored target is still enforced", func(t *testing.T) {
+		bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+		_, err := bc.AddBlock(CreateTestBlock("Author", "Title", "honest block"))
+		AssertNoError(t, err)
+
+		chain := append([]*Block{}, bc.Chain...)
+		forged := *chain[1]
+		forged.Target = TargetFromBits(40).String()
+		forged.Hash = forged.CalculateHash()
+		chain[1] = &forged
+
+		height, err := bc.invalidBlock(chain)
+		AssertEqual(t, height, 1, "First invalid block")
+		if !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("invalidBlock() error = %v, want ErrInvalidDifficulty", err)
+		}
+	})
+}
+
+func TestBlockchain_Retarget(t *testing.T) {
+	// Блоки майнятся за микросекунды при целевом часе: цель падает
+	// в retargetLimit раз, то есть на 2 бита
+	bc, err := NewBlockchain(NewTestStorage(), 1, WithRetarget(RetargetPolicy{
+		TargetTime: time.Hour,
+		Interval:   2,
+	}))
+	AssertNoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := bc.AddBlock(CreateTestBlock("Author", "Title", string(rune('a'+i))))
+		AssertNoError(t, err)
+	}
+	AssertEqual(t, bc.NextTarget().LeadingZeroBits(), 6, "Target bits after fast window")
+	AssertEqual(t, bc.GetChainInfo()["difficulty_bits"], 6, "Chain info difficulty bits")
+
+	block, err := bc.AddBlock(CreateTestBlock("Author", "Title", "after retarget"))
+	AssertNoError(t, err)
+	AssertEqual(t, block.Target, TargetFromBits(6).String(), "Retargeted block target")
+	AssertEqual(t, bc.ValidateChain(), true, "Chain with mixed targets is valid")
+}
+
+func TestWithTarget(t *testing.T) {
+	bc, err := NewBlockchain(NewTestStorage(), 1, WithTarget(TargetFromBits(7)))
+	AssertNoError(t, err)
+
+	block, err := bc.AddBlock(CreateTestBlock("Author", "Title", "seven bits"))
+	AssertNoError(t, err)
+	AssertEqual(t, block.Target, TargetFromBits(7).String(), "Block target")
+	AssertEqual(t, block.Hash[0], byte('0'), "First hex digit")
+	if block.Hash[1] > '1' {
+		t.Errorf("Hash %s does not start with 7 zero bits", block.Hash)
+	}
+}
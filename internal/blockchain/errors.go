@@ -48,6 +48,9 @@ var (
 	ErrChainValidationFailed = &BlockchainError{
 		Code:    "CHAIN_VALIDATION_FAILED",
 		Message: "blockchain validation failed"}
+	ErrMerkleRootMismatch = &BlockchainError{
+		Code:    "MERKLE_ROOT_MISMATCH",
+		Message: "merkle root doesn't match block deposits"}
 	ErrBlockNotFound = &BlockchainError{
 		Code:    "BLOCK_NOT_FOUND",
 		Message: "block not found"}
@@ -63,6 +66,57 @@ var (
 	ErrBackupRestoreFailed = &BlockchainError{
 		Code:    "BACKUP_RESTORE_FAILED",
 		Message: "failed to restore from backup"}
+	ErrBatcherClosed = &BlockchainError{
+		Code:    "BATCHER_CLOSED",
+		Message: "deposit batcher is closed"}
+	ErrJobNotFound = &BlockchainError{
+		Code:    "JOB_NOT_FOUND",
+		Message: "deposit job not found"}
+	ErrReadOnly = &BlockchainError{
+		Code:    "READ_ONLY",
+		Message: "storage is opened read-only"}
+	ErrInvalidPublicKey = &BlockchainError{
+		Code:    "INVALID_PUBLIC_KEY",
+		Message: "invalid author public key"}
+	ErrInvalidSignature = &BlockchainError{
+		Code:    "INVALID_SIGNATURE",
+		Message: "invalid author signature"}
+	ErrInvalidRecord = &BlockchainError{
+		Code:    "INVALID_RECORD",
+		Message: "invalid key record"}
+	ErrKeyRevoked = &BlockchainError{
+		Code:    "KEY_REVOKED",
+		Message: "author key is revoked"}
+	ErrKeyRotated = &BlockchainError{
+		Code:    "KEY_ROTATED",
+		Message: "author key is succeeded by another key"}
+	ErrParentNotFound = &BlockchainError{
+		Code:    "PARENT_NOT_FOUND",
+		Message: "previous version of the work not found"}
+	ErrVersionNotAuthorized = &BlockchainError{
+		Code:    "VERSION_NOT_AUTHORIZED",
+		Message: "new version is not signed by the key or added by the account of the previous version"}
+	ErrTargetNotFound = &BlockchainError{
+		Code:    "TARGET_NOT_FOUND",
+		Message: "retracted or corrected deposit not found"}
+	ErrNoticeNotAuthorized = &BlockchainError{
+		Code:    "NOTICE_NOT_AUTHORIZED",
+		Message: "retraction or correction is not signed by the key or added by the account of the deposit"}
+	ErrInvalidAuthors = &BlockchainError{
+		Code:    "INVALID_AUTHORS",
+		Message: "invalid list of co-authors"}
+	ErrMissingCosignature = &BlockchainError{
+		Code:    "MISSING_COSIGNATURE",
+		Message: "co-author has not signed the deposit"}
+	ErrCosignNotFound = &BlockchainError{
+		Code:    "COSIGN_NOT_FOUND",
+		Message: "pending co-authored deposit not found"}
+	ErrCosignCommitted = &BlockchainError{
+		Code:    "COSIGN_COMMITTED",
+		Message: "co-authored deposit is already committed"}
+	ErrGenesisMismatch = &BlockchainError{
+		Code:    "GENESIS_MISMATCH",
+		Message: "genesis block doesn't match pinned genesis hash"}
 	ErrDuplicateContentHash = &BlockchainError{
 		Code:    "DUPLICATE_CONTENT_HASH",
 		Message: "block with same content hash already exists",
@@ -0,0 +1,287 @@
+package blockchain
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType - тип события цепочки
+type EventType string
+
+const (
+	// EventBlockCommitted - блок записан в цепочку и хранилище
+	EventBlockCommitted EventType = "block_committed"
+	// EventChainRecovered - цепочка восстановлена после повреждения
+	EventChainRecovered EventType = "chain_recovered"
+	// EventValidationFailed - сохранённая цепочка или новый блок
+	// не прошли проверку
+	EventValidationFailed EventType = "validation_failed"
+)
+
+// Event - событие цепочки.
+//
+// Block и Height - записанный блок (BlockCommitted) или первый
+// невалидный (ValidationFailed); у отвергнутого нового блока Height
+// равен -1. Recovery - отчёт о восстановлении, если оно шло через
+// карантин
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Block    *Block
+	Height   int
+	Recovery *RecoveryReport
+	Err      error
+}
+
+// Backpressure - что делает издатель, когда буфер подписчика полон
+type Backpressure int
+
+const (
+	// BackpressureBlock - издатель ждёт, пока подписчик разберёт буфер,
+	// но не дольше SubscribeOptions.BlockTimeout. Событие публикует
+	// воркер майнинга, поэтому медленный подписчик замедляет запись
+	// следующих блоков
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropNewest - новое событие отбрасывается
+	BackpressureDropNewest
+	// BackpressureDropOldest - из буфера вытесняется самое старое событие
+	BackpressureDropOldest
+)
+
+const (
+	// defaultEventBuffer - буфер подписчика по умолчанию
+	defaultEventBuffer = 64
+	// defaultBlockTimeout - сколько издатель ждёт подписчика
+	// с BackpressureBlock по умолчанию
+	defaultBlockTimeout = 5 * time.Second
+)
+
+// SubscribeOptions - параметры подписки
+type SubscribeOptions struct {
+	// Types - на какие события подписаться, пусто - на все
+	Types []EventType
+	// Buffer - размер буфера событий, 0 - defaultEventBuffer
+	Buffer int
+	// Backpressure - поведение при полном буфере
+	Backpressure Backpressure
+	// BlockTimeout - сколько ждёт издатель при BackpressureBlock,
+	// 0 - defaultBlockTimeout. По истечении событие отбрасывается
+	BlockTimeout time.Duration
+}
+
+// EventBus раздаёт события цепочки подписчикам внутри процесса.
+//
+// Производные индексы, кэши и уведомления подписываются на события,
+// а не перечитывают GetAllBlocks. События одного издателя приходят
+// в порядке публикации. Шину можно создать до цепочки и передать
+// в NewBlockchain (WithEventBus), чтобы получить события открытия:
+// проверку сохранённой цепочки и её восстановление
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewEventBus создаёт шину событий без подписчиков
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]struct{})}
+}
+
+// WithEventBus задаёт шину событий цепочки. Без неё цепочка создаёт
+// свою, доступную через Events
+func WithEventBus(bus *EventBus) Option {
+	return func(o *chainOptions) {
+		o.events = bus
+	}
+}
+
+// Subscription - подписка на события шины
+type Subscription struct {
+	bus     *EventBus
+	ch      chan Event
+	types   map[EventType]bool
+	opts    SubscribeOptions
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+
+	// mu защищает ch от закрытия во время отправки: deliver держит
+	// RLock, Close закрывает ch под Lock
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Subscribe подписывает на события шины. События читаются из Events;
+// канал закрывается после Close подписки или шины
+func (b *EventBus) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultEventBuffer
+	}
+	if opts.BlockTimeout <= 0 {
+		opts.BlockTimeout = defaultBlockTimeout
+	}
+	sub := &Subscription{
+		bus:  b,
+		ch:   make(chan Event, opts.Buffer),
+		opts: opts,
+		done: make(chan struct{}),
+	}
+	if len(opts.Types) > 0 {
+		sub.types = make(map[EventType]bool, len(opts.Types))
+		for _, t := range opts.Types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.once.Do(sub.closeChannels)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// SubscribeFunc подписывает handler: он вызывается для каждого события
+// в отдельной горутине подписки, по одному событию за раз. Так строятся
+// подключаемые индексы и интеграции. Handler не должен ждать записи
+// блока при BackpressureBlock: блок записывает тот же воркер, который
+// ждёт handler
+func (b *EventBus) SubscribeFunc(opts SubscribeOptions, handler func(Event)) *Subscription {
+	sub := b.Subscribe(opts)
+	go func() {
+		for ev := range sub.ch {
+			handler(ev)
+		}
+	}()
+	return sub
+}
+
+// Events возвращает канал событий подписки
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped возвращает число событий, отброшенных из-за полного буфера
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close отменяет подписку и закрывает канал событий. Издатель,
+// ждущий места в буфере, отпускается
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+
+		s.closeChannels()
+	})
+}
+
+// closeChannels отпускает ждущего издателя и закрывает канал событий
+func (s *Subscription) closeChannels() {
+	close(s.done)
+
+	s.mu.Lock()
+	s.closed = true
+	close(s.ch)
+	s.mu.Unlock()
+}
+
+// Close закрывает шину и все подписки; события после Close
+// не доставляются
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+// Publish рассылает событие подписчикам с учётом их Backpressure.
+// Пустое Time заполняется текущим временем.
+//
+// Подписчики копируются под блокировкой шины, а доставка идёт без неё:
+// издатель, ждущий медленного подписчика, не держит Subscribe, Close
+// и других издателей
+func (b *EventBus) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		if sub.types == nil || sub.types[ev.Type] {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(ev)
+	}
+}
+
+// deliver кладёт событие в буфер подписки. Держит RLock подписки,
+// поэтому канал не закроется во время отправки: Close сначала
+// закрывает done, отпуская ждущего издателя, и только потом берёт Lock
+func (s *Subscription) deliver(ev Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	switch s.opts.Backpressure {
+	case BackpressureDropNewest:
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+
+	case BackpressureDropOldest:
+		for {
+			select {
+			case s.ch <- ev:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+
+	default:
+		timer := time.NewTimer(s.opts.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- ev:
+		case <-s.done:
+		case <-timer.C:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Events возвращает шину событий цепочки
+func (bc *Blockchain) Events() *EventBus {
+	return bc.events
+}
@@ -0,0 +1,227 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nextEvent ждёт событие подписки
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("Subscription closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event")
+	}
+	return Event{}
+}
+
+func TestEventBus_Backpressure(t *testing.T) {
+	publish := func(bus *EventBus, heights ...int) {
+		for _, h := range heights {
+			bus.Publish(Event{Type: EventBlockCommitted, Height: h})
+		}
+	}
+
+	t.Run("drop newest", func(t *testing.T) {
+		bus := NewEventBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 1, Backpressure: BackpressureDropNewest})
+		publish(bus, 1, 2, 3)
+		AssertEqual(t, nextEvent(t, sub).Height, 1, "Kept event")
+		AssertEqual(t, sub.Dropped(), uint64(2), "Dropped events")
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		bus := NewEventBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 1, Backpressure: BackpressureDropOldest})
+		publish(bus, 1, 2, 3)
+		AssertEqual(t, nextEvent(t, sub).Height, 3, "Kept event")
+		AssertEqual(t, sub.Dropped(), uint64(2), "Dropped events")
+	})
+
+	t.Run("block with timeout", func(t *testing.T) {
+		bus := NewEventBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 1, BlockTimeout: 10 * time.Millisecond})
+		publish(bus, 1, 2)
+		AssertEqual(t, nextEvent(t, sub).Height, 1, "Kept event")
+		AssertEqual(t, sub.Dropped(), uint64(1), "Dropped events")
+	})
+
+	t.Run("block waits for subscriber", func(t *testing.T) {
+		bus := NewEventBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 1})
+		done := make(chan struct{})
+		go func() {
+			publish(bus, 1, 2)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("Publish did not wait for a full subscriber")
+		case <-time.After(20 * time.Millisecond):
+		}
+		AssertEqual(t, nextEvent(t, sub).Height, 1, "First event")
+		AssertEqual(t, nextEvent(t, sub).Height, 2, "Second event")
+		<-done
+		AssertEqual(t, sub.Dropped(), uint64(0), "Dropped events")
+	})
+
+	t.Run("close releases publisher", func(t *testing.T) {
+		bus := NewEventBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 1})
+		done := make(chan struct{})
+		go func() {
+			publish(bus, 1, 2)
+			close(done)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		sub.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Publish is still blocked after Close")
+		}
+		publish(bus, 3) // после Close подписка событий не получает
+	})
+
+	t.Run("waiting publisher does not hold bus", func(t *testing.T) {
+		bus := NewEventBus()
+		slow := bus.Subscribe(SubscribeOptions{Buffer: 1})
+		AssertEqual(t, slow.opts.BlockTimeout, defaultBlockTimeout, "Default block timeout")
+		done := make(chan struct{})
+		go func() {
+			publish(bus, 1, 2)
+			close(done)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		// Подписка и отписка не ждут издателя, застрявшего на slow
+		subscribed := make(chan struct{})
+		go func() {
+			bus.Subscribe(SubscribeOptions{}).Close()
+			close(subscribed)
+		}()
+		select {
+		case <-subscribed:
+		case <-time.After(time.Second):
+			t.Fatal("Subscribe is blocked by a waiting publisher")
+		}
+
+		slow.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Publish is still blocked after Close")
+		}
+	})
+}
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := NewEventBus()
+	failures := bus.Subscribe(SubscribeOptions{Types: []EventType{EventValidationFailed}})
+	all := bus.Subscribe(SubscribeOptions{})
+
+	bus.Publish(Event{Type: EventBlockCommitted, Height: 1})
+	bus.Publish(Event{Type: EventValidationFailed, Height: -1})
+
+	AssertEqual(t, nextEvent(t, all).Type, EventBlockCommitted, "First event")
+	AssertEqual(t, nextEvent(t, all).Type, EventValidationFailed, "Second event")
+	ev := nextEvent(t, failures)
+	AssertEqual(t, ev.Type, EventValidationFailed, "Filtered event")
+	if ev.Time.IsZero() {
+		t.Error("Event time is not set")
+	}
+
+	got := make(chan Event, 1)
+	bus.SubscribeFunc(SubscribeOptions{}, func(ev Event) { got <- ev })
+	bus.Publish(Event{Type: EventChainRecovered})
+	select {
+	case ev := <-got:
+		AssertEqual(t, ev.Type, EventChainRecovered, "Handler event")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handler was not called")
+	}
+
+	bus.Close()
+	for range all.Events() {
+		// Буфер дочитывается, затем канал закрыт
+	}
+	if _, ok := <-bus.Subscribe(SubscribeOptions{}).Events(); ok {
+		t.Error("Subscription to closed bus is open")
+	}
+}
+
+func TestBlockchain_Events(t *testing.T) {
+	bc, err := NewBlockchain(NewTestStorage(), 1)
+	AssertNoError(t, err)
+	sub := bc.Events().Subscribe(SubscribeOptions{})
+	defer sub.Close()
+
+	first, err := bc.AddBlock(CreateTestBlock("Author", "First", "first text"))
+	AssertNoError(t, err)
+	_, err = bc.AddBlock(CreateTestBlock("Author", "First", "first text"))
+	AssertNoError(t, err)
+	second, err := bc.AddBlock(CreateTestBlock("Author", "Second", "second text"))
+	AssertNoError(t, err)
+
+	// Дубликат блока не записывает и события не даёт
+	ev := nextEvent(t, sub)
+	AssertEqual(t, ev.Type, EventBlockCommitted, "Event type")
+	AssertEqual(t, ev.Block, first, "Committed block")
+	AssertEqual(t, ev.Height, 1, "Committed height")
+	ev = nextEvent(t, sub)
+	AssertEqual(t, ev.Block, second, "Committed block")
+	AssertEqual(t, ev.Height, 2, "Committed height")
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+	bad := signedDeposit(t, priv, "Forged")
+	bad.Title = "Changed after signing"
+	_, err = bc.AddBlock(bad)
+	AssertError(t, err)
+
+	ev = nextEvent(t, sub)
+	AssertEqual(t, ev.Type, EventValidationFailed, "Event type")
+	AssertEqual(t, ev.Height, -1, "Rejected block height")
+	if !errors.Is(ev.Err, ErrInvalidSignature) {
+		t.Errorf("Event error = %v, want ErrInvalidSignature", ev.Err)
+	}
+}
+
+func TestNewBlockchain_RecoveryEvents(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewStorageWithOptions(dataDir, StoreOptions{})
+	AssertNoError(t, err)
+	defer storage.Close()
+
+	blocks := testChainBlocks(4)
+	blocks[2].Data.Title = "Tampered"
+	AssertNoError(t, storage.log.Reset(blocks))
+
+	// Шина создаётся до цепочки, чтобы получить события открытия
+	bus := NewEventBus()
+	sub := bus.Subscribe(SubscribeOptions{})
+	_, err = NewBlockchain(storage, 0, WithEventBus(bus), WithRecovery(filepath.Join(dataDir, "quarantine")))
+	AssertNoError(t, err)
+
+	ev := nextEvent(t, sub)
+	AssertEqual(t, ev.Type, EventValidationFailed, "Event type")
+	AssertEqual(t, ev.Height, 2, "First invalid height")
+	AssertEqual(t, ev.Block.ID, blocks[2].ID, "First invalid block")
+
+	ev = nextEvent(t, sub)
+	AssertEqual(t, ev.Type, EventChainRecovered, "Event type")
+	AssertEqual(t, ev.Height, 1, "Recovered tip height")
+	AssertNotNil(t, ev.Recovery, "Recovery report")
+	AssertEqual(t, ev.Recovery.QuarantinedBlocks, 2, "Quarantined blocks")
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package blockchain
+
+import "os"
+
+// lockFile на платформах без блокировок файлов ничего не делает
+func lockFile(f *os.File) error {
+	return nil
+}
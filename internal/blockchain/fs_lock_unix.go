@@ -0,0 +1,18 @@
+//go:build unix
+
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile берёт эксклюзивную неблокирующую flock-блокировку
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package blockchain
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile берёт эксклюзивную неблокирующую блокировку LockFileEx.
+// Блокируется байт далеко за концом файла, чтобы содержимое
+// оставалось доступным для чтения другим процессам
+func lockFile(f *os.File) error {
+	ol := &windows.Overlapped{OffsetHigh: 0x7fffffff}
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// genesisID - ID генезис-блока, от него считаются ID следующих блоков
+const genesisID = "000-000-000"
+
+// Genesis - содержимое генезис-блока развёртывания. У каждого
+// экземпляра (staging, prod) свой генезис, а его хеш закрепляется
+// в конфигурации (WithGenesisHash), чтобы чужая цепочка не прошла
+// проверку
+type Genesis struct {
+	// Timestamp - время генезиса. Нулевое - время создания цепочки;
+	// тогда хеш генезиса известен только после первого запуска
+	Timestamp   time.Time `json:"timestamp"`
+	AuthorName  string    `json:"author_name"`
+	Title       string    `json:"title"`
+	TextStart   string    `json:"text_start,omitempty"`
+	TextEnd     string    `json:"text_end,omitempty"`
+	ContentHash string    `json:"content_hash"`
+}
+
+// DefaultGenesis возвращает генезис, с которым цепочка создавалась
+// до настройки генезиса
+func DefaultGenesis() Genesis {
+	return Genesis{
+		AuthorName:  "Александр Сергеевич Пушкин",
+		Title:       "Евгений Онегин",
+		TextStart:   "Мой дядя самых честных правил",
+		TextEnd:     "Иных уж нет, а те далече",
+		ContentHash: "genesis_hash", // Для генезис-блока особый хеш
+	}
+}
+
+// LoadGenesis читает генезис из JSON-файла path. Неизвестные поля
+// считаются ошибкой: опечатка в имени поля иначе молча дала бы другой
+// генезис
+func LoadGenesis(path string) (Genesis, error) {
+	var g Genesis
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return g, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&g); err != nil {
+		return g, fmt.Errorf("invalid genesis file %s: %w", path, err)
+	}
+	if strings.TrimSpace(g.AuthorName) == "" && strings.TrimSpace(g.Title) == "" {
+		return g, fmt.Errorf("invalid genesis file %s: author_name or title is required", path)
+	}
+	if strings.TrimSpace(g.ContentHash) == "" {
+		return g, fmt.Errorf("invalid genesis file %s: content_hash is required", path)
+	}
+	return g, nil
+}
+
+// Block создает генезис-блок. Генезис не майнится и остаётся
+// в версии 0 без корня Меркла, чтобы хеш прежнего генезиса не изменился
+func (g Genesis) Block() *Block {
+	block := NewBlock(genesisID, "", DepositData{
+		AuthorName:  g.AuthorName,
+		Title:       g.Title,
+		TextStart:   g.TextStart,
+		TextEnd:     g.TextEnd,
+		ContentHash: g.ContentHash,
+	})
+	if !g.Timestamp.IsZero() {
+		block.Timestamp = g.Timestamp
+	}
+	block.Version, block.HashAlg, block.MerkleRoot = HeaderVersionJSON, "", ""
+	block.Hash = block.CalculateHash()
+	return block
+}
+
+// GenesisBlock создает генезис-блок по умолчанию
+func GenesisBlock() *Block {
+	return DefaultGenesis().Block()
+}
+
+// WithGenesis задаёт генезис новой цепочки. Уже записанный генезис
+// не меняется: с ним сверяется только закреплённый хеш
+func WithGenesis(g Genesis) Option {
+	return func(o *chainOptions) {
+		o.genesis = &g
+	}
+}
+
+// WithGenesisHash закрепляет хеш генезиса: NewBlockchain и проверки
+// цепочки отвергают цепочку с другим генезисом
+func WithGenesisHash(hash string) Option {
+	return func(o *chainOptions) {
+		o.genesisHash = strings.ToLower(hash)
+	}
+}
+
+// checkGenesis сверяет генезис chain с закреплённым хешем genesisHash.
+// Пустой genesisHash - генезис не закреплён
+func checkGenesis(chain []*Block, genesisHash string) error {
+	if genesisHash == "" || len(chain) == 0 {
+		return nil
+	}
+	if chain[0].Hash != genesisHash {
+		return fmt.Errorf("%w: got %s, want %s", ErrGenesisMismatch, chain[0].Hash, genesisHash)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testGenesis(instance string) Genesis {
+	return Genesis{
+		Timestamp:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		AuthorName:  "TextProof",
+		Title:       "Genesis " + instance,
+		ContentHash: "genesis_" + instance,
+	}
+}
+
+func TestLoadGenesis(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return path
+	}
+
+	g, err := LoadGenesis(write("staging.json", `{
+		"timestamp": "2025-01-01T00:00:00Z",
+		"author_name": "TextProof",
+		"title": "Genesis staging",
+		"content_hash": "genesis_staging"
+	}`))
+	AssertNoError(t, err)
+	AssertEqual(t, g.Block().Hash, testGenesis("staging").Block().Hash, "Genesis hash from file")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"title": "Genesis", "content_hash": "x", "contnet": "typo"}`},
+		{"no content hash", `{"title": "Genesis"}`},
+		{"no author and title", `{"content_hash": "x"}`},
+		{"not json", `title: Genesis`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadGenesis(write(tt.name+".json", tt.content)); err == nil {
+				t.Error("LoadGenesis() should fail")
+			}
+		})
+	}
+
+	if _, err := LoadGenesis(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("LoadGenesis() error = %v, want not exist", err)
+	}
+}
+
+func TestGenesis_Block(t *testing.T) {
+	staging, prod := testGenesis("staging"), testGenesis("prod")
+
+	block := staging.Block()
+	AssertEqual(t, block.ID, "000-000-000", "Genesis ID")
+	AssertEqual(t, block.Version, HeaderVersionJSON, "Genesis version")
+	if !block.ValidateHash() {
+		t.Error("Genesis hash should be valid")
+	}
+
+	// С заданным временем хеш генезиса известен заранее
+	AssertEqual(t, staging.Block().Hash, block.Hash, "Genesis hash is deterministic")
+	AssertNotEqual(t, prod.Block().Hash, block.Hash, "Instances have distinct genesis")
+}
+
+func TestNewBlockchain_Genesis(t *testing.T) {
+	staging := testGenesis("staging")
+	pinned := staging.Block().Hash
+
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1, WithGenesis(staging), WithGenesisHash(pinned))
+	AssertNoError(t, err)
+	AssertEqual(t, bc.Chain[0].Data.Title, "Genesis staging", "Configured genesis")
+	AssertEqual(t, bc.GetChainInfo()["genesis_hash"], pinned, "Genesis hash in chain info")
+	_, err = bc.AddBlock(CreateTestBlock("Author", "Title", "text"))
+	AssertNoError(t, err)
+
+	t.Run("reopen", func(t *testing.T) {
+		reopened, err := NewBlockchain(storage, 1, WithGenesisHash(pinned))
+		AssertNoError(t, err)
+		AssertEqual(t, len(reopened.Chain), 2, "Chain length")
+	})
+
+	t.Run("other instance", func(t *testing.T) {
+		_, err := NewBlockchain(storage, 1, WithGenesisHash(testGenesis("prod").Block().Hash))
+		if !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("NewBlockchain() error = %v, want ErrGenesisMismatch", err)
+		}
+	})
+
+	t.Run("recovery does not replace genesis", func(t *testing.T) {
+		_, err := NewBlockchain(storage, 1, WithGenesisHash(testGenesis("prod").Block().Hash), WithRecovery(t.TempDir()))
+		if !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("NewBlockchain() error = %v, want ErrGenesisMismatch", err)
+		}
+	})
+
+	t.Run("replaced chain", func(t *testing.T) {
+		// Свежая цепочка с генезисом по умолчанию вместо закреплённой
+		replaced := NewTestStorage()
+		_, err := NewBlockchain(replaced, 1)
+		AssertNoError(t, err)
+
+		_, err = NewBlockchain(replaced, 1, WithGenesisHash(pinned))
+		if !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("NewBlockchain() error = %v, want ErrGenesisMismatch", err)
+		}
+	})
+
+	t.Run("new chain with other genesis", func(t *testing.T) {
+		fresh := NewTestStorage()
+		_, err := NewBlockchain(fresh, 1, WithGenesis(testGenesis("prod")), WithGenesisHash(pinned))
+		if !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("NewBlockchain() error = %v, want ErrGenesisMismatch", err)
+		}
+		if _, err := fresh.LoadChain(); !os.IsNotExist(err) {
+			t.Errorf("Mismatched genesis was saved: LoadChain() error = %v", err)
+		}
+	})
+
+	t.Run("validate chain", func(t *testing.T) {
+		bc, err := NewBlockchain(NewTestStorage(), 1, WithGenesis(staging), WithGenesisHash(pinned))
+		AssertNoError(t, err)
+		if !bc.ValidateChain() {
+			t.Error("Chain with pinned genesis is invalid")
+		}
+
+		bc.Chain[0] = testGenesis("prod").Block()
+		if bc.ValidateChain() {
+			t.Error("Chain with other genesis is valid")
+		}
+		height, err := bc.invalidBlock(bc.Chain)
+		AssertEqual(t, height, 0, "Invalid height")
+		if !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("invalidBlock() error = %v, want ErrGenesisMismatch", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// HashAlgorithm - идентификатор хеш-функции блока или хеша содержимого.
+//
+// Все алгоритмы дают 256-битный хеш, поэтому цели Proof-of-Work и
+// формат хешей у них общие. Пустой идентификатор - SHA-256: так
+// записаны блоки и депозиты до появления идентификаторов
+type HashAlgorithm string
+
+const (
+	HashSHA256     HashAlgorithm = "sha256"
+	HashSHA3_256   HashAlgorithm = "sha3-256"
+	HashBLAKE2b256 HashAlgorithm = "blake2b-256"
+
+	// DefaultHashAlgorithm - алгоритм новых блоков без WithHashAlgorithm
+	DefaultHashAlgorithm = HashSHA256
+)
+
+// HashAlgorithms возвращает поддерживаемые алгоритмы
+func HashAlgorithms() []HashAlgorithm {
+	return []HashAlgorithm{HashSHA256, HashSHA3_256, HashBLAKE2b256}
+}
+
+// ParseHashAlgorithm разбирает идентификатор алгоритма.
+// Пустая строка - SHA-256
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	alg := HashAlgorithm(s).normalize()
+	if !alg.Supported() {
+		return "", fmt.Errorf("unknown hash algorithm %q", s)
+	}
+	return alg, nil
+}
+
+// normalize заменяет пустой идентификатор на SHA-256
+func (a HashAlgorithm) normalize() HashAlgorithm {
+	if a == "" {
+		return HashSHA256
+	}
+	return a
+}
+
+// Supported сообщает, что алгоритм известен
+func (a HashAlgorithm) Supported() bool {
+	switch a.normalize() {
+	case HashSHA256, HashSHA3_256, HashBLAKE2b256:
+		return true
+	}
+	return false
+}
+
+// New возвращает хеш-функцию алгоритма. Для неизвестного алгоритма
+// возвращает nil
+func (a HashAlgorithm) New() hash.Hash {
+	switch a.normalize() {
+	case HashSHA256:
+		return sha256.New()
+	case HashSHA3_256:
+		return sha3.New256()
+	case HashBLAKE2b256:
+		// Ошибка возможна только для ключа длиннее 64 байт
+		h, _ := blake2b.New256(nil)
+		return h
+	}
+	return nil
+}
+
+// sum вычисляет хеш data без выделения памяти. Вызывающий проверяет
+// Supported: для неизвестного алгоритма результат нулевой
+func (a HashAlgorithm) sum(data []byte) [sha256.Size]byte {
+	switch a.normalize() {
+	case HashSHA256:
+		return sha256.Sum256(data)
+	case HashSHA3_256:
+		return sha3.Sum256(data)
+	case HashBLAKE2b256:
+		return blake2b.Sum256(data)
+	}
+	return [sha256.Size]byte{}
+}
+
+// ContentHash вычисляет хеш содержимого текста в hex
+func (a HashAlgorithm) ContentHash(text []byte) string {
+	sum := a.sum(text)
+	return hex.EncodeToString(sum[:])
+}
+
+// WithHashAlgorithm задаёт алгоритм хеша новых блоков и их депозитов.
+// Блоки, записанные другим алгоритмом, проверяются по своему
+func WithHashAlgorithm(alg HashAlgorithm) Option {
+	return func(o *chainOptions) {
+		o.hashAlg = alg.normalize()
+	}
+}
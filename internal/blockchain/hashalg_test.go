@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashAlgorithm_ContentHash(t *testing.T) {
+	// Хеши пустой строки из спецификаций алгоритмов
+	tests := []struct {
+		alg  HashAlgorithm
+		want string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{HashSHA256, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{HashSHA3_256, "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{HashBLAKE2b256, "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.alg), func(t *testing.T) {
+			AssertEqual(t, tt.alg.ContentHash(nil), tt.want, "ContentHash")
+
+			h := tt.alg.New()
+			AssertEqual(t, h.Size(), 32, "Size")
+		})
+	}
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for _, alg := range HashAlgorithms() {
+		got, err := ParseHashAlgorithm(string(alg))
+		AssertNoError(t, err)
+		AssertEqual(t, got, alg, "Parsed algorithm")
+	}
+
+	got, err := ParseHashAlgorithm("")
+	AssertNoError(t, err)
+	AssertEqual(t, got, HashSHA256, "Empty algorithm")
+
+	_, err = ParseHashAlgorithm("md5")
+	AssertError(t, err)
+}
+
+func TestBlock_HashAlgorithm(t *testing.T) {
+	t.Run("mined with each algorithm", func(t *testing.T) {
+		for _, alg := range HashAlgorithms() {
+			block := newBatchBlock("000-000-001", "prev", alg, testDeposits(3))
+			_, err := block.mine(context.Background(), TargetFromBits(4), 2)
+			AssertNoError(t, err)
+
+			if !block.ValidateHash() {
+				t.Errorf("%s: hash is invalid", alg)
+			}
+			if !block.ValidateMerkleRoot() {
+				t.Errorf("%s: merkle root is invalid", alg)
+			}
+			AssertNoError(t, checkDifficulty(block))
+		}
+	})
+
+	t.Run("algorithm is covered by hash", func(t *testing.T) {
+		block := newBatchBlock("000-000-001", "prev", HashSHA3_256, testDeposits(1))
+		block.Hash = block.CalculateHash()
+
+		block.HashAlg = HashBLAKE2b256
+		if block.ValidateHash() {
+			t.Error("ValidateHash() = true after algorithm change")
+		}
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		block := NewBlock("000-000-001", "prev", CreateTestBlock("Author", "Title", "text"))
+		block.HashAlg = "md5"
+		AssertEqual(t, block.CalculateHash(), "", "Hash")
+	})
+
+	t.Run("old header versions carry no algorithm", func(t *testing.T) {
+		block := headerTestBlock(HeaderVersionBinary)
+		block.Data.ContentHashAlg = HashSHA3_256
+		_, err := block.HeaderBytes()
+		AssertError(t, err)
+	})
+}
+
+func TestMerkleProof_Algorithm(t *testing.T) {
+	deposits := testDeposits(5)
+	proof, err := newMerkleProof(HashBLAKE2b256, deposits, 3)
+	AssertNoError(t, err)
+	AssertEqual(t, proof.Root, merkleRoot(HashBLAKE2b256, deposits), "Root")
+	AssertNotEqual(t, proof.Root, MerkleRoot(deposits), "Root of another algorithm")
+	if !proof.Verify(deposits[3]) {
+		t.Error("Proof does not verify")
+	}
+
+	proof.Algorithm = HashSHA256
+	if proof.Verify(deposits[3]) {
+		t.Error("Proof verifies with another algorithm")
+	}
+}
+
+func TestBlockchain_SwitchHashAlgorithm(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+
+	oldText := []byte("Deposited with SHA-256")
+	old := CreateTestBlock("Author", "Old", string(oldText))
+	_, err = bc.AddBlock(old)
+	AssertNoError(t, err)
+
+	// Перезапуск с другим алгоритмом: старые блоки проверяются по своему
+	bc, err = NewBlockchain(storage, 1, WithHashAlgorithm(HashSHA3_256))
+	AssertNoError(t, err)
+	AssertEqual(t, bc.HashAlgorithm(), HashSHA3_256, "Chain algorithm")
+
+	newText := []byte("Deposited with SHA3-256")
+	block, err := bc.AddBlock(DepositData{
+		AuthorName:     "Author",
+		Title:          "New",
+		ContentHash:    HashSHA3_256.ContentHash(newText),
+		ContentHashAlg: HashSHA3_256,
+	})
+	AssertNoError(t, err)
+	AssertEqual(t, block.HashAlg, HashSHA3_256, "Block algorithm")
+	if !bc.ValidateChain() {
+		t.Fatal("Chain with mixed algorithms is invalid")
+	}
+
+	algs := bc.ContentHashAlgorithms()
+	AssertEqual(t, len(algs), 2, "Algorithms in use")
+	AssertEqual(t, algs[0], HashSHA3_256, "Current algorithm first")
+
+	for _, text := range [][]byte{oldText, newText} {
+		receipt, err := bc.FindDepositByText(text)
+		AssertNoError(t, err, "Find %q", text)
+		if receipt != nil && receipt.Proof != nil && !receipt.Proof.Verify(receipt.Data) {
+			t.Errorf("Proof for %q does not verify", text)
+		}
+	}
+
+	_, err = bc.FindDepositByText([]byte("never deposited"))
+	AssertEqual(t, err, ErrBlockNotFound, "Unknown text")
+
+	t.Run("same text with new algorithm", func(t *testing.T) {
+		// Хеш новым алгоритмом не находит старую копию, поэтому
+		// дубликат ищется по тексту всеми алгоритмами
+		hashes := HashText(oldText, HashAlgorithms()...)
+		if _, ok := bc.HasContentHash(hashes[HashSHA3_256]); ok {
+			t.Fatal("Old text found by the new algorithm hash")
+		}
+		existing, err := bc.FindDepositByHashes(hashes)
+		AssertNoError(t, err)
+		AssertEqual(t, existing.Block.Data.Title, "Old", "Duplicate found under the old algorithm")
+
+		// Копия, записанная в обход проверки, не заслоняет первую
+		_, err = bc.AddBlock(DepositData{
+			AuthorName:     "Author",
+			Title:          "Copy",
+			ContentHash:    hashes[HashSHA3_256],
+			ContentHashAlg: HashSHA3_256,
+		})
+		AssertNoError(t, err)
+		receipt, err := bc.FindDepositByText(oldText)
+		AssertNoError(t, err)
+		AssertEqual(t, receipt.Block.Data.Title, "Old", "Earliest deposit")
+	})
+}
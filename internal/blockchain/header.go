@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Версии заголовка блока. Хеш блока - хеш заголовка алгоритмом блока
+// (hash_alg, пустой - SHA-256), а версия определяет, как заголовок
+// кодируется.
+//
+// Версия 0 - заголовок блоков, записанных до версий: JSON структуры
+// hashData, как его выдаёт encoding/json. Поле version в таких блоках
+// отсутствует. Зависит от деталей кодировщика Go, поэтому новые блоки
+// её не используют; она остаётся для проверки старых блоков.
+//
+// Версия 1 - каноническая двоичная запись. Целые - big-endian, строка -
+// её длина в байтах (u32) и сами байты UTF-8. Поля идут в порядке:
+//
+//	u32 version        = 1
+//	str id
+//	str prev_hash
+//	i64 timestamp      время блока в наносекундах Unix (UTC)
+//	str author_name    поля data
+//	str title
+//	str text_start
+//	str text_end
+//	str content_hash
+//	str public_key
+//	str merkle_root    обязателен: поля депозита вне заголовка закрепляет он
+//	i64 difficulty
+//	str target         hex в нижнем регистре, пустая строка, если цели нет
+//	i64 nonce
+//
+// Версия 2 - версия 1, в которую добавлены алгоритмы хешей:
+// str hash_alg сразу после version и str content_hash_alg сразу после
+// content_hash. В версиях 0 и 1 алгоритмов нет, и блоки этих версий
+// с непустыми hash_alg или content_hash_alg отвергаются.
+//
+// Deposits в заголовок не входят: их закрепляет merkle_root.
+// Любое изменение состава или порядка полей - новая версия
+const (
+	HeaderVersionJSON       = 0
+	HeaderVersionBinary     = 1
+	HeaderVersionAlgorithms = 2
+
+	// CurrentHeaderVersion - версия заголовка новых блоков
+	CurrentHeaderVersion = HeaderVersionAlgorithms
+)
+
+// hashData структура только для хеширования заголовка версии 0.
+//
+// Deposits в хеш не входят: их закрепляет MerkleRoot. Пустые корень,
+// сложность и цель опускаются, чтобы хеши старых блоков не изменились
+type hashData struct {
+	ID         string      `json:"id"`
+	PrevHash   string      `json:"prev_hash"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Data       DepositData `json:"data"`
+	Nonce      int         `json:"nonce"`
+	MerkleRoot string      `json:"merkle_root,omitempty"`
+	Difficulty int         `json:"difficulty,omitempty"`
+	Target     string      `json:"target,omitempty"`
+}
+
+// HeaderBytes возвращает заголовок блока - байты, от которых
+// считается хеш. Неизвестная версия - ошибка
+func (b *Block) HeaderBytes() ([]byte, error) {
+	prefix, suffix, appendNonce, err := b.headerTemplate()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(prefix)+20+len(suffix))
+	header = append(header, prefix...)
+	header = appendNonce(header, b.Nonce)
+	return append(header, suffix...), nil
+}
+
+// headerTemplate кодирует заголовок блока и делит его вокруг nonce:
+// заголовок попытки - prefix + appendNonce(nonce) + suffix. Майнинг
+// кодирует заголовок один раз, между попытками меняется только nonce
+func (b *Block) headerTemplate() (prefix, suffix []byte, appendNonce func([]byte, int) []byte, err error) {
+	if !b.HashAlg.Supported() {
+		return nil, nil, nil, fmt.Errorf("unknown block hash algorithm %q", b.HashAlg)
+	}
+	if b.Version < HeaderVersionAlgorithms && (b.HashAlg != "" || b.Data.ContentHashAlg != "") {
+		return nil, nil, nil, fmt.Errorf("block header version %d does not support hash algorithms", b.Version)
+	}
+
+	switch b.Version {
+	case HeaderVersionJSON:
+		prefix, suffix, err = b.jsonHeaderTemplate()
+		return prefix, suffix, appendDecimalNonce, err
+	case HeaderVersionBinary, HeaderVersionAlgorithms:
+		return b.binaryHeaderPrefix(), nil, appendBinaryNonce, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown block header version %d", b.Version)
+	}
+}
+
+// jsonHeaderTemplate делит JSON заголовка версии 0 вокруг значения nonce
+func (b *Block) jsonHeaderTemplate() (prefix, suffix []byte, err error) {
+	data := hashData{
+		ID:         b.ID,
+		PrevHash:   b.PrevHash,
+		Timestamp:  b.Timestamp,
+		Data:       b.Data,
+		Nonce:      math.MinInt64,
+		MerkleRoot: b.MerkleRoot,
+		Difficulty: b.Difficulty,
+		Target:     b.Target,
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to serialize block header: %w", err)
+	}
+
+	// Кавычка внутри строки экранируется, поэтому "nonce": встречается
+	// в JSON только как ключ
+	marker := []byte(`"nonce":` + strconv.FormatInt(math.MinInt64, 10))
+	i := bytes.Index(raw, marker)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("nonce not found in serialized block header")
+	}
+	at := i + len(`"nonce":`)
+	return raw[:at], raw[i+len(marker):], nil
+}
+
+// binaryHeaderPrefix кодирует заголовок версии 1 или 2 без последнего
+// поля - nonce
+func (b *Block) binaryHeaderPrefix() []byte {
+	withAlgs := b.Version >= HeaderVersionAlgorithms
+
+	buf := make([]byte, 0, 256)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.Version))
+	if withAlgs {
+		buf = appendHeaderString(buf, string(b.HashAlg))
+	}
+	buf = appendHeaderString(buf, b.ID)
+	buf = appendHeaderString(buf, b.PrevHash)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.Timestamp.UnixNano()))
+	buf = appendHeaderString(buf, b.Data.AuthorName)
+	buf = appendHeaderString(buf, b.Data.Title)
+	buf = appendHeaderString(buf, b.Data.TextStart)
+	buf = appendHeaderString(buf, b.Data.TextEnd)
+	buf = appendHeaderString(buf, b.Data.ContentHash)
+	if withAlgs {
+		buf = appendHeaderString(buf, string(b.Data.ContentHashAlg))
+	}
+	buf = appendHeaderString(buf, b.Data.PublicKey)
+	buf = appendHeaderString(buf, b.MerkleRoot)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.Difficulty))
+	buf = appendHeaderString(buf, b.Target)
+	return buf
+}
+
+// appendHeaderString дописывает строку с длиной (u32)
+func appendHeaderString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// appendDecimalNonce дописывает nonce заголовка версии 0
+func appendDecimalNonce(buf []byte, nonce int) []byte {
+	return strconv.AppendInt(buf, int64(nonce), 10)
+}
+
+// appendBinaryNonce дописывает nonce заголовка версии 1
+func appendBinaryNonce(buf []byte, nonce int) []byte {
+	return binary.BigEndian.AppendUint64(buf, uint64(nonce))
+}
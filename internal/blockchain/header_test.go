@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// headerTestBlock возвращает блок с фиксированным содержимым
+func headerTestBlock(version int) *Block {
+	return &Block{
+		Version:   version,
+		ID:        "000-000-001",
+		PrevHash:  "ab",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Data: DepositData{
+			AuthorName:  "Автор",
+			Title:       "T",
+			ContentHash: "c",
+		},
+		Difficulty: 2,
+		Target:     "0f",
+		Nonce:      258,
+	}
+}
+
+func TestBlock_HeaderBytes(t *testing.T) {
+	t.Run("binary v1 layout", func(t *testing.T) {
+		block := headerTestBlock(HeaderVersionBinary)
+
+		want := []byte{
+			0, 0, 0, 1, // version
+			0, 0, 0, 11, '0', '0', '0', '-', '0', '0', '0', '-', '0', '0', '1', // id
+			0, 0, 0, 2, 'a', 'b', // prev_hash
+		}
+		ts := block.Timestamp.UnixNano()
+		for shift := 56; shift >= 0; shift -= 8 {
+			want = append(want, byte(ts>>shift))
+		}
+		want = append(want, 0, 0, 0, 10)
+		want = append(want, "Автор"...)
+		want = append(want,
+			0, 0, 0, 1, 'T', // title
+			0, 0, 0, 0, // text_start
+			0, 0, 0, 0, // text_end
+			0, 0, 0, 1, 'c', // content_hash
+			0, 0, 0, 0, // public_key
+			0, 0, 0, 0, // merkle_root
+			0, 0, 0, 0, 0, 0, 0, 2, // difficulty
+			0, 0, 0, 2, '0', 'f', // target
+			0, 0, 0, 0, 0, 0, 1, 2, // nonce
+		)
+
+		header, err := block.HeaderBytes()
+		AssertNoError(t, err)
+		if !bytes.Equal(header, want) {
+			t.Fatalf("HeaderBytes() =\n%x\nwant\n%x", header, want)
+		}
+
+		sum := sha256.Sum256(want)
+		AssertEqual(t, block.CalculateHash(), hex.EncodeToString(sum[:]), "Hash")
+	})
+
+	t.Run("binary v2 adds algorithms", func(t *testing.T) {
+		v1, err := headerTestBlock(HeaderVersionBinary).HeaderBytes()
+		AssertNoError(t, err)
+
+		block := headerTestBlock(HeaderVersionAlgorithms)
+		block.HashAlg = HashSHA3_256
+		block.Data.ContentHashAlg = HashBLAKE2b256
+		v2, err := block.HeaderBytes()
+		AssertNoError(t, err)
+
+		// После version - hash_alg, после content_hash - content_hash_alg
+		want := []byte{0, 0, 0, 2, 0, 0, 0, 8}
+		want = append(want, "sha3-256"...)
+		rest := v1[4:]
+		cut := bytes.Index(rest, []byte{0, 0, 0, 1, 'c'}) + 5
+		want = append(want, rest[:cut]...)
+		want = append(want, 0, 0, 0, 11)
+		want = append(want, "blake2b-256"...)
+		want = append(want, rest[cut:]...)
+		if !bytes.Equal(v2, want) {
+			t.Fatalf("HeaderBytes() =\n%x\nwant\n%x", v2, want)
+		}
+	})
+
+	t.Run("json v0 matches legacy encoding", func(t *testing.T) {
+		block := headerTestBlock(HeaderVersionJSON)
+
+		legacy, err := json.Marshal(hashData{
+			ID:         block.ID,
+			PrevHash:   block.PrevHash,
+			Timestamp:  block.Timestamp,
+			Data:       block.Data,
+			Nonce:      block.Nonce,
+			Difficulty: block.Difficulty,
+			Target:     block.Target,
+		})
+		AssertNoError(t, err)
+
+		header, err := block.HeaderBytes()
+		AssertNoError(t, err)
+		AssertEqual(t, string(header), string(legacy), "Header")
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		block := headerTestBlock(CurrentHeaderVersion + 1)
+
+		_, err := block.HeaderBytes()
+		AssertError(t, err)
+		AssertEqual(t, block.CalculateHash(), "", "Hash")
+		if block.ValidateHash() {
+			t.Error("ValidateHash() = true for unknown version")
+		}
+	})
+}
+
+func TestBlock_HeaderVersion(t *testing.T) {
+	t.Run("new blocks use current version", func(t *testing.T) {
+		block := NewBlock("000-000-001", "prev", CreateTestBlock("Author", "Title", "text"))
+		AssertEqual(t, block.Version, CurrentHeaderVersion, "Version")
+	})
+
+	t.Run("genesis keeps version 0", func(t *testing.T) {
+		genesis := GenesisBlock()
+		AssertEqual(t, genesis.Version, HeaderVersionJSON, "Version")
+		if !genesis.ValidateHash() {
+			t.Error("Genesis hash is invalid")
+		}
+	})
+
+	t.Run("version is covered by hash", func(t *testing.T) {
+		block := headerTestBlock(HeaderVersionBinary)
+		block.Hash = block.CalculateHash()
+
+		block.Version = HeaderVersionJSON
+		if block.ValidateHash() {
+			t.Error("ValidateHash() = true after version change")
+		}
+	})
+
+	t.Run("binary header requires merkle root", func(t *testing.T) {
+		// Поля депозита вне двоичного заголовка меняются без смены
+		// хеша, если корня нет
+		for _, version := range []int{HeaderVersionBinary, HeaderVersionAlgorithms} {
+			block := NewBlock("000-000-001", GenesisBlock().Hash, CreateTestBlock("Author", "Title", "text"))
+			block.Version, block.MerkleRoot = version, ""
+			if version < HeaderVersionAlgorithms {
+				block.HashAlg = ""
+			}
+			block.Hash = block.CalculateHash()
+			block.Data.ParentID = "000-000-000"
+			if !block.ValidateHash() {
+				t.Fatalf("Version %d hash should not cover parent_id", version)
+			}
+			if block.ValidateMerkleRoot() {
+				t.Errorf("ValidateMerkleRoot() = true for version %d block without root", version)
+			}
+
+			height, err := firstInvalidBlock([]*Block{GenesisBlock(), block})
+			AssertEqual(t, height, 1, "First invalid block")
+			if !errors.Is(err, ErrMerkleRootMismatch) {
+				t.Errorf("firstInvalidBlock() error = %v, want ErrMerkleRootMismatch", err)
+			}
+		}
+	})
+
+	t.Run("versions survive JSON round trip", func(t *testing.T) {
+		for _, version := range []int{HeaderVersionJSON, HeaderVersionBinary} {
+			block := headerTestBlock(version)
+			block.Hash = block.CalculateHash()
+
+			raw, err := json.Marshal(block)
+			AssertNoError(t, err)
+			var loaded Block
+			AssertNoError(t, json.Unmarshal(raw, &loaded))
+
+			AssertEqual(t, loaded.Version, version, "Version")
+			if !loaded.ValidateHash() {
+				t.Errorf("Hash of version %d block is invalid after round trip", version)
+			}
+		}
+	})
+}
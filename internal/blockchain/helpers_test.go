@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -29,7 +30,7 @@ func max(a, b int) int {
 // AssertEqual проверяет равенство двух значений
 func AssertEqual(t *testing.T, got, want interface{}, msgAndArgs ...interface{}) {
 	t.Helper()
-	if got != want {
+	if !equal(got, want) {
 		t.Errorf("got %v, want %v", got, want)
 		if len(msgAndArgs) > 0 {
 			t.Errorf("Context: %v", msgAndArgs...)
@@ -37,10 +38,19 @@ func AssertEqual(t *testing.T, got, want interface{}, msgAndArgs ...interface{})
 	}
 }
 
+// equal сравнивает значения через ==, а несравнимые (например,
+// DepositData со списком соавторов) - через reflect.DeepEqual
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil || reflect.TypeOf(a).Comparable() && reflect.TypeOf(b).Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // AssertNotEqual проверяет неравенство двух значений
 func AssertNotEqual(t *testing.T, got, notWant interface{}, msgAndArgs ...interface{}) {
 	t.Helper()
-	if got == notWant {
+	if equal(got, notWant) {
 		t.Errorf("got %v, did not want %v", got, notWant)
 		if len(msgAndArgs) > 0 {
 			t.Errorf("Context: %v", msgAndArgs...)
@@ -0,0 +1,381 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	jobsFileName    = "jobs.json"
+	jobsLogFileName = "jobs.log"
+
+	// jobsCompactMin - с какого числа записей журнал jobs.log
+	// сворачивается в jobs.json
+	jobsCompactMin = 1024
+
+	// jobRetention - сколько завершённое задание доступно для опроса
+	jobRetention = 24 * time.Hour
+)
+
+// JobStatus - состояние задания на депонирование
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"    // ждёт очереди майнинга
+	JobMining    JobStatus = "mining"    // блок майнится
+	JobCommitted JobStatus = "committed" // депозит записан в цепочку
+	JobFailed    JobStatus = "failed"    // депозит не записан
+)
+
+// Finished сообщает, что задание больше не изменится
+func (s JobStatus) Finished() bool {
+	return s == JobCommitted || s == JobFailed
+}
+
+// Job - асинхронное задание на депонирование
+type Job struct {
+	ID        string      `json:"id"`
+	Status    JobStatus   `json:"status"`
+	Deposit   DepositData `json:"deposit"`
+	BlockID   string      `json:"block_id,omitempty"`
+	Ref       string      `json:"ref,omitempty"`       // ссылка на депозит, см. DepositRef
+	Duplicate bool        `json:"duplicate,omitempty"` // текст уже был в цепочке
+	Error     string      `json:"error,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// jobsFile - формат jobs.json
+type jobsFile struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// JobManager принимает депозиты без ожидания майнинга и хранит
+// состояние заданий в каталоге данных. Незавершённые задания после
+// перезапуска ставятся в очередь заново.
+//
+// Каждое изменение задания дописывается строкой в журнал jobs.log.
+// Когда записей в журнале становится больше, чем заданий (и не меньше
+// jobsCompactMin), а также при открытии журнал сворачивается в снимок
+// jobs.json. Состояние задания - последняя его запись в журнале, а без
+// неё - запись в снимке
+type JobManager struct {
+	bc      *Blockchain
+	batcher *Batcher // nil - каждый депозит майнится своим блоком
+	fsys    FS
+	path    string
+	logPath string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	jobs       map[string]*Job
+	logRecords int // записей в jobs.log после последнего сворачивания
+}
+
+// NewJobManager загружает задания из dataDir и возобновляет
+// незавершённые. batcher может быть nil. Отмена ctx или Close
+// останавливают задания, не завершая их: они продолжатся после
+// перезапуска
+func NewJobManager(ctx context.Context, bc *Blockchain, batcher *Batcher, dataDir string) (*JobManager, error) {
+	return newJobManager(ctx, bc, batcher, OSFS{}, dataDir)
+}
+
+func newJobManager(ctx context.Context, bc *Blockchain, batcher *Batcher, fsys FS, dataDir string) (*JobManager, error) {
+	m := &JobManager{
+		bc:      bc,
+		batcher: batcher,
+		fsys:    fsys,
+		path:    filepath.Join(dataDir, jobsFileName),
+		logPath: filepath.Join(dataDir, jobsLogFileName),
+	}
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	jobs, err := loadJobs(fsys, m.path, m.logPath)
+	if err != nil {
+		return nil, err
+	}
+	m.jobs = jobs
+
+	// Задания, прерванные остановкой, начинают заново: майнинг
+	// не переживает перезапуск
+	var resume []*Job
+	for _, job := range m.jobs {
+		if !job.Status.Finished() {
+			job.Status = JobQueued
+			resume = append(resume, job)
+		}
+	}
+	sort.Slice(resume, func(i, j int) bool { return resume[i].CreatedAt.Before(resume[j].CreatedAt) })
+
+	m.mu.Lock()
+	err = m.compactLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range resume {
+		m.start(job.ID, job.Deposit, true)
+	}
+	if len(resume) > 0 {
+		slog.Info("Resumed pending deposit jobs", "jobs", len(resume))
+	}
+	return m, nil
+}
+
+// Submit создаёт задание на депозит и сразу возвращает его.
+// Задание сохранено на диск к моменту возврата
+func (m *JobManager) Submit(data DepositData) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &Job{ID: id, Status: JobQueued, Deposit: data, CreatedAt: now, UpdatedAt: now}
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	if err := m.appendLocked(job); err != nil {
+		delete(m.jobs, id)
+		m.mu.Unlock()
+		return nil, err
+	}
+	snapshot := *job
+	m.mu.Unlock()
+
+	m.start(id, data, false)
+	return &snapshot, nil
+}
+
+// Get возвращает копию задания
+func (m *JobManager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// Close останавливает выполняемые задания и дожидается их горутин.
+// Незавершённые задания остаются на диске
+func (m *JobManager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// start запускает выполнение задания. resumed - задание возобновлено
+// после перезапуска
+func (m *JobManager) start(id string, data DepositData, resumed bool) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run(id, data, resumed)
+	}()
+}
+
+// run выполняет задание и записывает его итог
+func (m *JobManager) run(id string, data DepositData, resumed bool) {
+	// Текст уже в цепочке - задание завершается сразу. Возобновлённое
+	// задание могло успеть записать депозит до остановки (например,
+	// пакет запечатан при закрытии Batcher), дубликатом оно не считается
+	if receipt, err := m.bc.FindDeposit(data.ContentHash); err == nil {
+		m.finish(id, receipt, !resumed, nil)
+		return
+	}
+
+	var receipt *DepositReceipt
+	var err error
+	if m.batcher != nil {
+		// Пакет майнится целиком, задание ждёт его в состоянии queued
+		receipt, err = m.batcher.Submit(m.ctx, data)
+	} else {
+		pending := m.bc.SubmitBlock(m.ctx, []DepositData{data})
+		select {
+		case <-pending.Started():
+			m.update(id, func(job *Job) { job.Status = JobMining })
+		case <-pending.Done():
+		}
+
+		_, err = pending.Wait(m.ctx)
+		var dup *DuplicateBlockError
+		if err == nil || errors.As(err, &dup) {
+			receipt, err = m.bc.FindDeposit(data.ContentHash)
+		}
+	}
+
+	if err != nil && m.ctx.Err() != nil {
+		// Остановка сервера: задание продолжится после перезапуска
+		return
+	}
+	m.finish(id, receipt, false, err)
+}
+
+// finish записывает итог задания
+func (m *JobManager) finish(id string, receipt *DepositReceipt, duplicate bool, err error) {
+	m.update(id, func(job *Job) {
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobCommitted
+		job.BlockID = receipt.Block.ID
+		job.Ref = receipt.Ref
+		job.Duplicate = duplicate
+	})
+}
+
+// update меняет задание и дописывает его в журнал
+func (m *JobManager) update(id string, change func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+	change(job)
+	job.UpdatedAt = time.Now()
+
+	if err := m.appendLocked(job); err != nil {
+		slog.Error("Failed to save deposit jobs", "job", id, "error", err)
+	}
+}
+
+// loadJobs читает снимок jobs.json и применяет к нему журнал jobs.log.
+// Неполная последняя строка журнала (сбой посреди записи) отбрасывается
+func loadJobs(fsys FS, path, logPath string) (map[string]*Job, error) {
+	jobs := make(map[string]*Job)
+
+	data, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err == nil {
+		var file jobsFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for _, job := range file.Jobs {
+			jobs[job.ID] = job
+		}
+	}
+
+	data, err = fsys.ReadFile(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", logPath, err)
+	}
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		var job Job
+		if end < 0 || json.Unmarshal(data[:end], &job) != nil || job.ID == "" {
+			slog.Warn("Discarding torn deposit job log tail", "path", logPath, "bytes", len(data))
+			break
+		}
+		jobs[job.ID] = &job
+		data = data[end+1:]
+	}
+	return jobs, nil
+}
+
+// appendLocked дописывает состояние задания в jobs.log и фиксирует
+// запись на диске, а разросшийся журнал сворачивает в jobs.json.
+// Вызывается под m.mu
+func (m *JobManager) appendLocked(job *Job) error {
+	line, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	f, err := m.fsys.OpenFile(m.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", m.logPath, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", m.logPath, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		// Обрывок строки отрезается, иначе следующие записи окажутся
+		// за ним и при загрузке будут отброшены вместе с хвостом
+		m.fsys.Truncate(m.logPath, info.Size())
+		return fmt.Errorf("failed to write %s: %w", m.logPath, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", m.logPath, err)
+	}
+	m.logRecords++
+
+	if m.logRecords >= max(jobsCompactMin, len(m.jobs)) {
+		// Запись уже на диске: неудачное сворачивание повторится позже
+		if err := m.compactLocked(); err != nil {
+			slog.Warn("Failed to compact deposit jobs", "error", err)
+		}
+	}
+	return nil
+}
+
+// compactLocked атомарно перезаписывает jobs.json всеми заданиями,
+// отбрасывая завершённые старше jobRetention, и удаляет журнал.
+// Сбой между записью снимка и удалением журнала безопасен: журнал
+// применяется к снимку повторно. Вызывается под m.mu
+func (m *JobManager) compactLocked() error {
+	cutoff := time.Now().Add(-jobRetention)
+	file := jobsFile{Jobs: make([]*Job, 0, len(m.jobs))}
+	for id, job := range m.jobs {
+		if job.Status.Finished() && job.UpdatedAt.Before(cutoff) {
+			delete(m.jobs, id)
+			continue
+		}
+		file.Jobs = append(file.Jobs, job)
+	}
+	sort.Slice(file.Jobs, func(i, j int) bool { return file.Jobs[i].CreatedAt.Before(file.Jobs[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := writeFileSync(m.fsys, tmp, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.path, err)
+	}
+	if err := m.fsys.Rename(tmp, m.path); err != nil {
+		return err
+	}
+	if err := m.fsys.Remove(m.logPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", m.logPath, err)
+	}
+	m.logRecords = 0
+	return nil
+}
+
+// newJobID генерирует случайный ID задания
+func newJobID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
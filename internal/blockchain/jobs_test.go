@@ -0,0 +1,244 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitJob ждёт, пока задание перейдёт в состояние status
+func waitJob(t *testing.T, m *JobManager, id string, status JobStatus) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(id)
+		AssertNoError(t, err)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %s status = %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readJobs читает задания каталога dataDir: снимок и журнал
+func readJobs(t *testing.T, fsys FS, dataDir string) map[string]*Job {
+	t.Helper()
+
+	jobs, err := loadJobs(fsys, filepath.Join(dataDir, jobsFileName), filepath.Join(dataDir, jobsLogFileName))
+	AssertNoError(t, err)
+	return jobs
+}
+
+func TestJobManager_Submit(t *testing.T) {
+	dataDir := t.TempDir()
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	m, err := NewJobManager(context.Background(), bc, nil, dataDir)
+	AssertNoError(t, err)
+	defer m.Close()
+
+	data := testDeposits(1)[0]
+	job, err := m.Submit(data)
+	AssertNoError(t, err)
+	AssertEqual(t, job.Status, JobQueued, "Status of new job")
+
+	// Задание на диске ещё до ответа клиенту
+	jobs := readJobs(t, OSFS{}, dataDir)
+	AssertEqual(t, len(jobs), 1, "Persisted jobs")
+	if _, ok := jobs[job.ID]; !ok {
+		t.Errorf("Job %s is not persisted", job.ID)
+	}
+
+	done := waitJob(t, m, job.ID, JobCommitted)
+	block := bc.GetLastBlock()
+	AssertEqual(t, done.BlockID, block.ID, "Job block")
+	AssertEqual(t, done.Ref, block.ID, "Job ref")
+	AssertEqual(t, done.Duplicate, false, "Duplicate")
+
+	t.Run("duplicate", func(t *testing.T) {
+		job, err := m.Submit(data)
+		AssertNoError(t, err)
+		done := waitJob(t, m, job.ID, JobCommitted)
+		AssertEqual(t, done.Duplicate, true, "Duplicate")
+		AssertEqual(t, done.BlockID, block.ID, "Existing block")
+		AssertEqual(t, len(bc.Chain), 2, "No new block for duplicate")
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		if _, err := m.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("Get() error = %v, want ErrJobNotFound", err)
+		}
+	})
+}
+
+func TestJobManager_Batcher(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	batcher := NewBatcher(context.Background(), bc, BatchOptions{MaxSize: 2, MaxWait: time.Hour})
+	defer batcher.Close()
+	m, err := NewJobManager(context.Background(), bc, batcher, t.TempDir())
+	AssertNoError(t, err)
+	defer m.Close()
+
+	deposits := testDeposits(2)
+	first, err := m.Submit(deposits[0])
+	AssertNoError(t, err)
+	second, err := m.Submit(deposits[1])
+	AssertNoError(t, err)
+
+	a := waitJob(t, m, first.ID, JobCommitted)
+	b := waitJob(t, m, second.ID, JobCommitted)
+	AssertEqual(t, a.BlockID, b.BlockID, "Both deposits in one block")
+	AssertNotEqual(t, a.Ref, b.Ref, "Deposit refs")
+}
+
+func TestJobManager_ResumesAfterRestart(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// 64 нуля недостижимы: задание зависает в майнинге до остановки
+	stuck := NewBlockchainWithStorage(NewTestStorage(), 64)
+	m, err := NewJobManager(context.Background(), stuck, nil, dataDir)
+	AssertNoError(t, err)
+	job, err := m.Submit(testDeposits(1)[0])
+	AssertNoError(t, err)
+	waitJob(t, m, job.ID, JobMining)
+	m.Close()
+
+	jobs := readJobs(t, OSFS{}, dataDir)
+	AssertEqual(t, jobs[job.ID].Status, JobMining, "Status after shutdown")
+
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	m, err = NewJobManager(context.Background(), bc, nil, dataDir)
+	AssertNoError(t, err)
+	defer m.Close()
+
+	done := waitJob(t, m, job.ID, JobCommitted)
+	AssertEqual(t, done.BlockID, bc.GetLastBlock().ID, "Resumed job block")
+}
+
+func TestJobManager_DropsExpiredJobs(t *testing.T) {
+	dataDir := t.TempDir()
+	old := time.Now().Add(-2 * jobRetention)
+	file := jobsFile{Jobs: []*Job{
+		{ID: "expired", Status: JobCommitted, CreatedAt: old, UpdatedAt: old},
+		{ID: "recent", Status: JobFailed, Error: "boom", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}}
+	data, _ := json.Marshal(file)
+	AssertNoError(t, os.WriteFile(filepath.Join(dataDir, jobsFileName), data, 0644))
+
+	m, err := NewJobManager(context.Background(), NewBlockchainWithStorage(NewTestStorage(), 1), nil, dataDir)
+	AssertNoError(t, err)
+	defer m.Close()
+
+	if _, err := m.Get("expired"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expired job should be dropped, Get() error = %v", err)
+	}
+	job, err := m.Get("recent")
+	AssertNoError(t, err)
+	AssertEqual(t, job.Error, "boom", "Recent job error")
+}
+
+func TestJobManager_ResumedJobAlreadyCommitted(t *testing.T) {
+	dataDir := t.TempDir()
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	data := testDeposits(1)[0]
+	_, err := bc.AddBlock(data)
+	AssertNoError(t, err)
+
+	// Депозит записан при остановке, а статус задания - нет
+	file := jobsFile{Jobs: []*Job{{ID: "queued", Status: JobQueued, Deposit: data, CreatedAt: time.Now()}}}
+	raw, _ := json.Marshal(file)
+	AssertNoError(t, os.WriteFile(filepath.Join(dataDir, jobsFileName), raw, 0644))
+
+	m, err := NewJobManager(context.Background(), bc, nil, dataDir)
+	AssertNoError(t, err)
+	defer m.Close()
+
+	job := waitJob(t, m, "queued", JobCommitted)
+	AssertEqual(t, job.Duplicate, false, "Resumed job is not a duplicate")
+	AssertEqual(t, job.BlockID, bc.GetLastBlock().ID, "Job block")
+}
+
+func TestJobManager_JobLog(t *testing.T) {
+	fsys := NewMemFS()
+	snapshot := func() []*Job {
+		data, err := fsys.ReadFile("/" + jobsFileName)
+		AssertNoError(t, err, "jobs.json should exist")
+		var file jobsFile
+		AssertNoError(t, json.Unmarshal(data, &file))
+		return file.Jobs
+	}
+
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	m, err := newJobManager(context.Background(), bc, nil, fsys, "/")
+	AssertNoError(t, err)
+	job, err := m.Submit(testDeposits(1)[0])
+	AssertNoError(t, err)
+	waitJob(t, m, job.ID, JobCommitted)
+	m.Close()
+
+	// Изменения дописываются в журнал, снимок не переписывается
+	AssertEqual(t, len(snapshot()), 0, "Jobs in snapshot")
+	AssertEqual(t, readJobs(t, fsys, "/")[job.ID].Status, JobCommitted, "Status from log")
+
+	t.Run("failed write leaves no torn line", func(t *testing.T) {
+		m, err := newJobManager(context.Background(), bc, nil, fsys, "/")
+		AssertNoError(t, err)
+		defer m.Close()
+
+		fsys.InjectFault(Fault{Op: FSOpWrite, Path: jobsLogFileName, ShortWrite: 10})
+		_, err = m.Submit(testDeposits(2)[1])
+		AssertError(t, err)
+
+		next, err := m.Submit(testDeposits(3)[2])
+		AssertNoError(t, err)
+		if _, ok := readJobs(t, fsys, "/")[next.ID]; !ok {
+			t.Errorf("Job %s written after a failed write is lost", next.ID)
+		}
+		waitJob(t, m, next.ID, JobCommitted)
+	})
+
+	t.Run("torn tail is discarded on open", func(t *testing.T) {
+		f, err := fsys.OpenFile("/"+jobsLogFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		AssertNoError(t, err)
+		_, err = f.Write([]byte(`{"id":"torn","sta`))
+		AssertNoError(t, err)
+		f.Close()
+
+		m, err := newJobManager(context.Background(), bc, nil, fsys, "/")
+		AssertNoError(t, err)
+		defer m.Close()
+
+		got, err := m.Get(job.ID)
+		AssertNoError(t, err)
+		AssertEqual(t, got.Status, JobCommitted, "Status after reopen")
+
+		// Открытие сворачивает журнал в снимок
+		AssertEqual(t, len(snapshot()), 2, "Jobs in snapshot")
+		if _, err := fsys.Stat("/" + jobsLogFileName); !os.IsNotExist(err) {
+			t.Errorf("Job log should be removed after compaction: %v", err)
+		}
+	})
+
+	t.Run("log is compacted", func(t *testing.T) {
+		m, err := newJobManager(context.Background(), bc, nil, fsys, "/")
+		AssertNoError(t, err)
+		defer m.Close()
+
+		for i := 0; i < jobsCompactMin-1; i++ {
+			m.update(job.ID, func(*Job) {})
+		}
+		AssertEqual(t, m.logRecords, jobsCompactMin-1, "Log records before compaction")
+		m.update(job.ID, func(*Job) {})
+		AssertEqual(t, m.logRecords, 0, "Log records after compaction")
+		if _, err := fsys.Stat("/" + jobsLogFileName); !os.IsNotExist(err) {
+			t.Errorf("Job log should be removed after compaction: %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import "strings"
+
+// depositPos - место депозита в цепочке: блок и номер депозита в нём
+type depositPos struct {
+	block *Block
+	index int
+}
+
+// data возвращает депозит
+func (p depositPos) data() DepositData {
+	return p.block.DepositList()[p.index]
+}
+
+// ref возвращает ссылку на депозит, см. DepositRef
+func (p depositPos) ref() string {
+	return DepositRef(p.block, p.index)
+}
+
+// rebuildKeyIndex перестраивает индекс отпечаток ключа -> подписанные
+// депозиты. Подписи проверяются при записи и загрузке цепочки, здесь
+// ключ только разбирается ради отпечатка
+func (bc *Blockchain) rebuildKeyIndex() {
+	bc.keyIndex = make(map[string][]depositPos)
+	for _, block := range bc.Chain {
+		if block != nil {
+			bc.indexKeys(block)
+		}
+	}
+	bc.keys = keyRegistryOf(bc.Chain)
+}
+
+// keyRegistryOf собирает ротации и отзывы ключей цепочки. Блоки, записи
+// которых не проходят проверку, пропускаются: загруженную цепочку
+// проверяет firstInvalidBlock
+func keyRegistryOf(chain []*Block) *keyRegistry {
+	keys := newKeyRegistry()
+	for _, block := range chain {
+		if block != nil {
+			keys, _ = keys.apply(block)
+		}
+	}
+	return keys
+}
+
+// KeyStatus возвращает состояние ключа с отпечатком fingerprint:
+// ротации к следующим ключам и отзыв
+func (bc *Blockchain) KeyStatus(fingerprint string) KeyStatus {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.keys.status(strings.ToLower(fingerprint))
+}
+
+// CheckKeys проверяет подписанный депозит или служебную запись по
+// записанным ротациям и отзывам ключей, не добавляя в цепочку. Нужна,
+// чтобы отвергнуть запись до майнинга
+func (bc *Blockchain) CheckKeys(data DepositData) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.keys.check(data)
+}
+
+// indexKeys добавляет подписанные депозиты блока в индекс ключей.
+// Работа с соавторами попадает в индекс каждого подписавшего
+func (bc *Blockchain) indexKeys(block *Block) {
+	for i, data := range block.DepositList() {
+		if data.IsRecord() {
+			continue
+		}
+		for _, publicKey := range data.SignerKeys() {
+			key, err := ParseAuthorKey(publicKey)
+			if err != nil {
+				continue
+			}
+			bc.keyIndex[key.Fingerprint] = append(bc.keyIndex[key.Fingerprint], depositPos{block: block, index: i})
+		}
+	}
+}
+
+// unindexKeys убирает из индекса ключей депозиты последнего блока
+func (bc *Blockchain) unindexKeys(block *Block) {
+	for fingerprint, deposits := range bc.keyIndex {
+		n := len(deposits)
+		for n > 0 && deposits[n-1].block == block {
+			n--
+		}
+		switch {
+		case n == 0:
+			delete(bc.keyIndex, fingerprint)
+		case n < len(deposits):
+			bc.keyIndex[fingerprint] = deposits[:n]
+		}
+	}
+}
+
+// DepositsByKey возвращает депозиты, подписанные ключом с отпечатком
+// fingerprint (см. AuthorKey), в порядке цепочки. Для ключа без
+// депозитов возвращает пустой список
+func (bc *Blockchain) DepositsByKey(fingerprint string) ([]*DepositReceipt, error) {
+	bc.mu.RLock()
+	deposits := bc.keyIndex[strings.ToLower(fingerprint)]
+	deposits = deposits[:len(deposits):len(deposits)]
+	bc.mu.RUnlock()
+
+	receipts := make([]*DepositReceipt, 0, len(deposits))
+	for _, d := range deposits {
+		receipt, err := newDepositReceipt(d.block, d.index)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
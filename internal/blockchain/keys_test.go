@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestBlockchain_DepositsByKey(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+
+	_, alice, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+	_, bob, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+
+	first := signedDeposit(t, alice, "First")
+	key, err := ParseAuthorKey(first.PublicKey)
+	AssertNoError(t, err)
+
+	_, err = bc.AddBlock(first)
+	AssertNoError(t, err)
+	_, err = bc.AddBlock(signedDeposit(t, bob, "Other author"))
+	AssertNoError(t, err)
+	_, err = bc.AddBatch([]DepositData{
+		CreateTestBlock("Unsigned", "Unsigned", "unsigned text"),
+		signedDeposit(t, alice, "Second"),
+	})
+	AssertNoError(t, err)
+
+	check := func(bc *Blockchain) {
+		t.Helper()
+		receipts, err := bc.DepositsByKey(strings.ToUpper(key.Fingerprint))
+		AssertNoError(t, err)
+		if len(receipts) != 2 {
+			t.Fatalf("DepositsByKey() returned %d deposits, want 2", len(receipts))
+		}
+		AssertEqual(t, receipts[0].Data.Title, "First", "First deposit")
+		AssertEqual(t, receipts[1].Data.Title, "Second", "Second deposit")
+		if receipts[1].Proof == nil || !receipts[1].Proof.Verify(receipts[1].Data) {
+			t.Error("Proof of batched deposit does not verify")
+		}
+	}
+
+	check(bc)
+
+	// Индекс перестраивается при загрузке цепочки
+	reloaded, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	check(reloaded)
+
+	receipts, err := bc.DepositsByKey(strings.Repeat("0", 64))
+	AssertNoError(t, err)
+	AssertEqual(t, len(receipts), 0, "Unknown key")
+}
+
+func TestBlockchain_UnindexKeys(t *testing.T) {
+	bc, err := NewBlockchain(NewTestStorage(), 1)
+	AssertNoError(t, err)
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+
+	data := signedDeposit(t, priv, "Rolled back")
+	key, err := ParseAuthorKey(data.PublicKey)
+	AssertNoError(t, err)
+
+	block, err := bc.AddBlock(data)
+	AssertNoError(t, err)
+	bc.removeLastBlock(block)
+
+	receipts, err := bc.DepositsByKey(key.Fingerprint)
+	AssertNoError(t, err)
+	AssertEqual(t, len(receipts), 0, "Deposits after rollback")
+}
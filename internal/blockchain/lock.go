@@ -0,0 +1,80 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockFileName - файл блокировки каталога данных
+const LockFileName = "LOCK"
+
+var (
+	// ErrDataDirLocked - каталог данных занят другим процессом
+	ErrDataDirLocked = errors.New("data directory is locked")
+
+	// errLockHeld возвращается FS.Lock, если блокировку держит другой
+	errLockHeld = errors.New("lock is held by another process")
+)
+
+// LockInfo - кто держит блокировку каталога данных
+type LockInfo struct {
+	PID   int       `json:"pid"`
+	Host  string    `json:"host"`
+	Since time.Time `json:"since"`
+}
+
+// DataDirLockedError - каталог данных уже открыт другим процессом
+type DataDirLockedError struct {
+	Dir    string
+	Holder *LockInfo // nil, если файл блокировки не читается
+}
+
+func (e *DataDirLockedError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("data directory %s is locked by another process", e.Dir)
+	}
+	return fmt.Sprintf("data directory %s is locked by pid %d on %s since %s",
+		e.Dir, e.Holder.PID, e.Holder.Host, e.Holder.Since.Format(time.RFC3339))
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrDataDirLocked)
+func (e *DataDirLockedError) Unwrap() error {
+	return ErrDataDirLocked
+}
+
+// lockDataDir берёт эксклюзивную блокировку каталога dir и записывает
+// в файл блокировки PID и имя хоста. Блокировка снимается закрытием
+// возвращённого файла или завершением процесса
+func lockDataDir(fsys FS, dir string) (File, error) {
+	path := filepath.Join(dir, LockFileName)
+
+	f, err := fsys.Lock(path)
+	if errors.Is(err, errLockHeld) {
+		lockErr := &DataDirLockedError{Dir: dir}
+		if data, err := fsys.ReadFile(path); err == nil {
+			var info LockInfo
+			if json.Unmarshal(data, &info) == nil {
+				lockErr.Holder = &info
+			}
+		}
+		return nil, lockErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock data directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(LockInfo{PID: os.Getpid(), Host: host, Since: time.Now().UTC()})
+
+	// Файл блокировки информационный: ошибка записи не отменяет блокировку
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt(data, 0)
+		f.Sync()
+	}
+
+	return f, nil
+}
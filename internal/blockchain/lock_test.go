@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStorage_LocksDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+
+	_, err = NewStorage(dataDir)
+	if !errors.Is(err, ErrDataDirLocked) {
+		t.Fatalf("Second NewStorage() error = %v, want ErrDataDirLocked", err)
+	}
+
+	var lockErr *DataDirLockedError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("Error type = %T, want *DataDirLockedError", err)
+	}
+	AssertNotNil(t, lockErr.Holder, "Lock holder")
+	AssertEqual(t, lockErr.Holder.PID, os.Getpid(), "Lock holder PID")
+
+	// После Close каталог снова можно открыть
+	AssertNoError(t, storage.Close())
+	storage, err = NewStorage(dataDir)
+	AssertNoError(t, err, "NewStorage after Close")
+	storage.Close()
+}
+
+func TestNewStorage_ReadOnly(t *testing.T) {
+	dataDir := t.TempDir()
+
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	defer storage.Close()
+
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	_, err = bc.AddBlock(CreateTestBlock("Author", "Title", "Locked text"))
+	AssertNoError(t, err)
+
+	// Каталог занят, но чтение разрешено
+	reader, err := NewStorageWithOptions(dataDir, StoreOptions{ReadOnly: true})
+	AssertNoError(t, err, "Read-only open of a locked directory")
+	defer reader.Close()
+
+	loaded, err := reader.LoadChain()
+	AssertNoError(t, err)
+	AssertEqual(t, len(loaded.Chain), 2, "Blocks visible to read-only store")
+
+	err = reader.SaveBlock(GenesisBlock())
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SaveBlock() error = %v, want ErrReadOnly", err)
+	}
+	if err := reader.WriteToWAL(GenesisBlock()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteToWAL() error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestSQLiteStore_LocksDataDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blockchain.db")
+
+	store, err := NewSQLiteStore(path)
+	AssertNoError(t, err)
+	defer store.Close()
+
+	_, err = NewSQLiteStore(path)
+	if !errors.Is(err, ErrDataDirLocked) {
+		t.Fatalf("Second NewSQLiteStore() error = %v, want ErrDataDirLocked", err)
+	}
+
+	reader, err := NewSQLiteStoreWithOptions(path, StoreOptions{ReadOnly: true})
+	AssertNoError(t, err, "Read-only open of a locked database")
+	defer reader.Close()
+
+	if err := reader.SaveBlock(GenesisBlock()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SaveBlock() error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestMemFS_LockReleasedOnRestart(t *testing.T) {
+	fsys := NewMemFS()
+
+	_, err := NewStorageWithOptions(crashDataDir, StoreOptions{FS: fsys})
+	AssertNoError(t, err)
+
+	_, err = NewStorageWithOptions(crashDataDir, StoreOptions{FS: fsys})
+	if !errors.Is(err, ErrDataDirLocked) {
+		t.Fatalf("Second open error = %v, want ErrDataDirLocked", err)
+	}
+
+	// Упавший процесс не держит блокировку
+	fsys.Crash()
+	fsys.Restart()
+
+	storage, err := NewStorageWithOptions(crashDataDir, StoreOptions{FS: fsys})
+	AssertNoError(t, err, "Open after restart")
+	storage.Close()
+}
@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Префиксы разделяют хеши листьев и узлов дерева: без них внутренний
+// узел можно было бы выдать за лист (second preimage)
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// ProofStep - один шаг доказательства включения: хеш соседнего узла
+// и его сторона
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"` // сосед слева от текущего узла
+}
+
+// MerkleProof доказывает, что депозит с номером Index входит
+// в блок с корнем Root
+type MerkleProof struct {
+	Algorithm HashAlgorithm `json:"algorithm,omitempty"` // алгоритм дерева, пустой - SHA-256
+	Index     int           `json:"index"`
+	Leaf      string        `json:"leaf"`
+	Siblings  []ProofStep   `json:"siblings"`
+	Root      string        `json:"root"`
+}
+
+// merkleLeaf вычисляет хеш листа для депозита
+func merkleLeaf(alg HashAlgorithm, data DepositData) []byte {
+	// DepositData состоит из строк, Marshal не может вернуть ошибку
+	raw, _ := json.Marshal(data)
+	h := alg.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(raw)
+	return h.Sum(nil)
+}
+
+// merkleNode вычисляет хеш узла по двум дочерним
+func merkleNode(alg HashAlgorithm, left, right []byte) []byte {
+	h := alg.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels строит дерево снизу вверх: levels[0] - листья,
+// последний уровень - корень. Узел без пары поднимается на уровень
+// выше как есть, а не дублируется
+func merkleLevels(alg HashAlgorithm, deposits []DepositData) [][][]byte {
+	level := make([][]byte, len(deposits))
+	for i, data := range deposits {
+		level[i] = merkleLeaf(alg, data)
+	}
+
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(alg, level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// MerkleRoot вычисляет корень дерева Меркла депозитов на SHA-256.
+// Для пустого списка возвращает пустую строку
+func MerkleRoot(deposits []DepositData) string {
+	return merkleRoot(HashSHA256, deposits)
+}
+
+// merkleRoot - MerkleRoot на алгоритме alg
+func merkleRoot(alg HashAlgorithm, deposits []DepositData) string {
+	if len(deposits) == 0 || !alg.Supported() {
+		return ""
+	}
+	levels := merkleLevels(alg, deposits)
+	return hex.EncodeToString(levels[len(levels)-1][0])
+}
+
+// NewMerkleProof строит доказательство включения депозита с номером
+// index в дерево deposits на SHA-256
+func NewMerkleProof(deposits []DepositData, index int) (*MerkleProof, error) {
+	return newMerkleProof(HashSHA256, deposits, index)
+}
+
+// newMerkleProof - NewMerkleProof на алгоритме alg
+func newMerkleProof(alg HashAlgorithm, deposits []DepositData, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(deposits) {
+		return nil, fmt.Errorf("deposit index %d out of range [0, %d)", index, len(deposits))
+	}
+	if !alg.Supported() {
+		return nil, fmt.Errorf("unknown hash algorithm %q", alg)
+	}
+
+	levels := merkleLevels(alg, deposits)
+	proof := &MerkleProof{
+		Algorithm: alg.normalize(),
+		Index:     index,
+		Leaf:      hex.EncodeToString(levels[0][index]),
+		Siblings:  []ProofStep{},
+		Root:      hex.EncodeToString(levels[len(levels)-1][0]),
+	}
+
+	pos := index
+	for _, level := range levels[:len(levels)-1] {
+		sibling := pos ^ 1
+		if sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, ProofStep{
+				Hash: hex.EncodeToString(level[sibling]),
+				Left: sibling < pos,
+			})
+		}
+		pos /= 2
+	}
+	return proof, nil
+}
+
+// Verify проверяет, что data - лист доказательства и что путь
+// от него приводит к Root. Неизвестный алгоритм - false
+func (p *MerkleProof) Verify(data DepositData) bool {
+	if !p.Algorithm.Supported() {
+		return false
+	}
+	node := merkleLeaf(p.Algorithm, data)
+	if hex.EncodeToString(node) != p.Leaf {
+		return false
+	}
+
+	for _, step := range p.Siblings {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		if step.Left {
+			node = merkleNode(p.Algorithm, sibling, node)
+		} else {
+			node = merkleNode(p.Algorithm, node, sibling)
+		}
+	}
+	return hex.EncodeToString(node) == p.Root
+}
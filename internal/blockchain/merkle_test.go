@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testDeposits возвращает n депозитов с разными хешами содержимого
+func testDeposits(n int) []DepositData {
+	deposits := make([]DepositData, n)
+	for i := range deposits {
+		deposits[i] = CreateTestBlock("Author", fmt.Sprintf("Title %d", i), fmt.Sprintf("text %d", i))
+	}
+	return deposits
+}
+
+func TestMerkleRoot(t *testing.T) {
+	t.Run("empty list has no root", func(t *testing.T) {
+		AssertEqual(t, MerkleRoot(nil), "", "Root of empty list")
+	})
+
+	t.Run("order matters", func(t *testing.T) {
+		deposits := testDeposits(2)
+		swapped := []DepositData{deposits[1], deposits[0]}
+		AssertNotEqual(t, MerkleRoot(deposits), MerkleRoot(swapped), "Root of swapped deposits")
+	})
+
+	t.Run("single leaf is not its own root", func(t *testing.T) {
+		// Лист и узел хешируются с разными префиксами
+		deposits := testDeposits(2)
+		node := MerkleRoot(deposits)
+		proof, err := NewMerkleProof(deposits, 0)
+		AssertNoError(t, err)
+		AssertNotEqual(t, proof.Leaf, node, "Leaf hash equals node hash")
+	})
+}
+
+func TestMerkleProof(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8, 13} {
+		deposits := testDeposits(n)
+		root := MerkleRoot(deposits)
+
+		for i := range deposits {
+			t.Run(fmt.Sprintf("%d of %d", i, n), func(t *testing.T) {
+				proof, err := NewMerkleProof(deposits, i)
+				AssertNoError(t, err)
+				AssertEqual(t, proof.Root, root, "Proof root")
+				if !proof.Verify(deposits[i]) {
+					t.Error("Proof should verify its own deposit")
+				}
+
+				other := deposits[(i+1)%n]
+				if n > 1 && proof.Verify(other) {
+					t.Error("Proof should not verify another deposit")
+				}
+			})
+		}
+	}
+
+	t.Run("tampered sibling", func(t *testing.T) {
+		deposits := testDeposits(4)
+		proof, err := NewMerkleProof(deposits, 2)
+		AssertNoError(t, err)
+
+		proof.Siblings[0].Hash = proof.Siblings[1].Hash
+		if proof.Verify(deposits[2]) {
+			t.Error("Proof with tampered sibling should not verify")
+		}
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		_, err := NewMerkleProof(testDeposits(2), 2)
+		AssertError(t, err)
+	})
+}
+
+func TestBlock_ValidateMerkleRoot(t *testing.T) {
+	t.Run("legacy block without root", func(t *testing.T) {
+		block := NewBlock("000-000-001", "prev", CreateTestBlock("Author", "Title", "text"))
+		if !block.ValidateMerkleRoot() {
+			t.Error("Block without deposits and root should be valid")
+		}
+	})
+
+	t.Run("batch block", func(t *testing.T) {
+		block := NewBatchBlock("000-000-001", "prev", testDeposits(3))
+		AssertEqual(t, len(block.DepositList()), 3, "Deposits in block")
+		if !block.ValidateMerkleRoot() {
+			t.Error("Batch block should have a valid root")
+		}
+
+		block.Deposits[1].Title = "forged"
+		if block.ValidateMerkleRoot() {
+			t.Error("Forged deposit should not match the root")
+		}
+	})
+
+	t.Run("single deposit stays in Data", func(t *testing.T) {
+		deposits := testDeposits(1)
+		block := NewBatchBlock("000-000-001", "prev", deposits)
+		AssertEqual(t, block.Data, deposits[0], "Data of single-deposit block")
+		AssertEqual(t, len(block.Deposits), 0, "Deposits of single-deposit block")
+		if !block.ValidateMerkleRoot() {
+			t.Error("Single-deposit block should have a valid root")
+		}
+	})
+
+	t.Run("deposits without root", func(t *testing.T) {
+		block := NewBatchBlock("000-000-001", "prev", testDeposits(2))
+		block.MerkleRoot = ""
+		if block.ValidateMerkleRoot() {
+			t.Error("Batch block without root should be invalid")
+		}
+	})
+
+	t.Run("root is part of the hash", func(t *testing.T) {
+		block := NewBatchBlock("000-000-001", "prev", testDeposits(2))
+		hash := block.CalculateHash()
+		block.MerkleRoot = MerkleRoot(testDeposits(3))
+		AssertNotEqual(t, block.CalculateHash(), hash, "Hash after root change")
+	})
+
+	t.Run("genesis hash is unchanged", func(t *testing.T) {
+		genesis := GenesisBlock()
+		AssertEqual(t, genesis.MerkleRoot, "", "Genesis root")
+		if !genesis.ValidateMerkleRoot() {
+			t.Error("Genesis should be valid")
+		}
+	})
+}
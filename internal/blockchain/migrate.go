@@ -0,0 +1,542 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Версии формата данных
+//
+// Версия формата описывает схему блока (Block, DepositData) во всех
+// местах, где блоки лежат на диске. Файловое хранилище записывает её в
+// format.json в каталоге данных, JSON-файлы цепочки (бэкапы, прежний
+// blockchain.json, отброшенные при восстановлении блоки) - в заголовке
+// файла, SQLite - в PRAGMA user_version.
+//
+// Версия 1 - всё, что записано до появления заголовка. Изменение схемы
+// блока добавляет в migrations шаг с версии N на N+1, который
+// преобразует JSON старого блока. Данные старых версий поднимаются до
+// текущей при открытии хранилища, исходные файлы сохраняются в
+// migrations/v<N>/ для отката.
+//
+// Версия поднимается при любом изменении того, как блоки хешируются и
+// проверяются, даже если старые блоки переписывать не нужно и новые
+// поля необязательны (omitempty). Прежняя сборка не знает новых правил:
+// она пересчитала бы хеш иначе или молча пропустила бы проверку, поэтому
+// такие данные должна отвергать с ErrUnsupportedFormat. Для этого в
+// migrations добавляется шаг без Block, меняющий только номер версии.
+const (
+	ChainFormat         = "textproof-chain"
+	formatFileName      = "format.json"
+	migrationsDirName   = "migrations"
+	legacyFormatVersion = 1
+)
+
+// Migration - шаг миграции формата с версии From на From+1
+type Migration struct {
+	From        int
+	Description string
+
+	// Block преобразует JSON одного блока; nil - блоки не меняются.
+	// Должна быть идемпотентной: после сбоя посреди миграции шаг
+	// повторяется над уже преобразованными блоками
+	Block func(block map[string]json.RawMessage) error
+}
+
+// migrations - реестр шагов миграции по возрастанию версии
+var migrations = []Migration{
+	{From: 1, Description: "add format version header, blocks unchanged"},
+	{From: 2, Description: "blocks may carry deposits and merkle_root, existing blocks unchanged"},
+	{From: 3, Description: "blocks carry their difficulty, existing blocks unchanged"},
+	{From: 4, Description: "blocks carry a 256-bit proof-of-work target, existing blocks unchanged"},
+	{From: 5, Description: "blocks carry a header version, new blocks hash a canonical binary header, existing blocks unchanged"},
+	{From: 6, Description: "blocks and deposits may carry a hash algorithm id, existing blocks unchanged"},
+	{From: 7, Description: "deposits may carry an author signature, existing blocks unchanged"},
+	{From: 8, Description: "blocks may carry key rotation and revocation records, existing blocks unchanged"},
+	{From: 9, Description: "deposits may reference the previous version of a work and carry an account id, existing blocks unchanged"},
+	{From: 10, Description: "deposits may list co-authors with their own signatures, existing blocks unchanged"},
+	{From: 11, Description: "blocks may carry retraction and correction records, signed or added by the deposit account, existing blocks unchanged"},
+}
+
+// CurrentFormatVersion возвращает версию формата, которую пишет эта
+// сборка: следующую за последним шагом миграции
+func CurrentFormatVersion() int {
+	return migrations[len(migrations)-1].From + 1
+}
+
+// ErrUnsupportedFormat - данные записаны более новой версией программы
+var ErrUnsupportedFormat = errors.New("unsupported data format version")
+
+// FormatVersionError сообщает о версии формата, которую нельзя прочитать
+type FormatVersionError struct {
+	Path    string
+	Version int
+}
+
+func (e *FormatVersionError) Error() string {
+	return fmt.Sprintf("%s has format version %d, this build supports up to %d",
+		e.Path, e.Version, CurrentFormatVersion())
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrUnsupportedFormat)
+func (e *FormatVersionError) Unwrap() error {
+	return ErrUnsupportedFormat
+}
+
+// FormatHeader - заголовок версии формата
+type FormatHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// chainFile - JSON-файл цепочки с заголовком формата
+type chainFile struct {
+	FormatHeader
+	Chain []json.RawMessage `json:"chain"`
+}
+
+// MigrationPlan описывает, что изменит миграция хранилища
+type MigrationPlan struct {
+	Path  string   `json:"path"` // каталог данных или файл базы
+	From  int      `json:"from"`
+	To    int      `json:"to"`
+	Steps []string `json:"steps"`
+
+	Blocks        int      `json:"blocks"`              // блоков в хранилище
+	ChangedBlocks int      `json:"changed_blocks"`      // блоков, чей JSON изменится
+	Rewrite       []string `json:"rewrite"`             // файлы, которые будут переписаны
+	Originals     string   `json:"originals,omitempty"` // куда будут сохранены исходные файлы
+}
+
+// Needed сообщает, отстаёт ли хранилище от текущей версии
+func (p *MigrationPlan) Needed() bool {
+	return p.From < p.To
+}
+
+// migrationSteps возвращает шаги с версии from до текущей
+func migrationSteps(from int) ([]Migration, error) {
+	if from > CurrentFormatVersion() {
+		return nil, ErrUnsupportedFormat
+	}
+
+	var steps []Migration
+	for _, m := range migrations {
+		if m.From >= from {
+			steps = append(steps, m)
+		}
+	}
+	return steps, nil
+}
+
+// newMigrationPlan заполняет версии и шаги плана
+func newMigrationPlan(path string, from int) (*MigrationPlan, []Migration, error) {
+	steps, err := migrationSteps(from)
+	if err != nil {
+		return nil, nil, &FormatVersionError{Path: path, Version: from}
+	}
+
+	plan := &MigrationPlan{Path: path, From: from, To: CurrentFormatVersion()}
+	for _, m := range steps {
+		plan.Steps = append(plan.Steps, fmt.Sprintf("v%d -> v%d: %s", m.From, m.From+1, m.Description))
+	}
+	return plan, steps, nil
+}
+
+// migrateBlock применяет шаги к JSON блока. Возвращает блок и признак
+// того, что его содержимое изменилось
+func migrateBlock(payload []byte, steps []Migration) (*Block, bool, error) {
+	changed := false
+	for _, m := range steps {
+		if m.Block == nil {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, false, fmt.Errorf("failed to parse block: %w", err)
+		}
+		before, _ := json.Marshal(fields)
+
+		if err := m.Block(fields); err != nil {
+			return nil, false, fmt.Errorf("migration v%d -> v%d: %w", m.From, m.From+1, err)
+		}
+		after, err := json.Marshal(fields)
+		if err != nil {
+			return nil, false, err
+		}
+
+		changed = changed || !bytes.Equal(before, after)
+		payload = after
+	}
+
+	var block Block
+	if err := json.Unmarshal(payload, &block); err != nil {
+		return nil, false, fmt.Errorf("failed to parse block: %w", err)
+	}
+	return &block, changed, nil
+}
+
+// migrateBlocks применяет шаги к каждому блоку. Возвращает блоки и
+// количество изменившихся
+func migrateBlocks(payloads [][]byte, steps []Migration) ([]*Block, int, error) {
+	blocks := make([]*Block, 0, len(payloads))
+	changed := 0
+	for height, payload := range payloads {
+		block, ok, err := migrateBlock(payload, steps)
+		if err != nil {
+			return nil, 0, fmt.Errorf("block at height %d: %w", height, err)
+		}
+		if ok {
+			changed++
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, changed, nil
+}
+
+// encodeChainFile кодирует цепочку в JSON-файл с заголовком текущей версии
+func encodeChainFile(blocks []*Block) ([]byte, error) {
+	return json.MarshalIndent(struct {
+		FormatHeader
+		Chain []*Block `json:"chain"`
+	}{
+		FormatHeader: FormatHeader{Format: ChainFormat, Version: CurrentFormatVersion()},
+		Chain:        blocks,
+	}, "", "  ")
+}
+
+// decodeChainFile разбирает JSON-файл цепочки любой поддерживаемой
+// версии и поднимает блоки до текущей. Файл без заголовка - версия 1
+func decodeChainFile(path string, data []byte) ([]*Block, error) {
+	var file chainFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	version := file.Version
+	if file.Format == "" {
+		version = legacyFormatVersion
+	} else if file.Format != ChainFormat {
+		return nil, fmt.Errorf("unknown chain file format %q", file.Format)
+	}
+
+	_, steps, err := newMigrationPlan(path, version)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([][]byte, len(file.Chain))
+	for i, raw := range file.Chain {
+		payloads[i] = raw
+	}
+	blocks, _, err := migrateBlocks(payloads, steps)
+	return blocks, err
+}
+
+// storageMigration - подготовленная миграция каталога данных
+type storageMigration struct {
+	plan  *MigrationPlan
+	steps []Migration
+	log   []*Block          // журнал после миграции; nil - журнал не меняется
+	wal   []byte            // WAL после миграции; nil - WAL не меняется
+	keep  map[string]string // файлы, сохраняемые в plan.Originals
+}
+
+// formatVersion возвращает версию формата каталога данных. Каталог
+// без format.json, где уже есть данные, - версия 1; пустой - текущая
+func (s *Storage) formatVersion() (int, error) {
+	data, err := s.fs.ReadFile(s.formatFile)
+	if err == nil {
+		var header FormatHeader
+		if err := json.Unmarshal(data, &header); err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", s.formatFile, err)
+		}
+		if header.Format != ChainFormat {
+			return 0, fmt.Errorf("%s: unknown format %q", s.formatFile, header.Format)
+		}
+		return header.Version, nil
+	}
+	if !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read %s: %w", s.formatFile, err)
+	}
+
+	if s.log != nil && s.log.Len() > 0 {
+		return legacyFormatVersion, nil
+	}
+	for _, path := range []string{s.chainFile, s.walFile} {
+		if _, err := s.fs.Stat(path); err == nil {
+			return legacyFormatVersion, nil
+		}
+	}
+	return CurrentFormatVersion(), nil
+}
+
+// writeFormat атомарно записывает format.json с текущей версией
+func (s *Storage) writeFormat() error {
+	data, err := json.MarshalIndent(FormatHeader{Format: ChainFormat, Version: CurrentFormatVersion()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.formatFile + ".tmp"
+	if err := writeFileSync(s.fs, tmp, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.formatFile, err)
+	}
+	return s.fs.Rename(tmp, s.formatFile)
+}
+
+// prepareMigration читает каталог данных и готовит миграцию, ничего
+// не записывая на диск. Прежний blockchain.json здесь не учитывается:
+// его версия записана в самом файле, и он переносится в журнал
+// migrateLegacyChain
+func (s *Storage) prepareMigration() (*storageMigration, error) {
+	from, err := s.formatVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, steps, err := newMigrationPlan(filepath.Dir(s.formatFile), from)
+	if err != nil {
+		return nil, err
+	}
+	m := &storageMigration{plan: plan, steps: steps}
+	if !plan.Needed() {
+		return m, nil
+	}
+	plan.Rewrite = append(plan.Rewrite, formatFileName)
+
+	if s.log != nil {
+		payloads, err := s.log.readPayloads()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read segment log: %w", err)
+		}
+		blocks, changed, err := migrateBlocks(payloads, steps)
+		if err != nil {
+			return nil, err
+		}
+		plan.Blocks = len(blocks)
+		plan.ChangedBlocks = changed
+		if changed > 0 {
+			m.log = blocks
+			plan.Rewrite = append(plan.Rewrite, filepath.Base(s.segmentDir)+"/")
+		}
+	}
+
+	data, err := s.fs.ReadFile(s.walFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	if len(data) > 0 {
+		wal, changed, err := migrateWAL(data, steps)
+		if err != nil {
+			slog.Warn("WAL is left as is, it will be handled on load", "error", err)
+		} else if changed {
+			m.wal = wal
+			plan.Rewrite = append(plan.Rewrite, filepath.Base(s.walFile))
+		}
+	}
+
+	if m.log != nil || m.wal != nil {
+		if m.keep, err = s.files(); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.fs.Stat(s.formatFile); err == nil {
+		// Блоки не меняются, но прежняя сборка не откроет каталог
+		// с новой версией: для отката достаточно прежнего format.json
+		m.keep = map[string]string{formatFileName: s.formatFile}
+	}
+	if m.keep != nil {
+		plan.Originals = s.migrationKeepDir(from)
+	}
+	return m, nil
+}
+
+// migrationKeepDir - каталог, куда сохраняются оригиналы версии from
+func (s *Storage) migrationKeepDir(from int) string {
+	return filepath.Join(filepath.Dir(s.formatFile), migrationsDirName, fmt.Sprintf("v%d", from))
+}
+
+// migrate поднимает формат каталога данных до текущей версии.
+//
+// Порядок: оригиналы копируются в migrations/v<N>/ (через .tmp и
+// переименование, повторная попытка их не перезаписывает), затем
+// переписываются журнал и WAL, и последним - format.json. Сбой до
+// записи format.json приводит к повтору миграции при следующем
+// открытии, поэтому шаги миграции идемпотентны
+func (s *Storage) migrate() error {
+	m, err := s.prepareMigration()
+	if err != nil {
+		return err
+	}
+	if !m.plan.Needed() {
+		// Новый каталог: версия фиксируется до появления данных
+		if _, err := s.fs.Stat(s.formatFile); os.IsNotExist(err) {
+			return s.writeFormat()
+		}
+		return nil
+	}
+
+	if m.plan.Originals != "" {
+		if err := s.keepOriginals(m.plan.Originals, m.keep); err != nil {
+			return fmt.Errorf("failed to keep original files: %w", err)
+		}
+	}
+
+	if m.log != nil {
+		if err := s.log.Reset(m.log); err != nil {
+			return fmt.Errorf("failed to rewrite segment log: %w", err)
+		}
+	}
+
+	if m.wal != nil {
+		tmp := s.walFile + ".tmp"
+		if err := writeFileSync(s.fs, tmp, m.wal); err != nil {
+			return fmt.Errorf("failed to rewrite WAL: %w", err)
+		}
+		if err := s.fs.Rename(tmp, s.walFile); err != nil {
+			return fmt.Errorf("failed to rewrite WAL: %w", err)
+		}
+	}
+
+	if err := s.writeFormat(); err != nil {
+		return err
+	}
+
+	slog.Info("Migrated data format",
+		"dir", m.plan.Path,
+		"from", m.plan.From,
+		"to", m.plan.To,
+		"blocks", m.plan.Blocks,
+		"changed_blocks", m.plan.ChangedBlocks,
+		"originals", m.plan.Originals,
+	)
+	return nil
+}
+
+// keepOriginals копирует файлы хранилища в каталог dir для отката
+func (s *Storage) keepOriginals(dir string, files map[string]string) error {
+	if _, err := s.fs.Stat(dir); err == nil {
+		// Оригиналы сохранены прерванной попыткой миграции
+		return nil
+	}
+
+	tmp := dir + ".tmp"
+	if err := s.fs.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if _, err := copyFiles(s.fs, s.fs, tmp, files); err != nil {
+		return err
+	}
+	return s.fs.Rename(tmp, dir)
+}
+
+// migrateWAL применяет шаги к записям WAL. Рваный хвост переносится
+// без изменений, чтобы его по-прежнему увидел ReadWAL
+func migrateWAL(data []byte, steps []Migration) ([]byte, bool, error) {
+	// Прежний формат: JSON-массив блоков
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, false, fmt.Errorf("failed to parse legacy WAL: %w", err)
+		}
+		payloads := make([][]byte, len(raw))
+		for i, r := range raw {
+			payloads[i] = r
+		}
+		blocks, changed, err := migrateBlocks(payloads, steps)
+		if err != nil || changed == 0 {
+			return nil, false, err
+		}
+		out, err := json.Marshal(blocks)
+		return out, true, err
+	}
+
+	var out []byte
+	changed := false
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(data[:end], &record); err != nil || len(record.Block) == 0 {
+			break
+		}
+		block, ok, err := migrateBlock(record.Block, steps)
+		if err != nil {
+			return nil, false, err
+		}
+		changed = changed || ok
+
+		line, err := encodeWALRecord(block)
+		if err != nil {
+			return nil, false, err
+		}
+		out = append(out, line...)
+		data = data[end+1:]
+	}
+
+	return append(out, data...), changed, nil
+}
+
+// PlanMigration описывает миграцию хранилища в каталоге dataDir, ничего
+// не меняя на диске и не беря блокировку каталога
+func PlanMigration(backend, dataDir string) (*MigrationPlan, error) {
+	switch backend {
+	case StoreFile, "":
+		return planStorageMigration(OSFS{}, dataDir)
+	case StoreSQLite:
+		path := filepath.Join(dataDir, "blockchain.db")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return &MigrationPlan{Path: path, From: CurrentFormatVersion(), To: CurrentFormatVersion()}, nil
+		}
+		db, err := openSQLiteReadOnly(path)
+		if err != nil {
+			return nil, err
+		}
+		store := &SQLiteStore{db: db, path: path, readOnly: true}
+		defer store.Close()
+		plan, _, err := store.prepareMigration()
+		return plan, err
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %q", backend)
+	}
+}
+
+// planStorageMigration описывает миграцию файлового хранилища
+func planStorageMigration(fsys FS, dataDir string) (*MigrationPlan, error) {
+	s := newStorage(fsys, dataDir, StoreOptions{})
+	s.readOnly = true
+
+	if _, err := fsys.Stat(s.segmentDir); err == nil {
+		log, err := openSegmentLog(fsys, s.segmentDir, DefaultSegmentSize, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open segment log: %w", err)
+		}
+		defer log.Close()
+		s.log = log
+	}
+
+	m, err := s.prepareMigration()
+	if err != nil {
+		return nil, err
+	}
+
+	// Прежний blockchain.json будет перенесён в журнал текущей версии
+	if data, err := fsys.ReadFile(s.chainFile); err == nil && (s.log == nil || s.log.Len() == 0) {
+		blocks, err := decodeChainFile(s.chainFile, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse chain file: %w", err)
+		}
+		m.plan.Blocks = len(blocks)
+		m.plan.Rewrite = append(m.plan.Rewrite, filepath.Base(s.chainFile))
+	}
+
+	return m.plan, nil
+}
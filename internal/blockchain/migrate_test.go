@@ -0,0 +1,272 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withTitleMigration регистрирует на время теста шаг миграции,
+// переводящий заголовок депозита в верхний регистр
+func withTitleMigration(t *testing.T) int {
+	t.Helper()
+
+	saved := migrations
+	from := CurrentFormatVersion()
+	migrations = append(append([]Migration{}, saved...), Migration{
+		From:        from,
+		Description: "upper-case titles",
+		Block: func(block map[string]json.RawMessage) error {
+			var data map[string]json.RawMessage
+			if err := json.Unmarshal(block["data"], &data); err != nil {
+				return err
+			}
+			var title string
+			if err := json.Unmarshal(data["title"], &title); err != nil {
+				return err
+			}
+			data["title"], _ = json.Marshal(strings.ToUpper(title))
+			raw, err := json.Marshal(data)
+			block["data"] = raw
+			return err
+		},
+	})
+	t.Cleanup(func() { migrations = saved })
+	return from
+}
+
+// readFormatVersion читает версию из format.json каталога dataDir
+func readFormatVersion(t *testing.T, dataDir string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dataDir, formatFileName))
+	AssertNoError(t, err, "format.json should exist")
+	var header FormatHeader
+	AssertNoError(t, json.Unmarshal(data, &header))
+	AssertEqual(t, header.Format, ChainFormat, "Format name")
+	return header.Version
+}
+
+func TestStorage_WritesFormatVersion(t *testing.T) {
+	dataDir := t.TempDir()
+
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	storage.Close()
+
+	AssertEqual(t, readFormatVersion(t, dataDir), CurrentFormatVersion(), "Version of a new data directory")
+}
+
+func TestStorage_MigratesUnversionedDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// Каталог, записанный до появления format.json
+	log, err := OpenSegmentLog(filepath.Join(dataDir, "segments"), 0)
+	AssertNoError(t, err)
+	AssertNoError(t, log.Reset(testChainBlocks(3)))
+	log.Close()
+
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	defer storage.Close()
+
+	AssertEqual(t, readFormatVersion(t, dataDir), CurrentFormatVersion(), "Version after migration")
+	AssertEqual(t, storage.log.Len(), 3, "Blocks after migration")
+
+	// Блоки не менялись - переписывать и сохранять было нечего
+	if _, err := os.Stat(filepath.Join(dataDir, migrationsDirName)); !os.IsNotExist(err) {
+		t.Error("Originals should not be kept when nothing is rewritten")
+	}
+}
+
+func TestStorage_MigrationRewritesBlocks(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	blocks := testChainBlocks(3)
+	AssertNoError(t, storage.log.Reset(blocks[:2]))
+	AssertNoError(t, storage.WriteToWAL(blocks[2]))
+	storage.Close()
+
+	from := withTitleMigration(t)
+
+	// Сухой прогон ничего не меняет
+	plan, err := PlanMigration(StoreFile, dataDir)
+	AssertNoError(t, err)
+	AssertEqual(t, plan.From, from, "Plan from")
+	AssertEqual(t, plan.To, from+1, "Plan to")
+	AssertEqual(t, plan.Blocks, 2, "Plan blocks")
+	AssertEqual(t, plan.ChangedBlocks, 2, "Plan changed blocks")
+	AssertEqual(t, len(plan.Steps), 1, "Plan steps")
+	AssertEqual(t, readFormatVersion(t, dataDir), from, "Version after dry run")
+
+	storage, err = NewStorage(dataDir)
+	AssertNoError(t, err)
+	defer storage.Close()
+
+	AssertEqual(t, readFormatVersion(t, dataDir), from+1, "Version after migration")
+
+	migrated, err := storage.log.ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, migrated[1].Data.Title, "TITLE", "Migrated title in log")
+
+	wal, err := storage.ReadWAL()
+	AssertNoError(t, err)
+	AssertEqual(t, len(wal), 1, "WAL records after migration")
+	AssertEqual(t, wal[0].Data.Title, "TITLE", "Migrated title in WAL")
+
+	// Оригиналы сохранены для отката
+	keep := filepath.Join(dataDir, migrationsDirName, fmt.Sprintf("v%d", from))
+	for _, name := range []string{"segments/000001.seg", "wal.json", formatFileName} {
+		if _, err := os.Stat(filepath.Join(keep, name)); err != nil {
+			t.Errorf("Original %s should be kept: %v", name, err)
+		}
+	}
+
+	plan, err = PlanMigration(StoreFile, dataDir)
+	AssertNoError(t, err)
+	if plan.Needed() {
+		t.Error("Migrated directory should not need migration")
+	}
+}
+
+func TestStorage_MigrationKeepsFormatFile(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	AssertNoError(t, storage.log.Reset(testChainBlocks(2)))
+	storage.Close()
+
+	// Шаг без изменения блоков переписывает только format.json
+	saved := migrations
+	from := CurrentFormatVersion()
+	migrations = append(append([]Migration{}, saved...), Migration{From: from, Description: "no-op"})
+	t.Cleanup(func() { migrations = saved })
+
+	storage, err = NewStorage(dataDir)
+	AssertNoError(t, err)
+	defer storage.Close()
+	AssertEqual(t, readFormatVersion(t, dataDir), from+1, "Version after migration")
+
+	// Для отката сохранён прежний format.json, блоки не копируются
+	keep := filepath.Join(dataDir, migrationsDirName, fmt.Sprintf("v%d", from))
+	AssertEqual(t, readFormatVersion(t, keep), from, "Kept format version")
+	if _, err := os.Stat(filepath.Join(keep, "segments")); !os.IsNotExist(err) {
+		t.Error("Segments should not be kept when blocks are unchanged")
+	}
+}
+
+func TestStorage_RejectsNewerFormat(t *testing.T) {
+	dataDir := t.TempDir()
+	data, _ := json.Marshal(FormatHeader{Format: ChainFormat, Version: CurrentFormatVersion() + 1})
+	AssertNoError(t, os.WriteFile(filepath.Join(dataDir, formatFileName), data, 0644))
+
+	_, err := NewStorage(dataDir)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("NewStorage() error = %v, want ErrUnsupportedFormat", err)
+	}
+
+	_, err = NewStorageWithOptions(dataDir, StoreOptions{ReadOnly: true})
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Read-only NewStorage() error = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestStorage_OlderBuildRejectsNewSemantics(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewStorage(dataDir)
+	AssertNoError(t, err)
+	AssertNoError(t, storage.log.Reset(testChainBlocks(2)))
+	storage.Close()
+
+	// Сборка до двоичных заголовков и целей PoW знала только первый шаг
+	saved := migrations
+	migrations = saved[:1]
+	t.Cleanup(func() { migrations = saved })
+
+	_, err = NewStorage(dataDir)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("NewStorage() error = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestDecodeChainFile(t *testing.T) {
+	blocks := testChainBlocks(2)
+
+	t.Run("headerless file is version 1", func(t *testing.T) {
+		data, err := json.Marshal(&Blockchain{Chain: blocks})
+		AssertNoError(t, err)
+
+		decoded, err := decodeChainFile("legacy.json", data)
+		AssertNoError(t, err)
+		AssertEqual(t, len(decoded), 2, "Decoded blocks")
+		AssertEqual(t, decoded[1].Hash, blocks[1].Hash, "Decoded tip")
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := encodeChainFile(blocks)
+		AssertNoError(t, err)
+		AssertContains(t, string(data), `"format": "`+ChainFormat+`"`)
+
+		decoded, err := decodeChainFile("chain.json", data)
+		AssertNoError(t, err)
+		AssertEqual(t, len(decoded), 2, "Decoded blocks")
+	})
+
+	t.Run("older version is migrated", func(t *testing.T) {
+		data, err := encodeChainFile(blocks)
+		AssertNoError(t, err)
+		withTitleMigration(t)
+
+		decoded, err := decodeChainFile("chain.json", data)
+		AssertNoError(t, err)
+		AssertEqual(t, decoded[1].Data.Title, "TITLE", "Migrated title")
+	})
+
+	t.Run("newer version is rejected", func(t *testing.T) {
+		data, _ := json.Marshal(FormatHeader{Format: ChainFormat, Version: CurrentFormatVersion() + 1})
+		_, err := decodeChainFile("chain.json", data)
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("decodeChainFile() error = %v, want ErrUnsupportedFormat", err)
+		}
+	})
+}
+
+func TestSQLiteStore_Migration(t *testing.T) {
+	dataDir := t.TempDir()
+	path := filepath.Join(dataDir, "blockchain.db")
+
+	store, err := NewSQLiteStore(path)
+	AssertNoError(t, err)
+	version, err := store.formatVersion()
+	AssertNoError(t, err)
+	AssertEqual(t, version, CurrentFormatVersion(), "Version of a new database")
+	AssertNoError(t, store.SaveChain(&Blockchain{Chain: testChainBlocks(2)}))
+	store.Close()
+
+	from := withTitleMigration(t)
+
+	plan, err := PlanMigration(StoreSQLite, dataDir)
+	AssertNoError(t, err)
+	AssertEqual(t, plan.ChangedBlocks, 2, "Plan changed blocks")
+
+	store, err = NewSQLiteStore(path)
+	AssertNoError(t, err)
+	defer store.Close()
+
+	version, err = store.formatVersion()
+	AssertNoError(t, err)
+	AssertEqual(t, version, from+1, "Version after migration")
+
+	blocks, err := store.GetAllBlocks()
+	AssertNoError(t, err)
+	AssertEqual(t, blocks[1].Data.Title, "TITLE", "Migrated title")
+
+	if _, err := os.Stat(plan.Originals); err != nil {
+		t.Errorf("Original database should be kept: %v", err)
+	}
+}
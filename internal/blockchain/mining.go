@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkEvery - через сколько попыток воркер проверяет отмену и
+// находку соседа
+const checkEvery = 1024
+
+// MiningStats - итог майнинга блока
+type MiningStats struct {
+	Attempts int64         // перебрано nonce всеми воркерами
+	Workers  int           // число воркеров
+	Duration time.Duration // время майнинга
+}
+
+// HashesPerSecond возвращает скорость перебора
+func (s MiningStats) HashesPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Attempts) / s.Duration.Seconds()
+}
+
+// MineContext майнит блок так, чтобы хеш начинался с difficulty
+// hex-нулей. См. MineTarget
+func (b *Block) MineContext(ctx context.Context, difficulty int) (MiningStats, error) {
+	return b.MineTarget(ctx, TargetFromDigits(difficulty))
+}
+
+// MineTarget записывает target в блок и подбирает nonce, при котором
+// хеш блока не больше цели. Пространство nonce делится между
+// runtime.NumCPU() воркерами: воркер i перебирает Nonce+i, Nonce+i+N, ...
+//
+// Заголовок сериализуется один раз, между попытками меняется только
+// nonce. При отмене ctx возвращает ctx.Err() и блок не меняет
+func (b *Block) MineTarget(ctx context.Context, target Target) (MiningStats, error) {
+	return b.mine(ctx, target, runtime.NumCPU())
+}
+
+// mine - MineTarget с заданным числом воркеров
+func (b *Block) mine(ctx context.Context, target Target, workers int) (MiningStats, error) {
+	// Цель входит в заголовок, поэтому записывается до перебора
+	prevDifficulty, prevTarget := b.Difficulty, b.Target
+	restore := func() { b.Difficulty, b.Target = prevDifficulty, prevTarget }
+	b.Difficulty, b.Target = 0, target.String()
+	prefix, suffix, appendNonce, err := b.headerTemplate()
+	if err != nil {
+		restore()
+		return MiningStats{}, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	alg := b.HashAlgorithm()
+
+	var (
+		found    atomic.Bool
+		attempts atomic.Int64
+		once     sync.Once
+		nonce    int
+		hash     [sha256.Size]byte
+		wg       sync.WaitGroup
+	)
+	stats := MiningStats{Workers: workers}
+	start := time.Now()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(first int) {
+			defer wg.Done()
+
+			buf := make([]byte, 0, len(prefix)+20+len(suffix))
+			var tried int64
+			for n := first; ; n += workers {
+				if tried%checkEvery == 0 && (found.Load() || ctx.Err() != nil) {
+					break
+				}
+				tried++
+
+				buf = append(buf[:0], prefix...)
+				buf = appendNonce(buf, n)
+				buf = append(buf, suffix...)
+				sum := alg.sum(buf)
+				if target.Met(sum[:]) {
+					once.Do(func() {
+						nonce, hash = n, sum
+						found.Store(true)
+					})
+					break
+				}
+			}
+			attempts.Add(tried)
+		}(b.Nonce + w)
+	}
+	wg.Wait()
+
+	stats.Attempts = attempts.Load()
+	stats.Duration = time.Since(start)
+	if !found.Load() {
+		restore()
+		return stats, ctx.Err()
+	}
+
+	b.Nonce = nonce
+	b.Hash = hex.EncodeToString(hash[:])
+	return stats, nil
+}
+
+// hasZeroPrefix проверяет, что hex-запись хеша начинается
+// с difficulty нулей
+func hasZeroPrefix(hash []byte, difficulty int) bool {
+	if difficulty > 2*len(hash) {
+		return false
+	}
+	for i := 0; i < difficulty/2; i++ {
+		if hash[i] != 0 {
+			return false
+		}
+	}
+	return difficulty%2 == 0 || hash[difficulty/2]>>4 == 0
+}
@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+)
+
+// miningQueue - очередь заявок на майнинг. Её разбирает единственный
+// воркер: он владеет вершиной цепочки, поэтому два блока никогда не
+// майнятся поверх одного и того же предыдущего.
+//
+// Воркер запускается при первой заявке и завершается, когда очередь
+// опустела, так что цепочке не нужен Close
+type miningQueue struct {
+	mu      sync.Mutex
+	jobs    []*miningJob
+	running bool
+}
+
+// miningJob - заявка на блок с депозитами
+type miningJob struct {
+	ctx      context.Context
+	deposits []DepositData
+	result   *PendingBlock
+}
+
+// PendingBlock - будущий блок заявки из SubmitBlock
+type PendingBlock struct {
+	started chan struct{}
+	done    chan struct{}
+	block   *Block
+	err     error
+}
+
+// Started закрывается, когда воркер взял заявку в работу
+func (p *PendingBlock) Started() <-chan struct{} {
+	return p.started
+}
+
+// Done закрывается, когда блок записан или заявка отклонена
+func (p *PendingBlock) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait ждёт результата заявки. Отмена ctx прекращает ожидание,
+// но не заявку: её отменяет контекст, переданный в SubmitBlock
+func (p *PendingBlock) Wait(ctx context.Context) (*Block, error) {
+	select {
+	case <-p.done:
+		return p.block, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve сообщает результат заявки
+func (p *PendingBlock) resolve(block *Block, err error) {
+	p.block, p.err = block, err
+	close(p.done)
+}
+
+// newPendingBlock создаёт будущий блок
+func newPendingBlock() *PendingBlock {
+	return &PendingBlock{started: make(chan struct{}), done: make(chan struct{})}
+}
+
+// SubmitBlock ставит депозиты в очередь майнинга и сразу возвращает
+// будущий блок. Заявки майнятся по одной в порядке поступления.
+//
+// Депозиты, уже записанные к моменту майнинга, в блок не попадают;
+// если не осталось ни одного, результат - *DuplicateBlockError с блоком
+// первого из них. Отмена ctx снимает заявку или прерывает её майнинг
+func (bc *Blockchain) SubmitBlock(ctx context.Context, deposits []DepositData) *PendingBlock {
+	job := &miningJob{
+		ctx:      ctx,
+		deposits: deposits,
+		result:   newPendingBlock(),
+	}
+
+	q := &bc.mining
+	q.mu.Lock()
+	q.jobs = append(q.jobs, job)
+	start := !q.running
+	q.running = true
+	q.mu.Unlock()
+
+	if start {
+		go bc.runMiningQueue()
+	}
+	return job.result
+}
+
+// runMiningQueue - воркер очереди майнинга
+func (bc *Blockchain) runMiningQueue() {
+	q := &bc.mining
+	for {
+		q.mu.Lock()
+		if len(q.jobs) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs[0] = nil
+		q.jobs = q.jobs[1:]
+		q.mu.Unlock()
+
+		close(job.result.started)
+		job.result.resolve(bc.processMiningJob(job))
+	}
+}
+
+// processMiningJob майнит и записывает блок заявки
+func (bc *Blockchain) processMiningJob(job *miningJob) (*Block, error) {
+	if err := job.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Отбрасываем уже записанные депозиты и повторы внутри заявки
+	fresh := make([]DepositData, 0, len(job.deposits))
+	seen := make(map[string]bool, len(job.deposits))
+	var existing *Block
+	for _, data := range job.deposits {
+		if block, ok := bc.HasContentHash(data.ContentHash); ok {
+			if existing == nil {
+				existing = block
+			}
+			continue
+		}
+		if seen[data.ContentHash] {
+			continue
+		}
+		seen[data.ContentHash] = true
+		fresh = append(fresh, data)
+	}
+	if len(fresh) == 0 {
+		return nil, &DuplicateBlockError{Block: existing}
+	}
+
+	block, err := bc.mineBlock(job.ctx, fresh)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.commitBlock(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSubmitBlock_NoTipRace(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 2)
+
+	const n = 8
+	pending := make([]*PendingBlock, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := CreateTestBlock("Author", "Title", fmt.Sprintf("queued text %d", i))
+			pending[i] = bc.SubmitBlock(context.Background(), []DepositData{data})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, p := range pending {
+		<-p.Done()
+		if _, err := p.Wait(context.Background()); err != nil {
+			t.Errorf("Block %d error = %v", i, err)
+		}
+	}
+
+	AssertEqual(t, len(bc.Chain), n+1, "Chain length")
+	if height, err := bc.invalidBlock(bc.Chain); err != nil {
+		t.Errorf("Chain invalid at height %d: %v", height, err)
+	}
+}
+
+func TestSubmitBlock_Duplicates(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	data := CreateTestBlock("Author", "Title", "duplicate text")
+
+	first, err := bc.SubmitBlock(context.Background(), []DepositData{data}).Wait(context.Background())
+	AssertNoError(t, err)
+
+	_, err = bc.SubmitBlock(context.Background(), []DepositData{data}).Wait(context.Background())
+	var dup *DuplicateBlockError
+	if !errors.As(err, &dup) {
+		t.Fatalf("Wait() error = %v, want *DuplicateBlockError", err)
+	}
+	AssertEqual(t, dup.Block, first, "Existing block")
+	AssertEqual(t, len(bc.Chain), 2, "Chain length")
+}
+
+func TestSubmitBlock_CanceledJob(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := bc.SubmitBlock(ctx, testDeposits(1)).Wait(context.Background())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait() error = %v, want context.Canceled", err)
+	}
+
+	// Снятая заявка не мешает следующим
+	block, err := bc.SubmitBlock(context.Background(), testDeposits(2)).Wait(context.Background())
+	AssertNoError(t, err)
+	AssertEqual(t, block.PrevHash, bc.Chain[0].Hash, "Block mined on genesis")
+}
@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlock_HeaderTemplate(t *testing.T) {
+	blocks := map[string]*Block{
+		"plain": NewBlock("000-000-001", "prev", CreateTestBlock("Author", "Title", "text")),
+		"batch": NewBatchBlock("000-000-002", "prev", testDeposits(3)),
+		"nonce in data": NewBlock("000-000-003", "prev", DepositData{
+			Title:       `"nonce":-9223372036854775808`,
+			ContentHash: "hash",
+		}),
+	}
+
+	for name, block := range blocks {
+		for _, version := range []int{HeaderVersionJSON, HeaderVersionBinary, HeaderVersionAlgorithms} {
+			t.Run(name+"/v"+strconv.Itoa(version), func(t *testing.T) {
+				block.Version = version
+				if version < HeaderVersionAlgorithms {
+					block.HashAlg = ""
+				}
+				prefix, suffix, appendNonce, err := block.headerTemplate()
+				AssertNoError(t, err)
+
+				for _, nonce := range []int{0, 7, 123456789, -5} {
+					block.Nonce = nonce
+					header := appendNonce(append([]byte{}, prefix...), nonce)
+					header = append(header, suffix...)
+					sum := sha256.Sum256(header)
+					AssertEqual(t, hex.EncodeToString(sum[:]), block.CalculateHash(), "Hash for nonce %d", nonce)
+				}
+			})
+		}
+	}
+}
+
+func TestBlock_MineContext(t *testing.T) {
+	t.Run("parallel workers", func(t *testing.T) {
+		block := NewBatchBlock("000-000-001", "prev", testDeposits(2))
+
+		stats, err := block.mine(context.Background(), TargetFromDigits(3), 4)
+		AssertNoError(t, err)
+		if !strings.HasPrefix(block.Hash, "000") {
+			t.Errorf("Hash = %s, want prefix 000", block.Hash)
+		}
+		AssertEqual(t, block.Hash, block.CalculateHash(), "Stored hash")
+		AssertEqual(t, stats.Workers, 4, "Workers")
+		if stats.Attempts < 1 {
+			t.Errorf("Attempts = %d, want > 0", stats.Attempts)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		block := NewBlock("000-000-001", "prev", CreateTestBlock("Author", "Title", "text"))
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// 64 нуля недостижимы: майнинг остановит только отмена
+		_, err := block.MineContext(ctx, 64)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("MineContext() error = %v, want DeadlineExceeded", err)
+		}
+		AssertEqual(t, block.Hash, "", "Hash of canceled block")
+		AssertEqual(t, block.Nonce, 0, "Nonce of canceled block")
+	})
+}
+
+func TestMiningStats_HashesPerSecond(t *testing.T) {
+	stats := MiningStats{Attempts: 500, Duration: 250 * time.Millisecond}
+	AssertEqual(t, stats.HashesPerSecond(), 2000.0, "Hashes per second")
+	AssertEqual(t, MiningStats{}.HashesPerSecond(), 0.0, "Hashes per second without duration")
+}
+
+func TestHasZeroPrefix(t *testing.T) {
+	hash := []byte{0x00, 0x0f, 0xff}
+	for difficulty, want := range []bool{true, true, true, true, false, false, false} {
+		if got := hasZeroPrefix(hash, difficulty); got != want {
+			t.Errorf("hasZeroPrefix(%x, %d) = %v, want %v", hash, difficulty, got, want)
+		}
+	}
+}
+
+func TestBlockchain_AddBlockContextCanceled(t *testing.T) {
+	bc := NewBlockchainWithStorage(NewTestStorage(), 64)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := bc.AddBlockContext(ctx, CreateTestBlock("Author", "Title", "text"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AddBlockContext() error = %v, want DeadlineExceeded", err)
+	}
+	AssertEqual(t, len(bc.Chain), 1, "Chain length after canceled mining")
+}
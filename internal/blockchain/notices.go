@@ -0,0 +1,128 @@
+package blockchain
+
+import "fmt"
+
+// IsNotice сообщает, что это отзыв или исправление депозита
+func (d DepositData) IsNotice() bool {
+	return d.Type == RecordRetraction || d.Type == RecordCorrection
+}
+
+// NewRetraction создаёт неподписанный отзыв депозита target. Его
+// подписывает signerKey - ключ депозита или ключ, к которому он перешёл.
+// Отзыв из учётной записи депозита создаётся с пустым signerKey и
+// заполненным Account
+func NewRetraction(alg HashAlgorithm, signerKey, target, reason string) DepositData {
+	return newNotice(RecordRetraction, alg, signerKey, target, reason)
+}
+
+// NewCorrection создаёт неподписанное исправление депозита target.
+// Подписывается так же, как отзыв
+func NewCorrection(alg HashAlgorithm, signerKey, target, reason string) DepositData {
+	return newNotice(RecordCorrection, alg, signerKey, target, reason)
+}
+
+func newNotice(typ RecordType, alg HashAlgorithm, signerKey, target, reason string) DepositData {
+	data := DepositData{
+		Type:           typ,
+		PublicKey:      signerKey,
+		Target:         target,
+		Reason:         reason,
+		ContentHashAlg: alg.normalize(),
+	}
+	data.ContentHash = data.RecordContentHash()
+	return data
+}
+
+// checkNotices проверяет отзывы и исправления блока: депозит Target
+// есть в цепочке до block или раньше в том же блоке, и запись подписана
+// его ключом или преемником этого ключа по keys либо добавлена из его
+// учётной записи. Депозит без подписи и без учётной записи отозвать
+// нельзя: авторство отзыва нечем доказать
+func checkNotices(block *Block, resolve func(ref string) (DepositData, bool), keys *keyRegistry) error {
+	for i, data := range block.DepositList() {
+		if !data.IsNotice() {
+			continue
+		}
+
+		target, ok := resolveInBlock(block, i, data.Target, resolve)
+		if !ok || target.IsRecord() {
+			return fmt.Errorf("%w: %s", ErrTargetNotFound, data.Target)
+		}
+		if sameAccount(target, data) {
+			continue
+		}
+		signed, err := signedByOwner(target, data, keys)
+		if err != nil {
+			return err
+		}
+		if !signed {
+			return ErrNoticeNotAuthorized
+		}
+	}
+	return nil
+}
+
+// CheckNotice проверяет отзыв или исправление по цепочке, не добавляя
+// в неё. Нужна, чтобы отвергнуть запись до майнинга
+func (bc *Blockchain) CheckNotice(data DepositData) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return checkNotices(&Block{Data: data}, bc.resolveDeposit, bc.keys)
+}
+
+// rebuildNoticeIndex перестраивает индекс депозит -> отзывы и исправления
+func (bc *Blockchain) rebuildNoticeIndex() {
+	bc.noticeIndex = make(map[string][]depositPos)
+	for _, block := range bc.Chain {
+		if block != nil {
+			bc.indexNotices(block)
+		}
+	}
+}
+
+// indexNotices добавляет в индекс отзывы и исправления блока
+func (bc *Blockchain) indexNotices(block *Block) {
+	for i, data := range block.DepositList() {
+		if data.IsNotice() {
+			bc.noticeIndex[data.Target] = append(bc.noticeIndex[data.Target], depositPos{block: block, index: i})
+		}
+	}
+}
+
+// unindexNotices убирает из индекса отзывы и исправления последнего блока
+func (bc *Blockchain) unindexNotices(block *Block) {
+	for _, data := range block.DepositList() {
+		if !data.IsNotice() {
+			continue
+		}
+		notices := bc.noticeIndex[data.Target]
+		n := len(notices)
+		for n > 0 && notices[n-1].block == block {
+			n--
+		}
+		if n == 0 {
+			delete(bc.noticeIndex, data.Target)
+		} else {
+			bc.noticeIndex[data.Target] = notices[:n]
+		}
+	}
+}
+
+// Notices возвращает отзывы и исправления депозита ref в порядке
+// цепочки. Депозит отозван, если среди них есть RecordRetraction
+func (bc *Blockchain) Notices(ref string) ([]*DepositReceipt, error) {
+	bc.mu.RLock()
+	notices := bc.noticeIndex[ref]
+	notices = notices[:len(notices):len(notices)]
+	bc.mu.RUnlock()
+
+	receipts := make([]*DepositReceipt, 0, len(notices))
+	for _, n := range notices {
+		receipt, err := newDepositReceipt(n.block, n.index)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
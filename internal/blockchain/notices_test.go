@@ -0,0 +1,181 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRecord_Notices(t *testing.T) {
+	a := newTestAuthorKey(t)
+
+	AssertNoError(t, checkRecord(a.sign(NewRetraction(HashSHA256, a.public, "000-000-001", "wrong file"))))
+	AssertNoError(t, checkRecord(a.sign(NewCorrection(HashSHA3_256, a.public, "000-000-001.2", "typo in title"))))
+
+	tests := []struct {
+		name   string
+		change func(d *DepositData)
+	}{
+		{"no target", func(d *DepositData) { d.Target = "" }},
+		{"no reason", func(d *DepositData) { d.Reason = "" }},
+		{"key fields", func(d *DepositData) { d.NewKey = a.public }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := NewRetraction(HashSHA256, a.public, "000-000-001", "wrong file")
+			tt.change(&data)
+			data.ContentHash = data.RecordContentHash()
+			if err := checkRecord(a.sign(data)); !errors.Is(err, ErrInvalidRecord) {
+				t.Errorf("checkRecord() error = %v, want ErrInvalidRecord", err)
+			}
+		})
+	}
+
+	t.Run("reason is hashed", func(t *testing.T) {
+		data := NewRetraction(HashSHA256, a.public, "000-000-001", "wrong file")
+		data.Reason = "plagiarism"
+		if err := checkRecord(a.sign(data)); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("checkRecord() error = %v, want ErrInvalidRecord", err)
+		}
+	})
+
+	t.Run("unsigned account notice", func(t *testing.T) {
+		data := NewRetraction(HashSHA256, "", "000-000-001", "wrong file")
+		if err := checkRecord(data); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("checkRecord() without account error = %v, want ErrInvalidRecord", err)
+		}
+		data.Account = AccountID("owner-account-token")
+		AssertNoError(t, checkRecord(data))
+
+		// Ключ без подписи ничего не доказывает
+		data.PublicKey = a.public
+		data.ContentHash = data.RecordContentHash()
+		if err := checkRecord(data); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("checkRecord() with unsigned key error = %v, want ErrInvalidRecord", err)
+		}
+	})
+
+	t.Run("key record with target", func(t *testing.T) {
+		data := NewKeyRotation(HashSHA256, a.public, newTestAuthorKey(t).public)
+		data.Target = "000-000-001"
+		data.ContentHash = data.RecordContentHash()
+		if err := checkRecord(a.sign(data)); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("checkRecord() error = %v, want ErrInvalidRecord", err)
+		}
+	})
+}
+
+func TestBlockchain_Notices(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	a, b, stranger := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+	alg := bc.HashAlgorithm()
+
+	deposit := CreateTestBlock("Author", "Essay", "essay with a wrong attachment")
+	deposit.PublicKey = a.public
+	block, err := bc.AddBlock(a.sign(deposit))
+	AssertNoError(t, err)
+
+	unsigned, err := bc.AddBlock(CreateTestBlock("Author", "Draft", "unsigned draft"))
+	AssertNoError(t, err)
+
+	t.Run("foreign key", func(t *testing.T) {
+		err := bc.CheckNotice(stranger.sign(NewRetraction(alg, stranger.public, block.ID, "not mine")))
+		if !errors.Is(err, ErrNoticeNotAuthorized) {
+			t.Errorf("CheckNotice() error = %v, want ErrNoticeNotAuthorized", err)
+		}
+		_, err = bc.AddBlock(stranger.sign(NewRetraction(alg, stranger.public, block.ID, "not mine")))
+		if !errors.Is(err, ErrNoticeNotAuthorized) {
+			t.Errorf("AddBlock() error = %v, want ErrNoticeNotAuthorized", err)
+		}
+	})
+
+	t.Run("unsigned deposit", func(t *testing.T) {
+		err := bc.CheckNotice(a.sign(NewRetraction(alg, a.public, unsigned.ID, "cannot prove")))
+		if !errors.Is(err, ErrNoticeNotAuthorized) {
+			t.Errorf("CheckNotice() error = %v, want ErrNoticeNotAuthorized", err)
+		}
+	})
+
+	t.Run("same account", func(t *testing.T) {
+		data := CreateTestBlock("Author", "Notes", "unsigned notes")
+		data.Account = AccountID("owner-account-token")
+		notes, err := bc.AddBlock(data)
+		AssertNoError(t, err)
+
+		notice := NewCorrection(alg, "", notes.ID, "typo in title")
+		notice.Account = AccountID("other-account-token")
+		if err := bc.CheckNotice(notice); !errors.Is(err, ErrNoticeNotAuthorized) {
+			t.Errorf("CheckNotice() error = %v, want ErrNoticeNotAuthorized", err)
+		}
+
+		notice.Account = AccountID("owner-account-token")
+		AssertNoError(t, bc.CheckNotice(notice))
+		_, err = bc.AddBlock(notice)
+		AssertNoError(t, err)
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		err := bc.CheckNotice(a.sign(NewRetraction(alg, a.public, "999-999-999", "missing")))
+		if !errors.Is(err, ErrTargetNotFound) {
+			t.Errorf("CheckNotice() error = %v, want ErrTargetNotFound", err)
+		}
+	})
+
+	t.Run("record target", func(t *testing.T) {
+		rotation, err := bc.AddBlock(a.sign(NewKeyRotation(alg, a.public, b.public)))
+		AssertNoError(t, err)
+		err = bc.CheckNotice(b.sign(NewRetraction(alg, b.public, rotation.ID, "undo rotation")))
+		if !errors.Is(err, ErrTargetNotFound) {
+			t.Errorf("CheckNotice() error = %v, want ErrTargetNotFound", err)
+		}
+	})
+
+	// После ротации отзывает уже новый ключ автора
+	correction, err := bc.AddBlock(b.sign(NewCorrection(alg, b.public, block.ID, "attachment is outdated")))
+	AssertNoError(t, err)
+	retraction, err := bc.AddBlock(b.sign(NewRetraction(alg, b.public, block.ID, "wrong file deposited")))
+	AssertNoError(t, err)
+
+	check := func(bc *Blockchain) {
+		t.Helper()
+		notices, err := bc.Notices(block.ID)
+		AssertNoError(t, err)
+		if len(notices) != 2 {
+			t.Fatalf("Notices() returned %d records, want 2", len(notices))
+		}
+		AssertEqual(t, notices[0].Ref, correction.ID, "Correction")
+		AssertEqual(t, notices[1].Ref, retraction.ID, "Retraction")
+		AssertEqual(t, notices[1].Data.Reason, "wrong file deposited", "Reason")
+		if !bc.ValidateChain() {
+			t.Error("Chain with notices is invalid")
+		}
+	}
+	check(bc)
+
+	reloaded, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	check(reloaded)
+
+	notices, err := bc.Notices(unsigned.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, len(notices), 0, "Notices of untouched deposit")
+}
+
+func TestCheckNotices_SameBlock(t *testing.T) {
+	a := newTestAuthorKey(t)
+	deposit := CreateTestBlock("Author", "Work", "text")
+	deposit.PublicKey = a.public
+	deposit = a.sign(deposit)
+	retraction := a.sign(NewRetraction(HashSHA256, a.public, "000-000-001.0", "deposited by mistake"))
+
+	none := func(string) (DepositData, bool) { return DepositData{}, false }
+
+	block := newBatchBlock("000-000-001", "prev", HashSHA256, []DepositData{deposit, retraction})
+	AssertNoError(t, checkNotices(block, none, newKeyRegistry()))
+
+	block = newBatchBlock("000-000-001", "prev", HashSHA256, []DepositData{retraction, deposit})
+	if err := checkNotices(block, none, newKeyRegistry()); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("checkNotices() error = %v, want ErrTargetNotFound", err)
+	}
+}
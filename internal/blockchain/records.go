@@ -0,0 +1,329 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// RecordType - тип записи блока. Пустой тип - депонированный текст,
+// остальные - служебные записи о ключах авторов.
+//
+// Служебная запись хранится в блоке так же, как депозит: в Data или
+// в Deposits, под корнем Меркла. Её ContentHash - хеш тела записи
+// (см. RecordContentHash), поэтому дубликаты и ссылки на записи
+// работают так же, как для текстов
+type RecordType string
+
+const (
+	RecordDeposit RecordType = ""
+
+	// RecordKeyRotation - ключ PublicKey передаёт авторство ключу NewKey.
+	// Подписана ключом PublicKey
+	RecordKeyRotation RecordType = "key_rotation"
+
+	// RecordKeyRevocation - ключ RevokedKey отозван начиная с RevokedAt.
+	// Подписана самим RevokedKey или ключом, к которому он перешёл
+	// ротациями
+	RecordKeyRevocation RecordType = "key_revocation"
+
+	// RecordRetraction - автор отзывает депозит Target (неверный файл,
+	// снятая работа) по причине Reason. Подписана ключом депозита или
+	// его преемником
+	RecordRetraction RecordType = "retraction"
+
+	// RecordCorrection - автор сообщает об ошибке в депозите Target,
+	// не отзывая его. Подписана так же, как отзыв
+	RecordCorrection RecordType = "correction"
+)
+
+// IsRecord сообщает, что это служебная запись, а не депонированный текст
+func (d DepositData) IsRecord() bool {
+	return d.Type != RecordDeposit
+}
+
+// recordBody кодирует поля служебной записи, как строки заголовка
+// блока (длина u32 и байты):
+//
+//	str type
+//	str public_key
+//	str new_key
+//	str revoked_key
+//	i64 revoked_at   наносекунды Unix, 0 - нет
+//	str target       только у отзыва и исправления
+//	str reason       только у отзыва и исправления
+func (d DepositData) recordBody() []byte {
+	var revokedAt int64
+	if !d.RevokedAt.IsZero() {
+		revokedAt = d.RevokedAt.UnixNano()
+	}
+
+	buf := make([]byte, 0, 64+len(d.PublicKey)+len(d.NewKey)+len(d.RevokedKey))
+	buf = appendHeaderString(buf, string(d.Type))
+	buf = appendHeaderString(buf, d.PublicKey)
+	buf = appendHeaderString(buf, d.NewKey)
+	buf = appendHeaderString(buf, d.RevokedKey)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(revokedAt))
+	if d.IsNotice() {
+		buf = appendHeaderString(buf, d.Target)
+		buf = appendHeaderString(buf, d.Reason)
+	}
+	return buf
+}
+
+// RecordContentHash вычисляет ContentHash служебной записи: хеш её
+// тела алгоритмом ContentHashAlg. Подпись автора покрывает ContentHash,
+// а через него - все поля записи
+func (d DepositData) RecordContentHash() string {
+	return d.ContentHashAlgorithm().ContentHash(d.recordBody())
+}
+
+// NewKeyRotation создаёт неподписанную запись о переходе от ключа
+// oldKey к newKey. Её подписывает oldKey (см. SigningMessage)
+func NewKeyRotation(alg HashAlgorithm, oldKey, newKey string) DepositData {
+	data := DepositData{
+		Type:           RecordKeyRotation,
+		PublicKey:      oldKey,
+		NewKey:         newKey,
+		ContentHashAlg: alg.normalize(),
+	}
+	data.ContentHash = data.RecordContentHash()
+	return data
+}
+
+// NewKeyRevocation создаёт неподписанную запись об отзыве ключа
+// revokedKey начиная с at. Её подписывает signerKey - сам revokedKey
+// или ключ, к которому он перешёл
+func NewKeyRevocation(alg HashAlgorithm, signerKey, revokedKey string, at time.Time) DepositData {
+	data := DepositData{
+		Type:           RecordKeyRevocation,
+		PublicKey:      signerKey,
+		RevokedKey:     revokedKey,
+		RevokedAt:      at.UTC(),
+		ContentHashAlg: alg.normalize(),
+	}
+	data.ContentHash = data.RecordContentHash()
+	return data
+}
+
+// checkRecord проверяет служебную запись без учёта состояния цепочки:
+// тип, поля и ContentHash. Подпись проверяет checkSignatures, право
+// на отзыв и исправление - checkNotices. Отзыв и исправление из
+// учётной записи (см. AccountID) могут быть без подписи, но тогда и
+// без ключа
+func checkRecord(data DepositData) error {
+	if !data.IsRecord() {
+		return nil
+	}
+	if !data.Signed() && !(data.IsNotice() && data.Account != "" && data.PublicKey == "") {
+		return fmt.Errorf("%w: %s record is not signed", ErrInvalidRecord, data.Type)
+	}
+	if data.ParentID != "" {
+		return fmt.Errorf("%w: %s record cannot have a previous version", ErrInvalidRecord, data.Type)
+	}
+
+	keyFields := data.NewKey != "" || data.RevokedKey != "" || !data.RevokedAt.IsZero()
+	noticeFields := data.Target != "" || data.Reason != ""
+	switch data.Type {
+	case RecordKeyRotation:
+		if data.NewKey == "" || data.RevokedKey != "" || !data.RevokedAt.IsZero() || noticeFields {
+			return fmt.Errorf("%w: key rotation needs only new_key", ErrInvalidRecord)
+		}
+	case RecordKeyRevocation:
+		if data.RevokedKey == "" || data.RevokedAt.IsZero() || data.NewKey != "" || noticeFields {
+			return fmt.Errorf("%w: key revocation needs revoked_key and revoked_at", ErrInvalidRecord)
+		}
+	case RecordRetraction, RecordCorrection:
+		if data.Target == "" || data.Reason == "" || keyFields {
+			return fmt.Errorf("%w: %s needs only target and reason", ErrInvalidRecord, data.Type)
+		}
+	default:
+		return fmt.Errorf("%w: unknown record type %q", ErrInvalidRecord, data.Type)
+	}
+
+	if data.ContentHash != data.RecordContentHash() {
+		return fmt.Errorf("%w: content hash does not match record", ErrInvalidRecord)
+	}
+	return nil
+}
+
+// KeyStatus - состояние ключа автора по записям цепочки
+type KeyStatus struct {
+	Fingerprint string
+
+	// Successors - ключи, к которым авторство перешло ротациями,
+	// по порядку; последний - текущий ключ автора
+	Successors []string
+
+	// Predecessors - ключи, от которых авторство перешло к этому,
+	// от ближайшего
+	Predecessors []string
+
+	// Revoked - ключ отозван начиная с RevokedAt записью RevocationRef
+	Revoked       bool
+	RevokedAt     time.Time
+	RevocationRef string
+}
+
+// keyRevocation - отзыв ключа: время, с которого ключ недействителен,
+// и ссылка на запись
+type keyRevocation struct {
+	at  time.Time
+	ref string
+}
+
+// keyRegistry - ротации и отзывы ключей, записанные в цепочке.
+// Ключи - отпечатки (см. AuthorKey)
+type keyRegistry struct {
+	successor   map[string]string
+	predecessor map[string]string
+	revoked     map[string]keyRevocation
+}
+
+func newKeyRegistry() *keyRegistry {
+	return &keyRegistry{
+		successor:   make(map[string]string),
+		predecessor: make(map[string]string),
+		revoked:     make(map[string]keyRevocation),
+	}
+}
+
+// clone копирует реестр, чтобы применить блок целиком или не применять
+func (r *keyRegistry) clone() *keyRegistry {
+	c := &keyRegistry{
+		successor:   make(map[string]string, len(r.successor)),
+		predecessor: make(map[string]string, len(r.predecessor)),
+		revoked:     make(map[string]keyRevocation, len(r.revoked)),
+	}
+	for k, v := range r.successor {
+		c.successor[k] = v
+	}
+	for k, v := range r.predecessor {
+		c.predecessor[k] = v
+	}
+	for k, v := range r.revoked {
+		c.revoked[k] = v
+	}
+	return c
+}
+
+// inheritsFrom сообщает, что ключ key получил авторство от ключа
+// ancestor одной или несколькими ротациями
+func (r *keyRegistry) inheritsFrom(key, ancestor string) bool {
+	for k, ok := r.predecessor[key]; ok; k, ok = r.predecessor[k] {
+		if k == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// check проверяет подписанный депозит или служебную запись по
+// состоянию ключей: ключи подписи не отозваны и не переданы другим,
+// ротация не ветвит и не замыкает цепочку ключей, отзыв подписан
+// самим ключом или его преемником
+func (r *keyRegistry) check(data DepositData) error {
+	if err := checkRecord(data); err != nil {
+		return err
+	}
+	// У работы с соавторами проверяется ключ каждого подписавшего
+	for _, publicKey := range data.SignerKeys() {
+		key, err := ParseAuthorKey(publicKey)
+		if err != nil {
+			return err
+		}
+		if _, ok := r.revoked[key.Fingerprint]; ok {
+			return ErrKeyRevoked
+		}
+		if _, ok := r.successor[key.Fingerprint]; ok {
+			return ErrKeyRotated
+		}
+	}
+	// Права на отзыв и исправление проверяет checkNotices
+	if !data.IsRecord() || data.IsNotice() {
+		return nil
+	}
+	signer, err := ParseAuthorKey(data.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	switch data.Type {
+	case RecordKeyRotation:
+		next, err := ParseAuthorKey(data.NewKey)
+		if err != nil {
+			return err
+		}
+		// Новый ключ ещё не участвовал в ротациях и не отозван:
+		// цепочка ключей остаётся линейной и без циклов
+		_, hasSuccessor := r.successor[next.Fingerprint]
+		_, hasPredecessor := r.predecessor[next.Fingerprint]
+		_, isRevoked := r.revoked[next.Fingerprint]
+		if next.Fingerprint == signer.Fingerprint || hasSuccessor || hasPredecessor || isRevoked {
+			return fmt.Errorf("%w: key %s cannot succeed %s", ErrInvalidRecord, next.Fingerprint, signer.Fingerprint)
+		}
+	case RecordKeyRevocation:
+		target, err := ParseAuthorKey(data.RevokedKey)
+		if err != nil {
+			return err
+		}
+		if _, ok := r.revoked[target.Fingerprint]; ok {
+			return ErrKeyRevoked
+		}
+		if target.Fingerprint != signer.Fingerprint && !r.inheritsFrom(signer.Fingerprint, target.Fingerprint) {
+			return fmt.Errorf("%w: key %s may not revoke %s", ErrInvalidRecord, signer.Fingerprint, target.Fingerprint)
+		}
+	}
+	return nil
+}
+
+// record вносит проверенную служебную запись в реестр
+func (r *keyRegistry) record(data DepositData, ref string) {
+	switch data.Type {
+	case RecordKeyRotation:
+		from, err1 := ParseAuthorKey(data.PublicKey)
+		to, err2 := ParseAuthorKey(data.NewKey)
+		if err1 == nil && err2 == nil {
+			r.successor[from.Fingerprint] = to.Fingerprint
+			r.predecessor[to.Fingerprint] = from.Fingerprint
+		}
+	case RecordKeyRevocation:
+		if key, err := ParseAuthorKey(data.RevokedKey); err == nil {
+			r.revoked[key.Fingerprint] = keyRevocation{at: data.RevokedAt, ref: ref}
+		}
+	}
+}
+
+// apply проверяет записи блока по порядку и возвращает реестр с ними.
+// Реестр r не меняется; для блока без служебных записей возвращается
+// он сам
+func (r *keyRegistry) apply(block *Block) (*keyRegistry, error) {
+	next := r
+	for i, data := range block.DepositList() {
+		if err := next.check(data); err != nil {
+			return r, err
+		}
+		if data.IsRecord() {
+			if next == r {
+				next = r.clone()
+			}
+			next.record(data, DepositRef(block, i))
+		}
+	}
+	return next, nil
+}
+
+// status возвращает состояние ключа с отпечатком fingerprint
+func (r *keyRegistry) status(fingerprint string) KeyStatus {
+	status := KeyStatus{Fingerprint: fingerprint}
+	for k, ok := r.successor[fingerprint]; ok; k, ok = r.successor[k] {
+		status.Successors = append(status.Successors, k)
+	}
+	for k, ok := r.predecessor[fingerprint]; ok; k, ok = r.predecessor[k] {
+		status.Predecessors = append(status.Predecessors, k)
+	}
+	if rev, ok := r.revoked[fingerprint]; ok {
+		status.Revoked, status.RevokedAt, status.RevocationRef = true, rev.at, rev.ref
+	}
+	return status
+}
@@ -0,0 +1,162 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testAuthorKey - ключ Ed25519 для тестов: закрытый ключ, публичный
+// в base64 и отпечаток
+type testAuthorKey struct {
+	priv        ed25519.PrivateKey
+	public      string
+	fingerprint string
+}
+
+func newTestAuthorKey(t *testing.T) testAuthorKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+	key := testAuthorKey{priv: priv, public: base64.StdEncoding.EncodeToString(pub)}
+	parsed, err := ParseAuthorKey(key.public)
+	AssertNoError(t, err)
+	key.fingerprint = parsed.Fingerprint
+	return key
+}
+
+// sign подписывает запись ключом k
+func (k testAuthorKey) sign(data DepositData) DepositData {
+	data.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, data.SigningMessage()))
+	return data
+}
+
+func TestCheckRecord(t *testing.T) {
+	a, b := newTestAuthorKey(t), newTestAuthorKey(t)
+
+	t.Run("valid records", func(t *testing.T) {
+		AssertNoError(t, checkRecord(a.sign(NewKeyRotation(HashSHA256, a.public, b.public))))
+		AssertNoError(t, checkRecord(a.sign(NewKeyRevocation(HashSHA3_256, a.public, a.public, time.Now()))))
+	})
+
+	t.Run("deposits are not records", func(t *testing.T) {
+		AssertNoError(t, checkRecord(CreateTestBlock("Author", "Title", "text")))
+	})
+
+	tests := map[string]DepositData{
+		"unsigned": NewKeyRotation(HashSHA256, a.public, b.public),
+		"unknown type": func() DepositData {
+			data := NewKeyRotation(HashSHA256, a.public, b.public)
+			data.Type = "key_escrow"
+			return a.sign(data)
+		}(),
+		"rotation without new key": a.sign(NewKeyRotation(HashSHA256, a.public, "")),
+		"revocation without time":  a.sign(NewKeyRevocation(HashSHA256, a.public, a.public, time.Time{})),
+		"new key changed after hashing": func() DepositData {
+			data := a.sign(NewKeyRotation(HashSHA256, a.public, b.public))
+			data.NewKey = a.public
+			return data
+		}(),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := checkRecord(data); !errors.Is(err, ErrInvalidRecord) {
+				t.Errorf("checkRecord() error = %v, want ErrInvalidRecord", err)
+			}
+		})
+	}
+}
+
+func TestBlockchain_KeyRotationAndRevocation(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	a, b, c := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+
+	deposit := func(key testAuthorKey, title string) DepositData {
+		data := CreateTestBlock("Author", title, "text of "+title)
+		data.PublicKey = key.public
+		return key.sign(data)
+	}
+
+	old, err := bc.AddBlock(deposit(a, "Before rotation"))
+	AssertNoError(t, err)
+
+	// A передаёт авторство B
+	_, err = bc.AddBlock(a.sign(NewKeyRotation(bc.HashAlgorithm(), a.public, b.public)))
+	AssertNoError(t, err)
+	status := bc.KeyStatus(a.fingerprint)
+	if len(status.Successors) != 1 || status.Successors[0] != b.fingerprint {
+		t.Fatalf("Successors = %v, want [%s]", status.Successors, b.fingerprint)
+	}
+
+	// Переданный ключ больше не подписывает
+	_, err = bc.AddBlock(deposit(a, "After rotation"))
+	if !errors.Is(err, ErrKeyRotated) {
+		t.Errorf("deposit by rotated key: error = %v, want ErrKeyRotated", err)
+	}
+	_, err = bc.AddBlock(deposit(b, "By successor"))
+	AssertNoError(t, err)
+
+	// Ротация обратно замкнула бы цепочку ключей
+	err = bc.CheckKeys(b.sign(NewKeyRotation(bc.HashAlgorithm(), b.public, a.public)))
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("rotation cycle: error = %v, want ErrInvalidRecord", err)
+	}
+
+	// Чужой ключ не может отозвать A, преемник - может
+	revokedAt := old.Timestamp.Add(-time.Hour)
+	err = bc.CheckKeys(c.sign(NewKeyRevocation(bc.HashAlgorithm(), c.public, a.public, revokedAt)))
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("revocation by stranger: error = %v, want ErrInvalidRecord", err)
+	}
+	revocation, err := bc.AddBlock(b.sign(NewKeyRevocation(bc.HashAlgorithm(), b.public, a.public, revokedAt)))
+	AssertNoError(t, err)
+
+	check := func(bc *Blockchain) {
+		t.Helper()
+		status := bc.KeyStatus(a.fingerprint)
+		if !status.Revoked {
+			t.Fatal("key A is not revoked")
+		}
+		AssertEqual(t, status.RevokedAt.Equal(revokedAt), true, "RevokedAt")
+		AssertEqual(t, status.RevocationRef, revocation.ID, "RevocationRef")
+		if !bc.ValidateChain() {
+			t.Error("Chain with key records is invalid")
+		}
+	}
+	check(bc)
+
+	// Состояние ключей восстанавливается при загрузке цепочки
+	reloaded, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	check(reloaded)
+
+	// Старый депозит A остался в цепочке, а в списке работ нет записей
+	receipts, err := reloaded.DepositsByKey(a.fingerprint)
+	AssertNoError(t, err)
+	AssertEqual(t, len(receipts), 1, "Deposits of A")
+	receipts, err = reloaded.DepositsByKey(b.fingerprint)
+	AssertNoError(t, err)
+	AssertEqual(t, len(receipts), 1, "Deposits of B")
+}
+
+func TestKeyRegistry_ApplyIsAtomic(t *testing.T) {
+	a, b, c := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+	registry := newKeyRegistry()
+
+	// Вторая ротация A в том же блоке недопустима: блок не применяется целиком
+	block := newBatchBlock("000-000-001", "prev", HashSHA256, []DepositData{
+		a.sign(NewKeyRotation(HashSHA256, a.public, b.public)),
+		a.sign(NewKeyRotation(HashSHA256, a.public, c.public)),
+	})
+	next, err := registry.apply(block)
+	if !errors.Is(err, ErrKeyRotated) {
+		t.Errorf("apply() error = %v, want ErrKeyRotated", err)
+	}
+	AssertEqual(t, next, registry, "Registry after failed apply")
+	AssertEqual(t, len(registry.successor), 0, "Successors after failed apply")
+}
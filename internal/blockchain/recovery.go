@@ -0,0 +1,272 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Option - параметр NewBlockchain
+type Option func(*chainOptions)
+
+// chainOptions - параметры открытия цепочки
+type chainOptions struct {
+	recovery      bool
+	quarantineDir string
+	retarget      *RetargetPolicy
+	target        *Target
+	hashAlg       HashAlgorithm
+	genesis       *Genesis
+	genesisHash   string
+	events        *EventBus
+}
+
+// WithRecovery разрешает NewBlockchain чинить повреждённую цепочку.
+//
+// Без этой опции NewBlockchain отказывается открывать цепочку, которая
+// не проходит проверку или чей WAL не читается, и ничего не меняет на
+// диске. С опцией цепочка обрезается до самого длинного валидного
+// префикса (или восстанавливается из бэкапа, продолжающего этот
+// префикс), а отброшенные блоки, копии исходных файлов хранилища и
+// отчёт об инциденте сохраняются в quarantineDir/<время>/
+func WithRecovery(quarantineDir string) Option {
+	return func(o *chainOptions) {
+		o.recovery = true
+		o.quarantineDir = quarantineDir
+	}
+}
+
+// forensicStore - хранилище, умеющее скопировать свои файлы "как есть"
+type forensicStore interface {
+	// CopyFiles копирует файлы хранилища в каталог dst и возвращает
+	// их пути относительно dst
+	CopyFiles(dst string) ([]string, error)
+}
+
+// InvalidBlock описывает первый блок, не прошедший проверку
+type InvalidBlock struct {
+	Height int    `json:"height"`
+	ID     string `json:"id"`
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
+// RecoveryReport - отчёт об инциденте, сохраняемый в карантине
+type RecoveryReport struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	Error  string    `json:"error"`
+
+	// Состояние до восстановления
+	OriginalLength int           `json:"original_length"`
+	ValidPrefix    int           `json:"valid_prefix"`
+	FirstInvalid   *InvalidBlock `json:"first_invalid,omitempty"`
+
+	// Что сохранено в карантине
+	Dir               string   `json:"dir"`
+	QuarantinedBlocks int      `json:"quarantined_blocks"`
+	OriginalFiles     []string `json:"original_files"`
+
+	// Результат
+	Backup          *BackupEntry `json:"backup,omitempty"`
+	RecoveredLength int          `json:"recovered_length"`
+	RecoveredTip    string       `json:"recovered_tip"`
+}
+
+const (
+	quarantineTimeFormat  = "20060102T150405.000Z"
+	quarantineReportName  = "report.json"
+	quarantineSuffixName  = "suffix.json"
+	quarantineOriginalDir = "original"
+)
+
+// recoverChain чинит цепочку после сбоя WAL или проверки целостности.
+// Ничего не удаляет, пока исходные файлы не скопированы в карантин
+func (bc *Blockchain) recoverChain(reason string, cause error, quarantineDir string) (*RecoveryReport, error) {
+	now := time.Now().UTC()
+	dir := filepath.Join(quarantineDir, now.Format(quarantineTimeFormat))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	report := &RecoveryReport{
+		Time:           now,
+		Reason:         reason,
+		Error:          cause.Error(),
+		OriginalLength: len(bc.Chain),
+		ValidPrefix:    len(bc.Chain),
+		Dir:            dir,
+	}
+
+	// Сначала сохраняем исходные файлы хранилища
+	if forensic, ok := bc.store.(forensicStore); ok {
+		files, err := forensic.CopyFiles(filepath.Join(dir, quarantineOriginalDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy original files: %w", err)
+		}
+		report.OriginalFiles = files
+	} else {
+		slog.Warn("Store cannot copy its files, quarantine will hold only the invalid suffix")
+	}
+
+	// Самый длинный валидный префикс
+	if height, err := bc.invalidBlock(bc.Chain); err != nil {
+		block := bc.Chain[height]
+		report.ValidPrefix = height
+		report.FirstInvalid = &InvalidBlock{
+			Height: height,
+			ID:     block.ID,
+			Hash:   block.Hash,
+			Reason: err.Error(),
+		}
+	}
+	prefix := bc.Chain[:report.ValidPrefix]
+	suffix := bc.Chain[report.ValidPrefix:]
+
+	if len(suffix) > 0 {
+		data, err := encodeChainFile(suffix)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileSync(OSFS{}, filepath.Join(dir, quarantineSuffixName), data); err != nil {
+			return nil, fmt.Errorf("failed to write quarantined blocks: %w", err)
+		}
+		report.QuarantinedBlocks = len(suffix)
+	}
+
+	// Бэкап годится, только если он валиден, длиннее префикса
+	// и продолжает его
+	var restored []*Block
+	if backups, ok := bc.store.(backupStore); ok && len(suffix) > 0 {
+		accept := func(chain []*Block) bool {
+			if len(chain) <= len(prefix) || !bc.validateBlocks(chain) {
+				return false
+			}
+			for i := range prefix {
+				if chain[i].Hash != prefix[i].Hash {
+					return false
+				}
+			}
+			restored = chain
+			return true
+		}
+		if entry, err := backups.RestoreFromBackup(accept); err == nil {
+			report.Backup = entry
+		} else {
+			restored = nil
+		}
+	}
+
+	recovered := prefix
+	if report.Backup != nil {
+		recovered = restored
+	}
+	if len(recovered) == 0 {
+		recovered = []*Block{bc.genesis.Block()}
+	}
+
+	bc.mu.Lock()
+	bc.Chain = recovered
+	bc.rebuildIndexes()
+	bc.mu.Unlock()
+
+	// Журнал должен совпадать с цепочкой в памяти,
+	// иначе новые блоки допишутся к повреждённым. Бэкап при этом
+	// не создаётся: исходные файлы уже в карантине, а лишний бэкап
+	// при KeepCount вытеснил бы хорошие, в том числе восстановленный
+	var err error
+	if backups, ok := bc.store.(backupStore); ok {
+		err = backups.rewriteChain(recovered)
+	} else {
+		err = bc.store.SaveChain(&Blockchain{Chain: recovered})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recovered chain: %w", err)
+	}
+
+	// WAL уже скопирован в карантин
+	if wal, ok := bc.store.(walStore); ok {
+		if err := wal.ClearWAL(); err != nil {
+			slog.Warn("Failed to clear WAL after recovery", "error", err)
+		}
+	}
+
+	report.RecoveredLength = len(recovered)
+	report.RecoveredTip = recovered[len(recovered)-1].Hash
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileSync(OSFS{}, filepath.Join(dir, quarantineReportName), data); err != nil {
+		return nil, fmt.Errorf("failed to write incident report: %w", err)
+	}
+
+	bc.events.Publish(Event{
+		Type:     EventChainRecovered,
+		Block:    recovered[len(recovered)-1],
+		Height:   len(recovered) - 1,
+		Recovery: report,
+	})
+
+	slog.Warn("Chain recovered",
+		"reason", reason,
+		"original_length", report.OriginalLength,
+		"recovered_length", report.RecoveredLength,
+		"quarantined_blocks", report.QuarantinedBlocks,
+		"report", filepath.Join(dir, quarantineReportName),
+	)
+
+	return report, nil
+}
+
+// copyFile копирует файл src из файловой системы srcFS в файл dst
+// файловой системы dstFS, создавая каталоги
+func copyFile(srcFS FS, src string, dstFS FS, dst string) error {
+	in, err := openFile(srcFS, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := dstFS.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	out, err := dstFS.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyFiles копирует существующие из files в dst под именами rel.
+// Отсутствующие файлы пропускаются
+func copyFiles(srcFS, dstFS FS, dst string, files map[string]string) ([]string, error) {
+	var copied []string
+	for rel, src := range files {
+		if _, err := srcFS.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(srcFS, src, dstFS, filepath.Join(dst, rel)); err != nil {
+			return copied, err
+		}
+		copied = append(copied, rel)
+	}
+	sort.Strings(copied)
+	return copied, nil
+}
@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readRecoveryReport читает единственный отчёт из каталога карантина
+func readRecoveryReport(t *testing.T, quarantineDir string) *RecoveryReport {
+	t.Helper()
+
+	dirs, err := os.ReadDir(quarantineDir)
+	AssertNoError(t, err, "Quarantine directory should exist")
+	AssertEqual(t, len(dirs), 1, "Incidents in quarantine")
+
+	data, err := os.ReadFile(filepath.Join(quarantineDir, dirs[0].Name(), quarantineReportName))
+	AssertNoError(t, err, "Incident report should exist")
+
+	var report RecoveryReport
+	AssertNoError(t, json.Unmarshal(data, &report))
+	return &report
+}
+
+func TestNewBlockchain_Recovery(t *testing.T) {
+	t.Run("truncates to valid prefix and quarantines suffix", func(t *testing.T) {
+		dataDir := t.TempDir()
+		storage, err := NewStorageWithOptions(dataDir, StoreOptions{})
+		AssertNoError(t, err)
+		defer storage.Close()
+
+		blocks := testChainBlocks(4)
+		blocks[2].Data.Title = "Tampered"
+		AssertNoError(t, storage.log.Reset(blocks))
+
+		// Без WithRecovery ничего не меняется
+		_, err = NewBlockchain(storage, 0)
+		var bcErr *BlockchainError
+		if !errors.As(err, &bcErr) || bcErr.Code != "RECOVERY_REQUIRED" {
+			t.Fatalf("NewBlockchain() error = %v, want RECOVERY_REQUIRED", err)
+		}
+		AssertEqual(t, storage.log.Len(), 4, "Log must stay untouched")
+
+		quarantine := filepath.Join(dataDir, "quarantine")
+		bc, err := NewBlockchain(storage, 0, WithRecovery(quarantine))
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.Chain), 2, "Recovered chain length")
+		AssertEqual(t, storage.log.Len(), 2, "Log should match recovered chain")
+
+		report := readRecoveryReport(t, quarantine)
+		AssertEqual(t, report.OriginalLength, 4, "Original length")
+		AssertEqual(t, report.ValidPrefix, 2, "Valid prefix")
+		AssertEqual(t, report.QuarantinedBlocks, 2, "Quarantined blocks")
+		AssertNotNil(t, report.FirstInvalid, "First invalid block")
+		AssertEqual(t, report.FirstInvalid.ID, blocks[2].ID, "First invalid block ID")
+
+		// Отброшенные блоки и исходный журнал сохранены
+		data, err := os.ReadFile(filepath.Join(report.Dir, quarantineSuffixName))
+		AssertNoError(t, err)
+		var suffix Blockchain
+		AssertNoError(t, json.Unmarshal(data, &suffix))
+		AssertEqual(t, len(suffix.Chain), 2, "Blocks in suffix.json")
+
+		_, err = os.Stat(filepath.Join(report.Dir, quarantineOriginalDir, "segments", "000001.seg"))
+		AssertNoError(t, err, "Original segment should be copied")
+	})
+
+	t.Run("unreadable WAL requires recovery", func(t *testing.T) {
+		dataDir := t.TempDir()
+		storage, err := NewStorageWithOptions(dataDir, StoreOptions{})
+		AssertNoError(t, err)
+		defer storage.Close()
+
+		blocks := testChainBlocks(3)
+		AssertNoError(t, storage.log.Reset(blocks[:2]))
+		AssertNoError(t, storage.WriteToWAL(blocks[2]))
+		data, _ := os.ReadFile(storage.GetWALPath())
+		AssertNoError(t, os.WriteFile(storage.GetWALPath(), append([]byte("garbage\n"), data...), 0644))
+
+		_, err = NewBlockchain(storage, 0)
+		AssertError(t, err, "NewBlockchain should refuse an unreadable WAL")
+
+		quarantine := filepath.Join(dataDir, "quarantine")
+		bc, err := NewBlockchain(storage, 0, WithRecovery(quarantine))
+		AssertNoError(t, err)
+		AssertEqual(t, len(bc.Chain), 2, "Chain is kept as is")
+
+		report := readRecoveryReport(t, quarantine)
+		AssertEqual(t, report.QuarantinedBlocks, 0, "No blocks quarantined")
+		_, err = os.Stat(filepath.Join(report.Dir, quarantineOriginalDir, "wal.json"))
+		AssertNoError(t, err, "Original WAL should be copied")
+
+		if storage.FileExists(storage.GetWALPath()) {
+			t.Error("WAL should be cleared after recovery")
+		}
+	})
+
+	t.Run("keeps backups as they are", func(t *testing.T) {
+		dataDir := t.TempDir()
+		storage, err := NewStorageWithOptions(dataDir, StoreOptions{Backups: BackupPolicy{KeepCount: 2}})
+		AssertNoError(t, err)
+		defer storage.Close()
+
+		blocks := testChainBlocks(4)
+		AssertNoError(t, storage.log.Reset(blocks[:2]))
+		AssertNoError(t, storage.CreateBackup())
+		AssertNoError(t, storage.log.Reset(blocks[:3]))
+		AssertNoError(t, storage.CreateBackup())
+		before, err := storage.Backups()
+		AssertNoError(t, err)
+
+		blocks[2].Data.Title = "Tampered"
+		AssertNoError(t, storage.log.Reset(blocks))
+
+		// Повторное восстановление не вытесняет бэкапы новыми
+		for i := 0; i < 2; i++ {
+			bc, err := NewBlockchain(storage, 0, WithRecovery(filepath.Join(dataDir, "quarantine")))
+			AssertNoError(t, err)
+			AssertEqual(t, len(bc.Chain), 3, "Chain restored from backup")
+			AssertNoError(t, storage.log.Reset(blocks))
+		}
+
+		after, err := storage.Backups()
+		AssertNoError(t, err)
+		AssertEqual(t, len(after), len(before), "Backups after recovery")
+		for i := range before {
+			AssertEqual(t, after[i].File, before[i].File, "Backup file")
+		}
+	})
+}
@@ -0,0 +1,845 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Формат журнала блоков
+//
+// Журнал хранится в каталоге segments/ в виде последовательности файлов
+// 000001.seg, 000002.seg, ... Каждый сегмент начинается с заголовка
+// segmentMagic, за которым идут записи:
+//
+//	[4 байта длина payload, big-endian]
+//	[4 байта CRC-32C payload, big-endian]
+//	[payload: JSON блока]
+//
+// Когда размер сегмента превышает maxSegmentSize, следующая запись
+// открывает новый сегмент. Записи только дописываются в конец, каждая
+// фиксируется через fsync.
+//
+// Рядом лежит компактный индекс blocks.idx: по одной записи фиксированной
+// длины indexEntrySize на каждую высоту. Индекс производный — при
+// открытии каждая запись сверяется с сегментом, и при расхождении
+// индекс перестраивается сканированием.
+const (
+	segmentMagic       = "TPSEG\x00\x00\x01"
+	segmentExt         = ".seg"
+	segmentIndexFile   = "blocks.idx"
+	recordHeaderSize   = 8
+	indexEntrySize     = 32
+	indexIDSize        = 16
+	maxRecordSize      = 64 << 20 // 64MB - защита от мусорной длины
+	DefaultSegmentSize = 16 << 20 // 16MB
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorruptRecord сигнализирует о повреждённой записи в сегменте
+var errCorruptRecord = errors.New("corrupt segment record")
+
+// segmentPos указывает на запись блока внутри сегмента
+type segmentPos struct {
+	segment uint32
+	offset  int64
+	size    uint32
+	id      string
+}
+
+// SegmentLog - append-only журнал блоков с ротацией сегментов
+type SegmentLog struct {
+	mu sync.Mutex
+
+	fs             FS
+	dir            string
+	maxSegmentSize int64
+	readOnly       bool
+
+	positions []segmentPos   // высота -> позиция записи
+	ids       map[string]int // ID блока -> высота
+	active    File           // текущий сегмент для дозаписи
+	activeNum uint32         // номер текущего сегмента
+	activeLen int64          // размер текущего сегмента
+	index     File           // файл индекса
+
+	// indexDirty - запись в индекс не удалась: следующая запись
+	// перестраивает его из positions целиком
+	indexDirty bool
+}
+
+// OpenSegmentLog открывает (или создаёт) журнал в каталоге dir
+func OpenSegmentLog(dir string, maxSegmentSize int64) (*SegmentLog, error) {
+	return openSegmentLog(OSFS{}, dir, maxSegmentSize, false)
+}
+
+// openSegmentLog открывает журнал поверх файловой системы fsys.
+//
+// Журнал, открытый только для чтения, ничего не пишет на диск: не
+// обрезает недописанный хвост, не чинит индекс и не принимает
+// новых блоков. Так его можно открыть рядом с работающим сервером
+func openSegmentLog(fsys FS, dir string, maxSegmentSize int64, readOnly bool) (*SegmentLog, error) {
+	if maxSegmentSize <= 0 {
+		maxSegmentSize = DefaultSegmentSize
+	}
+
+	if !readOnly {
+		if err := finishReset(fsys, dir); err != nil {
+			return nil, err
+		}
+
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create segment directory: %w", err)
+		}
+	}
+
+	l := &SegmentLog{
+		fs:             fsys,
+		dir:            dir,
+		maxSegmentSize: maxSegmentSize,
+		readOnly:       readOnly,
+		ids:            make(map[string]int),
+	}
+
+	if err := l.recover(); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// segmentPath возвращает путь к сегменту с номером num
+func (l *SegmentLog) segmentPath(num uint32) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%06d%s", num, segmentExt))
+}
+
+// listSegments возвращает номера существующих сегментов по возрастанию
+func (l *SegmentLog) listSegments() ([]uint32, error) {
+	entries, err := l.fs.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment directory: %w", err)
+	}
+
+	var nums []uint32
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		var num uint32
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, segmentExt), "%d", &num); err != nil {
+			continue
+		}
+		nums = append(nums, num)
+	}
+
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	return nums, nil
+}
+
+// recover восстанавливает состояние журнала: читает индекс, досканирует
+// сегменты за последней проиндексированной записью и обрезает
+// недописанный хвост последнего сегмента
+func (l *SegmentLog) recover() error {
+	nums, err := l.listSegments()
+	if err != nil {
+		return err
+	}
+
+	indexed, clean := l.readIndex(nums)
+
+	// Сканируем сегменты начиная с того, где закончился индекс
+	var startSeg uint32
+	var startOff int64
+	if n := len(indexed); n > 0 {
+		last := indexed[n-1]
+		startSeg = last.segment
+		startOff = last.offset + recordHeaderSize + int64(last.size)
+	}
+
+	positions := indexed
+	for i, num := range nums {
+		if num < startSeg {
+			continue
+		}
+
+		offset := int64(len(segmentMagic))
+		if num == startSeg && startOff > 0 {
+			offset = startOff
+		}
+
+		found, end, err := l.scanSegment(num, offset)
+		if err != nil && !errors.Is(err, errCorruptRecord) {
+			return err
+		}
+
+		if errors.Is(err, errCorruptRecord) {
+			// Повреждение допустимо только в хвосте последнего сегмента:
+			// это незавершённая запись, прерванная сбоем
+			if i != len(nums)-1 {
+				return fmt.Errorf("segment %06d: %w at offset %d", num, err, end)
+			}
+			if l.readOnly {
+				// Запись может дописываться прямо сейчас - просто не читаем её
+				positions = append(positions, found...)
+				break
+			}
+			slog.Warn("Truncating torn tail of segment log",
+				"segment", num, "offset", end)
+			if err := l.fs.Truncate(l.segmentPath(num), end); err != nil {
+				return fmt.Errorf("failed to truncate segment: %w", err)
+			}
+		}
+
+		positions = append(positions, found...)
+	}
+
+	l.positions = positions
+	for height, pos := range positions {
+		l.ids[pos.id] = height
+	}
+
+	if l.readOnly {
+		return nil
+	}
+
+	// Индекс мог отстать от сегментов или разойтись с ними -
+	// переписываем его целиком
+	if !clean {
+		slog.Warn("Rebuilding segment index", "indexed", len(indexed), "blocks", len(positions))
+	}
+	if !clean || len(positions) != len(indexed) {
+		if err := l.rewriteIndex(); err != nil {
+			return err
+		}
+	}
+
+	if l.index == nil {
+		index, err := l.fs.OpenFile(filepath.Join(l.dir, segmentIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open segment index: %w", err)
+		}
+		l.index = index
+	}
+
+	// Открываем последний сегмент для дозаписи
+	if len(nums) == 0 {
+		return l.openSegment(1)
+	}
+	return l.openSegment(nums[len(nums)-1])
+}
+
+// readIndex читает файл индекса и сверяет его записи с сегментами nums:
+// записи идут подряд без промежутков, с первого сегмента, а по смещению
+// каждой лежит целая запись блока с тем же ID. Возвращает проверенный
+// префикс индекса; clean - индекс совпал с ним целиком, иначе его
+// нужно перестроить
+func (l *SegmentLog) readIndex(nums []uint32) (positions []segmentPos, clean bool) {
+	data, err := l.fs.ReadFile(filepath.Join(l.dir, segmentIndexFile))
+	if err != nil {
+		return nil, os.IsNotExist(err)
+	}
+
+	var f File
+	var current uint32
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	sizes := make(map[uint32]int64)
+	positions = make([]segmentPos, 0, len(data)/indexEntrySize)
+	for off := 0; off+indexEntrySize <= len(data); off += indexEntrySize {
+		entry := data[off : off+indexEntrySize]
+		pos := segmentPos{
+			segment: binary.BigEndian.Uint32(entry[0:4]),
+			offset:  int64(binary.BigEndian.Uint64(entry[4:12])),
+			size:    binary.BigEndian.Uint32(entry[12:16]),
+			id:      string(bytes.TrimRight(entry[16:32], "\x00")),
+		}
+
+		// Запись продолжает предыдущую или открывает следующий сегмент,
+		// а предыдущий сегмент тогда кончается на предыдущей записи
+		want := int64(len(segmentMagic))
+		if n := len(positions); n > 0 {
+			prev := positions[n-1]
+			prevEnd := prev.offset + recordHeaderSize + int64(prev.size)
+			switch {
+			case pos.segment == prev.segment:
+				want = prevEnd
+			case pos.segment != prev.segment+1 || sizes[prev.segment] != prevEnd:
+				return positions, false
+			}
+		} else if len(nums) == 0 || pos.segment != nums[0] {
+			return positions, false
+		}
+		if pos.offset != want {
+			return positions, false
+		}
+
+		if f == nil || pos.segment != current {
+			if f != nil {
+				f.Close()
+			}
+			if f, err = openFile(l.fs, l.segmentPath(pos.segment)); err != nil {
+				return positions, false
+			}
+			current = pos.segment
+			info, err := f.Stat()
+			if err != nil {
+				return positions, false
+			}
+			sizes[pos.segment] = info.Size()
+		}
+		if pos.offset+recordHeaderSize+int64(pos.size) > sizes[pos.segment] {
+			return positions, false
+		}
+
+		payload, err := readRecord(io.NewSectionReader(f, pos.offset, recordHeaderSize+int64(pos.size)))
+		if err != nil || len(payload) != int(pos.size) {
+			return positions, false
+		}
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(payload, &header); err != nil || header.ID != pos.id {
+			return positions, false
+		}
+		positions = append(positions, pos)
+	}
+
+	return positions, len(data)%indexEntrySize == 0
+}
+
+// scanSegment читает записи сегмента num начиная со смещения offset.
+// Возвращает найденные позиции и смещение конца последней целой записи
+func (l *SegmentLog) scanSegment(num uint32, offset int64) ([]segmentPos, int64, error) {
+	f, err := openFile(l.fs, l.segmentPath(num))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(segmentMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != segmentMagic {
+		return nil, 0, fmt.Errorf("%w: bad segment header", errCorruptRecord)
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+
+	r := bufio.NewReader(f)
+	var found []segmentPos
+	for {
+		payload, err := readRecord(r)
+		if err == io.EOF {
+			return found, offset, nil
+		}
+		if err != nil {
+			return found, offset, err
+		}
+
+		var block Block
+		if err := json.Unmarshal(payload, &block); err != nil {
+			return found, offset, fmt.Errorf("%w: %v", errCorruptRecord, err)
+		}
+
+		found = append(found, segmentPos{
+			segment: num,
+			offset:  offset,
+			size:    uint32(len(payload)),
+			id:      block.ID,
+		})
+		offset += recordHeaderSize + int64(len(payload))
+	}
+}
+
+// readRecord читает одну запись и проверяет её контрольную сумму
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: short header (%d bytes)", errCorruptRecord, n)
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if size == 0 || size > maxRecordSize {
+		return nil, fmt.Errorf("%w: invalid length %d", errCorruptRecord, size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("%w: short payload", errCorruptRecord)
+	}
+
+	if crc32.Checksum(payload, castagnoli) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", errCorruptRecord)
+	}
+
+	return payload, nil
+}
+
+// encodeRecord кодирует payload в запись с заголовком
+func encodeRecord(payload []byte) []byte {
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, castagnoli))
+	copy(record[recordHeaderSize:], payload)
+	return record
+}
+
+// encodeIndexEntry кодирует позицию в запись индекса
+func encodeIndexEntry(pos segmentPos) []byte {
+	entry := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint32(entry[0:4], pos.segment)
+	binary.BigEndian.PutUint64(entry[4:12], uint64(pos.offset))
+	binary.BigEndian.PutUint32(entry[12:16], pos.size)
+	copy(entry[16:32], pos.id)
+	return entry
+}
+
+// openSegment открывает сегмент num для дозаписи, создавая его при необходимости
+func (l *SegmentLog) openSegment(num uint32) error {
+	if l.active != nil {
+		l.active.Close()
+		l.active = nil
+	}
+
+	f, err := l.fs.OpenFile(l.segmentPath(num), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat segment: %w", err)
+	}
+
+	size := info.Size()
+	if size < int64(len(segmentMagic)) {
+		// Новый (или обрезанный до заголовка) сегмент
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to reset segment: %w", err)
+		}
+		if _, err := f.WriteAt([]byte(segmentMagic), 0); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write segment header: %w", err)
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync segment: %w", err)
+		}
+		size = int64(len(segmentMagic))
+	}
+
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	l.active = f
+	l.activeNum = num
+	l.activeLen = size
+	return nil
+}
+
+// Append дописывает блок в конец журнала и фиксирует запись через fsync
+func (l *SegmentLog) Append(block *Block) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.appendLocked(block)
+}
+
+func (l *SegmentLog) appendLocked(block *Block) error {
+	if l.readOnly {
+		return ErrReadOnly
+	}
+	if l.active == nil {
+		return fmt.Errorf("segment log is closed")
+	}
+
+	if len(block.ID) > indexIDSize {
+		return fmt.Errorf("block ID %q is too long for segment index", block.ID)
+	}
+
+	payload, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to marshal block: %w", err)
+	}
+	record := encodeRecord(payload)
+
+	// Ротация: открываем новый сегмент, если текущий переполнится
+	if l.activeLen > int64(len(segmentMagic)) && l.activeLen+int64(len(record)) > l.maxSegmentSize {
+		if err := l.openSegment(l.activeNum + 1); err != nil {
+			return err
+		}
+	}
+
+	pos := segmentPos{
+		segment: l.activeNum,
+		offset:  l.activeLen,
+		size:    uint32(len(payload)),
+		id:      block.ID,
+	}
+
+	if _, err := l.active.Write(record); err != nil {
+		l.rollbackActive()
+		return fmt.Errorf("failed to write segment record: %w", err)
+	}
+	if err := l.active.Sync(); err != nil {
+		// Запись не подтверждена - не должна стать видимой после сбоя
+		l.rollbackActive()
+		return fmt.Errorf("failed to sync segment: %w", err)
+	}
+	l.activeLen += int64(len(record))
+
+	l.ids[block.ID] = len(l.positions)
+	l.positions = append(l.positions, pos)
+
+	// Индекс производный: сбой записи в него не отменяет блок
+	if err := l.appendIndex(pos); err != nil {
+		slog.Warn("Failed to update segment index", "error", err)
+	}
+	return nil
+}
+
+// appendIndex дописывает в индекс запись pos - последней позиции журнала.
+// Частично записанная запись отрезается, чтобы не сдвинуть следующие,
+// а индекс помечается грязным: следующая запись перестраивает его
+// из памяти целиком
+func (l *SegmentLog) appendIndex(pos segmentPos) error {
+	if l.indexDirty {
+		if err := l.rewriteIndex(); err != nil {
+			return err
+		}
+		l.indexDirty = false
+		return nil
+	}
+
+	if _, err := l.index.Write(encodeIndexEntry(pos)); err != nil {
+		l.indexDirty = true
+		if terr := l.index.Truncate(int64(len(l.positions)-1) * indexEntrySize); terr != nil {
+			slog.Warn("Failed to truncate segment index", "error", terr)
+		}
+		return err
+	}
+	return nil
+}
+
+// rollbackActive отрезает частично записанную запись в конце
+// текущего сегмента
+func (l *SegmentLog) rollbackActive() {
+	l.active.Truncate(l.activeLen)
+	l.active.Seek(l.activeLen, io.SeekStart)
+}
+
+// rewriteIndex атомарно перезаписывает файл индекса из памяти
+func (l *SegmentLog) rewriteIndex() error {
+	buf := make([]byte, 0, len(l.positions)*indexEntrySize)
+	for _, pos := range l.positions {
+		buf = append(buf, encodeIndexEntry(pos)...)
+	}
+
+	path := filepath.Join(l.dir, segmentIndexFile)
+	tmp := path + ".tmp"
+	if err := writeFileSync(l.fs, tmp, buf); err != nil {
+		return fmt.Errorf("failed to write segment index: %w", err)
+	}
+	if err := l.fs.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace segment index: %w", err)
+	}
+
+	if l.index != nil {
+		l.index.Close()
+	}
+	index, err := l.fs.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment index: %w", err)
+	}
+	l.index = index
+	return nil
+}
+
+// Len возвращает количество блоков в журнале
+func (l *SegmentLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.positions)
+}
+
+// HeightOf возвращает высоту блока с данным ID
+func (l *SegmentLog) HeightOf(id string) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	height, ok := l.ids[id]
+	return height, ok
+}
+
+// Read читает блок на высоте height
+func (l *SegmentLog) Read(height int) (*Block, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if height < 0 || height >= len(l.positions) {
+		return nil, ErrBlockNotFound
+	}
+	pos := l.positions[height]
+
+	f, err := openFile(l.fs, l.segmentPath(pos.segment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer f.Close()
+
+	payload, err := readRecord(io.NewSectionReader(f, pos.offset, recordHeaderSize+int64(pos.size)))
+	if err != nil {
+		return nil, err
+	}
+
+	var block Block
+	if err := json.Unmarshal(payload, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block: %w", err)
+	}
+	return &block, nil
+}
+
+// ReadAll читает все блоки журнала по порядку высоты
+func (l *SegmentLog) ReadAll() ([]*Block, error) {
+	payloads, err := l.readPayloads()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*Block, 0, len(payloads))
+	for _, payload := range payloads {
+		var block Block
+		if err := json.Unmarshal(payload, &block); err != nil {
+			return nil, fmt.Errorf("failed to parse block: %w", err)
+		}
+		blocks = append(blocks, &block)
+	}
+
+	return blocks, nil
+}
+
+// readPayloads читает JSON всех блоков журнала по порядку высоты,
+// не разбирая его. Нужен миграциям формата
+func (l *SegmentLog) readPayloads() ([][]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	payloads := make([][]byte, 0, len(l.positions))
+
+	var current uint32
+	var r *bufio.Reader
+	var f File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for _, pos := range l.positions {
+		if f == nil || pos.segment != current {
+			if f != nil {
+				f.Close()
+			}
+			var err error
+			f, err = openFile(l.fs, l.segmentPath(pos.segment))
+			if err != nil {
+				return nil, fmt.Errorf("failed to open segment: %w", err)
+			}
+			current = pos.segment
+			if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			r = bufio.NewReader(f)
+		}
+
+		payload, err := readRecord(r)
+		if err != nil {
+			return nil, fmt.Errorf("segment %06d offset %d: %w", pos.segment, pos.offset, err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return payloads, nil
+}
+
+// Reset полностью заменяет содержимое журнала переданными блоками.
+// Используется при миграции и восстановлении, а не на горячем пути.
+//
+// Новый журнал пишется в соседний каталог <dir>.tmp, фиксируется на
+// диске и подменяет старый двумя переименованиями, после которых
+// фиксируется родительский каталог. Если подмена не удалась, старый
+// журнал возвращается на место и открывается заново; OpenSegmentLog
+// доводит до конца подмену, прерванную сбоем
+func (l *SegmentLog) Reset(blocks []*Block) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readOnly {
+		return ErrReadOnly
+	}
+
+	tmpDir := l.dir + ".tmp"
+	oldDir := l.dir + ".old"
+
+	if err := l.fs.RemoveAll(tmpDir); err != nil {
+		return fmt.Errorf("failed to clean temp segment directory: %w", err)
+	}
+
+	if err := writeSegmentDir(l.fs, tmpDir, l.maxSegmentSize, blocks); err != nil {
+		l.removeTemp(tmpDir)
+		return err
+	}
+
+	l.closeFiles()
+
+	if err := l.fs.Rename(l.dir, oldDir); err != nil {
+		l.removeTemp(tmpDir)
+		return l.reopen(fmt.Errorf("failed to move old segments aside: %w", err))
+	}
+	if err := l.fs.Rename(tmpDir, l.dir); err != nil {
+		return l.rollbackReset(fmt.Errorf("failed to install new segments: %w", err))
+	}
+	if err := l.fs.SyncDir(filepath.Dir(l.dir)); err != nil {
+		// Подмена могла не дойти до диска: откатываем, чтобы память
+		// и диск не разошлись после сбоя
+		if rbErr := l.fs.Rename(l.dir, tmpDir); rbErr != nil {
+			// Новый журнал остался на месте - работаем с ним
+			return l.reopen(fmt.Errorf("failed to sync segment directory: %w (rollback failed: %v)", err, rbErr))
+		}
+		return l.rollbackReset(fmt.Errorf("failed to sync segment directory: %w", err))
+	}
+	if err := l.fs.RemoveAll(oldDir); err != nil {
+		slog.Warn("Failed to remove old segments", "dir", oldDir, "error", err)
+	}
+
+	l.positions = nil
+	l.ids = make(map[string]int, len(blocks))
+	return l.recover()
+}
+
+// writeSegmentDir записывает blocks новым журналом в каталог dir и
+// фиксирует его на диске
+func writeSegmentDir(fsys FS, dir string, maxSegmentSize int64, blocks []*Block) error {
+	fresh, err := openSegmentLog(fsys, dir, maxSegmentSize, false)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if err := fresh.Append(block); err != nil {
+			fresh.Close()
+			return err
+		}
+	}
+	if err := fresh.Close(); err != nil {
+		return err
+	}
+	if err := fsys.SyncDir(dir); err != nil {
+		return fmt.Errorf("failed to sync new segments: %w", err)
+	}
+	return nil
+}
+
+// rollbackReset возвращает отодвинутый старый журнал на место
+// нового, который не удалось установить, и открывает его заново
+func (l *SegmentLog) rollbackReset(cause error) error {
+	if err := l.fs.Rename(l.dir+".old", l.dir); err != nil {
+		// Каталога журнала нет: при следующем открытии OpenSegmentLog
+		// установит новый журнал из <dir>.tmp, он уже записан целиком
+		return fmt.Errorf("%w (rollback failed: %v)", cause, err)
+	}
+	l.removeTemp(l.dir + ".tmp")
+	return l.reopen(cause)
+}
+
+// reopen заново открывает файлы журнала после неудачной подмены.
+// Возвращает cause, если журнал открылся
+func (l *SegmentLog) reopen(cause error) error {
+	l.positions = nil
+	l.ids = make(map[string]int)
+	if err := l.recover(); err != nil {
+		return fmt.Errorf("%w (reopen failed: %v)", cause, err)
+	}
+	return cause
+}
+
+// removeTemp удаляет недописанный или отменённый новый журнал
+func (l *SegmentLog) removeTemp(tmpDir string) {
+	if err := l.fs.RemoveAll(tmpDir); err != nil {
+		slog.Warn("Failed to remove temp segments", "dir", tmpDir, "error", err)
+	}
+}
+
+// finishReset доводит до конца подмену каталога, прерванную сбоем
+func finishReset(fsys FS, dir string) error {
+	tmpDir := dir + ".tmp"
+	oldDir := dir + ".old"
+
+	if _, err := fsys.Stat(dir); os.IsNotExist(err) {
+		// Сбой между переименованиями: новый журнал уже полностью записан
+		if _, err := fsys.Stat(tmpDir); err == nil {
+			if err := fsys.Rename(tmpDir, dir); err != nil {
+				return fmt.Errorf("failed to finish segment reset: %w", err)
+			}
+		}
+	}
+
+	// Недописанный новый журнал или неудалённый старый
+	if err := fsys.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	return fsys.RemoveAll(oldDir)
+}
+
+// closeFiles закрывает открытые файлы журнала
+func (l *SegmentLog) closeFiles() error {
+	var firstErr error
+	if l.active != nil {
+		if err := l.active.Close(); err != nil {
+			firstErr = err
+		}
+		l.active = nil
+	}
+	if l.index != nil {
+		if err := l.index.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		l.index = nil
+	}
+	return firstErr
+}
+
+// Close закрывает файлы журнала
+func (l *SegmentLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.closeFiles()
+}
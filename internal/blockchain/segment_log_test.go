@@ -0,0 +1,326 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestSegmentLog открывает журнал во временном каталоге
+func newTestSegmentLog(t *testing.T, maxSegmentSize int64) (*SegmentLog, string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "segments")
+	log, err := OpenSegmentLog(dir, maxSegmentSize)
+	if err != nil {
+		t.Fatalf("OpenSegmentLog() error = %v", err)
+	}
+	return log, dir
+}
+
+// testChainBlocks возвращает n связанных блоков без майнинга
+func testChainBlocks(n int) []*Block {
+	blocks := []*Block{GenesisBlock()}
+	for i := 1; i < n; i++ {
+		data := CreateTestBlock(fmt.Sprintf("Author%d", i), "Title", fmt.Sprintf("Text %d", i))
+		id, _ := incrementID(blocks[i-1].ID)
+		block := NewBlock(id, blocks[i-1].Hash, data)
+		block.Hash = block.CalculateHash()
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func TestSegmentLog_AppendAndReopen(t *testing.T) {
+	log, dir := newTestSegmentLog(t, 0)
+
+	blocks := testChainBlocks(5)
+	for _, block := range blocks {
+		AssertNoError(t, log.Append(block))
+	}
+	AssertEqual(t, log.Len(), 5, "Len after append")
+	AssertNoError(t, log.Close())
+
+	reopened, err := OpenSegmentLog(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	loaded, err := reopened.ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, len(loaded), 5, "Loaded blocks")
+	for i := range blocks {
+		AssertEqual(t, loaded[i].Hash, blocks[i].Hash, "Block hash at height", i)
+	}
+
+	height, ok := reopened.HeightOf(blocks[3].ID)
+	if !ok || height != 3 {
+		t.Errorf("HeightOf(%s) = %d, %v, want 3, true", blocks[3].ID, height, ok)
+	}
+
+	block, err := reopened.Read(2)
+	AssertNoError(t, err)
+	AssertEqual(t, block.ID, blocks[2].ID, "Read(2)")
+}
+
+func TestSegmentLog_Rotation(t *testing.T) {
+	// Маленький предел - каждый блок в своём сегменте
+	log, dir := newTestSegmentLog(t, 64)
+	defer log.Close()
+
+	for _, block := range testChainBlocks(4) {
+		AssertNoError(t, log.Append(block))
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	AssertEqual(t, len(segments), 4, "Segment count")
+
+	loaded, err := log.ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, len(loaded), 4, "Loaded blocks")
+}
+
+func TestSegmentLog_TornTail(t *testing.T) {
+	log, dir := newTestSegmentLog(t, 0)
+	for _, block := range testChainBlocks(3) {
+		AssertNoError(t, log.Append(block))
+	}
+	log.Close()
+
+	// Имитируем сбой посреди записи: дописываем половину записи
+	path := filepath.Join(dir, "000001"+segmentExt)
+	record := encodeRecord([]byte(`{"id":"000-000-003"}`))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	AssertNoError(t, err)
+	f.Write(record[:len(record)/2])
+	f.Close()
+
+	reopened, err := OpenSegmentLog(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+	AssertEqual(t, reopened.Len(), 3, "Torn record should be dropped")
+
+	// После обрезки хвоста журнал принимает новые записи
+	extra := testChainBlocks(4)[3]
+	AssertNoError(t, reopened.Append(extra))
+	loaded, err := reopened.ReadAll()
+	AssertNoError(t, err)
+	AssertEqual(t, len(loaded), 4, "Blocks after append")
+}
+
+func TestSegmentLog_IndexRebuild(t *testing.T) {
+	log, dir := newTestSegmentLog(t, 0)
+	for _, block := range testChainBlocks(3) {
+		AssertNoError(t, log.Append(block))
+	}
+	log.Close()
+
+	// Индекс потерян - журнал перестраивает его по сегментам
+	AssertNoError(t, os.Remove(filepath.Join(dir, segmentIndexFile)))
+
+	reopened, err := OpenSegmentLog(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	AssertEqual(t, reopened.Len(), 3, "Len after index rebuild")
+	info, err := os.Stat(filepath.Join(dir, segmentIndexFile))
+	AssertNoError(t, err)
+	AssertEqual(t, info.Size(), int64(3*indexEntrySize), "Rebuilt index size")
+}
+
+func TestSegmentLog_IndexWriteFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults []Fault
+	}{
+		{"short write", []Fault{{Op: FSOpWrite, Path: segmentIndexFile, After: 2, ShortWrite: 10}}},
+		{"failed write", []Fault{{Op: FSOpWrite, Path: segmentIndexFile, After: 2}}},
+		{"short write not truncated", []Fault{
+			{Op: FSOpWrite, Path: segmentIndexFile, After: 2, ShortWrite: 10},
+			{Op: FSOpTruncate, Path: segmentIndexFile},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			log, err := openSegmentLog(fsys, "/data/segments", 0, false)
+			AssertNoError(t, err)
+
+			blocks := testChainBlocks(5)
+			for _, f := range tt.faults {
+				fsys.InjectFault(f)
+			}
+			// Блок пишется, даже если его запись в индексе не удалась
+			for _, block := range blocks {
+				AssertNoError(t, log.Append(block))
+			}
+			AssertNoError(t, log.Close())
+
+			reopened, err := openSegmentLog(fsys, "/data/segments", 0, false)
+			AssertNoError(t, err)
+			defer reopened.Close()
+
+			loaded, err := reopened.ReadAll()
+			AssertNoError(t, err)
+			AssertEqual(t, len(loaded), len(blocks), "Blocks after reopen")
+			for i, block := range loaded {
+				AssertEqual(t, block.ID, blocks[i].ID, "Block at height %d", i)
+			}
+
+			indexed, clean := reopened.readIndex([]uint32{1})
+			AssertEqual(t, clean, true, "Index is clean")
+			AssertEqual(t, len(indexed), len(blocks), "Indexed blocks")
+		})
+	}
+}
+
+func TestSegmentLog_IndexMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(index []byte) []byte
+	}{
+		{"missing entry", func(index []byte) []byte {
+			return append(index[:indexEntrySize:indexEntrySize], index[2*indexEntrySize:]...)
+		}},
+		{"misaligned tail", func(index []byte) []byte {
+			return append(index[:indexEntrySize:indexEntrySize], index[indexEntrySize+5:]...)
+		}},
+		{"swapped ids", func(index []byte) []byte {
+			a, b := index[indexEntrySize+16:2*indexEntrySize], index[2*indexEntrySize+16:3*indexEntrySize]
+			tmp := append([]byte(nil), a...)
+			copy(a, b)
+			copy(b, tmp)
+			return index
+		}},
+		{"offset into record", func(index []byte) []byte {
+			binary.BigEndian.PutUint64(index[indexEntrySize+4:], uint64(len(segmentMagic))+recordHeaderSize)
+			return index
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, dir := newTestSegmentLog(t, 0)
+			blocks := testChainBlocks(4)
+			for _, block := range blocks {
+				AssertNoError(t, log.Append(block))
+			}
+			log.Close()
+
+			path := filepath.Join(dir, segmentIndexFile)
+			index, err := os.ReadFile(path)
+			AssertNoError(t, err)
+			AssertNoError(t, os.WriteFile(path, tt.corrupt(index), 0644))
+
+			reopened, err := OpenSegmentLog(dir, 0)
+			if err != nil {
+				t.Fatalf("reopen error = %v", err)
+			}
+			defer reopened.Close()
+
+			AssertEqual(t, reopened.Len(), len(blocks), "Len after reopen")
+			for i, block := range blocks {
+				height, ok := reopened.HeightOf(block.ID)
+				AssertEqual(t, ok, true, "Block %s found", block.ID)
+				AssertEqual(t, height, i, "Height of %s", block.ID)
+			}
+			rebuilt, err := os.ReadFile(path)
+			AssertNoError(t, err)
+			AssertEqual(t, len(rebuilt), len(index), "Rebuilt index size")
+		})
+	}
+}
+
+func TestSegmentLog_Reset(t *testing.T) {
+	t.Run("replace contents", func(t *testing.T) {
+		log, _ := newTestSegmentLog(t, 0)
+		defer log.Close()
+
+		blocks := testChainBlocks(4)
+		for _, block := range blocks {
+			AssertNoError(t, log.Append(block))
+		}
+
+		AssertNoError(t, log.Reset(blocks[:2]))
+		AssertEqual(t, log.Len(), 2, "Len after reset")
+
+		AssertNoError(t, log.Append(blocks[2]))
+		loaded, err := log.ReadAll()
+		AssertNoError(t, err)
+		AssertEqual(t, len(loaded), 3, "Blocks after reset and append")
+	})
+
+	t.Run("failed swap keeps old log", func(t *testing.T) {
+		blocks := testChainBlocks(4)
+
+		// Сбой на каждом по очереди переименовании и fsync подмены
+		for _, op := range []string{FSOpRename, FSOpSync} {
+			for after := 0; ; after++ {
+				fsys := NewMemFS()
+				log, err := openSegmentLog(fsys, "/data/segments", 0, false)
+				AssertNoError(t, err)
+				for _, block := range blocks[:3] {
+					AssertNoError(t, log.Append(block))
+				}
+
+				fault := Fault{Op: op, Path: "/data", After: after}
+				fsys.InjectFault(fault)
+				err = log.Reset(blocks[:2])
+				if !fsys.faults[0].fired {
+					log.Close()
+					break
+				}
+
+				// Ошибка - остался старый журнал, успех - новый
+				point := fmt.Sprintf("%s#%d", op, after)
+				want := 2
+				if err != nil {
+					want = 3
+				}
+				AssertEqual(t, log.Len(), want, point+": Len after reset")
+				AssertNoError(t, log.Append(blocks[want]), point+": Append after reset")
+				log.Close()
+
+				reopened, err := openSegmentLog(fsys, "/data/segments", 0, false)
+				AssertNoError(t, err, point+": reopen")
+				AssertEqual(t, reopened.Len(), want+1, point+": Len after reopen")
+				reopened.Close()
+			}
+		}
+	})
+
+	t.Run("crash between renames", func(t *testing.T) {
+		log, dir := newTestSegmentLog(t, 0)
+		blocks := testChainBlocks(3)
+		for _, block := range blocks {
+			AssertNoError(t, log.Append(block))
+		}
+		log.Close()
+
+		// Новый журнал записан, старый отодвинут, но подмена не завершена
+		fresh, err := OpenSegmentLog(dir+".tmp", 0)
+		AssertNoError(t, err)
+		AssertNoError(t, fresh.Append(blocks[0]))
+		fresh.Close()
+		AssertNoError(t, os.Rename(dir, dir+".old"))
+
+		reopened, err := OpenSegmentLog(dir, 0)
+		if err != nil {
+			t.Fatalf("reopen error = %v", err)
+		}
+		defer reopened.Close()
+
+		AssertEqual(t, reopened.Len(), 1, "New log should be installed")
+		if _, err := os.Stat(dir + ".old"); !os.IsNotExist(err) {
+			t.Error("Old segment directory should be removed")
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// signatureDomain отделяет подписи депозитов от подписей того же
+// ключа в других протоколах
+const signatureDomain = "textproof-deposit-signature-v1"
+
+// KeyType - тип ключа автора
+type KeyType string
+
+const (
+	KeyEd25519   KeyType = "ed25519"
+	KeyECDSAP256 KeyType = "ecdsa-p256"
+)
+
+// AuthorKey - разобранный публичный ключ автора
+type AuthorKey struct {
+	Type KeyType
+
+	// Fingerprint - SHA-256 от DER ключа в формате SubjectPublicKeyInfo,
+	// в hex. Не зависит от того, в каком виде ключ передан
+	Fingerprint string
+
+	ed25519 ed25519.PublicKey
+	ecdsa   *ecdsa.PublicKey
+}
+
+// ParseAuthorKey разбирает публичный ключ автора. Принимаются PEM
+// ("PUBLIC KEY") и DER SubjectPublicKeyInfo в base64 с ключом Ed25519
+// или ECDSA P-256, а также 32 байта ключа Ed25519 в base64 или hex
+func ParseAuthorKey(s string) (*AuthorKey, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty key", ErrInvalidPublicKey)
+	}
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidPublicKey, block.Type)
+		}
+		der = block.Bytes
+	} else {
+		raw, err := decodeKeyBytes(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+		// Сначала голый ключ Ed25519, затем SubjectPublicKeyInfo
+		if len(raw) == ed25519.PublicKeySize {
+			return newAuthorKey(ed25519.PublicKey(raw))
+		}
+		der = raw
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	return newAuthorKey(pub)
+}
+
+// decodeKeyBytes декодирует ключ из base64 или, если это голый ключ
+// Ed25519, из hex
+func decodeKeyBytes(s string) ([]byte, error) {
+	if len(s) == 2*ed25519.PublicKeySize {
+		if raw, err := hex.DecodeString(s); err == nil {
+			return raw, nil
+		}
+	}
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return raw, nil
+	}
+	return nil, fmt.Errorf("key is neither PEM, hex nor base64")
+}
+
+// newAuthorKey проверяет тип ключа и вычисляет отпечаток
+func newAuthorKey(pub any) (*AuthorKey, error) {
+	key := &AuthorKey{}
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		key.Type, key.ed25519 = KeyEd25519, k
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: unsupported ECDSA curve %s", ErrInvalidPublicKey, k.Curve.Params().Name)
+		}
+		key.Type, key.ecdsa = KeyECDSAP256, k
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidPublicKey, pub)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	sum := sha256.Sum256(der)
+	key.Fingerprint = hex.EncodeToString(sum[:])
+	return key, nil
+}
+
+// Verify проверяет подпись message. Подпись ECDSA принимается в DER
+// (ASN.1) или как r||s по 32 байта, как её выдаёт Web Crypto
+func (k *AuthorKey) Verify(message, sig []byte) bool {
+	switch k.Type {
+	case KeyEd25519:
+		return ed25519.Verify(k.ed25519, message, sig)
+	case KeyECDSAP256:
+		digest := sha256.Sum256(message)
+		if len(sig) == 64 {
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+			return ecdsa.Verify(k.ecdsa, digest[:], r, s)
+		}
+		return ecdsa.VerifyASN1(k.ecdsa, digest[:], sig)
+	}
+	return false
+}
+
+// SigningMessage возвращает байты, которые подписывает автор депозита.
+// Строки кодируются как в заголовке блока: длина (u32 big-endian)
+// и байты UTF-8:
+//
+//	str "textproof-deposit-signature-v1"
+//	str content_hash_alg   алгоритм хеша текста, "sha256" для пустого
+//	str content_hash
+//	str author_name
+//	str title
+//	str parent_id          только у новой версии произведения
+//
+// Соавторы подписывают другое сообщение, см. coauthoredSigningMessage
+func (d DepositData) SigningMessage() []byte {
+	if d.Coauthored() {
+		return d.coauthoredSigningMessage()
+	}
+	buf := make([]byte, 0, 128+len(d.AuthorName)+len(d.Title))
+	buf = appendHeaderString(buf, signatureDomain)
+	buf = appendHeaderString(buf, string(d.ContentHashAlgorithm()))
+	buf = appendHeaderString(buf, d.ContentHash)
+	buf = appendHeaderString(buf, d.AuthorName)
+	buf = appendHeaderString(buf, d.Title)
+	if d.ParentID != "" {
+		buf = appendHeaderString(buf, d.ParentID)
+	}
+	return buf
+}
+
+// Signed сообщает, что депозит несёт подпись автора
+func (d DepositData) Signed() bool {
+	return d.Signature != ""
+}
+
+// VerifySignature проверяет подпись депозита ключом PublicKey
+// и возвращает ключ. Депозит без подписи - ErrInvalidSignature
+func (d DepositData) VerifySignature() (*AuthorKey, error) {
+	if !d.Signed() {
+		return nil, fmt.Errorf("%w: deposit is not signed", ErrInvalidSignature)
+	}
+	key, err := ParseAuthorKey(d.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(d.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64", ErrInvalidSignature)
+	}
+	if !key.Verify(d.SigningMessage(), sig) {
+		return nil, ErrInvalidSignature
+	}
+	return key, nil
+}
+
+// checkSignatures проверяет подписи депозитов блока. Подпись и соавторы
+// не входят в заголовок, их закрепляет только merkle_root, поэтому
+// подписанные депозиты и депозиты с соавторами допустимы лишь в блоках
+// с корнем
+func checkSignatures(block *Block) error {
+	for _, data := range block.DepositList() {
+		if data.Coauthored() {
+			if block.MerkleRoot == "" {
+				return ErrInvalidAuthors
+			}
+			if err := data.CheckAuthors(true); err != nil {
+				return err
+			}
+			continue
+		}
+		if !data.Signed() {
+			continue
+		}
+		if block.MerkleRoot == "" {
+			return ErrInvalidSignature
+		}
+		if _, err := data.VerifySignature(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
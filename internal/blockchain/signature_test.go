@@ -0,0 +1,170 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+// signedDeposit возвращает депозит, подписанный ключом Ed25519
+func signedDeposit(t *testing.T, priv ed25519.PrivateKey, title string) DepositData {
+	t.Helper()
+	data := CreateTestBlock("Author", title, "signed text "+title)
+	data.PublicKey = base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+	data.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data.SigningMessage()))
+	return data
+}
+
+func TestParseAuthorKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	AssertNoError(t, err)
+	sum := sha256.Sum256(der)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	t.Run("ed25519 encodings share fingerprint", func(t *testing.T) {
+		encodings := []string{
+			base64.StdEncoding.EncodeToString(pub),
+			hex.EncodeToString(pub),
+			base64.StdEncoding.EncodeToString(der),
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		}
+		for _, s := range encodings {
+			key, err := ParseAuthorKey(s)
+			AssertNoError(t, err, "Parse %q", s)
+			if key == nil {
+				continue
+			}
+			AssertEqual(t, key.Type, KeyEd25519, "Type")
+			AssertEqual(t, key.Fingerprint, fingerprint, "Fingerprint")
+		}
+	})
+
+	t.Run("ecdsa curves", func(t *testing.T) {
+		p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		AssertNoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(&p256.PublicKey)
+		AssertNoError(t, err)
+		key, err := ParseAuthorKey(base64.StdEncoding.EncodeToString(der))
+		AssertNoError(t, err)
+		AssertEqual(t, key.Type, KeyECDSAP256, "Type")
+
+		p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		AssertNoError(t, err)
+		der, err = x509.MarshalPKIXPublicKey(&p384.PublicKey)
+		AssertNoError(t, err)
+		_, err = ParseAuthorKey(base64.StdEncoding.EncodeToString(der))
+		if !errors.Is(err, ErrInvalidPublicKey) {
+			t.Errorf("P-384 key error = %v, want ErrInvalidPublicKey", err)
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, s := range []string{"", "test-key-123", base64.StdEncoding.EncodeToString([]byte("short"))} {
+			_, err := ParseAuthorKey(s)
+			if !errors.Is(err, ErrInvalidPublicKey) {
+				t.Errorf("ParseAuthorKey(%q) error = %v, want ErrInvalidPublicKey", s, err)
+			}
+		}
+	})
+}
+
+func TestDepositData_VerifySignature(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+
+	t.Run("ed25519", func(t *testing.T) {
+		data := signedDeposit(t, priv, "Title")
+		key, err := data.VerifySignature()
+		AssertNoError(t, err)
+		AssertEqual(t, key.Type, KeyEd25519, "Type")
+	})
+
+	t.Run("ecdsa p256 der and raw", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		AssertNoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		AssertNoError(t, err)
+
+		data := CreateTestBlock("Author", "Title", "ecdsa text")
+		data.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		digest := sha256.Sum256(data.SigningMessage())
+
+		asn1Sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+		AssertNoError(t, err)
+		data.Signature = base64.StdEncoding.EncodeToString(asn1Sig)
+		_, err = data.VerifySignature()
+		AssertNoError(t, err, "ASN.1 signature")
+
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		AssertNoError(t, err)
+		raw := make([]byte, 64)
+		r.FillBytes(raw[:32])
+		s.FillBytes(raw[32:])
+		data.Signature = base64.StdEncoding.EncodeToString(raw)
+		_, err = data.VerifySignature()
+		AssertNoError(t, err, "r||s signature")
+	})
+
+	t.Run("tampered fields", func(t *testing.T) {
+		for name, tamper := range map[string]func(*DepositData){
+			"title":            func(d *DepositData) { d.Title = "Other" },
+			"author":           func(d *DepositData) { d.AuthorName = "Other" },
+			"content hash":     func(d *DepositData) { d.ContentHash = HashSHA256.ContentHash([]byte("other")) },
+			"content hash alg": func(d *DepositData) { d.ContentHashAlg = HashSHA3_256 },
+		} {
+			data := signedDeposit(t, priv, "Title")
+			tamper(&data)
+			if _, err := data.VerifySignature(); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("%s: error = %v, want ErrInvalidSignature", name, err)
+			}
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		data := CreateTestBlock("Author", "Title", "text")
+		if data.Signed() {
+			t.Error("Signed() = true without signature")
+		}
+		_, err := data.VerifySignature()
+		AssertError(t, err)
+	})
+}
+
+func TestBlockchain_SignedDeposits(t *testing.T) {
+	bc, err := NewBlockchain(NewTestStorage(), 1)
+	AssertNoError(t, err)
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	AssertNoError(t, err)
+
+	block, err := bc.AddBlock(signedDeposit(t, priv, "Signed"))
+	AssertNoError(t, err)
+	AssertNotEqual(t, block.Data.Signature, "", "Stored signature")
+
+	bad := signedDeposit(t, priv, "Forged")
+	bad.Title = "Changed after signing"
+	_, err = bc.AddBlock(bad)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("AddBlock() error = %v, want ErrInvalidSignature", err)
+	}
+
+	// Подписанный депозит в блоке без корня Меркла не закреплён
+	unrooted := NewBlock("000-000-009", block.Hash, signedDeposit(t, priv, "Unrooted"))
+	unrooted.MerkleRoot = ""
+	if err := checkSignatures(unrooted); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("checkSignatures() error = %v, want ErrInvalidSignature", err)
+	}
+
+	if !bc.ValidateChain() {
+		t.Error("Chain with signed deposit is invalid")
+	}
+}
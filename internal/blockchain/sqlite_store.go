@@ -0,0 +1,367 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // драйвер SQLite без CGO
+)
+
+// sqliteSchema описывает таблицу блоков. Блок хранится в JSON целиком,
+// отдельные колонки нужны для индексов
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS blocks (
+	height INTEGER PRIMARY KEY,
+	id     TEXT    NOT NULL UNIQUE,
+	hash   TEXT    NOT NULL,
+	data   BLOB    NOT NULL
+);`
+
+// SQLiteStore хранит цепочку во встроенной базе SQLite
+type SQLiteStore struct {
+	db       *sql.DB
+	path     string
+	lock     File // блокировка каталога данных; nil в режиме чтения
+	readOnly bool
+}
+
+// NewSQLiteStore открывает (или создаёт) базу по пути path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	return NewSQLiteStoreWithOptions(path, StoreOptions{})
+}
+
+// NewSQLiteStoreWithOptions открывает базу по пути path. Каталог базы
+// блокируется так же, как у Storage: SQLite сериализует запись, но два
+// сервера всё равно дописывали бы блоки каждый к своей вершине
+func NewSQLiteStoreWithOptions(path string, opts StoreOptions) (*SQLiteStore, error) {
+	if opts.ReadOnly {
+		db, err := openSQLiteReadOnly(path)
+		if err != nil {
+			return nil, err
+		}
+		s := &SQLiteStore{db: db, path: path, readOnly: true}
+
+		version, err := s.formatVersion()
+		if err == nil && version > CurrentFormatVersion() {
+			err = &FormatVersionError{Path: path, Version: version}
+		} else if err == nil && version < CurrentFormatVersion() {
+			err = fmt.Errorf("data format version %d is not migrated yet, open the database read-write first", version)
+		}
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	lock, err := lockDataDir(OSFS{}, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// journal_mode=WAL и synchronous=FULL: транзакция зафиксирована
+	// на диске к моменту возврата из Commit
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite допускает одного писателя
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		lock.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+
+	s := &SQLiteStore{db: db, path: path, lock: lock}
+	if err := s.migrate(); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to migrate data format: %w", err)
+	}
+
+	return s, nil
+}
+
+// openSQLiteReadOnly открывает существующую базу только для чтения
+func openSQLiteReadOnly(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+// formatVersion читает версию формата из PRAGMA user_version. Непустая
+// база без версии - версия 1, пустая - текущая
+func (s *SQLiteStore) formatVersion() (int, error) {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read format version: %w", err)
+	}
+	if version > 0 {
+		return version, nil
+	}
+
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM blocks`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count blocks: %w", err)
+	}
+	if count > 0 {
+		return legacyFormatVersion, nil
+	}
+	return CurrentFormatVersion(), nil
+}
+
+// prepareMigration готовит миграцию базы, ничего не записывая.
+// Возвращает план и блоки после миграции (nil - блоки не меняются)
+func (s *SQLiteStore) prepareMigration() (*MigrationPlan, []*Block, error) {
+	from, err := s.formatVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, steps, err := newMigrationPlan(s.path, from)
+	if err != nil || !plan.Needed() {
+		return plan, nil, err
+	}
+	plan.Rewrite = []string{filepath.Base(s.path)}
+
+	rows, err := s.db.Query(`SELECT data FROM blocks ORDER BY height`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var payloads [][]byte
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		payloads = append(payloads, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	blocks, changed, err := migrateBlocks(payloads, steps)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan.Blocks = len(blocks)
+	plan.ChangedBlocks = changed
+	if changed == 0 {
+		return plan, nil, nil
+	}
+
+	plan.Originals = fmt.Sprintf("%s.v%d", s.path, from)
+	return plan, blocks, nil
+}
+
+// migrate поднимает формат базы до текущей версии. Копия исходной базы
+// сохраняется рядом (blockchain.db.v<N>), блоки и версия меняются в
+// одной транзакции
+func (s *SQLiteStore) migrate() error {
+	plan, blocks, err := s.prepareMigration()
+	if err != nil {
+		return err
+	}
+
+	if blocks != nil {
+		if _, err := os.Stat(plan.Originals); os.IsNotExist(err) {
+			tmp := plan.Originals + ".tmp"
+			os.Remove(tmp)
+			if _, err := s.db.Exec(`VACUUM INTO ?`, tmp); err != nil {
+				return fmt.Errorf("failed to keep original database: %w", err)
+			}
+			if err := os.Rename(tmp, plan.Originals); err != nil {
+				return fmt.Errorf("failed to keep original database: %w", err)
+			}
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for height, block := range blocks {
+		data, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE blocks SET id = ?, hash = ?, data = ? WHERE height = ?`,
+			block.ID, block.Hash, data, height); err != nil {
+			return fmt.Errorf("failed to update block: %w", err)
+		}
+	}
+
+	// Для новой базы версия фиксируется до появления данных
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, CurrentFormatVersion())); err != nil {
+		return fmt.Errorf("failed to write format version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if plan.Needed() {
+		slog.Info("Migrated data format",
+			"db", s.path,
+			"from", plan.From,
+			"to", plan.To,
+			"blocks", plan.Blocks,
+			"changed_blocks", plan.ChangedBlocks,
+			"originals", plan.Originals,
+		)
+	}
+	return nil
+}
+
+// LoadChain загружает цепочку из базы
+func (s *SQLiteStore) LoadChain() (*Blockchain, error) {
+	blocks, err := s.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) == 0 {
+		return nil, &os.PathError{Op: "load", Path: s.path, Err: os.ErrNotExist}
+	}
+
+	return &Blockchain{Chain: blocks}, nil
+}
+
+// SaveBlock дописывает блок следующей высотой
+func (s *SQLiteStore) SaveBlock(block *Block) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	data, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to marshal block: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO blocks (height, id, hash, data)
+		 VALUES ((SELECT COALESCE(MAX(height) + 1, 0) FROM blocks), ?, ?, ?)`,
+		block.ID, block.Hash, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert block: %w", err)
+	}
+	return nil
+}
+
+// SaveChain заменяет содержимое таблицы в одной транзакции
+func (s *SQLiteStore) SaveChain(bc *Blockchain) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM blocks`); err != nil {
+		return fmt.Errorf("failed to clear blocks: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO blocks (height, id, hash, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for height, block := range bc.Chain {
+		data, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block: %w", err)
+		}
+		if _, err := stmt.Exec(height, block.ID, block.Hash, data); err != nil {
+			return fmt.Errorf("failed to insert block: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBlock возвращает блок по ID
+func (s *SQLiteStore) GetBlock(id string) (*Block, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM blocks WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query block: %w", err)
+	}
+
+	var block Block
+	if err := json.Unmarshal(data, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block: %w", err)
+	}
+	return &block, nil
+}
+
+// GetAllBlocks возвращает все блоки по порядку высоты
+func (s *SQLiteStore) GetAllBlocks() ([]*Block, error) {
+	rows, err := s.db.Query(`SELECT data FROM blocks ORDER BY height`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks []*Block
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+
+		var block Block
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, fmt.Errorf("failed to parse block: %w", err)
+		}
+		blocks = append(blocks, &block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// CopyFiles копирует файл базы и её журнал WAL в каталог dst
+func (s *SQLiteStore) CopyFiles(dst string) ([]string, error) {
+	base := filepath.Base(s.path)
+	return copyFiles(OSFS{}, OSFS{}, dst, map[string]string{
+		base:          s.path,
+		base + "-wal": s.path + "-wal",
+	})
+}
+
+// Close закрывает базу и снимает блокировку каталога
+func (s *SQLiteStore) Close() error {
+	err := s.db.Close()
+	if s.lock != nil {
+		if lockErr := s.lock.Close(); err == nil {
+			err = lockErr
+		}
+		s.lock = nil
+	}
+	return err
+}
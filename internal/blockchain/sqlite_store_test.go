@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blockchain.db")
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+
+	// Пустая база - как отсутствующий файл
+	_, err = store.LoadChain()
+	if !os.IsNotExist(err) {
+		t.Errorf("LoadChain() on empty store error = %v, want not exist", err)
+	}
+
+	bc, err := NewBlockchain(store, 1)
+	if err != nil {
+		t.Fatalf("NewBlockchain() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := bc.AddBlock(CreateTestBlock(fmt.Sprintf("Author%d", i), "Title", fmt.Sprintf("Text %d", i)))
+		AssertNoError(t, err)
+	}
+	AssertNoError(t, store.Close())
+
+	// Повторное открытие загружает цепочку из базы
+	reopened, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	bc2, err := NewBlockchain(reopened, 1)
+	if err != nil {
+		t.Fatalf("NewBlockchain() on reopened store error = %v", err)
+	}
+	AssertEqual(t, len(bc2.Chain), 4, "Chain length after reopen")
+	if !bc2.ValidateChain() {
+		t.Error("Reopened chain should be valid")
+	}
+
+	block, err := reopened.GetBlock(bc.Chain[2].ID)
+	AssertNoError(t, err)
+	AssertEqual(t, block.Hash, bc.Chain[2].Hash, "GetBlock hash")
+
+	_, err = reopened.GetBlock("999-999-999")
+	AssertError(t, err, "GetBlock for missing ID")
+
+	// SaveChain заменяет содержимое целиком
+	AssertNoError(t, reopened.SaveChain(&Blockchain{Chain: bc.Chain[:2]}))
+	blocks, err := reopened.GetAllBlocks()
+	AssertNoError(t, err)
+	AssertEqual(t, len(blocks), 2, "Blocks after SaveChain")
+}
+
+func TestOpenStore(t *testing.T) {
+	for _, backend := range []string{StoreFile, StoreSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := OpenStore(backend, t.TempDir(), DefaultStoreOptions())
+			if err != nil {
+				t.Fatalf("OpenStore(%q) error = %v", backend, err)
+			}
+			defer store.Close()
+
+			bc, err := NewBlockchain(store, 1)
+			if err != nil {
+				t.Fatalf("NewBlockchain() error = %v", err)
+			}
+			AssertEqual(t, len(bc.Chain), 1, "Genesis only")
+		})
+	}
+
+	t.Run("unknown backend", func(t *testing.T) {
+		_, err := OpenStore("postgres", t.TempDir(), DefaultStoreOptions())
+		AssertError(t, err)
+	})
+}
@@ -1,316 +1,329 @@
 package blockchain
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
-	"sort"
+	"sync"
 )
 
-const (
-	MaxBackups = 5
-)
-
-// BlockStorage интерфейс для хранилища блоков
-type BlockStorage interface {
-	SaveBlock(block *Block) error
-	GetBlock(id string) (*Block, error)
-	GetAllBlocks() ([]*Block, error)
-	Close() error
-}
-
-// Storage управляет файловой системой для блокчейна
+// Storage - файловый бэкенд хранилища (Store) для блокчейна.
+//
+// Блоки хранятся в append-only журнале сегментов (см. SegmentLog):
+// добавление блока стоит O(1) независимо от длины цепочки. Файл
+// blockchain.json остался от прежнего формата и при открытии
+// мигрирует в журнал.
 type Storage struct {
-	chainFile string
-	walFile   string
-	backupDir string
+	chainFile  string
+	walFile    string
+	formatFile string
+	backupDir  string
+	segmentDir string
+
+	fs       FS
+	log      *SegmentLog
+	backups  BackupPolicy
+	lock     File // блокировка каталога данных; nil в режиме чтения
+	readOnly bool
+
+	backupMu   sync.Mutex    // бэкапы создаются и восстанавливаются по одному
+	backupReq  chan struct{} // запросы периодического бэкапа; nil - фонового бэкапа нет
+	backupDone chan struct{} // закрывается, когда фоновый бэкап остановлен
 }
 
-// NewStorage создает новый Storage
+// NewStorage создает новый Storage с параметрами по умолчанию
 func NewStorage(dataDir string) (*Storage, error) {
+	return NewStorageWithOptions(dataDir, DefaultStoreOptions())
+}
+
+// NewStorageWithOptions создает новый Storage с заданными параметрами
+func NewStorageWithOptions(dataDir string, opts StoreOptions) (*Storage, error) {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OSFS{}
+	}
+
+	if opts.ReadOnly {
+		return openStorageReadOnly(fsys, dataDir, opts)
+	}
+
 	// Создаем директории, если их нет
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
+	if err := fsys.MkdirAll(dataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 
+	// Второй процесс на том же каталоге испортит цепочку
+	lock, err := lockDataDir(fsys, dataDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Создаем директорию для бэкапов
 	backupDir := filepath.Join(dataDir, "backups")
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
+	if err := fsys.MkdirAll(backupDir, 0755); err != nil {
+		lock.Close()
 		return nil, fmt.Errorf("failed to create backup directory: %v", err)
 	}
 
-	// Возвращаем новый Storage
-	return &Storage{
-		chainFile: filepath.Join(dataDir, "blockchain.json"),
-		walFile:   filepath.Join(dataDir, "wal.json"),
-		backupDir: backupDir,
-	}, nil
-}
-
-// CreateBackup создает резервную копию блокчейна
-func (s *Storage) CreateBackup() error {
-	// Проверяем, существует ли основной файл
-	if _, err := os.Stat(s.chainFile); os.IsNotExist(err) {
-		return nil // Нет файла для бэкапа
-	}
-
-	// Создаем имя для бэкапа с временной меткой
-	backupName := fmt.Sprintf("blockchain_backup_%d.json", os.Getpid())
-	backupPath := filepath.Join(s.backupDir, backupName)
+	s := newStorage(fsys, dataDir, opts)
+	s.lock = lock
 
-	// Копируем файл
-	data, err := os.ReadFile(s.chainFile)
+	// Открываем журнал блоков
+	log, err := openSegmentLog(fsys, s.segmentDir, DefaultSegmentSize, false)
 	if err != nil {
-		return fmt.Errorf("failed to read blockchain file: %v", err)
+		lock.Close()
+		return nil, fmt.Errorf("failed to open segment log: %w", err)
 	}
+	s.log = log
 
-	// Записываем бэкап
-	if err := os.WriteFile(backupPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write backup: %v", err)
+	// Переносим цепочку из старого blockchain.json
+	if err := s.migrateLegacyChain(); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %w", s.chainFile, err)
 	}
 
-	// Удаляем старые бэкапы (оставляем только последние 5)
-	if err := s.cleanupOldBackups(); err != nil {
-		// Не критическая ошибка, можно продолжить
-		slog.Warn("Failed to cleanup old backups", "error", err)
+	// Поднимаем формат данных до текущей версии
+	if err := s.migrate(); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to migrate data format: %w", err)
 	}
 
-	return nil
+	if s.backups.Every > 0 {
+		s.startBackupWorker()
+	}
+	return s, nil
 }
 
-// fileInfoWithPath вспомогательная структура для сортировки файлов
-type fileInfoWithPath struct {
-	path    string
-	modTime int64
-}
+// openStorageReadOnly открывает каталог данных только для чтения.
+// Блокировка не берётся, поэтому каталог можно открыть рядом с
+// работающим сервером (например, инструментами проверки). Все
+// изменяющие методы возвращают ErrReadOnly
+func openStorageReadOnly(fsys FS, dataDir string, opts StoreOptions) (*Storage, error) {
+	s := newStorage(fsys, dataDir, opts)
+	s.readOnly = true
 
-// cleanupOldBackups удаляет старые бэкапы, оставляя только последние 5
-func (s *Storage) cleanupOldBackups() error {
-	entries, err := os.ReadDir(s.backupDir)
+	log, err := openSegmentLog(fsys, s.segmentDir, DefaultSegmentSize, true)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to open segment log: %w", err)
 	}
+	s.log = log
 
-	// Если бэкапов меньше 5, ничего не делаем
-	if len(entries) <= MaxBackups {
-		return nil
+	if _, err := fsys.Stat(s.chainFile); err == nil && log.Len() == 0 {
+		log.Close()
+		return nil, fmt.Errorf("%s is not migrated yet, open the data directory read-write first", s.chainFile)
 	}
 
-	// Собираем информацию о файлах
-	var files []fileInfoWithPath
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		files = append(files, fileInfoWithPath{
-			path:    filepath.Join(s.backupDir, entry.Name()),
-			modTime: info.ModTime().Unix(),
-		})
+	version, err := s.formatVersion()
+	if err != nil {
+		log.Close()
+		return nil, err
 	}
-
-	// Сортируем по времени модификации
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].modTime < files[j].modTime // От старых к новым
-	})
-
-	// Удаляем самые старые
-	toDelete := len(files) - MaxBackups
-	for i := 0; i < toDelete; i++ {
-		if err := os.Remove(files[i].path); err != nil {
-			return err
-		}
+	if version > CurrentFormatVersion() {
+		log.Close()
+		return nil, &FormatVersionError{Path: s.formatFile, Version: version}
+	}
+	if version < CurrentFormatVersion() {
+		log.Close()
+		return nil, fmt.Errorf("data format version %d is not migrated yet, open the data directory read-write first", version)
 	}
 
-	return nil
+	return s, nil
 }
 
-// RestoreFromBackup восстанавливает блокчейн из последнего бэкапа
-func (s *Storage) RestoreFromBackup() error {
-	entries, err := os.ReadDir(s.backupDir)
-	if err != nil {
-		return fmt.Errorf("failed to read backup directory: %v", err)
+// newStorage заполняет пути Storage в каталоге dataDir
+func newStorage(fsys FS, dataDir string, opts StoreOptions) *Storage {
+	return &Storage{
+		chainFile:  filepath.Join(dataDir, "blockchain.json"),
+		walFile:    filepath.Join(dataDir, "wal.json"),
+		formatFile: filepath.Join(dataDir, formatFileName),
+		segmentDir: filepath.Join(dataDir, "segments"),
+		backupDir:  filepath.Join(dataDir, "backups"),
+		fs:         fsys,
+		backups:    opts.Backups,
 	}
+}
 
-	if len(entries) == 0 {
-		return fmt.Errorf("no backups found")
+// migrateLegacyChain переносит blockchain.json в журнал сегментов.
+// Исходный файл сохраняется как blockchain.json.migrated для отката
+func (s *Storage) migrateLegacyChain() error {
+	if _, err := s.fs.Stat(s.chainFile); os.IsNotExist(err) {
+		return nil
 	}
 
-	// Находим самый свежий бэкап
-	var latestBackup string
-	var latestTime int64 = 0
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		if info.ModTime().Unix() > latestTime {
-			latestTime = info.ModTime().Unix()
-			latestBackup = filepath.Join(s.backupDir, entry.Name())
-		}
+	if s.log.Len() > 0 {
+		// Журнал уже заполнен: файл остался от прерванной миграции
+		slog.Warn("Ignoring legacy chain file, segment log is not empty", "file", s.chainFile)
+		return s.fs.Rename(s.chainFile, s.chainFile+".migrated")
 	}
 
-	if latestBackup == "" {
-		return fmt.Errorf("no valid backups found")
+	data, err := s.fs.ReadFile(s.chainFile)
+	if err != nil {
+		return err
 	}
 
-	// Копируем бэкап в основной файл
-	data, err := os.ReadFile(latestBackup)
+	// Файл может быть любой поддерживаемой версии формата
+	blocks, err := decodeChainFile(s.chainFile, data)
 	if err != nil {
-		return fmt.Errorf("failed to read backup file: %v", err)
+		return fmt.Errorf("failed to parse chain file: %w", err)
 	}
 
-	if err := os.WriteFile(s.chainFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to restore from backup: %v", err)
+	if err := s.log.Reset(blocks); err != nil {
+		return err
+	}
+	if err := s.writeFormat(); err != nil {
+		return err
+	}
+
+	if err := s.fs.Rename(s.chainFile, s.chainFile+".migrated"); err != nil {
+		return err
 	}
 
-	slog.Info("Restored from backup", "file", latestBackup)
+	slog.Info("Migrated chain to segment log", "blocks", len(blocks), "dir", s.segmentDir)
 	return nil
 }
 
-// LoadChain загружает цепочку из файла
+// LoadChain загружает цепочку из журнала.
+// Для пустого журнала возвращает ошибку, удовлетворяющую os.IsNotExist
 func (s *Storage) LoadChain() (*Blockchain, error) {
-	// Проверяем существование файла
-	if _, err := os.Stat(s.chainFile); err != nil {
-		// Возвращаем оригинальную ошибку для проверки os.IsNotExist
-		return nil, err
+	if s.log.Len() == 0 {
+		return nil, &os.PathError{Op: "load", Path: s.segmentDir, Err: os.ErrNotExist}
 	}
 
-	// Читаем файл
-	data, err := os.ReadFile(s.chainFile)
+	blocks, err := s.log.ReadAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read chain file: %w", err)
-	}
-
-	// Парсим JSON
-	var bc Blockchain
-	if err := json.Unmarshal(data, &bc); err != nil {
-		return nil, fmt.Errorf("failed to parse chain file: %w", err)
+		return nil, fmt.Errorf("failed to read segment log: %w", err)
 	}
 
-	return &bc, nil
+	return &Blockchain{Chain: blocks}, nil
 }
 
-// SaveChain сохраняет цепочку в файл
-func (s *Storage) SaveChain(bc *Blockchain) error {
-	// Создаем бэкап перед сохранением
-	if err := s.CreateBackup(); err != nil {
-		// Это не критическая ошибка, можно продолжить
-		slog.Warn("Failed to create backup", "error", err)
-	}
-
-	// Сохраняем через временный файл для атомарности
-	tmpFile := s.chainFile + ".tmp"
-
-	data, err := json.MarshalIndent(bc, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal chain: %w", err)
+// SaveBlock дописывает блок в журнал. Запись фиксируется на диске
+// до возврата из метода
+func (s *Storage) SaveBlock(block *Block) error {
+	if s.readOnly {
+		return ErrReadOnly
 	}
-
-	// Записываем во временный файл
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+	if err := s.log.Append(block); err != nil {
+		return fmt.Errorf("failed to append block: %w", err)
 	}
 
-	// Атомарно переименовываем
-	if err := os.Rename(tmpFile, s.chainFile); err != nil {
-		return fmt.Errorf("failed to rename temp file: %w", err)
+	// Периодический бэкап перечитывает всю цепочку, поэтому делается
+	// в фоне и не задерживает подтверждение блока
+	if every := s.backups.Every; every > 0 && s.log.Len()%every == 0 {
+		s.requestBackup()
 	}
-
 	return nil
 }
 
-// WriteToWAL записывает блок в WAL
-func (s *Storage) WriteToWAL(block *Block) error {
-	// Читаем существующий WAL
-	var blocks []*Block
-
-	data, err := os.ReadFile(s.walFile)
-	if err != nil {
-		// Если файла нет, начинаем с пустого списка
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to read WAL: %w", err)
-		}
-		blocks = []*Block{}
-	} else {
-		if err := json.Unmarshal(data, &blocks); err != nil {
-			// Если файл поврежден, начинаем заново
-			blocks = []*Block{}
-		}
-	}
-
-	// Добавляем новый блок
-	blocks = append(blocks, block)
-
-	// Записываем обратно
-	data, err = json.MarshalIndent(blocks, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal WAL: %w", err)
+// GetBlock читает блок из журнала по ID
+func (s *Storage) GetBlock(id string) (*Block, error) {
+	height, ok := s.log.HeightOf(id)
+	if !ok {
+		return nil, ErrBlockNotFound
 	}
-
-	// Записываем через временный файл
-	tmpFile := s.walFile + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write WAL temp file: %w", err)
-	}
-
-	return os.Rename(tmpFile, s.walFile)
+	return s.log.Read(height)
 }
 
-// ReadWAL читает блоки из WAL
-func (s *Storage) ReadWAL() ([]*Block, error) {
-	if _, err := os.Stat(s.walFile); os.IsNotExist(err) {
-		return []*Block{}, nil
-	}
+// GetAllBlocks читает все блоки журнала
+func (s *Storage) GetAllBlocks() ([]*Block, error) {
+	return s.log.ReadAll()
+}
 
-	// Читаем файл
-	data, err := os.ReadFile(s.walFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read WAL: %w", err)
+// SaveChain полностью перезаписывает журнал цепочкой bc.
+// Нужен только для восстановления и миграций: новые блоки
+// добавляются через SaveBlock
+func (s *Storage) SaveChain(bc *Blockchain) error {
+	if s.readOnly {
+		return ErrReadOnly
 	}
 
-	// Парсим JSON
-	var blocks []*Block
-	if err := json.Unmarshal(data, &blocks); err != nil {
-		return nil, fmt.Errorf("failed to parse WAL: %w", err)
+	// Создаем бэкап перед перезаписью
+	if err := s.CreateBackup(); err != nil {
+		// Это не критическая ошибка, можно продолжить
+		slog.Warn("Failed to create backup", "error", err)
 	}
 
-	return blocks, nil
+	return s.rewriteChain(bc.Chain)
 }
 
-// ClearWAL удаляет WAL файл
-func (s *Storage) ClearWAL() error {
-	if _, err := os.Stat(s.walFile); os.IsNotExist(err) {
-		return nil // Файла нет
+// rewriteChain перезаписывает журнал цепочкой chain без бэкапа
+func (s *Storage) rewriteChain(chain []*Block) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if err := s.log.Reset(chain); err != nil {
+		return fmt.Errorf("failed to rewrite segment log: %w", err)
 	}
-	return os.Remove(s.walFile)
+	return nil
 }
 
 // FileExists проверяет существование файла
 func (s *Storage) FileExists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := s.fs.Stat(path)
 	return !os.IsNotExist(err)
 }
 
-// GetChainPath возвращает путь к файлу цепочки
+// GetChainPath возвращает путь к каталогу журнала цепочки
 func (s *Storage) GetChainPath() string {
-	return s.chainFile
+	return s.segmentDir
 }
 
 // GetWALPath возвращает путь к файлу WAL
 func (s *Storage) GetWALPath() string {
 	return s.walFile
 }
+
+// CopyFiles копирует сегменты журнала, WAL и format.json в каталог dst
+func (s *Storage) CopyFiles(dst string) ([]string, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	return copyFiles(s.fs, OSFS{}, dst, files)
+}
+
+// files возвращает файлы хранилища: путь относительно каталога
+// данных -> путь на диске
+func (s *Storage) files() (map[string]string, error) {
+	files := map[string]string{
+		filepath.Base(s.walFile):    s.walFile,
+		filepath.Base(s.formatFile): s.formatFile,
+	}
+
+	entries, err := s.fs.ReadDir(s.segmentDir)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		rel := filepath.Join(filepath.Base(s.segmentDir), e.Name())
+		files[rel] = filepath.Join(s.segmentDir, e.Name())
+	}
+	return files, nil
+}
+
+// ReadOnly сообщает, открыто ли хранилище только для чтения
+func (s *Storage) ReadOnly() bool {
+	return s.readOnly
+}
+
+// Close дожидается фонового бэкапа, закрывает журнал блоков и
+// снимает блокировку каталога
+func (s *Storage) Close() error {
+	s.stopBackupWorker()
+
+	err := s.log.Close()
+	if s.lock != nil {
+		if lockErr := s.lock.Close(); err == nil {
+			err = lockErr
+		}
+		s.lock = nil
+	}
+	return err
+}
@@ -154,18 +154,9 @@ func TestStorage_CreateBackup(t *testing.T) {
 	data := CreateTestBlock("Author", "Title", "Text")
 	bc.AddBlock(data)
 
-	// Сохраняем в файл
-	chainData := struct {
-		Chain      []*Block `json:"chain"`
-		Difficulty int      `json:"difficulty"`
-	}{
-		Chain:      bc.Chain,
-		Difficulty: bc.Difficulty,
-	}
-
-	file, _ := os.Create(storage.chainFile)
-	json.NewEncoder(file).Encode(chainData)
-	file.Close()
+	// Сохраняем в журнал
+	err = storage.SaveChain(bc)
+	AssertNoError(t, err)
 
 	// Создаём бэкап
 	err = storage.CreateBackup()
@@ -179,29 +170,35 @@ func TestStorage_CreateBackup(t *testing.T) {
 	}
 }
 
-func TestStorage_CleanupOldBackups(t *testing.T) {
-	tempDir := filepath.Join(os.TempDir(), "textproof-cleanup-test")
+func TestStorage_MigrateLegacyChain(t *testing.T) {
+	tempDir := filepath.Join(os.TempDir(), "textproof-migrate-test")
+	os.RemoveAll(tempDir)
 	defer os.RemoveAll(tempDir)
 
-	storage, err := NewStorage(tempDir)
+	bc := NewBlockchainWithStorage(NewTestStorage(), 1)
+	bc.AddBlock(CreateTestBlock("Author", "Title", "Legacy text"))
+
+	// Пишем цепочку в старом формате blockchain.json
+	AssertNoError(t, os.MkdirAll(tempDir, 0755))
+	data, err := json.MarshalIndent(bc, "", "  ")
 	AssertNoError(t, err)
+	AssertNoError(t, os.WriteFile(filepath.Join(tempDir, "blockchain.json"), data, 0644))
 
-	// Создаём больше MaxBackups файлов
-	for i := 0; i < 8; i++ {
-		backupName := filepath.Join(storage.backupDir, fmt.Sprintf("backup_%d.json", i))
-		err := os.WriteFile(backupName, []byte("test"), 0644)
-		AssertNoError(t, err)
-		time.Sleep(10 * time.Millisecond) // Чтобы время модификации различалось
-	}
+	storage, err := NewStorage(tempDir)
+	AssertNoError(t, err, "NewStorage should migrate legacy chain")
+	defer storage.Close()
 
-	// Запускаем cleanup
-	err = storage.cleanupOldBackups()
+	loaded, err := storage.LoadChain()
 	AssertNoError(t, err)
-
-	// Проверяем что осталось только MaxBackups файлов
-	files, _ := os.ReadDir(storage.backupDir)
-	if len(files) > MaxBackups {
-		t.Errorf("Too many backups: got %d, want max %d", len(files), MaxBackups)
+	AssertEqual(t, len(loaded.Chain), 2, "Migrated chain length")
+	AssertEqual(t, loaded.Chain[1].Hash, bc.Chain[1].Hash, "Migrated block hash")
+
+	// Исходный файл сохранён для отката
+	_, err = os.Stat(filepath.Join(tempDir, "blockchain.json.migrated"))
+	AssertNoError(t, err, "Legacy file should be kept as .migrated")
+	_, err = os.Stat(filepath.Join(tempDir, "blockchain.json"))
+	if !os.IsNotExist(err) {
+		t.Error("Legacy file should be moved away after migration")
 	}
 }
 
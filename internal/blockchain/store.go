@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Поддерживаемые бэкенды хранилища
+const (
+	StoreFile   = "file"   // журнал сегментов в каталоге данных
+	StoreSQLite = "sqlite" // встроенная база SQLite
+)
+
+// Store - хранилище блоков цепочки.
+//
+// Реализации: Storage (файловый журнал), SQLiteStore, а также
+// тестовые TestStorage и MockStorage.
+type Store interface {
+	// LoadChain загружает цепочку целиком. Для пустого хранилища
+	// возвращает ошибку, удовлетворяющую os.IsNotExist
+	LoadChain() (*Blockchain, error)
+
+	// SaveBlock дописывает блок в конец цепочки. Блок должен быть
+	// зафиксирован к моменту возврата из метода
+	SaveBlock(block *Block) error
+
+	// SaveChain полностью заменяет сохранённую цепочку.
+	// Используется при восстановлении, а не при добавлении блоков
+	SaveChain(bc *Blockchain) error
+
+	// GetBlock возвращает блок по ID
+	GetBlock(id string) (*Block, error)
+
+	// GetAllBlocks возвращает все блоки по порядку высоты
+	GetAllBlocks() ([]*Block, error)
+
+	// Close освобождает ресурсы хранилища
+	Close() error
+}
+
+// walStore - хранилище с журналом упреждающей записи
+type walStore interface {
+	WriteToWAL(block *Block) error
+	ReadWAL() ([]*Block, error)
+	ClearWAL() error
+	PreserveWAL() (string, error)
+}
+
+// backupStore - хранилище с резервными копиями
+type backupStore interface {
+	CreateBackup() error
+	RestoreFromBackup(accept func(chain []*Block) bool) (*BackupEntry, error)
+
+	// rewriteChain заменяет цепочку, не создавая бэкап
+	rewriteChain(chain []*Block) error
+}
+
+// StoreOptions - параметры открытия хранилища
+type StoreOptions struct {
+	// Backups - политика резервных копий (только для StoreFile)
+	Backups BackupPolicy
+
+	// FS - файловая система (только для StoreFile); nil - OSFS
+	FS FS
+
+	// ReadOnly открывает каталог без блокировки и без права записи,
+	// например для инструментов проверки рядом с работающим сервером
+	ReadOnly bool
+}
+
+// DefaultStoreOptions возвращает параметры по умолчанию
+func DefaultStoreOptions() StoreOptions {
+	return StoreOptions{Backups: DefaultBackupPolicy()}
+}
+
+// OpenStore открывает хранилище выбранного бэкенда в каталоге dataDir
+func OpenStore(backend, dataDir string, opts StoreOptions) (Store, error) {
+	switch backend {
+	case StoreFile, "":
+		return NewStorageWithOptions(dataDir, opts)
+	case StoreSQLite:
+		return NewSQLiteStoreWithOptions(filepath.Join(dataDir, "blockchain.db"), opts)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %q", backend)
+	}
+}
@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Target - 256-битная цель Proof-of-Work. Хеш блока, прочитанный как
+// число big-endian, должен быть не больше цели: чем меньше цель, тем
+// больше работы. Сложность в нулевых битах или в hex-нулях - частный
+// случай цели вида 0...0f...f
+type Target [sha256.Size]byte
+
+// maxTargetBits - самая трудная цель, которую можно задать битами
+const maxTargetBits = 8*sha256.Size - 1
+
+// TargetFromBits возвращает цель, которой соответствуют хеши,
+// начинающиеся с n нулевых бит
+func TargetFromBits(n int) Target {
+	if n < 0 {
+		n = 0
+	}
+	if n > maxTargetBits {
+		n = maxTargetBits
+	}
+
+	var t Target
+	for i := range t {
+		switch {
+		case (i+1)*8 <= n:
+			t[i] = 0
+		case i*8 >= n:
+			t[i] = 0xff
+		default:
+			t[i] = 0xff >> (n - i*8)
+		}
+	}
+	return t
+}
+
+// TargetFromDigits возвращает цель для прежней сложности: хеш
+// начинается с digits hex-нулей
+func TargetFromDigits(digits int) Target {
+	return TargetFromBits(4 * digits)
+}
+
+// ParseTarget разбирает цель из 64 hex-символов
+func ParseTarget(s string) (Target, error) {
+	var t Target
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != len(t) {
+		return t, fmt.Errorf("target must be %d hex characters", 2*len(t))
+	}
+	copy(t[:], raw)
+	if t == (Target{}) {
+		return t, fmt.Errorf("target must not be zero")
+	}
+	return t, nil
+}
+
+// String возвращает цель в hex
+func (t Target) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// Met проверяет, что хеш не больше цели
+func (t Target) Met(hash []byte) bool {
+	return bytes.Compare(hash, t[:]) <= 0
+}
+
+// Cmp сравнивает цели как числа: -1, если t труднее u, и т.д.
+func (t Target) Cmp(u Target) int {
+	return bytes.Compare(t[:], u[:])
+}
+
+// LeadingZeroBits возвращает число нулевых бит в начале цели -
+// сложность, округлённую до бита вниз
+func (t Target) LeadingZeroBits() int {
+	for i, b := range t {
+		if b != 0 {
+			return i*8 + bits.LeadingZeros8(b)
+		}
+	}
+	return 8 * len(t)
+}
+
+// scale возвращает цель t*num/den: num > den облегчает цель.
+// Результат не выходит за пределы [1, 2^256-1]
+func (t Target) scale(num, den int64) Target {
+	n := new(big.Int).SetBytes(t[:])
+	n.Mul(n, big.NewInt(num))
+	n.Quo(n, big.NewInt(den))
+
+	var out Target
+	switch {
+	case n.Sign() <= 0:
+		out[len(out)-1] = 1
+	case n.BitLen() > 8*len(out):
+		for i := range out {
+			out[i] = 0xff
+		}
+	default:
+		n.FillBytes(out[:])
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTargetFromBits(t *testing.T) {
+	tests := []struct {
+		bits int
+		want string
+	}{
+		{0, strings.Repeat("f", 64)},
+		{1, "7" + strings.Repeat("f", 63)},
+		{4, "0" + strings.Repeat("f", 63)},
+		{13, "0007" + strings.Repeat("f", 60)},
+		{16, "0000" + strings.Repeat("f", 60)},
+	}
+
+	for _, tt := range tests {
+		got := TargetFromBits(tt.bits)
+		AssertEqual(t, got.String(), tt.want, "TargetFromBits(%d)", tt.bits)
+		AssertEqual(t, got.LeadingZeroBits(), tt.bits, "LeadingZeroBits of %d bits", tt.bits)
+	}
+
+	AssertEqual(t, TargetFromDigits(3).String(), TargetFromBits(12).String(), "Hex digits are 4 bits each")
+}
+
+func TestTarget_Met(t *testing.T) {
+	target := TargetFromBits(12)
+	hash := make([]byte, 32)
+
+	AssertEqual(t, target.Met(hash), true, "Zero hash meets any target")
+	hash[1] = 0x0f
+	AssertEqual(t, target.Met(hash), true, "Hash with 12 zero bits")
+	hash[1] = 0x10
+	AssertEqual(t, target.Met(hash), false, "Hash with 11 zero bits")
+}
+
+func TestParseTarget(t *testing.T) {
+	want := TargetFromBits(20)
+	got, err := ParseTarget(want.String())
+	AssertNoError(t, err)
+	AssertEqual(t, got, want, "Parsed target")
+
+	for _, bad := range []string{"", "00ff", strings.Repeat("0", 64), strings.Repeat("g", 64)} {
+		if _, err := ParseTarget(bad); err == nil {
+			t.Errorf("ParseTarget(%q) should fail", bad)
+		}
+	}
+}
+
+func TestTarget_Scale(t *testing.T) {
+	target := TargetFromBits(10)
+
+	AssertEqual(t, target.scale(1, 2).LeadingZeroBits(), 11, "Half target")
+	AssertEqual(t, target.scale(2, 1).LeadingZeroBits(), 9, "Double target")
+	AssertEqual(t, TargetFromBits(0).scale(2, 1), TargetFromBits(0), "Saturates at easiest")
+}
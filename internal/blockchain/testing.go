@@ -9,38 +9,15 @@ import (
 	"time"
 )
 
-// NewBlockchainWithStorage создает новый блокчейн для тестов
+// NewBlockchainWithStorage создает новый блокчейн для тестов.
+// storage может быть любой реализацией Store (TestStorage, MockStorage,
+// TempDirStorage); иначе создаётся файловое хранилище во временном каталоге
 func NewBlockchainWithStorage(storage interface{}, difficulty int) *Blockchain {
-	// Проверяем если это TempDirStorage - у него есть и BlockStorage и реальный Storage
-	if tempStorage, ok := storage.(*TempDirStorage); ok {
-		bc, err := NewBlockchain(tempStorage.GetStorage(), difficulty)
+	if store, ok := storage.(Store); ok {
+		bc, err := NewBlockchain(store, difficulty)
 		if err != nil {
 			return nil
 		}
-		bc.blockStorage = tempStorage
-		return bc
-	}
-
-	// Если передан BlockStorage, используем его напрямую для тестирования
-	if blockStorage, ok := storage.(BlockStorage); ok {
-		bc := &Blockchain{
-			Chain:            make([]*Block, 0),
-			Difficulty:       difficulty,
-			storage:          nil, // не используем файловое хранилище в тестах
-			blockStorage:     blockStorage,
-			contentHashIndex: make(map[string]*Block),
-		}
-
-		// Создаем genesis блок
-		genesis := GenesisBlock()
-		bc.Chain = append(bc.Chain, genesis)
-		bc.contentHashIndex[genesis.Data.ContentHash] = genesis
-
-		// Сохраняем genesis в тестовое хранилище
-		if err := blockStorage.SaveBlock(genesis); err != nil {
-			return nil
-		}
-
 		return bc
 	}
 
@@ -0,0 +1,659 @@
+package blockchain
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Операции MemFS, на которые можно внедрить сбой
+const (
+	FSOpOpen     = "open"
+	FSOpRead     = "read"
+	FSOpWrite    = "write"
+	FSOpSync     = "sync"
+	FSOpTruncate = "truncate"
+	FSOpRename   = "rename"
+	FSOpRemove   = "remove"
+	FSOpMkdir    = "mkdir"
+)
+
+var (
+	// ErrInjectedFault возвращается внедрённым сбоем без своей ошибки
+	ErrInjectedFault = errors.New("injected fault")
+
+	// ErrCrashed возвращается любыми операциями после имитации падения
+	ErrCrashed = errors.New("filesystem crashed")
+)
+
+// Fault - сбой, внедряемый в MemFS
+type Fault struct {
+	Op    string // операция (FSOp*); пусто - любая
+	Path  string // подстрока пути; пусто - любой
+	After int    // сколько подходящих операций пропустить
+
+	// Err возвращается вместо результата операции.
+	// По умолчанию ErrInjectedFault (ErrCrashed при Crash)
+	Err error
+
+	// ShortWrite - сколько байт записи успевает попасть в файл
+	// до сбоя (только для FSOpWrite)
+	ShortWrite int
+
+	// Crash - после сбоя FS "падает": все операции возвращают
+	// ErrCrashed до вызова Restart
+	Crash bool
+
+	seen  int
+	fired bool
+}
+
+// MemFS - файловая система в памяти для тестов хранилища.
+//
+// Модель надёжности: создание, переименование и удаление файлов и
+// каталогов фиксируются сразу, а содержимое файла переживает падение
+// только в объёме последнего Sync. Restart имитирует перезапуск после
+// падения: несинхронизированные данные теряются, открытые файлы
+// становятся недействительными
+type MemFS struct {
+	mu sync.Mutex
+
+	files map[string]*memInode
+	dirs  map[string]bool
+
+	locks   map[string]*memFile
+	faults  []*Fault
+	ops     int
+	crashed bool
+	epoch   int
+}
+
+// memInode - содержимое файла
+type memInode struct {
+	data    []byte
+	synced  []byte
+	modTime time.Time
+}
+
+// NewMemFS создаёт пустую MemFS с корневым каталогом
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string]*memInode),
+		dirs:  map[string]bool{"/": true, ".": true},
+		locks: make(map[string]*memFile),
+	}
+}
+
+// InjectFault добавляет сбой. Каждый сбой срабатывает один раз
+func (m *MemFS) InjectFault(f Fault) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fault := f
+	m.faults = append(m.faults, &fault)
+}
+
+// Crash имитирует падение процесса
+func (m *MemFS) Crash() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.crashed = true
+}
+
+// Crashed сообщает, упала ли FS
+func (m *MemFS) Crashed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.crashed
+}
+
+// Restart имитирует перезапуск: содержимое файлов откатывается к
+// последнему Sync, несработавшие сбои снимаются
+func (m *MemFS) Restart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, inode := range m.files {
+		inode.data = append([]byte(nil), inode.synced...)
+	}
+	m.locks = make(map[string]*memFile)
+	m.faults = nil
+	m.crashed = false
+	m.epoch++
+}
+
+// Ops возвращает число выполненных операций
+func (m *MemFS) Ops() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ops
+}
+
+// check учитывает операцию и возвращает сработавший сбой
+func (m *MemFS) check(op, name string) (*Fault, error) {
+	if m.crashed {
+		return nil, ErrCrashed
+	}
+	m.ops++
+
+	for _, f := range m.faults {
+		if f.fired || (f.Op != "" && f.Op != op) || !strings.Contains(name, f.Path) {
+			continue
+		}
+		if f.seen < f.After {
+			f.seen++
+			continue
+		}
+
+		f.fired = true
+		err := f.Err
+		if f.Crash {
+			m.crashed = true
+			if err == nil {
+				err = ErrCrashed
+			}
+		}
+		if err == nil {
+			err = ErrInjectedFault
+		}
+		return f, err
+	}
+	return nil, nil
+}
+
+func memPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (m *MemFS) parentExists(name string) bool {
+	return m.dirs[path.Dir(name)]
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpOpen, name); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if m.dirs[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	inode, ok := m.files[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if !m.parentExists(name) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		inode = &memInode{modTime: time.Now()}
+		m.files[name] = inode
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		inode.data = nil
+		inode.modTime = time.Now()
+	}
+
+	return &memFile{fs: m, name: name, inode: inode, flag: flag, epoch: m.epoch}, nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpRead, name); err != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	inode, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), inode.data...), nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpRead, name); err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !m.dirs[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []os.DirEntry
+	for p, inode := range m.files {
+		if path.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo(p, inode)))
+		}
+	}
+	for p := range m.dirs {
+		if p != name && path.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memDirInfo(p)))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if m.crashed {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: ErrCrashed}
+	}
+	if inode, ok := m.files[name]; ok {
+		return memFileInfo(name, inode), nil
+	}
+	if m.dirs[name] {
+		return memDirInfo(name), nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = memPath(oldpath), memPath(newpath)
+	if _, err := m.check(FSOpRename, oldpath); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	if !m.parentExists(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+
+	if inode, ok := m.files[oldpath]; ok {
+		delete(m.files, oldpath)
+		m.files[newpath] = inode
+		return nil
+	}
+
+	if !m.dirs[oldpath] {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if m.dirs[newpath] {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+
+	prefix := oldpath + "/"
+	for p, inode := range m.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(m.files, p)
+			m.files[newpath+"/"+strings.TrimPrefix(p, prefix)] = inode
+		}
+	}
+	for p := range m.dirs {
+		if p == oldpath || strings.HasPrefix(p, prefix) {
+			delete(m.dirs, p)
+			m.dirs[newpath+strings.TrimPrefix(p, oldpath)] = true
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpRemove, name); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if !m.dirs[name] {
+		return &os.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	for p := range m.files {
+		if path.Dir(p) == name {
+			return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	for p := range m.dirs {
+		if p != name && path.Dir(p) == name {
+			return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	delete(m.dirs, name)
+	return nil
+}
+
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpRemove, name); err != nil {
+		return &os.PathError{Op: "removeall", Path: name, Err: err}
+	}
+
+	prefix := name + "/"
+	delete(m.files, name)
+	delete(m.dirs, name)
+	for p := range m.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(m.files, p)
+		}
+	}
+	for p := range m.dirs {
+		if strings.HasPrefix(p, prefix) {
+			delete(m.dirs, p)
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpMkdir, name); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	for p := name; !m.dirs[p]; p = path.Dir(p) {
+		if _, ok := m.files[p]; ok {
+			return &os.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+		}
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+// Lock в MemFS: блокировки снимаются при закрытии файла и при Restart
+// SyncDir только учитывает операцию: по модели MemFS записи каталога
+// фиксируются сразу
+func (m *MemFS) SyncDir(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpSync, name); err != nil {
+		return &os.PathError{Op: "sync", Path: name, Err: err}
+	}
+	if !m.dirs[name] {
+		return &os.PathError{Op: "sync", Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+func (m *MemFS) Lock(name string) (File, error) {
+	f, err := m.OpenFile(name, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, held := m.locks[name]; held {
+		f.(*memFile).closed = true
+		return nil, &os.PathError{Op: "lock", Path: name, Err: errLockHeld}
+	}
+	m.locks[name] = f.(*memFile)
+	return f, nil
+}
+
+func (m *MemFS) Truncate(name string, size int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+	if _, err := m.check(FSOpTruncate, name); err != nil {
+		return &os.PathError{Op: "truncate", Path: name, Err: err}
+	}
+
+	inode, ok := m.files[name]
+	if !ok {
+		return &os.PathError{Op: "truncate", Path: name, Err: fs.ErrNotExist}
+	}
+	inode.truncate(size)
+	return nil
+}
+
+func (n *memInode) truncate(size int64) {
+	if size <= int64(len(n.data)) {
+		n.data = n.data[:size]
+	} else {
+		n.data = append(n.data, make([]byte, size-int64(len(n.data)))...)
+	}
+	n.modTime = time.Now()
+}
+
+// memFile - открытый файл MemFS
+type memFile struct {
+	fs     *MemFS
+	name   string
+	inode  *memInode
+	flag   int
+	offset int64
+	epoch  int
+	closed bool
+}
+
+// usable проверяет, что файл открыт и FS не перезапускалась
+func (f *memFile) usable() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.fs.crashed || f.epoch != f.fs.epoch {
+		return ErrCrashed
+	}
+	return nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	n, err := f.readAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *memFile) readAt(p []byte, off int64) (int, error) {
+	if err := f.usable(); err != nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if _, err := f.fs.check(FSOpRead, f.name); err != nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if off >= int64(len(f.inode.data)) {
+		return 0, io.EOF
+	}
+	return copy(p, f.inode.data[off:]), nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.inode.data))
+	}
+	n, err := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	return f.writeAt(p, off)
+}
+
+func (f *memFile) writeAt(p []byte, off int64) (int, error) {
+	if err := f.usable(); err != nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: err}
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+
+	fault, err := f.fs.check(FSOpWrite, f.name)
+	if err != nil {
+		n := 0
+		if fault != nil && fault.ShortWrite > 0 {
+			n = min(fault.ShortWrite, len(p))
+			f.inode.write(p[:n], off)
+		}
+		return n, &os.PathError{Op: "write", Path: f.name, Err: err}
+	}
+
+	f.inode.write(p, off)
+	return len(p), nil
+}
+
+func (n *memInode) write(p []byte, off int64) {
+	if end := off + int64(len(p)); end > int64(len(n.data)) {
+		n.data = append(n.data, make([]byte, end-int64(len(n.data)))...)
+	}
+	copy(n.data[off:], p)
+	n.modTime = time.Now()
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.usable(); err != nil {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: err}
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.inode.data))
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.usable(); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: f.name, Err: err}
+	}
+	return memFileInfo(f.name, f.inode), nil
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.usable(); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	if _, err := f.fs.check(FSOpSync, f.name); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	f.inode.synced = append(f.inode.synced[:0], f.inode.data...)
+	return nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.usable(); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+	}
+	if _, err := f.fs.check(FSOpTruncate, f.name); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+	}
+	f.inode.truncate(size)
+	return nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	if f.fs.locks[f.name] == f {
+		delete(f.fs.locks, f.name)
+	}
+	return nil
+}
+
+// memInfo - os.FileInfo для MemFS
+type memInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func memFileInfo(name string, inode *memInode) memInfo {
+	return memInfo{name: path.Base(name), size: int64(len(inode.data)), modTime: inode.modTime}
+}
+
+func memDirInfo(name string) memInfo {
+	return memInfo{name: path.Base(name), dir: true}
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() any           { return nil }
+
+func (i memInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
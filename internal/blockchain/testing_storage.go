@@ -12,6 +12,7 @@ import (
 // TestStorage - in-memory хранилище для тестов
 type TestStorage struct {
 	mu     sync.RWMutex
+	chain  []*Block
 	blocks map[string]*Block
 	wal    []string // имитация WAL
 }
@@ -24,6 +25,19 @@ func NewTestStorage() *TestStorage {
 	}
 }
 
+func (s *TestStorage) LoadChain() (*Blockchain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.chain) == 0 {
+		return nil, &os.PathError{Op: "load", Path: "memory", Err: os.ErrNotExist}
+	}
+
+	chain := make([]*Block, len(s.chain))
+	copy(chain, s.chain)
+	return &Blockchain{Chain: chain}, nil
+}
+
 func (s *TestStorage) SaveBlock(block *Block) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -36,10 +50,24 @@ func (s *TestStorage) SaveBlock(block *Block) error {
 	s.wal = append(s.wal, string(data))
 
 	// Сохранение в память
+	s.chain = append(s.chain, block)
 	s.blocks[block.ID] = block
 	return nil
 }
 
+func (s *TestStorage) SaveChain(bc *Blockchain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chain = make([]*Block, len(bc.Chain))
+	copy(s.chain, bc.Chain)
+	s.blocks = make(map[string]*Block, len(bc.Chain))
+	for _, block := range bc.Chain {
+		s.blocks[block.ID] = block
+	}
+	return nil
+}
+
 func (s *TestStorage) GetBlock(id string) (*Block, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -55,10 +83,8 @@ func (s *TestStorage) GetAllBlocks() ([]*Block, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	blocks := make([]*Block, 0, len(s.blocks))
-	for _, block := range s.blocks {
-		blocks = append(blocks, block)
-	}
+	blocks := make([]*Block, len(s.chain))
+	copy(blocks, s.chain)
 	return blocks, nil
 }
 
@@ -66,18 +92,18 @@ func (s *TestStorage) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.chain = nil
 	s.blocks = make(map[string]*Block)
 	s.wal = make([]string, 0)
 	return nil
 }
 
-// TempDirStorage - хранилище в временной директории для тестов
+// TempDirStorage - файловое хранилище во временной директории для тестов
 type TempDirStorage struct {
-	Dir     string
-	storage *Storage
-	blocks  map[string]*Block
-	mu      sync.RWMutex
-	t       *testing.T
+	*Storage
+
+	Dir string
+	t   *testing.T
 }
 
 // NewTempDirStorage создает временную директорию и storage для тестов
@@ -97,42 +123,15 @@ func NewTempDirStorage(t *testing.T) *TempDirStorage {
 	}
 
 	return &TempDirStorage{
+		Storage: storage,
 		Dir:     tempDir,
-		storage: storage,
-		blocks:  make(map[string]*Block),
 		t:       t,
 	}
 }
 
-func (s *TempDirStorage) SaveBlock(block *Block) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	// Сохраняем в in-memory map
-	s.blocks[block.ID] = block
-	return nil
-}
-
-func (s *TempDirStorage) GetBlock(id string) (*Block, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	block, exists := s.blocks[id]
-	if !exists {
-		return nil, fmt.Errorf("block not found: %s", id)
-	}
-	return block, nil
-}
-
-func (s *TempDirStorage) GetAllBlocks() ([]*Block, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	blocks := make([]*Block, 0, len(s.blocks))
-	for _, block := range s.blocks {
-		blocks = append(blocks, block)
-	}
-	return blocks, nil
-}
-
+// Close закрывает хранилище и удаляет временную директорию
 func (s *TempDirStorage) Close() error {
+	s.Storage.Close()
 	err := os.RemoveAll(s.Dir)
 	if err != nil {
 		s.t.Logf("Warning: temp dir cleanup error: %v", err)
@@ -140,9 +139,9 @@ func (s *TempDirStorage) Close() error {
 	return nil
 }
 
-// GetStorage возвращает storage для работы с цепочкой (SaveChain/LoadChain)
+// GetStorage возвращает файловое хранилище (SaveChain/LoadChain, WAL, бэкапы)
 func (s *TempDirStorage) GetStorage() *Storage {
-	return s.storage
+	return s.Storage
 }
 
 // MockStorage - мок для тестирования ошибок
@@ -150,6 +149,7 @@ type MockStorage struct {
 	SaveError     error
 	GetError      error
 	GetAllError   error
+	chain         []*Block
 	blocks        map[string]*Block
 	SaveCallCount int
 }
@@ -160,15 +160,38 @@ func NewMockStorage() *MockStorage {
 	}
 }
 
+func (s *MockStorage) LoadChain() (*Blockchain, error) {
+	if s.GetAllError != nil {
+		return nil, s.GetAllError
+	}
+	if len(s.chain) == 0 {
+		return nil, &os.PathError{Op: "load", Path: "mock", Err: os.ErrNotExist}
+	}
+	return &Blockchain{Chain: append([]*Block(nil), s.chain...)}, nil
+}
+
 func (s *MockStorage) SaveBlock(block *Block) error {
 	s.SaveCallCount++
 	if s.SaveError != nil {
 		return s.SaveError
 	}
+	s.chain = append(s.chain, block)
 	s.blocks[block.ID] = block
 	return nil
 }
 
+func (s *MockStorage) SaveChain(bc *Blockchain) error {
+	if s.SaveError != nil {
+		return s.SaveError
+	}
+	s.chain = append([]*Block(nil), bc.Chain...)
+	s.blocks = make(map[string]*Block, len(bc.Chain))
+	for _, block := range bc.Chain {
+		s.blocks[block.ID] = block
+	}
+	return nil
+}
+
 func (s *MockStorage) GetBlock(id string) (*Block, error) {
 	if s.GetError != nil {
 		return nil, s.GetError
@@ -184,11 +207,7 @@ func (s *MockStorage) GetAllBlocks() ([]*Block, error) {
 	if s.GetAllError != nil {
 		return nil, s.GetAllError
 	}
-	blocks := make([]*Block, 0, len(s.blocks))
-	for _, block := range s.blocks {
-		blocks = append(blocks, block)
-	}
-	return blocks, nil
+	return append([]*Block(nil), s.chain...), nil
 }
 
 func (s *MockStorage) Close() error {
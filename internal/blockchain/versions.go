@@ -0,0 +1,185 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// checkVersions проверяет ссылки депозитов блока на прежние версии.
+// resolve ищет депозит по ссылке в цепочке до block; прежняя версия
+// может быть и раньше в том же блоке.
+//
+// Новую версию подписывает тот же ключ, что и прежнюю, или ключ,
+// к которому он перешёл ротациями по keys, либо добавляет та же
+// учётная запись (см. AccountID). Депозит без подписи и без учётной
+// записи продолжить новой версией нельзя
+func checkVersions(block *Block, resolve func(ref string) (DepositData, bool), keys *keyRegistry) error {
+	for i, data := range block.DepositList() {
+		if data.ParentID == "" {
+			continue
+		}
+
+		parent, ok := resolveInBlock(block, i, data.ParentID, resolve)
+		if !ok || parent.IsRecord() {
+			return fmt.Errorf("%w: %s", ErrParentNotFound, data.ParentID)
+		}
+		if sameAccount(parent, data) {
+			continue
+		}
+		signed, err := signedByOwner(parent, data, keys)
+		if err != nil {
+			return err
+		}
+		if !signed {
+			return ErrVersionNotAuthorized
+		}
+	}
+	return nil
+}
+
+// resolveInBlock ищет депозит, на который ссылается депозит i блока
+// block: в цепочке через resolve или раньше в том же блоке
+func resolveInBlock(block *Block, i int, ref string, resolve func(ref string) (DepositData, bool)) (DepositData, bool) {
+	if data, ok := resolve(ref); ok {
+		return data, true
+	}
+	deposits := block.DepositList()
+	for j := 0; j < i; j++ {
+		if DepositRef(block, j) == ref {
+			return deposits[j], true
+		}
+	}
+	return DepositData{}, false
+}
+
+// signedByOwner сообщает, что data подписал ключ депозита owner или
+// ключ, к которому он перешёл ротациями по keys. У работ с соавторами
+// достаточно одного такого ключа среди подписавших
+func signedByOwner(owner, data DepositData, keys *keyRegistry) (bool, error) {
+	for _, dataKey := range data.SignerKeys() {
+		d, err := ParseAuthorKey(dataKey)
+		if err != nil {
+			return false, err
+		}
+		for _, ownerKey := range owner.SignerKeys() {
+			o, err := ParseAuthorKey(ownerKey)
+			if err != nil {
+				continue
+			}
+			if d.Fingerprint == o.Fingerprint || keys.inheritsFrom(d.Fingerprint, o.Fingerprint) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// depositByRef ищет депозит по ссылке. Вызывается под bc.mu
+func (bc *Blockchain) depositByRef(ref string) (depositPos, bool) {
+	id, index, ok := parseDepositRef(ref)
+	if !ok {
+		return depositPos{}, false
+	}
+	block := bc.indexedBlock(bc.idIndex, id)
+	if block == nil || !refersTo(block, ref, index) {
+		return depositPos{}, false
+	}
+	return depositPos{block: block, index: index}, true
+}
+
+// resolveDeposit - depositByRef для checkVersions. Вызывается под bc.mu
+func (bc *Blockchain) resolveDeposit(ref string) (DepositData, bool) {
+	pos, ok := bc.depositByRef(ref)
+	if !ok {
+		return DepositData{}, false
+	}
+	return pos.data(), true
+}
+
+// CheckVersion проверяет ссылку депозита на прежнюю версию, не добавляя
+// его в цепочку. Нужна, чтобы отвергнуть депозит до майнинга
+func (bc *Blockchain) CheckVersion(data DepositData) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return checkVersions(&Block{Data: data}, bc.resolveDeposit, bc.keys)
+}
+
+// rebuildVersionIndex перестраивает индекс прежняя версия -> следующие
+func (bc *Blockchain) rebuildVersionIndex() {
+	bc.versionIndex = make(map[string][]depositPos)
+	for _, block := range bc.Chain {
+		if block != nil {
+			bc.indexVersions(block)
+		}
+	}
+}
+
+// indexVersions добавляет в индекс версий депозиты блока
+func (bc *Blockchain) indexVersions(block *Block) {
+	for i, data := range block.DepositList() {
+		if data.ParentID != "" {
+			bc.versionIndex[data.ParentID] = append(bc.versionIndex[data.ParentID], depositPos{block: block, index: i})
+		}
+	}
+}
+
+// unindexVersions убирает из индекса версий депозиты последнего блока
+func (bc *Blockchain) unindexVersions(block *Block) {
+	for _, data := range block.DepositList() {
+		children := bc.versionIndex[data.ParentID]
+		n := len(children)
+		for n > 0 && children[n-1].block == block {
+			n--
+		}
+		if n == 0 {
+			delete(bc.versionIndex, data.ParentID)
+		} else {
+			bc.versionIndex[data.ParentID] = children[:n]
+		}
+	}
+}
+
+// Versions возвращает историю версий произведения, в которое входит
+// депозит ref: первую версию и все следующие от неё, в порядке цепочки.
+// У депозита без версий история - он сам
+func (bc *Blockchain) Versions(ref string) ([]*DepositReceipt, error) {
+	bc.mu.RLock()
+	pos, ok := bc.depositByRef(ref)
+	if !ok {
+		bc.mu.RUnlock()
+		return nil, ErrBlockNotFound
+	}
+
+	// Поднимаемся к первой версии
+	for parent := pos.data().ParentID; parent != ""; parent = pos.data().ParentID {
+		p, ok := bc.depositByRef(parent)
+		if !ok {
+			break
+		}
+		pos = p
+	}
+
+	// Следующие версии могут ветвиться: собираем всё дерево
+	versions := []depositPos{pos}
+	for i := 0; i < len(versions); i++ {
+		versions = append(versions, bc.versionIndex[versions[i].ref()]...)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		hi, hj := bc.idIndex[versions[i].block.ID], bc.idIndex[versions[j].block.ID]
+		if hi != hj {
+			return hi < hj
+		}
+		return versions[i].index < versions[j].index
+	})
+	bc.mu.RUnlock()
+
+	receipts := make([]*DepositReceipt, 0, len(versions))
+	for _, v := range versions {
+		receipt, err := newDepositReceipt(v.block, v.index)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlockchain_Versions(t *testing.T) {
+	storage := NewTestStorage()
+	bc, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	a, b, stranger := newTestAuthorKey(t), newTestAuthorKey(t), newTestAuthorKey(t)
+
+	draft := func(key testAuthorKey, text, parent string) DepositData {
+		data := CreateTestBlock("Author", "Manuscript", text)
+		data.PublicKey = key.public
+		data.ParentID = parent
+		return key.sign(data)
+	}
+
+	first, err := bc.AddBlock(draft(a, "draft 1", ""))
+	AssertNoError(t, err)
+	second, err := bc.AddBlock(draft(a, "draft 2", first.ID))
+	AssertNoError(t, err)
+
+	t.Run("foreign key", func(t *testing.T) {
+		err := bc.CheckVersion(draft(stranger, "stolen draft", first.ID))
+		if !errors.Is(err, ErrVersionNotAuthorized) {
+			t.Errorf("CheckVersion() error = %v, want ErrVersionNotAuthorized", err)
+		}
+		_, err = bc.AddBlock(draft(stranger, "stolen draft", first.ID))
+		if !errors.Is(err, ErrVersionNotAuthorized) {
+			t.Errorf("AddBlock() error = %v, want ErrVersionNotAuthorized", err)
+		}
+	})
+
+	t.Run("unsigned version", func(t *testing.T) {
+		data := CreateTestBlock("Author", "Manuscript", "unsigned draft")
+		data.ParentID = first.ID
+		if err := bc.CheckVersion(data); !errors.Is(err, ErrVersionNotAuthorized) {
+			t.Errorf("CheckVersion() error = %v, want ErrVersionNotAuthorized", err)
+		}
+	})
+
+	t.Run("same account", func(t *testing.T) {
+		data := CreateTestBlock("Author", "Essay", "unsigned essay")
+		data.Account = AccountID("owner-account-token")
+		essay, err := bc.AddBlock(data)
+		AssertNoError(t, err)
+
+		revised := CreateTestBlock("Author", "Essay", "unsigned essay, revised")
+		revised.ParentID = essay.ID
+		revised.Account = AccountID("owner-account-token")
+		AssertNoError(t, bc.CheckVersion(revised))
+
+		revised.Account = AccountID("other-account-token")
+		if err := bc.CheckVersion(revised); !errors.Is(err, ErrVersionNotAuthorized) {
+			t.Errorf("CheckVersion() error = %v, want ErrVersionNotAuthorized", err)
+		}
+
+		// Без подписи и без учётной записи новую версию не добавить
+		revised.Account = ""
+		if err := bc.CheckVersion(revised); !errors.Is(err, ErrVersionNotAuthorized) {
+			t.Errorf("CheckVersion() without account error = %v, want ErrVersionNotAuthorized", err)
+		}
+	})
+
+	t.Run("unknown parent", func(t *testing.T) {
+		err := bc.CheckVersion(draft(a, "orphan draft", "999-999-999"))
+		if !errors.Is(err, ErrParentNotFound) {
+			t.Errorf("CheckVersion() error = %v, want ErrParentNotFound", err)
+		}
+	})
+
+	t.Run("parent id is signed", func(t *testing.T) {
+		data := draft(a, "draft 3", second.ID)
+		data.ParentID = first.ID
+		if _, err := data.VerifySignature(); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifySignature() error = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	// После ротации следующие версии подписывает новый ключ, в том числе
+	// ветку от первой версии
+	_, err = bc.AddBlock(a.sign(NewKeyRotation(bc.HashAlgorithm(), a.public, b.public)))
+	AssertNoError(t, err)
+	third, err := bc.AddBlock(draft(b, "draft 3", second.ID))
+	AssertNoError(t, err)
+	_, err = bc.AddBlock(draft(b, "alternative draft 2", first.ID))
+	AssertNoError(t, err)
+
+	_, err = bc.AddBatch([]DepositData{draft(b, "draft 4", third.ID)})
+	AssertNoError(t, err)
+
+	check := func(bc *Blockchain) {
+		t.Helper()
+		for _, ref := range []string{first.ID, third.ID} {
+			versions, err := bc.Versions(ref)
+			AssertNoError(t, err)
+			if len(versions) != 5 {
+				t.Fatalf("Versions(%s) returned %d versions, want 5", ref, len(versions))
+			}
+			AssertEqual(t, versions[0].Ref, first.ID, "First version")
+			AssertEqual(t, versions[1].Ref, second.ID, "Second version")
+			AssertEqual(t, versions[2].Ref, third.ID, "Third version")
+			for i := 1; i < len(versions); i++ {
+				if versions[i].Block.Timestamp.Before(versions[i-1].Block.Timestamp) {
+					t.Errorf("Version %d is older than version %d", i+1, i)
+				}
+			}
+		}
+		if !bc.ValidateChain() {
+			t.Error("Chain with versions is invalid")
+		}
+	}
+	check(bc)
+
+	reloaded, err := NewBlockchain(storage, 1)
+	AssertNoError(t, err)
+	check(reloaded)
+
+	unrelated, err := bc.AddBlock(CreateTestBlock("Other", "Other", "unrelated"))
+	AssertNoError(t, err)
+	versions, err := bc.Versions(unrelated.ID)
+	AssertNoError(t, err)
+	AssertEqual(t, len(versions), 1, "Versions of unversioned deposit")
+
+	_, err = bc.Versions("999-999-999")
+	AssertEqual(t, err, ErrBlockNotFound, "Unknown ref")
+}
+
+func TestCheckVersions_SameBlock(t *testing.T) {
+	a := newTestAuthorKey(t)
+	parent := CreateTestBlock("Author", "Work", "v1")
+	parent.PublicKey = a.public
+	parent = a.sign(parent)
+
+	child := CreateTestBlock("Author", "Work", "v2")
+	child.PublicKey = a.public
+	child.ParentID = "000-000-001.0"
+	child = a.sign(child)
+
+	none := func(string) (DepositData, bool) { return DepositData{}, false }
+
+	block := newBatchBlock("000-000-001", "prev", HashSHA256, []DepositData{parent, child})
+	AssertNoError(t, checkVersions(block, none, newKeyRegistry()))
+
+	// Ссылка вперёд по пакету не допускается
+	block = newBatchBlock("000-000-001", "prev", HashSHA256, []DepositData{child, parent})
+	if err := checkVersions(block, none, newKeyRegistry()); !errors.Is(err, ErrParentNotFound) {
+		t.Errorf("checkVersions() error = %v, want ErrParentNotFound", err)
+	}
+}
@@ -0,0 +1,195 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+)
+
+// Формат WAL
+//
+// wal.json - журнал упреждающей записи в формате JSON Lines. Каждая
+// строка - одна запись:
+//
+//	{"crc":<CRC-32C поля block>,"block":{...}}
+//
+// Записи только дописываются в конец файла и фиксируются через fsync
+// до возврата из WriteToWAL. Сбой посреди записи оставляет в конце
+// файла "рваный хвост" - неполную строку или строку с неверной CRC.
+// ReadWAL возвращает все целые записи до хвоста и сообщает о нём
+// ошибкой *WALTornTailError.
+//
+// Файлы прежнего формата (JSON-массив блоков) читаются как раньше.
+
+// walRecord - одна запись WAL
+type walRecord struct {
+	CRC   uint32          `json:"crc"`
+	Block json.RawMessage `json:"block"`
+}
+
+// WALTornTailError сообщает о неполной последней записи WAL
+type WALTornTailError struct {
+	Offset  int64 // смещение начала хвоста
+	Size    int64 // размер хвоста в байтах
+	Records int   // количество целых записей до хвоста
+}
+
+func (e *WALTornTailError) Error() string {
+	return fmt.Sprintf("torn WAL tail at offset %d (%d bytes) after %d intact records",
+		e.Offset, e.Size, e.Records)
+}
+
+// encodeWALRecord кодирует блок в строку WAL (с переводом строки)
+func encodeWALRecord(block *Block) ([]byte, error) {
+	payload, err := json.Marshal(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal block: %w", err)
+	}
+
+	line, err := json.Marshal(walRecord{
+		CRC:   crc32.Checksum(payload, castagnoli),
+		Block: payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal WAL record: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// WriteToWAL дописывает блок в WAL и фиксирует запись на диске
+func (s *Storage) WriteToWAL(block *Block) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	line, err := encodeWALRecord(block)
+	if err != nil {
+		return err
+	}
+
+	f, err := s.fs.OpenFile(s.walFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write WAL record: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+
+	return nil
+}
+
+// ReadWAL читает блоки из WAL.
+//
+// Если последняя запись повреждена, возвращает все предшествующие ей
+// блоки вместе с *WALTornTailError. Повреждение в середине файла
+// (за битой записью следуют целые) - ошибка ErrWALRecoveryFailed
+func (s *Storage) ReadWAL() ([]*Block, error) {
+	data, err := s.fs.ReadFile(s.walFile)
+	if os.IsNotExist(err) {
+		return []*Block{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	return parseWAL(data)
+}
+
+// parseWAL разбирает содержимое WAL
+func parseWAL(data []byte) ([]*Block, error) {
+	// Прежний формат: JSON-массив блоков
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var blocks []*Block
+		if err := json.Unmarshal(trimmed, &blocks); err != nil {
+			return nil, fmt.Errorf("failed to parse legacy WAL: %w", err)
+		}
+		return blocks, nil
+	}
+
+	blocks := []*Block{}
+	var offset int64
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		complete := end >= 0
+		if !complete {
+			end = len(data)
+		}
+		line := data[:end]
+
+		block, ok := decodeWALRecord(line)
+		if !ok || !complete {
+			rest := data[end:]
+			if complete {
+				rest = rest[1:]
+			}
+			// Битая запись, за которой есть ещё данные, - не хвост
+			if len(bytes.TrimSpace(rest)) > 0 {
+				return blocks, NewBlockchainError(ErrWALRecoveryFailed.Code,
+					fmt.Sprintf("corrupt WAL record at offset %d", offset), nil)
+			}
+			return blocks, &WALTornTailError{
+				Offset:  offset,
+				Size:    int64(len(data)),
+				Records: len(blocks),
+			}
+		}
+
+		blocks = append(blocks, block)
+		offset += int64(end + 1)
+		data = data[end+1:]
+	}
+
+	return blocks, nil
+}
+
+// decodeWALRecord разбирает одну строку WAL и проверяет CRC
+func decodeWALRecord(line []byte) (*Block, bool) {
+	var record walRecord
+	if err := json.Unmarshal(line, &record); err != nil || len(record.Block) == 0 {
+		return nil, false
+	}
+
+	if crc32.Checksum(record.Block, castagnoli) != record.CRC {
+		return nil, false
+	}
+
+	var block Block
+	if err := json.Unmarshal(record.Block, &block); err != nil {
+		return nil, false
+	}
+	return &block, true
+}
+
+// ClearWAL удаляет WAL файл
+func (s *Storage) ClearWAL() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if _, err := s.fs.Stat(s.walFile); os.IsNotExist(err) {
+		return nil // Файла нет
+	}
+	return s.fs.Remove(s.walFile)
+}
+
+// PreserveWAL переносит WAL с повреждённым хвостом в файл
+// wal.json.torn-<время>, чтобы хвост остался доступен для разбора.
+// Возвращает путь к сохранённому файлу
+func (s *Storage) PreserveWAL() (string, error) {
+	if s.readOnly {
+		return "", ErrReadOnly
+	}
+
+	path := fmt.Sprintf("%s.torn-%s", s.walFile, time.Now().UTC().Format("20060102T150405Z"))
+	if err := s.fs.Rename(s.walFile, path); err != nil {
+		return "", fmt.Errorf("failed to preserve WAL: %w", err)
+	}
+	return path, nil
+}
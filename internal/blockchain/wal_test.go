@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStorage_WALAppend(t *testing.T) {
+	tempStorage := NewTempDirStorage(t)
+	defer tempStorage.Close()
+
+	blocks := testChainBlocks(3)
+	for _, block := range blocks {
+		AssertNoError(t, tempStorage.WriteToWAL(block))
+	}
+
+	// Каждая запись - отдельная строка
+	data, err := os.ReadFile(tempStorage.GetWALPath())
+	AssertNoError(t, err)
+	AssertEqual(t, strings.Count(string(data), "\n"), 3, "WAL lines")
+
+	loaded, err := tempStorage.ReadWAL()
+	AssertNoError(t, err)
+	AssertEqual(t, len(loaded), 3, "WAL records")
+	for i := range blocks {
+		AssertEqual(t, loaded[i].Hash, blocks[i].Hash, "WAL record hash", i)
+	}
+}
+
+func TestParseWAL(t *testing.T) {
+	var valid strings.Builder
+	for _, block := range testChainBlocks(2) {
+		line, err := encodeWALRecord(block)
+		AssertNoError(t, err)
+		valid.Write(line)
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		blocks, err := parseWAL([]byte(valid.String()))
+		AssertNoError(t, err)
+		AssertEqual(t, len(blocks), 2, "Records")
+	})
+
+	t.Run("torn tail", func(t *testing.T) {
+		blocks, err := parseWAL([]byte(valid.String() + `{"crc":1,"blo`))
+
+		var torn *WALTornTailError
+		if !errors.As(err, &torn) {
+			t.Fatalf("parseWAL() error = %v, want WALTornTailError", err)
+		}
+		AssertEqual(t, len(blocks), 2, "Intact records before tail")
+		AssertEqual(t, torn.Records, 2, "Torn.Records")
+		AssertEqual(t, torn.Offset, int64(valid.Len()), "Torn.Offset")
+	})
+
+	t.Run("checksum mismatch in last record", func(t *testing.T) {
+		lines := strings.SplitAfter(valid.String(), "\n")
+		broken := lines[0] + strings.Replace(lines[1], `"author_name":"Author`, `"author_name":"Mallory`, 1)
+
+		blocks, err := parseWAL([]byte(broken))
+		var torn *WALTornTailError
+		if !errors.As(err, &torn) {
+			t.Fatalf("parseWAL() error = %v, want WALTornTailError", err)
+		}
+		AssertEqual(t, len(blocks), 1, "Intact records before bad CRC")
+	})
+
+	t.Run("corruption in the middle", func(t *testing.T) {
+		lines := strings.SplitAfter(valid.String(), "\n")
+		_, err := parseWAL([]byte("garbage\n" + lines[0] + lines[1]))
+
+		var torn *WALTornTailError
+		if err == nil || errors.As(err, &torn) {
+			t.Errorf("parseWAL() error = %v, want hard corruption error", err)
+		}
+	})
+
+	t.Run("legacy array format", func(t *testing.T) {
+		blocks, err := parseWAL([]byte(`[{"id":"000-000-001"}]`))
+		AssertNoError(t, err)
+		AssertEqual(t, len(blocks), 1, "Legacy records")
+	})
+}
@@ -1,35 +1,89 @@
 package config
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
+// MaxDifficultyBits - предел -difficulty-bits: дальше майнинг
+// недостижим за разумное время
+const MaxDifficultyBits = 64
+
 // Config содержит конфигурацию приложения
 type Config struct {
-	DataDir     string
-	Port        int
-	Difficulty  int
-	EnableDebug bool
+	DataDir        string
+	StorageBackend string // "file" или "sqlite"
+	Port           int
+	Difficulty     int    // сложность в hex-нулях (шаг - 16-кратная работа)
+	DifficultyBits int    // сложность в нулевых битах, 0 - из Difficulty
+	Target         string // 256-битная цель в hex, "" - из сложности
+	HashAlg        string // алгоритм хеша новых блоков и текстов
+	EnableDebug    bool
+	Recover        bool // разрешить восстановление повреждённой цепочки
+	MigrateDryRun  bool // показать план миграции формата и выйти
+
+	// Генезис развёртывания
+	Genesis     string // JSON-файл с генезисом новой цепочки, "" - генезис по умолчанию
+	GenesisHash string // закреплённый хеш генезиса в hex, "" - не закреплён
+
+	// Резервные копии (бэкенд file)
+	BackupEvery  int           // бэкап каждые N блоков, 0 - отключено
+	BackupKeep   int           // сколько последних бэкапов хранить
+	BackupMaxAge time.Duration // удалять бэкапы старше, 0 - без ограничения
+
+	// Пакетное депонирование
+	BatchSize int           // депозитов в блоке, 1 - каждый депозит своим блоком
+	BatchWait time.Duration // сколько депозит ждёт заполнения пакета
+
+	// Пересчёт сложности
+	TargetBlockTime  time.Duration // целевое время блока, 0 - сложность постоянна
+	RetargetInterval int           // блоков между пересчётами сложности
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию
 func DefaultConfig() *Config {
 	return &Config{
-		DataDir:     "data",
-		Port:        8080,
-		Difficulty:  4,
-		EnableDebug: false,
+		DataDir:        "data",
+		StorageBackend: "file",
+		Port:           8080,
+		Difficulty:     4,
+		HashAlg:        "sha256",
+		EnableDebug:    false,
+		BackupEvery:    100,
+		BackupKeep:     5,
+		BackupMaxAge:   0,
+		BatchSize:      1,
+		BatchWait:      2 * time.Second,
+
+		RetargetInterval: 10,
 	}
 }
 
 // LoadFromFlags загружает конфигурацию из флагов командной строки
 func (c *Config) LoadFromFlags() {
 	flag.StringVar(&c.DataDir, "data-dir", c.DataDir, "Директория для хранения данных")
+	flag.StringVar(&c.StorageBackend, "storage", c.StorageBackend, "Бэкенд хранилища: file или sqlite")
 	flag.IntVar(&c.Port, "port", c.Port, "Порт для HTTP сервера")
 	flag.IntVar(&c.Difficulty, "difficulty", c.Difficulty, "Сложность майнинга (количество нулей)")
+	flag.IntVar(&c.DifficultyBits, "difficulty-bits", c.DifficultyBits, "Сложность майнинга в нулевых битах, точнее -difficulty (0 - не задана)")
+	flag.StringVar(&c.Target, "target", c.Target, "Цель майнинга: 256-битное число в hex, хеш блока не больше цели")
+	flag.StringVar(&c.HashAlg, "hash-alg", c.HashAlg, "Алгоритм хеша новых блоков и текстов: sha256, sha3-256 или blake2b-256")
 	flag.BoolVar(&c.EnableDebug, "debug", c.EnableDebug, "Включить режим отладки")
+	flag.BoolVar(&c.Recover, "recover", c.Recover, "Восстановить повреждённую цепочку (отброшенные блоки уходят в карантин)")
+	flag.BoolVar(&c.MigrateDryRun, "migrate-dry-run", c.MigrateDryRun, "Показать, что изменит миграция формата данных, и выйти без изменений")
+	flag.StringVar(&c.Genesis, "genesis", c.Genesis, "JSON-файл с генезисом новой цепочки (у каждого экземпляра свой)")
+	flag.StringVar(&c.GenesisHash, "genesis-hash", c.GenesisHash, "Ожидаемый хеш генезиса: цепочка с другим генезисом не открывается")
+	flag.IntVar(&c.BackupEvery, "backup-every", c.BackupEvery, "Создавать бэкап каждые N блоков (0 - отключить)")
+	flag.IntVar(&c.BackupKeep, "backup-keep", c.BackupKeep, "Сколько последних бэкапов хранить")
+	flag.DurationVar(&c.BackupMaxAge, "backup-max-age", c.BackupMaxAge, "Удалять бэкапы старше (например 720h, 0 - без ограничения)")
+	flag.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "Депозитов в одном блоке (1 - каждый депозит своим блоком)")
+	flag.DurationVar(&c.BatchWait, "batch-wait", c.BatchWait, "Сколько депозит ждёт заполнения пакета, прежде чем блок будет запечатан")
+	flag.DurationVar(&c.TargetBlockTime, "target-block-time", c.TargetBlockTime, "Целевое время блока для пересчёта сложности (0 - сложность постоянна)")
+	flag.IntVar(&c.RetargetInterval, "retarget-interval", c.RetargetInterval, "Через сколько блоков пересчитывать сложность")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Использование: %s [опции]\n\n", os.Args[0])
@@ -38,6 +92,15 @@ func (c *Config) LoadFromFlags() {
 		fmt.Fprintln(os.Stderr, "\nПримеры:")
 		fmt.Fprintln(os.Stderr, "  server -data-dir ./my_data -port 9090")
 		fmt.Fprintln(os.Stderr, "  server -difficulty 3 -debug")
+		fmt.Fprintln(os.Stderr, "  server -difficulty-bits 18")
+		fmt.Fprintln(os.Stderr, "  server -hash-alg sha3-256")
+		fmt.Fprintln(os.Stderr, "  server -storage sqlite -data-dir /var/lib/textproof")
+		fmt.Fprintln(os.Stderr, "  server -backup-keep 10 -backup-max-age 720h")
+		fmt.Fprintln(os.Stderr, "  server -batch-size 500 -batch-wait 5s")
+		fmt.Fprintln(os.Stderr, "  server -target-block-time 10s -retarget-interval 20")
+		fmt.Fprintln(os.Stderr, "  server -genesis genesis.staging.json -genesis-hash 3f9a...")
+		fmt.Fprintln(os.Stderr, "  server -recover")
+		fmt.Fprintln(os.Stderr, "  server -migrate-dry-run -data-dir /var/lib/textproof")
 	}
 
 	flag.Parse()
@@ -48,8 +111,51 @@ func (c *Config) Validate() error {
 	if c.Difficulty < 1 || c.Difficulty > 6 {
 		return fmt.Errorf("сложность должна быть от 1 до 6")
 	}
+	if c.DifficultyBits < 0 || c.DifficultyBits > MaxDifficultyBits {
+		return fmt.Errorf("сложность в битах должна быть от 1 до %d", MaxDifficultyBits)
+	}
+	if c.Target != "" {
+		if c.DifficultyBits > 0 {
+			return fmt.Errorf("задайте либо -difficulty-bits, либо -target")
+		}
+		raw, err := hex.DecodeString(c.Target)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("цель должна быть 64 hex-символами")
+		}
+		if strings.Trim(c.Target, "0") == "" {
+			return fmt.Errorf("цель не может быть нулевой")
+		}
+	}
+	if c.GenesisHash != "" {
+		raw, err := hex.DecodeString(c.GenesisHash)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("хеш генезиса должен быть 64 hex-символами")
+		}
+	}
+	switch c.HashAlg {
+	case "", "sha256", "sha3-256", "blake2b-256":
+	default:
+		return fmt.Errorf("неизвестный алгоритм хеша %q (допустимо: sha256, sha3-256, blake2b-256)", c.HashAlg)
+	}
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("порт должен быть от 1 до 65535")
 	}
+	switch c.StorageBackend {
+	case "", "file", "sqlite":
+	default:
+		return fmt.Errorf("неизвестный бэкенд хранилища %q (допустимо: file, sqlite)", c.StorageBackend)
+	}
+	if c.BackupEvery < 0 || c.BackupKeep < 0 || c.BackupMaxAge < 0 {
+		return fmt.Errorf("параметры бэкапов не могут быть отрицательными")
+	}
+	if c.BatchSize < 0 || c.BatchWait < 0 {
+		return fmt.Errorf("параметры пакетов не могут быть отрицательными")
+	}
+	if c.TargetBlockTime < 0 {
+		return fmt.Errorf("целевое время блока не может быть отрицательным")
+	}
+	if c.TargetBlockTime > 0 && c.RetargetInterval < 1 {
+		return fmt.Errorf("интервал пересчёта сложности должен быть не меньше 1")
+	}
 	return nil
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"flag"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -24,6 +26,10 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.EnableDebug != false {
 		t.Errorf("EnableDebug = %v, want false", cfg.EnableDebug)
 	}
+
+	if cfg.StorageBackend != "file" {
+		t.Errorf("StorageBackend = %s, want file", cfg.StorageBackend)
+	}
 }
 
 func TestLoadFromFlags(t *testing.T) {
@@ -88,6 +94,75 @@ func TestLoadFromFlags(t *testing.T) {
 		}
 	})
 
+	t.Run("storage backend", func(t *testing.T) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		os.Args = []string{"cmd", "-storage", "sqlite"}
+
+		cfg := DefaultConfig()
+		cfg.LoadFromFlags()
+
+		if cfg.StorageBackend != "sqlite" {
+			t.Errorf("StorageBackend = %s, want sqlite", cfg.StorageBackend)
+		}
+	})
+
+	t.Run("backup retention", func(t *testing.T) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		os.Args = []string{"cmd", "-backup-keep", "10", "-backup-max-age", "720h", "-backup-every", "50"}
+
+		cfg := DefaultConfig()
+		cfg.LoadFromFlags()
+
+		if cfg.BackupKeep != 10 {
+			t.Errorf("BackupKeep = %d, want 10", cfg.BackupKeep)
+		}
+		if cfg.BackupMaxAge != 720*time.Hour {
+			t.Errorf("BackupMaxAge = %v, want 720h", cfg.BackupMaxAge)
+		}
+		if cfg.BackupEvery != 50 {
+			t.Errorf("BackupEvery = %d, want 50", cfg.BackupEvery)
+		}
+	})
+
+	t.Run("batching", func(t *testing.T) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		os.Args = []string{"cmd", "-batch-size", "500", "-batch-wait", "5s"}
+
+		cfg := DefaultConfig()
+		cfg.LoadFromFlags()
+
+		if cfg.BatchSize != 500 {
+			t.Errorf("BatchSize = %d, want 500", cfg.BatchSize)
+		}
+		if cfg.BatchWait != 5*time.Second {
+			t.Errorf("BatchWait = %v, want 5s", cfg.BatchWait)
+		}
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		os.Args = []string{"cmd", "-recover"}
+
+		cfg := DefaultConfig()
+		cfg.LoadFromFlags()
+
+		if !cfg.Recover {
+			t.Error("Recover = false, want true")
+		}
+	})
+
+	t.Run("migrate dry run", func(t *testing.T) {
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		os.Args = []string{"cmd", "-migrate-dry-run"}
+
+		cfg := DefaultConfig()
+		cfg.LoadFromFlags()
+
+		if !cfg.MigrateDryRun {
+			t.Error("MigrateDryRun = false, want true")
+		}
+	})
+
 	t.Run("enable debug", func(t *testing.T) {
 		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 		os.Args = []string{"cmd", "-debug"}
@@ -157,3 +232,140 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_ValidateStorageBackend(t *testing.T) {
+	for _, backend := range []string{"", "file", "sqlite"} {
+		cfg := DefaultConfig()
+		cfg.StorageBackend = backend
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with backend %q error = %v", backend, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.StorageBackend = "postgres"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with unknown backend should fail")
+	}
+}
+
+func TestConfig_ValidateBackups(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BackupKeep = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative BackupKeep should fail")
+	}
+
+	cfg = DefaultConfig()
+	cfg.BackupMaxAge = -time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative BackupMaxAge should fail")
+	}
+}
+
+func TestConfig_ValidateBatching(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BatchSize = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative BatchSize should fail")
+	}
+
+	cfg = DefaultConfig()
+	cfg.BatchWait = -time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative BatchWait should fail")
+	}
+}
+
+func TestConfig_ValidateRetarget(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TargetBlockTime = -time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative TargetBlockTime should fail")
+	}
+
+	cfg = DefaultConfig()
+	cfg.TargetBlockTime = 10 * time.Second
+	cfg.RetargetInterval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with zero RetargetInterval should fail")
+	}
+
+	cfg = DefaultConfig()
+	cfg.RetargetInterval = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() without retargeting error = %v", err)
+	}
+}
+
+func TestConfig_ValidateTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		target  string
+		wantErr bool
+	}{
+		{"bits", 18, "", false},
+		{"bits above hex cap", MaxDifficultyBits, "", false},
+		{"bits too high", MaxDifficultyBits + 1, "", true},
+		{"negative bits", -1, "", true},
+		{"target", 0, "00003fff" + strings.Repeat("f", 56), false},
+		{"short target", 0, "00ff", true},
+		{"zero target", 0, strings.Repeat("0", 64), true},
+		{"bits and target", 18, strings.Repeat("f", 64), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.DifficultyBits = tt.bits
+			cfg.Target = tt.target
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateHashAlg(t *testing.T) {
+	for _, alg := range []string{"", "sha256", "sha3-256", "blake2b-256"} {
+		cfg := DefaultConfig()
+		cfg.HashAlg = alg
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with hash algorithm %q error = %v", alg, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.HashAlg = "md5"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with unknown hash algorithm should fail")
+	}
+}
+
+func TestConfig_ValidateGenesisHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{"not pinned", "", false},
+		{"pinned", strings.Repeat("ab", 32), false},
+		{"uppercase", strings.Repeat("AB", 32), false},
+		{"short", "abcd", true},
+		{"not hex", strings.Repeat("zz", 32), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.GenesisHash = tt.hash
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
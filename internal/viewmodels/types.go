@@ -14,6 +14,24 @@ type DepositRequest struct {
 	Title      string `json:"title"`
 	Text       string `json:"text"`
 	PublicKey  string `json:"public_key,omitempty"`
+	Signature  string `json:"signature,omitempty"` // подпись автора в base64, см. README
+	ParentID   string `json:"parent_id,omitempty"` // ID прежней версии этой работы
+
+	// Секретный токен учётной записи автора: в блок попадает только
+	// его хеш. Новую версию работы можно добавить с тем же токеном
+	AccountToken string `json:"account_token,omitempty"`
+
+	// Соавторы: вместо author_name, public_key и signature. Депозит
+	// записывается, когда подпишут все соавторы с ключами
+	Authors []DepositAuthor `json:"authors,omitempty"`
+}
+
+// Соавтор в запросе на депонирование
+type DepositAuthor struct {
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+	Signature string `json:"signature,omitempty"` // подпись соавтора в base64, см. README
 }
 
 // Ответ на депонирование
@@ -36,20 +54,72 @@ type VerifyByTextRequest struct {
 	Text string `json:"text"`
 }
 
-// Ответ на проверку
+// Ответ на проверку. BlockID - ID блока, для депозита из пакетного
+// блока - ссылка вида "<ID>.<номер>"
 type VerificationResponse struct {
-	Found     bool      `json:"found"`
-	BlockID   string    `json:"block_id,omitempty"`
-	Author    string    `json:"author,omitempty"`
-	Title     string    `json:"title,omitempty"`
-	Timestamp time.Time `json:"timestamp,omitempty"`
-	Hash      string    `json:"hash,omitempty"`
-	Matches   bool      `json:"matches,omitempty"` // Совпадает ли хеш
+	Found      bool         `json:"found"`
+	BlockID    string       `json:"block_id,omitempty"`
+	Author     string       `json:"author,omitempty"`
+	Title      string       `json:"title,omitempty"`
+	Timestamp  time.Time    `json:"timestamp,omitempty"`
+	Hash       string       `json:"hash,omitempty"`
+	HashAlg    string       `json:"hash_alg,omitempty"` // алгоритм хеша текста
+	Matches    bool         `json:"matches,omitempty"`  // Совпадает ли хеш
+	BlockHash  string       `json:"block_hash,omitempty"`
+	MerkleRoot string       `json:"merkle_root,omitempty"`
+	Proof      *MerkleProof `json:"proof,omitempty"` // Нет у блоков без корня Меркла
+
+	// Подпись автора: депозит подписан ключом с этим отпечатком
+	Signed         bool   `json:"signed"`
+	KeyType        string `json:"key_type,omitempty"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+
+	// Состояние ключа подписи по записям цепочки: отзыв и ротации
+	// к следующим ключам (последний - текущий ключ автора)
+	KeyRevoked            bool       `json:"key_revoked,omitempty"`
+	KeyRevokedAt          *time.Time `json:"key_revoked_at,omitempty"`
+	SignedAfterRevocation bool       `json:"signed_after_revocation,omitempty"`
+	KeySuccessors         []string   `json:"key_successors,omitempty"`
+
+	RecordType string       `json:"record_type,omitempty"` // Служебная запись о ключах, а не текст
+	Authors    []AuthorInfo `json:"authors,omitempty"`     // Соавторы работы
+
+	// Отзыв и исправления депозита более поздними записями автора:
+	// retracted_by - ссылка на запись об отзыве
+	Retracted   bool            `json:"retracted"`
+	RetractedBy string          `json:"retracted_by,omitempty"`
+	Notices     []DepositNotice `json:"notices,omitempty"`
+
+	// Депозит, который отзывает или исправляет эта запись, и причина
+	Target string `json:"target,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	// Версии работы: прежняя версия этого депозита и вся история,
+	// если версий больше одной
+	ParentID string        `json:"parent_id,omitempty"`
+	Versions []WorkVersion `json:"versions,omitempty"`
+}
+
+// Шаг доказательства включения: хеш соседнего узла и его сторона
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// Доказательство включения депозита в блок: путь от листа Leaf
+// к корню Root из заголовка блока
+type MerkleProof struct {
+	Algorithm string      `json:"algorithm"` // хеш-функция дерева
+	Index     int         `json:"index"`
+	Leaf      string      `json:"leaf"`
+	Siblings  []ProofStep `json:"siblings"`
+	Root      string      `json:"root"`
 }
 
 // Ответ со статистикой
 type StatsResponse struct {
 	TotalBlocks   int       `json:"total_blocks"`
+	TotalDeposits int       `json:"total_deposits"`
 	UniqueAuthors int       `json:"unique_authors"`
 	LastAdded     time.Time `json:"last_added"`
 	ChainValid    bool      `json:"chain_valid"`
@@ -57,10 +127,34 @@ type StatsResponse struct {
 
 // Ответ с информацией о блокчейне
 type BlockchainInfoResponse struct {
-	Length     int    `json:"length"`
-	Difficulty int    `json:"difficulty"`
-	Valid      bool   `json:"valid"`
-	LastBlock  string `json:"last_block"`
+	Length         int    `json:"length"`
+	Difficulty     int    `json:"difficulty"`      // сложность следующего блока в hex-нулях, округлённая вниз
+	DifficultyBits int    `json:"difficulty_bits"` // она же в нулевых битах
+	Target         string `json:"target"`          // точная цель следующего блока
+	HashAlg        string `json:"hash_alg"`        // алгоритм хеша новых блоков и текстов
+	Valid          bool   `json:"valid"`
+	LastBlock      string `json:"last_block"`
+	GenesisHash    string `json:"genesis_hash"`   // хеш генезиса: по нему отличают экземпляры
+	GenesisPinned  bool   `json:"genesis_pinned"` // хеш генезиса закреплён в конфигурации
+}
+
+// Ответ с блоком цепочки
+type BlockResponse struct {
+	Height      int       `json:"height"`
+	Version     int       `json:"version"`  // версия заголовка, от которого считается хеш
+	HashAlg     string    `json:"hash_alg"` // алгоритм хеша блока
+	ID          string    `json:"id"`
+	Hash        string    `json:"hash"`
+	PrevHash    string    `json:"prev_hash"`
+	Timestamp   time.Time `json:"timestamp"`
+	Nonce       int       `json:"nonce"`
+	Author      string    `json:"author"`
+	Title       string    `json:"title"`
+	ContentHash string    `json:"content_hash"`
+	MerkleRoot  string    `json:"merkle_root,omitempty"`
+	Deposits    int       `json:"deposits"`
+	Difficulty  int       `json:"difficulty"`       // сложность в hex-нулях у блоков до целей
+	Target      string    `json:"target,omitempty"` // цель Proof-of-Work
 }
 
 // Общий ответ об ошибке
@@ -92,12 +186,15 @@ type FlashData struct {
 
 // DepositResponse для JSON API
 type DepositResponsePublic struct {
-	Success   bool      `json:"success"`
-	BlockID   string    `json:"block_id"`
-	Hash      string    `json:"hash"`
-	Timestamp time.Time `json:"timestamp"`
-	VerifyURL string    `json:"verify_url"`
-	QRCodeURL string    `json:"qr_code_url"`
+	Success    bool         `json:"success"`
+	BlockID    string       `json:"block_id"`
+	DepositRef string       `json:"deposit_ref"`
+	Hash       string       `json:"hash"`
+	HashAlg    string       `json:"hash_alg"`
+	Timestamp  time.Time    `json:"timestamp"`
+	VerifyURL  string       `json:"verify_url"`
+	QRCodeURL  string       `json:"qr_code_url"`
+	Proof      *MerkleProof `json:"proof,omitempty"`
 }
 
 // VerifyByIDRequest для JSON API
@@ -109,3 +206,154 @@ type VerifyByIDRequestPublic struct {
 type VerifyByTextRequestPublic struct {
 	ID string `json:"id"`
 }
+
+// Ответ с состоянием асинхронного задания на депонирование.
+// Block заполнен, когда депозит записан в цепочку
+type JobResponse struct {
+	ID         string         `json:"id"`
+	Status     string         `json:"status"` // queued, mining, committed, failed
+	StatusURL  string         `json:"status_url"`
+	DepositRef string         `json:"deposit_ref,omitempty"`
+	VerifyURL  string         `json:"verify_url,omitempty"`
+	Duplicate  bool           `json:"duplicate,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Block      *BlockResponse `json:"block,omitempty"`
+}
+
+// Работы, подписанные одним ключом автора, в порядке цепочки
+type KeyDepositsResponse struct {
+	Fingerprint string       `json:"fingerprint"`
+	KeyType     string       `json:"key_type"`
+	ProfileURL  string       `json:"profile_url"`
+	Count       int          `json:"count"`
+	Deposits    []KeyDeposit `json:"deposits"`
+
+	// Ротации: ключи, к которым перешло авторство (последний - текущий),
+	// и ключи, от которых оно перешло к этому (от ближайшего)
+	Successors   []string   `json:"successors,omitempty"`
+	Predecessors []string   `json:"predecessors,omitempty"`
+	Revoked      bool       `json:"revoked,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Автор работы с соавторами и его ключ
+type AuthorInfo struct {
+	Name           string `json:"name"`
+	Role           string `json:"role,omitempty"`
+	KeyType        string `json:"key_type,omitempty"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+	Signed         bool   `json:"signed"`
+	KeyRevoked     bool   `json:"key_revoked,omitempty"`
+}
+
+// Сбор подписей соавторов: работа ждёт подписей (pending) или
+// записана (committed)
+type CosignResponse struct {
+	ID             string       `json:"id"`
+	Status         string       `json:"status"`
+	SigningMessage string       `json:"signing_message"` // base64: сообщение, которое подписывает каждый соавтор
+	Authors        []AuthorInfo `json:"authors"`
+	Missing        int          `json:"missing"` // сколько подписей не хватает
+	CreatedAt      time.Time    `json:"created_at"`
+	BlockID        string       `json:"block_id,omitempty"`
+	DepositRef     string       `json:"deposit_ref,omitempty"`
+	VerifyURL      string       `json:"verify_url,omitempty"`
+}
+
+// Подпись соавтора
+type CosignRequest struct {
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// История версий работы
+type VersionsResponse struct {
+	DepositRef string        `json:"deposit_ref"`
+	Count      int           `json:"count"`
+	Versions   []WorkVersion `json:"versions"`
+}
+
+// Версия работы: депозит и ссылка на прежнюю версию
+type WorkVersion struct {
+	Version        int       `json:"version"` // номер с единицы в порядке цепочки
+	DepositRef     string    `json:"deposit_ref"`
+	ParentID       string    `json:"parent_id,omitempty"`
+	Author         string    `json:"author"`
+	Title          string    `json:"title"`
+	Timestamp      time.Time `json:"timestamp"`
+	Hash           string    `json:"hash"`
+	HashAlg        string    `json:"hash_alg"`
+	KeyFingerprint string    `json:"key_fingerprint,omitempty"`
+}
+
+// Депозит в списке работ ключа
+type KeyDeposit struct {
+	DepositRef string    `json:"deposit_ref"`
+	BlockID    string    `json:"block_id"`
+	Author     string    `json:"author"`
+	Title      string    `json:"title"`
+	Timestamp  time.Time `json:"timestamp"`
+	Hash       string    `json:"hash"`
+	HashAlg    string    `json:"hash_alg"`
+	VerifyURL  string    `json:"verify_url"`
+}
+
+// Запрос на служебную запись о ключе автора: ротацию (key_rotation,
+// подписана старым ключом) или отзыв (key_revocation, подписан
+// отзываемым ключом или его преемником)
+type KeyRecordRequest struct {
+	Type       string    `json:"type"`
+	PublicKey  string    `json:"public_key"` // Ключ подписи
+	NewKey     string    `json:"new_key,omitempty"`
+	RevokedKey string    `json:"revoked_key,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+	Signature  string    `json:"signature"`
+}
+
+// Ответ после записи служебной записи о ключе
+type KeyRecordResponse struct {
+	Success   bool      `json:"success"`
+	Type      string    `json:"type"`
+	BlockID   string    `json:"block_id"`
+	RecordRef string    `json:"record_ref"`
+	Hash      string    `json:"hash"`
+	HashAlg   string    `json:"hash_alg"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Отзыв или исправление депозита
+type DepositNotice struct {
+	Type      string    `json:"type"` // retraction или correction
+	Reason    string    `json:"reason"`
+	RecordRef string    `json:"record_ref"`
+	BlockID   string    `json:"block_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Запрос на отзыв (retraction) или исправление (correction) депозита,
+// подписанный ключом депозита или его преемником либо с токеном
+// учётной записи депозита
+type NoticeRequest struct {
+	Type      string `json:"type"`
+	Target    string `json:"target"` // ID блока или депозита
+	Reason    string `json:"reason"`
+	PublicKey string `json:"public_key,omitempty"` // Ключ подписи
+	Signature string `json:"signature,omitempty"`
+
+	// Токен учётной записи депозита: вместо ключа и подписи
+	AccountToken string `json:"account_token,omitempty"`
+}
+
+// Ответ после записи отзыва или исправления
+type NoticeResponse struct {
+	Success   bool      `json:"success"`
+	Type      string    `json:"type"`
+	Target    string    `json:"target"`
+	BlockID   string    `json:"block_id"`
+	RecordRef string    `json:"record_ref"`
+	Hash      string    `json:"hash"`
+	HashAlg   string    `json:"hash_alg"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -130,6 +130,21 @@ func TestBlockchainInfoResponse(t *testing.T) {
 	}
 }
 
+func TestBlockResponse(t *testing.T) {
+	resp := BlockResponse{
+		Height: 7,
+		ID:     "000-000-007",
+		Hash:   "00ab",
+	}
+
+	if resp.Height != 7 {
+		t.Error("Height incorrect")
+	}
+	if resp.ID != "000-000-007" {
+		t.Error("ID incorrect")
+	}
+}
+
 func TestErrorResponse(t *testing.T) {
 	resp := ErrorResponse{
 		Error:   "error message",
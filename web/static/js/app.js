@@ -15,16 +15,33 @@ function formatTimestamp(timestamp) {
     return date.toLocaleString('ru-RU');
 }
 
-// Calculate SHA-256 hash of a string (using Web Crypto API)
-async function calculateSHA256(text) {
+// Web Crypto names of content hash algorithms (ids as in the API's hash_alg).
+// SHA3-256 and BLAKE2b-256 are not available in Web Crypto
+const WEB_CRYPTO_ALGORITHMS = {
+    'sha256': 'SHA-256',
+};
+
+// Calculate content hash of a string with the given algorithm id.
+// Returns null if the browser cannot compute it: verification by text
+// on the server tries every algorithm in use anyway
+async function calculateContentHash(text, algorithm = 'sha256') {
+    const name = WEB_CRYPTO_ALGORITHMS[algorithm || 'sha256'];
+    if (!name) {
+        return null;
+    }
     const encoder = new TextEncoder();
     const data = encoder.encode(text);
-    const hashBuffer = await crypto.subtle.digest('SHA-256', data);
+    const hashBuffer = await crypto.subtle.digest(name, data);
     const hashArray = Array.from(new Uint8Array(hashBuffer));
     const hashHex = hashArray.map(b => b.toString(16).padStart(2, '0')).join('');
     return hashHex;
 }
 
+// Calculate SHA-256 hash of a string (using Web Crypto API)
+async function calculateSHA256(text) {
+    return calculateContentHash(text, 'sha256');
+}
+
 // Alpine.js data components
 document.addEventListener('alpine:init', () => {
     // Global store for deposit results
@@ -1,6 +1,6 @@
 // Code generated by templ - DO NOT EDIT.
 
-// templ: version: v0.3.977
+// templ: version: v0.3.960
 package templates
 
 //lint:file-ignore SA4006 This context is only used if a nested component is present.
@@ -73,7 +73,7 @@ func DepositContent() templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Простая форма --><div class=\"box\"><form id=\"deposit-form\" method=\"POST\" action=\"/api/deposit\"><!-- Автор --><div class=\"field\"><label class=\"label\">Автор (ФИО или псевдоним)</label><div class=\"control has-icons-left\"><input class=\"input\" type=\"text\" id=\"author\" name=\"author_name\" placeholder=\"Иванов Иван Иванович\" required> <span class=\"icon is-small is-left\"><i class=\"fas fa-user\"></i></span></div><p class=\"help\">Имя, под которым будет зафиксировано авторство</p></div><!-- Название --><div class=\"field\"><label class=\"label\">Название произведения</label><div class=\"control has-icons-left\"><input class=\"input\" type=\"text\" id=\"title\" name=\"title\" placeholder=\"Моя статья о блокчейне\" required> <span class=\"icon is-small is-left\"><i class=\"fas fa-heading\"></i></span></div><p class=\"help\">Краткое название или заголовок</p></div><!-- Текст --><div x-data=\"{ text: '' }\" class=\"field\"><label class=\"label\">Текст</label><div class=\"control\"><textarea class=\"textarea\" id=\"text\" name=\"text\" placeholder=\"Введите ваш текст здесь...\" rows=\"10\" required x-model=\"text\"></textarea></div><p class=\"help\">Длина текста в символах: <span x-text=\"text.length\"></span></p></div><!-- Публичный ключ (опционально) --><div class=\"field\"><label class=\"label\">Публичный ключ для подписи (опционально) <span class=\"tag is-light ml-2\">Необязательно</span></label><div class=\"control\"><textarea class=\"textarea\" id=\"public_key\" name=\"public_key\" placeholder=\"-----BEGIN PUBLIC KEY-----&#10;Ваш публичный ключ&#10;-----END PUBLIC KEY-----\" rows=\"4\"></textarea></div><p class=\"help\">Если хотите связать текст с вашей электронной подписью</p></div><!-- Важное примечание -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Простая форма --><div class=\"box\"><form id=\"deposit-form\" method=\"POST\" action=\"/api/deposit\"><!-- Автор --><div class=\"field\"><label class=\"label\">Автор (ФИО или псевдоним)</label><div class=\"control has-icons-left\"><input class=\"input\" type=\"text\" id=\"author\" name=\"author_name\" placeholder=\"Иванов Иван Иванович\" required> <span class=\"icon is-small is-left\"><i class=\"fas fa-user\"></i></span></div><p class=\"help\">Имя, под которым будет зафиксировано авторство</p></div><!-- Название --><div class=\"field\"><label class=\"label\">Название произведения</label><div class=\"control has-icons-left\"><input class=\"input\" type=\"text\" id=\"title\" name=\"title\" placeholder=\"Моя статья о блокчейне\" required> <span class=\"icon is-small is-left\"><i class=\"fas fa-heading\"></i></span></div><p class=\"help\">Краткое название или заголовок</p></div><!-- Текст --><div x-data=\"{ text: '' }\" class=\"field\"><label class=\"label\">Текст</label><div class=\"control\"><textarea class=\"textarea\" id=\"text\" name=\"text\" placeholder=\"Введите ваш текст здесь...\" rows=\"10\" required x-model=\"text\"></textarea></div><p class=\"help\">Длина текста в символах: <span x-text=\"text.length\"></span></p></div><!-- Публичный ключ (опционально) --><div class=\"field\"><label class=\"label\">Публичный ключ для подписи (опционально) <span class=\"tag is-light ml-2\">Необязательно</span></label><div class=\"control\"><textarea class=\"textarea\" id=\"public_key\" name=\"public_key\" placeholder=\"-----BEGIN PUBLIC KEY-----&#10;Ваш публичный ключ&#10;-----END PUBLIC KEY-----\" rows=\"4\"></textarea></div><p class=\"help\">Ed25519 или ECDSA P-256: PEM, DER в base64 или голый ключ Ed25519</p></div><!-- Подпись автора (опционально) --><div class=\"field\"><label class=\"label\">Подпись автора (опционально) <span class=\"tag is-light ml-2\">Необязательно</span></label><div class=\"control\"><textarea class=\"textarea is-family-monospace\" id=\"signature\" name=\"signature\" placeholder=\"Подпись в base64\" rows=\"2\"></textarea></div><p class=\"help\">Подпись хеша текста, имени автора и названия ключом выше. Без подписи ключ просто сохраняется в блоке и ничего не доказывает; с подписью проверка покажет отпечаток ключа</p></div><!-- Прежняя версия работы (опционально) --><div class=\"field\"><label class=\"label\">Новая версия работы <span class=\"tag is-light ml-2\">Необязательно</span></label><div class=\"control\"><input class=\"input is-family-monospace\" type=\"text\" id=\"parent_id\" name=\"parent_id\" placeholder=\"ID прежней версии\"></div><p class=\"help\">ID депозита прежней версии этого текста. Новую версию нужно подписать тем же ключом, что и прежнюю, или ключом, которому он передан, либо указать токен её учётной записи</p></div><!-- Учётная запись автора (опционально) --><div class=\"field\"><label class=\"label\">Токен учётной записи <span class=\"tag is-light ml-2\">Необязательно</span></label><div class=\"control\"><input class=\"input is-family-monospace\" type=\"password\" id=\"account_token\" name=\"account_token\" autocomplete=\"off\" minlength=\"16\" placeholder=\"Секретная строка не короче 16 символов\"></div><p class=\"help\">Придумайте и сохраните секретную строку: в блок попадёт только её хеш. С тем же токеном можно добавить новую версию работы, отозвать или исправить её без ключа подписи</p></div><!-- Важное примечание -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}